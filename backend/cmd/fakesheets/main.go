@@ -0,0 +1,82 @@
+// Command fakesheets serves an in-memory fake of the Google Sheets API so the
+// server and sheets commands can run offline. Point them at it with
+// GOOGLE_SHEETS_ENDPOINT and leave GOOGLE_CREDENTIALS_FILE unset.
+package main
+
+import (
+	_ "embed"
+	"encoding/csv"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/stevebennett/slack-invite-mgr/backend/internal/fakesheets"
+	"github.com/stevebennett/slack-invite-mgr/backend/internal/logger"
+)
+
+// sampleRows seeds the sheet when no seed file is given
+//
+//go:embed sample.csv
+var sampleRows string
+
+func main() {
+	log := logger.FromEnv("slack-invite-fakesheets")
+
+	addr := flag.String("addr", "localhost:8085", "address to listen on")
+	spreadsheetID := flag.String("spreadsheet-id", "fake-spreadsheet", "spreadsheet ID to serve")
+	sheetName := flag.String("sheet", "Sheet1", "name of the tab to create")
+	seed := flag.String("seed", "", "CSV file of rows, columns A-K, to load into the tab (default built-in sample rows)")
+	flag.Parse()
+
+	var in io.Reader = strings.NewReader(sampleRows)
+	if *seed != "" {
+		f, err := os.Open(*seed)
+		if err != nil {
+			log.Error("failed to open seed file", slog.String("error", err.Error()))
+			os.Exit(1)
+		}
+		defer f.Close()
+		in = f
+	}
+	rows, err := readRows(in)
+	if err != nil {
+		log.Error("failed to read seed rows", slog.String("error", err.Error()))
+		os.Exit(1)
+	}
+
+	server := fakesheets.New(*spreadsheetID)
+	server.AddSheet(*sheetName, rows)
+
+	log.Info("fake sheets server starting",
+		slog.String("endpoint", fmt.Sprintf("http://%s/", *addr)),
+		slog.String("spreadsheet_id", *spreadsheetID),
+		slog.String("sheet", *sheetName),
+		slog.Int("rows", len(rows)),
+	)
+	if err := http.ListenAndServe(*addr, server); err != nil {
+		log.Error("fake sheets server failed", slog.String("error", err.Error()))
+		os.Exit(1)
+	}
+}
+
+// readRows reads CSV rows as sheet values
+func readRows(r io.Reader) ([][]interface{}, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	rows := make([][]interface{}, len(records))
+	for i, record := range records {
+		rows[i] = make([]interface{}, len(record))
+		for j, value := range record {
+			rows[i][j] = value
+		}
+	}
+	return rows, nil
+}
@@ -1,6 +1,8 @@
 package main
 
 import (
+	"context"
+	"fmt"
 	"log/slog"
 	"net/http"
 	"os"
@@ -8,6 +10,7 @@ import (
 	"github.com/stevebennett/slack-invite-mgr/backend/internal/api"
 	"github.com/stevebennett/slack-invite-mgr/backend/internal/config"
 	"github.com/stevebennett/slack-invite-mgr/backend/internal/logger"
+	"github.com/stevebennett/slack-invite-mgr/backend/internal/services"
 )
 
 func main() {
@@ -21,19 +24,78 @@ func main() {
 		os.Exit(1)
 	}
 
-	// Initialize router
-	router := api.NewRouter(cfg, log)
-
-	// Get port from environment or use default
-	port := os.Getenv("PORT")
-	if port == "" {
-		port = "8080"
+	// Serve each tenant with its own router and services
+	ctx := context.Background()
+	var tenants []api.Tenant
+	for _, tenantCfg := range cfg.TenantConfigs() {
+		tenantLog := log
+		if tenantCfg.Name != "" {
+			tenantLog = log.With(slog.String("tenant", tenantCfg.Name))
+		}
+		router, err := newTenantRouter(ctx, tenantCfg, tenantLog)
+		if err != nil {
+			tenantLog.Error("failed to start tenant", slog.String("error", err.Error()))
+			os.Exit(1)
+		}
+		tenants = append(tenants, api.Tenant{Name: tenantCfg.Name, Hosts: tenantCfg.Hosts, Handler: router})
+	}
+	handler := tenants[0].Handler
+	if len(cfg.Tenants) > 0 {
+		handler = api.NewTenantRouter(tenants, log)
 	}
 
 	// Start server
-	log.Info("server starting", slog.String("port", port))
-	if err := http.ListenAndServe(":"+port, router); err != nil {
+	log.Info("server starting", slog.String("port", cfg.Port), slog.Any("tenants", cfg.TenantNames()))
+	if err := http.ListenAndServe(":"+cfg.Port, handler); err != nil {
 		log.Error("server failed to start", slog.String("error", err.Error()))
 		os.Exit(1)
 	}
 }
+
+// newTenantRouter starts the services of one tenant, such as its sheet
+// watcher and applicant emails, and returns its router
+func newTenantRouter(ctx context.Context, cfg *config.Config, log *slog.Logger) (http.Handler, error) {
+	// Create the event broker and watch the sheet for changes made elsewhere
+	events := services.NewEventBroker(config.DefaultEventHistorySize)
+	webhooks := services.NewWebhookDispatcher(cfg.Webhooks, services.NewDeliveryLog(cfg.WebhookDeliveryLog), log)
+	audit := services.NewAuditLog(cfg.AuditLogFile)
+
+	// Email applicants about decisions if any templates are configured
+	var mailer *services.DecisionMailer
+	var outbox *services.Outbox
+	if len(cfg.ApplicantEmails) > 0 {
+		emailService, err := services.LoadEmailService(nil, &config.SheetsConfig{SMTP: cfg.SMTPConfig()})
+		if err != nil {
+			return nil, fmt.Errorf("failed to configure applicant emails: %w", err)
+		}
+		outbox, err = services.OpenOutbox(cfg.EmailOutboxFile, cfg.EmailDedupeWindow)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open email outbox: %w", err)
+		}
+		emailService.UseOutbox(outbox)
+		worker := services.NewOutboxWorker(outbox, emailService, log)
+		worker.UseAuditLog(audit)
+		go worker.Run(ctx)
+		mailer, err = services.NewDecisionMailer(cfg.ApplicantEmails, emailService, audit, log)
+		if err != nil {
+			return nil, fmt.Errorf("failed to configure applicant emails: %w", err)
+		}
+	}
+	sheetsService, err := services.NewSheetsService(ctx, cfg.SheetsConfig())
+	if err != nil {
+		log.Warn("sheet watcher disabled", slog.String("error", err.Error()))
+	} else {
+		watcher := services.NewSheetWatcher(sheetsService, events, cfg.EventsPollInterval, log)
+		watcher.NotifyNewInvites(webhooks)
+		go watcher.Run(ctx)
+	}
+
+	// Initialize router
+	return api.NewRouter(cfg, log, api.Dependencies{
+		Events:   events,
+		Webhooks: webhooks,
+		Audit:    audit,
+		Mailer:   mailer,
+		Outbox:   outbox,
+	}), nil
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/stevebennett/slack-invite-mgr/backend/internal/config"
+	"github.com/stevebennett/slack-invite-mgr/backend/internal/services"
+)
+
+const archiveUsage = `Usage:
+  sheets archive [-days n] [-per-year] [-dry-run]
+`
+
+// defaultArchiveDays is how long processed invites stay on the sheet
+const defaultArchiveDays = 90
+
+// runArchive moves processed invites older than a number of days to the
+// archive tab. It returns the process exit code.
+func runArchive(args []string, log *slog.Logger) int {
+	flags := flag.NewFlagSet("archive", flag.ContinueOnError)
+	flags.Usage = func() { fmt.Fprint(os.Stderr, archiveUsage) }
+	days := flags.Int("days", defaultArchiveDays, "archive sent, denied and duplicate invites decided more than this many days ago")
+	perYear := flags.Bool("per-year", false, "archive to a tab per year, such as \"Archive 2024\", instead of a single tab")
+	dryRun := flags.Bool("dry-run", false, "count the invites that would be archived without moving them")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() != 0 || *days < 0 {
+		flags.Usage()
+		return 2
+	}
+
+	sheetsCfg, err := config.LoadSheetsConfig(tenant)
+	if err != nil {
+		log.Error("failed to load configuration", slog.String("error", err.Error()))
+		return 1
+	}
+	ctx := context.Background()
+	sheetsService, err := services.NewSheetsService(ctx, sheetsCfg)
+	if err != nil {
+		log.Error("failed to create sheets service", slog.String("error", err.Error()))
+		return 1
+	}
+
+	archived, err := sheetsService.ArchiveInvites(ctx, services.ArchiveOptions{
+		Before:  time.Now().AddDate(0, 0, -*days),
+		PerYear: *perYear,
+		DryRun:  *dryRun,
+	})
+	tabs := make([]string, 0, len(archived))
+	for tab := range archived {
+		tabs = append(tabs, tab)
+	}
+	sort.Strings(tabs)
+	for _, tab := range tabs {
+		log.Info("archived invites",
+			slog.String("tab", tab),
+			slog.Int("count", archived[tab]),
+			slog.Bool("dry_run", *dryRun),
+		)
+	}
+	if err != nil {
+		log.Error("failed to archive invites", slog.String("error", err.Error()))
+		return 1
+	}
+	if len(tabs) == 0 {
+		log.Info("no invites to archive", slog.Int("days", *days))
+	}
+	return 0
+}
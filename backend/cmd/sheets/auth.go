@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"time"
+
+	"github.com/stevebennett/slack-invite-mgr/backend/internal/config"
+	"github.com/stevebennett/slack-invite-mgr/backend/internal/googleauth"
+)
+
+const authUsage = `Usage:
+  sheets auth login [-timeout duration]
+`
+
+// runAuth manages OAuth user credentials. It returns the process exit code.
+func runAuth(args []string, log *slog.Logger) int {
+	if len(args) == 0 {
+		fmt.Fprint(os.Stderr, authUsage)
+		return 2
+	}
+
+	sheetsCfg, err := config.LoadSheetsConfig(tenant)
+	if err != nil {
+		log.Error("failed to load configuration", slog.String("error", err.Error()))
+		return 1
+	}
+
+	switch args[0] {
+	case "login":
+		return authLogin(sheetsCfg, args[1:], log)
+	default:
+		fmt.Fprintf(os.Stderr, "unknown auth command %q\n\n%s", args[0], authUsage)
+		return 2
+	}
+}
+
+// authLogin asks the user to consent in a browser and saves the resulting
+// token to GOOGLE_TOKEN_FILE, where the other commands and the server refresh
+// it as needed
+func authLogin(sheetsCfg *config.SheetsConfig, args []string, log *slog.Logger) int {
+	flags := flag.NewFlagSet("auth login", flag.ContinueOnError)
+	timeout := flags.Duration("timeout", 5*time.Minute, "how long to wait for consent")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() != 0 {
+		fmt.Fprint(os.Stderr, authUsage)
+		return 2
+	}
+	if sheetsCfg.TokenFile == "" {
+		fmt.Fprintln(os.Stderr, "GOOGLE_TOKEN_FILE is not set, so there is nowhere to save the token")
+		return 1
+	}
+	client, err := config.OAuthClientConfig(sheetsCfg)
+	if err != nil {
+		log.Error("failed to load OAuth client", slog.String("error", err.Error()))
+		return 1
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+	token, err := googleauth.Login(ctx, client, func(url string) error {
+		fmt.Fprintf(os.Stderr, "Open this URL in a browser on this machine and allow access:\n\n  %s\n\n", url)
+		return nil
+	})
+	if err != nil {
+		log.Error("failed to authorize", slog.String("error", err.Error()))
+		return 1
+	}
+	if err := googleauth.SaveToken(sheetsCfg.TokenFile, token); err != nil {
+		log.Error("failed to save token", slog.String("error", err.Error()))
+		return 1
+	}
+	log.Info("saved OAuth token", slog.String("file", sheetsCfg.TokenFile))
+	return 0
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+
+	"github.com/stevebennett/slack-invite-mgr/backend/internal/config"
+)
+
+const configUsage = `Usage:
+  sheets config validate [-file config.yaml]
+  sheets config print [-file config.yaml] [-reveal]
+`
+
+// runConfig checks and prints the configuration the server and the sheets
+// commands load. It returns the process exit code.
+func runConfig(args []string, log *slog.Logger) int {
+	if len(args) == 0 {
+		fmt.Fprint(os.Stderr, configUsage)
+		return 2
+	}
+	flags := flag.NewFlagSet("config "+args[0], flag.ContinueOnError)
+	flags.Usage = func() { fmt.Fprint(os.Stderr, configUsage) }
+	file := flags.String("file", os.Getenv("CONFIG_FILE"), "YAML config file (default CONFIG_FILE)")
+	reveal := flags.Bool("reveal", false, "print secret values rather than [redacted]")
+	if args[0] != "validate" && args[0] != "print" {
+		fmt.Fprintf(os.Stderr, "unknown config command %q\n\n%s", args[0], configUsage)
+		return 2
+	}
+	if err := flags.Parse(args[1:]); err != nil {
+		return 2
+	}
+	if flags.NArg() != 0 {
+		flags.Usage()
+		return 2
+	}
+
+	cfg, err := config.LoadFile(*file, os.Getenv)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Invalid configuration:")
+		for _, problem := range configProblems(err) {
+			fmt.Fprintf(os.Stderr, "  - %v\n", problem)
+		}
+		return 1
+	}
+
+	if args[0] == "validate" {
+		fmt.Println("Configuration is valid")
+		return 0
+	}
+	if err := cfg.WriteYAML(os.Stdout, !*reveal); err != nil {
+		log.Error("failed to print configuration", slog.String("error", err.Error()))
+		return 1
+	}
+	return 0
+}
+
+// configProblems flattens the joined errors LoadFile returns into one per line
+func configProblems(err error) []error {
+	joined, ok := err.(interface{ Unwrap() []error })
+	if !ok {
+		return []error{err}
+	}
+	var problems []error
+	for _, e := range joined.Unwrap() {
+		problems = append(problems, configProblems(e)...)
+	}
+	return problems
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/url"
+	"os"
+
+	"github.com/stevebennett/slack-invite-mgr/backend/internal/api"
+	"github.com/stevebennett/slack-invite-mgr/backend/internal/config"
+	"github.com/stevebennett/slack-invite-mgr/backend/internal/services"
+)
+
+const exportUsage = `Usage:
+  sheets export [-format csv|xlsx|json] [-status pending,sent,...|all] [-q text] [-source name]
+                [-submitted-after date] [-submitted-before date] [-archived] [-o file]
+`
+
+// runExport writes the invites matching the same filters as GET /api/invites
+// to stdout or a file. It returns the process exit code.
+func runExport(args []string, log *slog.Logger) int {
+	flags := flag.NewFlagSet("export", flag.ContinueOnError)
+	flags.Usage = func() { fmt.Fprint(os.Stderr, exportUsage) }
+	format := flags.String("format", services.ExportCSV, "output format: csv, xlsx or json")
+	status := flags.String("status", "", "comma-separated statuses to export, or all (default pending)")
+	search := flags.String("q", "", "only export invites whose name, company or reasons contain this text")
+	source := flags.String("source", "", "only export invites from this source")
+	after := flags.String("submitted-after", "", "only export invites submitted on or after this date")
+	before := flags.String("submitted-before", "", "only export invites submitted before this date")
+	archived := flags.Bool("archived", false, "also export invites moved to the archive tabs")
+	output := flags.String("o", "", "write to this file instead of stdout")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	if _, ok := services.ExportContentTypes[*format]; !ok {
+		fmt.Fprintf(os.Stderr, "unknown format %q\n", *format)
+		return 2
+	}
+	values := url.Values{}
+	for name, value := range map[string]string{
+		"status":           *status,
+		"q":                *search,
+		"source":           *source,
+		"submitted_after":  *after,
+		"submitted_before": *before,
+	} {
+		if value != "" {
+			values.Set(name, value)
+		}
+	}
+	if *archived {
+		values.Set("archived", "true")
+	}
+
+	sheetsCfg, err := config.LoadSheetsConfig(tenant)
+	if err != nil {
+		log.Error("failed to load configuration", slog.String("error", err.Error()))
+		return 1
+	}
+	query, err := api.ParseInviteQuery(values, sheetsCfg.Location)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "invalid filter: %v\n", err)
+		return 2
+	}
+	ctx := context.Background()
+	sheetsService, err := services.NewSheetsService(ctx, sheetsCfg)
+	if err != nil {
+		log.Error("failed to create sheets service", slog.String("error", err.Error()))
+		return 1
+	}
+
+	var out io.Writer = os.Stdout
+	if *output != "" {
+		f, err := os.Create(*output)
+		if err != nil {
+			log.Error("failed to create output file", slog.String("error", err.Error()))
+			return 1
+		}
+		defer f.Close()
+		out = f
+	}
+
+	writer, err := services.NewInviteWriter(out, *format)
+	if err != nil {
+		log.Error("failed to start export", slog.String("error", err.Error()))
+		return 1
+	}
+	exported := 0
+	write := func(row []interface{}, archived bool) error {
+		invite := services.InviteFromRow(row)
+		if !services.IsKnownStatus(invite.Status) || !query.Matches(invite) {
+			return nil // Skip the header row, hand-edited statuses and filtered rows
+		}
+		invite.Archived = archived
+		exported++
+		return writer.Write(invite)
+	}
+	err = sheetsService.EachRow(ctx, func(row []interface{}) error {
+		return write(row, false)
+	})
+	if err == nil && query.Archived {
+		var rows [][]interface{}
+		if rows, err = sheetsService.GetArchivedSheetData(ctx); err == nil {
+			for _, row := range rows {
+				if err = write(row, true); err != nil {
+					break
+				}
+			}
+		}
+	}
+	if err == nil {
+		err = writer.Close()
+	}
+	if err != nil {
+		log.Error("failed to export invites", slog.String("error", err.Error()))
+		if *output != "" {
+			os.Remove(*output)
+		}
+		return 1
+	}
+
+	log.Info("exported invites", slog.String("format", *format), slog.Int("count", exported))
+	return 0
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+
+	"github.com/stevebennett/slack-invite-mgr/backend/internal/config"
+	"github.com/stevebennett/slack-invite-mgr/backend/internal/services"
+)
+
+const formatUsage = `Usage:
+  sheets format [-admins email,...]
+`
+
+// runFormat applies status colours, a frozen header, a status dropdown and
+// protection of columns J and K to the sheet. It returns the process exit code.
+func runFormat(args []string, log *slog.Logger) int {
+	flags := flag.NewFlagSet("format", flag.ContinueOnError)
+	flags.Usage = func() { fmt.Fprint(os.Stderr, formatUsage) }
+	admins := flags.String("admins", "", "comma-separated emails that may edit columns J and K (default SHEET_ADMIN_EMAILS)")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() != 0 {
+		flags.Usage()
+		return 2
+	}
+
+	sheetsCfg, err := config.LoadSheetsConfig(tenant)
+	if err != nil {
+		log.Error("failed to load configuration", slog.String("error", err.Error()))
+		return 1
+	}
+
+	editors := sheetsCfg.SheetAdmins
+	if *admins != "" {
+		editors = nil
+		for _, admin := range strings.Split(*admins, ",") {
+			if admin = strings.TrimSpace(admin); admin != "" {
+				editors = append(editors, admin)
+			}
+		}
+	}
+
+	// The account the tool signs in as must stay an editor or it could no
+	// longer update statuses. If it cannot be found, the admins must be given
+	// explicitly, including that account.
+	ctx := context.Background()
+	account, err := config.AuthenticatedEmail(ctx, sheetsCfg)
+	if err != nil {
+		log.Warn("failed to find the signed-in account", slog.String("error", err.Error()))
+	}
+	switch {
+	case account != "":
+		editors = append([]string{account}, editors...)
+	case *admins == "":
+		log.Error("could not find the account the sheets tool signs in as; pass -admins with its email and the other admins so it can still edit columns J and K")
+		return 1
+	default:
+		log.Warn("could not find the account the sheets tool signs in as; it must be one of -admins to keep editing columns J and K")
+	}
+	sheetsService, err := services.NewSheetsService(ctx, sheetsCfg)
+	if err != nil {
+		log.Error("failed to create sheets service", slog.String("error", err.Error()))
+		return 1
+	}
+	if err := sheetsService.ApplyFormatting(ctx, services.SheetFormatting{Editors: editors}); err != nil {
+		log.Error("failed to format sheet", slog.String("error", err.Error()))
+		return 1
+	}
+	log.Info("formatted sheet", slog.String("sheet", sheetsCfg.SheetName), slog.Int("editors", len(editors)))
+	return 0
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/stevebennett/slack-invite-mgr/backend/internal/api"
+	"github.com/stevebennett/slack-invite-mgr/backend/internal/config"
+	"github.com/stevebennett/slack-invite-mgr/backend/internal/services"
+)
+
+const importUsage = `Usage:
+  sheets import [-source name] [-dry-run] [-json] file.csv|-
+`
+
+// runImport appends the applications in a CSV file to the sheet as pending
+// invites and prints a per-row report. It returns the process exit code.
+func runImport(args []string, log *slog.Logger) int {
+	flags := flag.NewFlagSet("import", flag.ContinueOnError)
+	flags.Usage = func() { fmt.Fprint(os.Stderr, importUsage) }
+	source := flags.String("source", "", "tag every imported row with this source (default the file's source column, or import)")
+	dryRun := flags.Bool("dry-run", false, "report what would be imported without writing to the sheet")
+	asJSON := flags.Bool("json", false, "print the report as JSON")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return 2
+	}
+
+	var in io.Reader = os.Stdin
+	if path := flags.Arg(0); path != "-" {
+		f, err := os.Open(path)
+		if err != nil {
+			log.Error("failed to open import file", slog.String("error", err.Error()))
+			return 1
+		}
+		defer f.Close()
+		in = f
+	}
+	rows, err := api.ReadImportFile(in)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "invalid import file: %v\n", err)
+		return 2
+	}
+
+	sheetsCfg, err := config.LoadSheetsConfig(tenant)
+	if err != nil {
+		log.Error("failed to load configuration", slog.String("error", err.Error()))
+		return 1
+	}
+	ctx := context.Background()
+	sheetsService, err := services.NewSheetsService(ctx, sheetsCfg)
+	if err != nil {
+		log.Error("failed to create sheets service", slog.String("error", err.Error()))
+		return 1
+	}
+
+	report, err := api.ImportInvites(ctx, sheetsService, rows, api.ImportOptions{
+		Source: strings.TrimSpace(*source),
+		DryRun: *dryRun,
+		Now:    time.Now().In(sheetsCfg.Location),
+	})
+	if err != nil {
+		log.Error("failed to import invites", slog.String("error", err.Error()))
+		return 1
+	}
+
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(report); err != nil {
+			return 1
+		}
+		return 0
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "LINE\tEMAIL\tRESULT\tDETAILS")
+	for _, row := range report.Rows {
+		var details []string
+		switch {
+		case row.ExistingRow > 0:
+			details = append(details, fmt.Sprintf("already in sheet row %d", row.ExistingRow))
+		case row.Archived:
+			details = append(details, "already in the archive")
+		case row.DuplicateOfLine > 0:
+			details = append(details, fmt.Sprintf("same as line %d", row.DuplicateOfLine))
+		}
+		for _, fieldErr := range row.Errors {
+			details = append(details, fieldErr.Error())
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\n", row.Line, row.Email, row.Result, strings.Join(details, "; "))
+	}
+	w.Flush()
+
+	verb := "imported"
+	if report.DryRun {
+		verb = "would import"
+	}
+	fmt.Printf("\n%s %d, skipped %d duplicates and %d invalid rows\n", verb, report.New, report.Duplicates, report.Invalid)
+	return 0
+}
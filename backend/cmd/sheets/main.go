@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"log/slog"
 	"os"
+	"strings"
 	"time"
 
 	"github.com/stevebennett/slack-invite-mgr/backend/internal/config"
@@ -12,49 +13,204 @@ import (
 	"github.com/stevebennett/slack-invite-mgr/backend/internal/services"
 )
 
+// outboxDrainTimeout bounds how long a sync run waits to deliver queued email
+// before exiting; anything still pending is retried on the next run
+const outboxDrainTimeout = 2 * time.Minute
+
+const usage = `Usage: sheets [-tenant name] [command]
+
+Commands:
+  sync      Mark duplicates and email a summary of new invites (default)
+  report    Print or email funnel statistics
+  export    Export invites as CSV, XLSX or JSON
+  import    Import applications from a CSV file
+  outbox    Inspect and retry queued email
+  format    Colour, validate and protect the status columns of the sheet
+  archive   Move old processed invites to the archive tab
+  retention Redact personal data of old processed invites
+  auth      Sign in with a Google account for OAuth user credentials
+  secrets   Create and inspect the encrypted secrets file
+  config    Validate or print the configuration
+
+With tenants configured, -tenant or TENANT chooses the tenant a command works
+on; sync runs for every tenant when none is chosen.
+`
+
+// tenant is the tenant chosen with -tenant or TENANT, or empty for the only one
+var tenant = os.Getenv("TENANT")
+
 func main() {
 	// Initialize logger
 	log := logger.FromEnv("slack-invite-sheets")
 
-	// Load configuration
-	sheetsCfg := config.LoadSheetsConfig()
+	args := os.Args[1:]
+	if len(args) > 0 {
+		if name, ok := strings.CutPrefix(args[0], "-tenant="); ok {
+			tenant, args = name, args[1:]
+		} else if args[0] == "-tenant" && len(args) > 1 {
+			tenant, args = args[1], args[2:]
+		}
+	}
+	command := "sync"
+	if len(args) > 0 {
+		command, args = args[0], args[1:]
+	}
 
-	// Validate required configuration
-	if sheetsCfg.CredentialsFile == "" {
-		log.Error("missing required configuration", slog.String("field", "GOOGLE_CREDENTIALS_FILE"))
-		os.Exit(1)
+	// Commands that print data to stdout log to stderr so the two never mix
+	if command == "report" || command == "export" || command == "import" || command == "secrets" {
+		log = logger.New(logger.Config{
+			Level:   logger.ParseLevel(os.Getenv("LOG_LEVEL")),
+			AppName: "slack-invite-sheets",
+			Output:  os.Stderr,
+		})
 	}
-	if sheetsCfg.SpreadsheetID == "" {
-		log.Error("missing required configuration", slog.String("field", "GOOGLE_SPREADSHEET_ID"))
-		os.Exit(1)
+
+	switch command {
+	case "sync":
+		os.Exit(runSync(log))
+	case "report":
+		os.Exit(runReport(args, log))
+	case "export":
+		os.Exit(runExport(args, log))
+	case "import":
+		os.Exit(runImport(args, log))
+	case "outbox":
+		os.Exit(runOutbox(args, log))
+	case "format":
+		os.Exit(runFormat(args, log))
+	case "archive":
+		os.Exit(runArchive(args, log))
+	case "retention":
+		os.Exit(runRetention(args, log))
+	case "auth":
+		os.Exit(runAuth(args, log))
+	case "secrets":
+		os.Exit(runSecrets(args, log))
+	case "config":
+		os.Exit(runConfig(args, log))
+	case "help", "-h", "-help", "--help":
+		fmt.Print(usage)
+	default:
+		fmt.Fprintf(os.Stderr, "unknown command %q\n\n%s", command, usage)
+		os.Exit(2)
 	}
-	if sheetsCfg.SheetName == "" {
-		log.Error("missing required configuration", slog.String("field", "GOOGLE_SHEET_NAME"))
-		os.Exit(1)
+}
+
+// runSync syncs the chosen tenant, or every tenant when none is chosen. It
+// returns the process exit code, which is 1 if any tenant failed.
+func runSync(log *slog.Logger) int {
+	// Load configuration
+	cfg, err := config.Load()
+	if err != nil {
+		log.Error("failed to load configuration", slog.String("error", err.Error()))
+		return 1
+	}
+	tenants := cfg.TenantConfigs()
+	if tenant != "" {
+		chosen, err := cfg.Tenant(tenant)
+		if err != nil {
+			log.Error("failed to load configuration", slog.String("error", err.Error()))
+			return 1
+		}
+		tenants = []*config.Config{chosen}
 	}
 
+	code := 0
+	for _, tenantCfg := range tenants {
+		tenantLog := log
+		if tenantCfg.Name != "" {
+			tenantLog = log.With(slog.String("tenant", tenantCfg.Name))
+		}
+		code = max(code, syncTenant(tenantCfg.SheetsConfig(), tenantLog))
+	}
+	return code
+}
+
+// syncTenant marks duplicate requests, notifies webhooks and emails a
+// summary of new invites for one tenant. It returns the process exit code.
+func syncTenant(sheetsCfg *config.SheetsConfig, log *slog.Logger) int {
 	// Create context
 	ctx := context.Background()
 
+	// Queue email in the outbox and deliver it before exiting, retrying
+	// failures with backoff
+	outbox, err := services.OpenOutbox(sheetsCfg.EmailOutboxFile, sheetsCfg.EmailDedupeWindow)
+	if err != nil {
+		log.Error("failed to open email outbox", slog.String("error", err.Error()))
+		return 1
+	}
+	routing, err := config.LoadEmailRouting(sheetsCfg.EmailRoutingFile, sheetsCfg.EmailRecipient)
+	if err != nil {
+		log.Error("failed to load email routing", slog.String("error", err.Error()))
+		return 1
+	}
+	events := []string{config.EmailEventErrors, config.EmailEventNewApplications}
+	if len(sheetsCfg.StaleThresholds) > 0 {
+		events = append(events, config.EmailEventStaleReminder, config.EmailEventStaleEscalation)
+	}
+	for _, event := range events {
+		if _, _, _, ok := routing.Route(event); !ok {
+			log.Error("no email recipients configured",
+				slog.String("event", event),
+				slog.String("field", "EMAIL_RECIPIENT"),
+			)
+			return 1
+		}
+	}
+	emailService, err := services.LoadEmailService(routing, sheetsCfg)
+	if err != nil {
+		log.Error("failed to create email service", slog.String("error", err.Error()))
+		return 1
+	}
+	emailService.UseOutbox(outbox)
+	defer func() {
+		drainCtx, cancel := context.WithTimeout(ctx, outboxDrainTimeout)
+		defer cancel()
+		services.NewOutboxWorker(outbox, emailService, log).Drain(drainCtx)
+		if pending := len(outbox.List(services.OutboxPending)); pending > 0 {
+			log.Warn("queued email not yet delivered", slog.Int("pending", pending))
+		}
+	}()
+
 	// Create sheets service
 	log.Info("creating sheets service")
 	sheetsService, err := services.NewSheetsService(ctx, sheetsCfg)
 	if err != nil {
 		log.Error("failed to create sheets service", slog.String("error", err.Error()))
-		os.Exit(1)
+		return 1
 	}
 
+	webhooks := services.NewWebhookDispatcher(sheetsCfg.Webhooks, services.NewDeliveryLog(sheetsCfg.WebhookDeliveryLog), log)
+
 	// Update duplicate requests
 	log.Info("updating duplicate requests")
-	timestamp := time.Now().Format("2006-01-02 15:04:05")
-	if err := sheetsService.UpdateDuplicateRequests(ctx, timestamp); err != nil {
-		log.Error("failed to update duplicate requests", slog.String("error", err.Error()))
+	timestamp := time.Now().In(sheetsCfg.Location).Format("2006-01-02 15:04:05")
+	marked, markErr := sheetsService.UpdateDuplicateRequests(ctx, timestamp)
+
+	// Notify webhooks about the duplicates we marked, including any marked
+	// before a failed batch
+	for _, result := range marked {
+		event := services.InviteEvent{
+			Type:           services.EventDuplicateMarked,
+			Email:          result.Email,
+			Status:         services.StatusDuplicate,
+			PreviousStatus: result.PreviousStatus,
+			Invite:         result.Invite,
+		}
+		if result.Invite != nil {
+			event.SubmittedAt = result.Invite.SubmittedAt
+		}
+		webhooks.Dispatch(event)
+	}
+	webhooks.Wait()
+
+	if markErr != nil {
+		log.Error("failed to update duplicate requests", slog.String("error", markErr.Error()), slog.Int("marked", len(marked)))
 		// Send error email
-		emailService := services.NewEmailService(sheetsCfg.EmailRecipient, sheetsCfg.EmailTemplate)
-		if emailErr := emailService.SendEmail(ctx, "Error Updating Duplicate Requests", fmt.Sprintf("Error: %v", err)); emailErr != nil {
+		if emailErr := emailService.SendEmail(ctx, config.EmailEventErrors, "Error Updating Duplicate Requests", fmt.Sprintf("Error: %v", markErr)); emailErr != nil {
 			log.Error("failed to send error email", slog.String("error", emailErr.Error()))
 		}
-		os.Exit(1)
+		return 1
 	}
 
 	// Get new invites count
@@ -63,28 +219,39 @@ func main() {
 	if err != nil {
 		log.Error("failed to get new invites", slog.String("error", err.Error()))
 		// Send error email
-		emailService := services.NewEmailService(sheetsCfg.EmailRecipient, sheetsCfg.EmailTemplate)
-		if emailErr := emailService.SendEmail(ctx, "Error Retrieving New Invites", fmt.Sprintf("Error: %v", err)); emailErr != nil {
+		if emailErr := emailService.SendEmail(ctx, config.EmailEventErrors, "Error Retrieving New Invites", fmt.Sprintf("Error: %v", err)); emailErr != nil {
 			log.Error("failed to send error email", slog.String("error", emailErr.Error()))
 		}
-		os.Exit(1)
+		return 1
 	}
 
 	// Send success email if there are new invites
 	if newInvites > 0 {
 		log.Info("sending notification email", slog.Int("new_invites", newInvites))
-		emailService := services.NewEmailService(sheetsCfg.EmailRecipient, sheetsCfg.EmailTemplate)
-		if err := emailService.SendEmail(ctx, "New Invites Need Processing", fmt.Sprintf("There are %d new invites that need processing.", newInvites)); err != nil {
+		if err := emailService.SendEmail(ctx, config.EmailEventNewApplications, "New Invites Need Processing", fmt.Sprintf("There are %d new invites that need processing.", newInvites)); err != nil {
 			log.Error("failed to send success email", slog.String("error", err.Error()))
 		}
 	}
 
+	// Remind reviewers about invites that have been pending too long
+	if len(sheetsCfg.StaleThresholds) > 0 {
+		log.Info("checking for stale invites", slog.Any("threshold_days", sheetsCfg.StaleThresholds))
+		if err := remindStaleInvites(ctx, sheetsService, sheetsCfg, emailService, log); err != nil {
+			log.Error("failed to send stale invite reminders", slog.String("error", err.Error()))
+			// Send error email
+			if emailErr := emailService.SendEmail(ctx, config.EmailEventErrors, "Error Sending Stale Invite Reminders", fmt.Sprintf("Error: %v", err)); emailErr != nil {
+				log.Error("failed to send error email", slog.String("error", emailErr.Error()))
+			}
+			return 1
+		}
+	}
+
 	// Get updated sheet data to count duplicates
 	log.Debug("retrieving updated sheet data")
 	updatedData, err := sheetsService.GetSheetData(ctx)
 	if err != nil {
 		log.Error("failed to get updated sheet data", slog.String("error", err.Error()))
-		os.Exit(1)
+		return 1
 	}
 
 	// Count duplicates
@@ -100,4 +267,21 @@ func main() {
 		slog.Int("new_invites", newInvites),
 		slog.Int("duplicates_found", duplicateCount),
 	)
+	return 0
+}
+
+// remindStaleInvites emails reminders about invites pending past the
+// configured thresholds
+func remindStaleInvites(ctx context.Context, sheetsService services.SheetsServiceInterface, cfg *config.SheetsConfig, sender services.NotificationSender, log *slog.Logger) error {
+	rows, err := sheetsService.GetAllSheetData(ctx)
+	if err != nil {
+		return err
+	}
+	reminder := services.NewStaleInviteReminder(cfg.StaleThresholds, cfg.Location, services.NewReminderLog(cfg.ReminderLogFile), sender, log)
+	reminded, err := reminder.Run(ctx, rows)
+	if err != nil {
+		return err
+	}
+	log.Info("stale invite reminders sent", slog.Int("invites", reminded))
+	return nil
 }
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/stevebennett/slack-invite-mgr/backend/internal/config"
+	"github.com/stevebennett/slack-invite-mgr/backend/internal/services"
+)
+
+const outboxUsage = `Usage:
+  sheets outbox list [-status pending|failed|sent|all] [-json]
+  sheets outbox retry <message-id>...
+`
+
+// runOutbox inspects the email outbox. It returns the process exit code.
+func runOutbox(args []string, log *slog.Logger) int {
+	if len(args) == 0 {
+		fmt.Fprint(os.Stderr, outboxUsage)
+		return 2
+	}
+
+	sheetsCfg, err := config.LoadSheetsConfig(tenant)
+	if err != nil {
+		log.Error("failed to load configuration", slog.String("error", err.Error()))
+		return 1
+	}
+	if sheetsCfg.EmailOutboxFile == "" {
+		fmt.Fprintln(os.Stderr, "SHEETS_EMAIL_OUTBOX_FILE is not set, so there is no outbox to inspect")
+		return 1
+	}
+	outbox, err := services.OpenOutbox(sheetsCfg.EmailOutboxFile, sheetsCfg.EmailDedupeWindow)
+	if err != nil {
+		log.Error("failed to open email outbox", slog.String("error", err.Error()))
+		return 1
+	}
+
+	switch args[0] {
+	case "list":
+		return listOutbox(outbox, args[1:])
+	case "retry":
+		return retryOutbox(outbox, args[1:])
+	default:
+		fmt.Fprintf(os.Stderr, "unknown outbox command %q\n\n%s", args[0], outboxUsage)
+		return 2
+	}
+}
+
+// listOutbox prints queued messages, pending and failed ones by default
+func listOutbox(outbox *services.Outbox, args []string) int {
+	flags := flag.NewFlagSet("outbox list", flag.ContinueOnError)
+	status := flags.String("status", "", "only list messages with this status: pending, failed, sent or all")
+	asJSON := flags.Bool("json", false, "print messages as JSON")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	var messages []services.OutboxMessage
+	switch *status {
+	case "":
+		messages = append(outbox.List(services.OutboxPending), outbox.List(services.OutboxFailed)...)
+	case "all":
+		messages = outbox.List("")
+	case services.OutboxPending, services.OutboxFailed, services.OutboxSent:
+		messages = outbox.List(*status)
+	default:
+		fmt.Fprintf(os.Stderr, "unknown status %q\n", *status)
+		return 2
+	}
+
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(messages); err != nil {
+			return 1
+		}
+		return 0
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tSTATUS\tATTEMPTS\tCREATED\tNEXT ATTEMPT\tTO\tSUBJECT\tLAST ERROR")
+	for _, msg := range messages {
+		next := "-"
+		if msg.Status == services.OutboxPending {
+			next = msg.NextAttemptAt.Format(time.RFC3339)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%d\t%s\t%s\t%s\t%s\t%s\n",
+			msg.ID, msg.Status, msg.Attempts, msg.CreatedAt.Format(time.RFC3339), next, strings.Join(msg.All(), ", "), msg.Subject, msg.LastError)
+	}
+	w.Flush()
+	return 0
+}
+
+// retryOutbox moves failed messages back to pending for the next run
+func retryOutbox(outbox *services.Outbox, ids []string) int {
+	if len(ids) == 0 {
+		fmt.Fprint(os.Stderr, outboxUsage)
+		return 2
+	}
+
+	code := 0
+	for _, id := range ids {
+		if err := outbox.Retry(id); err != nil {
+			if errors.Is(err, services.ErrOutboxMessageNotFound) {
+				fmt.Fprintf(os.Stderr, "%s: not found\n", id)
+			} else {
+				fmt.Fprintf(os.Stderr, "%s: %v\n", id, err)
+			}
+			code = 1
+			continue
+		}
+		fmt.Printf("%s: queued for retry\n", id)
+	}
+	return code
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"time"
+
+	"github.com/stevebennett/slack-invite-mgr/backend/internal/config"
+	"github.com/stevebennett/slack-invite-mgr/backend/internal/services"
+)
+
+const reportUsage = `Usage:
+  sheets report [-period week|month] [-since YYYY-MM-DD] [-until YYYY-MM-DD] [-format json|csv|html] [-send]
+`
+
+// runReport prints funnel statistics or emails them to the weekly_report
+// recipients. It returns the process exit code.
+func runReport(args []string, log *slog.Logger) int {
+	flags := flag.NewFlagSet("report", flag.ContinueOnError)
+	flags.Usage = func() { fmt.Fprint(os.Stderr, reportUsage) }
+	period := flags.String("period", services.PeriodMonth, "group invites by week or month")
+	since := flags.String("since", "", "only include invites submitted on or after this date")
+	until := flags.String("until", "", "only include invites submitted before this date")
+	format := flags.String("format", "json", "output format: json, csv or html")
+	send := flags.Bool("send", false, "email the HTML report to the weekly_report recipients instead of printing it")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	if *period != services.PeriodWeek && *period != services.PeriodMonth {
+		fmt.Fprintf(os.Stderr, "unknown period %q\n", *period)
+		return 2
+	}
+	if *format != "json" && *format != "csv" && *format != "html" {
+		fmt.Fprintf(os.Stderr, "unknown format %q\n", *format)
+		return 2
+	}
+
+	sheetsCfg, err := config.LoadSheetsConfig(tenant)
+	if err != nil {
+		log.Error("failed to load configuration", slog.String("error", err.Error()))
+		return 1
+	}
+
+	// Dates are midnight in the sheet's time zone, as the timestamps are
+	opts := services.ReportOptions{Period: *period, Location: sheetsCfg.Location}
+	if opts.SubmittedAfter, err = parseReportDate(*since, sheetsCfg.Location); err != nil {
+		fmt.Fprintf(os.Stderr, "invalid -since: %v\n", err)
+		return 2
+	}
+	if opts.SubmittedBefore, err = parseReportDate(*until, sheetsCfg.Location); err != nil {
+		fmt.Fprintf(os.Stderr, "invalid -until: %v\n", err)
+		return 2
+	}
+
+	ctx := context.Background()
+	sheetsService, err := services.NewSheetsService(ctx, sheetsCfg)
+	if err != nil {
+		log.Error("failed to create sheets service", slog.String("error", err.Error()))
+		return 1
+	}
+	rows, err := services.InviteHistory(ctx, sheetsService)
+	if err != nil {
+		log.Error("failed to get sheet data", slog.String("error", err.Error()))
+		return 1
+	}
+	report, err := services.BuildReport(services.InvitesFromRows(rows), opts, time.Now().UTC())
+	if err != nil {
+		log.Error("failed to build report", slog.String("error", err.Error()))
+		return 1
+	}
+
+	if *send {
+		return sendReport(ctx, sheetsCfg, report, log)
+	}
+
+	switch *format {
+	case "csv":
+		err = services.WriteReportCSV(os.Stdout, report)
+	case "html":
+		var body, template string
+		if body, err = services.RenderReportHTML(report); err == nil {
+			if template, err = services.LoadEmailTemplate(sheetsCfg.EmailTemplate, sheetsCfg.DashboardURL); err == nil {
+				_, err = fmt.Println(services.ApplyEmailTemplate(template, body))
+			}
+		}
+	default:
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		err = enc.Encode(report)
+	}
+	if err != nil {
+		log.Error("failed to write report", slog.String("error", err.Error()))
+		return 1
+	}
+	return 0
+}
+
+// sendReport emails the report through the outbox and waits for it to be delivered
+func sendReport(ctx context.Context, cfg *config.SheetsConfig, report services.Report, log *slog.Logger) int {
+	routing, err := config.LoadEmailRouting(cfg.EmailRoutingFile, cfg.EmailRecipient)
+	if err != nil {
+		log.Error("failed to load email routing", slog.String("error", err.Error()))
+		return 1
+	}
+	emailService, err := services.LoadEmailService(routing, cfg)
+	if err != nil {
+		log.Error("failed to create email service", slog.String("error", err.Error()))
+		return 1
+	}
+	outbox, err := services.OpenOutbox(cfg.EmailOutboxFile, cfg.EmailDedupeWindow)
+	if err != nil {
+		log.Error("failed to open email outbox", slog.String("error", err.Error()))
+		return 1
+	}
+	emailService.UseOutbox(outbox)
+
+	body, err := services.RenderReportHTML(report)
+	if err != nil {
+		log.Error("failed to render report", slog.String("error", err.Error()))
+		return 1
+	}
+	subject := fmt.Sprintf("Invite report: %d received, %d sent", report.Totals.Received, report.Totals.Sent)
+	if err := emailService.SendEmail(ctx, config.EmailEventWeeklyReport, subject, body); err != nil {
+		log.Error("failed to send report", slog.String("error", err.Error()))
+		return 1
+	}
+
+	drainCtx, cancel := context.WithTimeout(ctx, outboxDrainTimeout)
+	defer cancel()
+	services.NewOutboxWorker(outbox, emailService, log).Drain(drainCtx)
+	if pending := len(outbox.List(services.OutboxPending)); pending > 0 {
+		log.Warn("report queued but not yet delivered", slog.Int("pending", pending))
+		return 1
+	}
+	log.Info("report sent")
+	return 0
+}
+
+// parseReportDate parses an optional YYYY-MM-DD date as midnight in loc
+func parseReportDate(value string, loc *time.Location) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	return time.ParseInLocation("2006-01-02", value, loc)
+}
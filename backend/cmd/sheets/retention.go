@@ -0,0 +1,124 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/stevebennett/slack-invite-mgr/backend/internal/config"
+	"github.com/stevebennett/slack-invite-mgr/backend/internal/services"
+)
+
+const retentionUsage = `Usage:
+  sheets retention [-days n] [-dry-run]
+`
+
+// runRetention redacts the personal data of processed invites decided longer
+// ago than the retention period, and of the audit, outbox, webhook delivery and
+// reminder records written before then. It returns the process exit code.
+func runRetention(args []string, log *slog.Logger) int {
+	sheetsCfg, err := config.LoadSheetsConfig(tenant)
+	if err != nil {
+		log.Error("failed to load configuration", slog.String("error", err.Error()))
+		return 1
+	}
+
+	flags := flag.NewFlagSet("retention", flag.ContinueOnError)
+	flags.Usage = func() { fmt.Fprint(os.Stderr, retentionUsage) }
+	days := flags.Int("days", sheetsCfg.RetentionDays, "redact sent, denied and duplicate invites decided more than this many days ago (default RETENTION_DAYS)")
+	dryRun := flags.Bool("dry-run", false, "count the invites that would be redacted without changing them")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() != 0 {
+		flags.Usage()
+		return 2
+	}
+	if *days <= 0 {
+		fmt.Fprintln(os.Stderr, "set a retention period with -days or RETENTION_DAYS")
+		return 2
+	}
+
+	ctx := context.Background()
+	sheetsService, err := services.NewSheetsService(ctx, sheetsCfg)
+	if err != nil {
+		log.Error("failed to create sheets service", slog.String("error", err.Error()))
+		return 1
+	}
+
+	cutoff := time.Now().AddDate(0, 0, -*days)
+	redacted, err := sheetsService.RedactInvites(ctx, cutoff, *dryRun)
+	if err != nil {
+		log.Error("failed to redact invites", slog.String("error", err.Error()))
+		return 1
+	}
+	tabs := make([]string, 0, len(redacted))
+	for tab := range redacted {
+		tabs = append(tabs, tab)
+	}
+	sort.Strings(tabs)
+	for _, tab := range tabs {
+		log.Info("redacted invites",
+			slog.String("tab", tab),
+			slog.Int("count", redacted[tab]),
+			slog.Bool("dry_run", *dryRun),
+		)
+	}
+	if len(tabs) == 0 {
+		log.Info("no invites to redact", slog.Int("days", *days))
+	}
+
+	stores, err := retentionStores(sheetsCfg)
+	if err != nil {
+		log.Error("failed to open email outbox", slog.String("error", err.Error()))
+		return 1
+	}
+	records, err := stores.Redact(cutoff, *dryRun)
+	if err != nil {
+		log.Error("failed to redact records", slog.String("error", err.Error()))
+		return 1
+	}
+	names := make([]string, 0, len(records))
+	for name := range records {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		log.Info("redacted records",
+			slog.String("store", name),
+			slog.Int("count", records[name]),
+			slog.Bool("dry_run", *dryRun),
+		)
+	}
+	return 0
+}
+
+// retentionStores opens the file-backed stores that can hold applicant
+// emails; stores kept only in memory have nothing to redact here
+func retentionStores(cfg *config.SheetsConfig) (services.PersonalDataStores, error) {
+	var stores services.PersonalDataStores
+	if cfg.AuditLogFile != "" {
+		stores.Audit = services.NewAuditLog(cfg.AuditLogFile)
+	}
+	for _, path := range []string{cfg.ServerOutboxFile, cfg.EmailOutboxFile} {
+		if path == "" {
+			continue
+		}
+		outbox, err := services.OpenOutbox(path, cfg.EmailDedupeWindow)
+		if err != nil {
+			return stores, err
+		}
+		stores.Outboxes = append(stores.Outboxes, outbox)
+	}
+	if cfg.WebhookDeliveryLog != "" {
+		stores.Deliveries = services.NewDeliveryLog(cfg.WebhookDeliveryLog)
+	}
+	if cfg.ReminderLogFile != "" {
+		stores.Reminders = services.NewReminderLog(cfg.ReminderLogFile)
+	}
+	return stores, nil
+}
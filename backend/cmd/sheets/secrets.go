@@ -0,0 +1,104 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+
+	"github.com/stevebennett/slack-invite-mgr/backend/internal/config"
+)
+
+const secretsUsage = `Usage:
+  sheets secrets keygen
+  sheets secrets seal [-out file] <values.json|->
+  sheets secrets list
+`
+
+// runSecrets manages the encrypted secrets file. It returns the process exit
+// code. Secret values are never printed or logged.
+func runSecrets(args []string, log *slog.Logger) int {
+	if len(args) == 0 {
+		fmt.Fprint(os.Stderr, secretsUsage)
+		return 2
+	}
+	switch args[0] {
+	case "keygen":
+		key, err := config.GenerateSecretsKey()
+		if err != nil {
+			log.Error("failed to generate key", slog.String("error", err.Error()))
+			return 1
+		}
+		fmt.Println(key)
+		return 0
+	case "seal":
+		return sealSecrets(args[1:], log)
+	case "list":
+		secrets, err := config.NewSecrets(os.Getenv)
+		if err != nil {
+			log.Error("failed to open secrets", slog.String("error", err.Error()))
+			return 1
+		}
+		for _, name := range secrets.Names() {
+			fmt.Println(name)
+		}
+		return 0
+	default:
+		fmt.Fprintf(os.Stderr, "unknown secrets command %q\n\n%s", args[0], secretsUsage)
+		return 2
+	}
+}
+
+// sealSecrets encrypts a JSON object of secret values by name with
+// SECRETS_KEY and writes it to SECRETS_FILE, or the -out file
+func sealSecrets(args []string, log *slog.Logger) int {
+	flags := flag.NewFlagSet("secrets seal", flag.ContinueOnError)
+	out := flags.String("out", os.Getenv("SECRETS_FILE"), "file to write (default SECRETS_FILE)")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() != 1 || *out == "" {
+		fmt.Fprint(os.Stderr, secretsUsage)
+		return 2
+	}
+
+	key, err := config.EnvSecret("SECRETS_KEY")
+	if err != nil {
+		log.Error("failed to read key", slog.String("error", err.Error()))
+		return 1
+	}
+	if key == "" {
+		fmt.Fprintln(os.Stderr, "SECRETS_KEY is not set; create one with \"sheets secrets keygen\"")
+		return 1
+	}
+
+	var data []byte
+	if flags.Arg(0) == "-" {
+		data, err = io.ReadAll(os.Stdin)
+	} else {
+		data, err = os.ReadFile(flags.Arg(0))
+	}
+	if err != nil {
+		log.Error("failed to read secret values", slog.String("error", err.Error()))
+		return 1
+	}
+	var values map[string]string
+	if err := json.Unmarshal(data, &values); err != nil {
+		log.Error("secret values must be a JSON object of strings", slog.String("error", err.Error()))
+		return 1
+	}
+
+	sealed, err := config.SealSecrets(values, key)
+	if err != nil {
+		log.Error("failed to encrypt secrets", slog.String("error", err.Error()))
+		return 1
+	}
+	if err := os.WriteFile(*out, sealed, 0o600); err != nil {
+		log.Error("failed to write secrets file", slog.String("error", err.Error()))
+		return 1
+	}
+	log.Info("sealed secrets", slog.String("file", *out), slog.Int("secrets", len(values)))
+	return 0
+}
@@ -0,0 +1,142 @@
+package api
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/mail"
+	"strconv"
+	"strings"
+
+	"github.com/stevebennett/slack-invite-mgr/backend/internal/config"
+	"github.com/stevebennett/slack-invite-mgr/backend/internal/services"
+)
+
+// EraseApplicantResponse reports how many rows were deleted for an applicant,
+// and how many records about them were changed in the other stores
+type EraseApplicantResponse struct {
+	Erased  int            `json:"erased"`
+	Tabs    map[string]int `json:"tabs"`
+	Records map[string]int `json:"records"`
+}
+
+// EraseApplicantHandler deletes every row for the email in the query from the
+// sheet and the archive tabs, then removes or pseudonymizes the applicant in
+// the audit trail, email outbox, webhook delivery log and reminder log. Each
+// erasure is recorded in the audit trail with a hash of the email, not the
+// address itself. Routes must wrap it in RequireAdminToken.
+func EraseApplicantHandler(cfg *config.Config, logger *slog.Logger, deps Dependencies) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// Get request-scoped logger from context
+		log := LoggerFromContext(r.Context(), logger)
+
+		if r.Method != http.MethodDelete {
+			writeMethodNotAllowed(w, r)
+			return
+		}
+
+		email := strings.TrimSpace(r.URL.Query().Get("email"))
+		if email == "" {
+			writeValidationError(w, r, FieldError{Field: "email", Message: "is required"})
+			return
+		}
+		if addr, err := mail.ParseAddress(email); err != nil || addr.Address != email {
+			writeValidationError(w, r, FieldError{Field: "email", Message: "must be a valid email address"})
+			return
+		}
+
+		// Get sheets service from context
+		sheetsService, ok := r.Context().Value("sheetsService").(services.SheetsServiceInterface)
+		if !ok {
+			var err error
+			sheetsService, err = services.NewSheetsService(r.Context(), cfg.SheetsConfig())
+			if err != nil {
+				log.Error("failed to create sheets service", slog.String("error", err.Error()))
+				writeError(w, r, http.StatusInternalServerError, ErrCodeInternal, "Failed to create sheets service")
+				return
+			}
+		}
+
+		stores, err := personalDataStores(cfg, deps)
+		if err != nil {
+			log.Error("failed to open email outbox", slog.String("error", err.Error()))
+			writeError(w, r, http.StatusInternalServerError, ErrCodeInternal, "Failed to open email outbox")
+			return
+		}
+
+		entry := services.AuditEntry{
+			Action:    services.AuditApplicantErased,
+			RequestID: RequestIDFromContext(r.Context()),
+			Details:   map[string]string{"emailHash": services.EmailHash(email)},
+		}
+		tabs, err := sheetsService.EraseApplicant(r.Context(), email)
+		if err != nil {
+			log.Error("failed to erase applicant", slog.String("error", err.Error()))
+			entry.Result, entry.Error = services.AuditResultFailed, err.Error()
+			if auditErr := deps.Audit.Record(entry); auditErr != nil {
+				log.Error("failed to record audit entry", slog.String("error", auditErr.Error()))
+			}
+			writeUpstreamError(w, r, err, "Failed to erase applicant")
+			return
+		}
+
+		response := EraseApplicantResponse{Tabs: tabs}
+		for _, count := range tabs {
+			response.Erased += count
+		}
+		entry.Details["rows"] = strconv.Itoa(response.Erased)
+		response.Records, err = stores.Erase(email)
+		if err != nil {
+			log.Error("failed to erase applicant records", slog.String("error", err.Error()))
+			entry.Result, entry.Error = services.AuditResultFailed, err.Error()
+			if auditErr := deps.Audit.Record(entry); auditErr != nil {
+				log.Error("failed to record audit entry", slog.String("error", auditErr.Error()))
+			}
+			writeError(w, r, http.StatusInternalServerError, ErrCodeInternal, "Failed to erase applicant records")
+			return
+		}
+		entry.Result = services.AuditResultSuccess
+		if err := deps.Audit.Record(entry); err != nil {
+			log.Error("failed to record audit entry", slog.String("error", err.Error()))
+		}
+
+		log.Info("erased applicant", slog.Int("rows", response.Erased))
+
+		// Set response headers
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Access-Control-Allow-Origin", "*")
+		if err := json.NewEncoder(w).Encode(response); err != nil {
+			log.Error("failed to encode response", slog.String("error", err.Error()))
+		}
+	}
+}
+
+// personalDataStores returns the stores that may hold applicant emails,
+// including the sheets tool's outbox, whose reminder digests name applicants.
+// The server's outbox file is opened afresh when applicant emails are off.
+func personalDataStores(cfg *config.Config, deps Dependencies) (services.PersonalDataStores, error) {
+	stores := services.PersonalDataStores{
+		Audit:     deps.Audit,
+		Reminders: services.NewReminderLog(cfg.ReminderLogFile),
+	}
+	if deps.Webhooks != nil {
+		stores.Deliveries = deps.Webhooks.Deliveries()
+	}
+	if deps.Outbox != nil {
+		stores.Outboxes = append(stores.Outboxes, deps.Outbox)
+	} else if cfg.EmailOutboxFile != "" {
+		outbox, err := services.OpenOutbox(cfg.EmailOutboxFile, cfg.EmailDedupeWindow)
+		if err != nil {
+			return stores, err
+		}
+		stores.Outboxes = append(stores.Outboxes, outbox)
+	}
+	if cfg.SheetsOutboxFile != "" {
+		outbox, err := services.OpenOutbox(cfg.SheetsOutboxFile, cfg.EmailDedupeWindow)
+		if err != nil {
+			return stores, err
+		}
+		stores.Outboxes = append(stores.Outboxes, outbox)
+	}
+	return stores, nil
+}
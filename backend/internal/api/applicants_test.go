@@ -0,0 +1,233 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/stevebennett/slack-invite-mgr/backend/internal/config"
+	"github.com/stevebennett/slack-invite-mgr/backend/internal/services"
+)
+
+func TestEraseApplicantHandler(t *testing.T) {
+	tests := []struct {
+		name           string
+		adminToken     string
+		authorization  string
+		method         string
+		query          string
+		mockError      error
+		expectedStatus int
+		expectedResult string
+	}{
+		{
+			name:           "erases every row",
+			adminToken:     "secret",
+			authorization:  "Bearer secret",
+			query:          "email=john@example.com",
+			expectedStatus: http.StatusOK,
+			expectedResult: services.AuditResultSuccess,
+		},
+		{
+			name:           "disabled without a configured token",
+			authorization:  "Bearer ",
+			query:          "email=john@example.com",
+			expectedStatus: http.StatusForbidden,
+		},
+		{
+			name:           "missing token",
+			adminToken:     "secret",
+			query:          "email=john@example.com",
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           "wrong token",
+			adminToken:     "secret",
+			authorization:  "Bearer guess",
+			query:          "email=john@example.com",
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           "wrong method",
+			adminToken:     "secret",
+			authorization:  "Bearer secret",
+			method:         http.MethodGet,
+			query:          "email=john@example.com",
+			expectedStatus: http.StatusMethodNotAllowed,
+		},
+		{
+			name:           "missing email",
+			adminToken:     "secret",
+			authorization:  "Bearer secret",
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "invalid email",
+			adminToken:     "secret",
+			authorization:  "Bearer secret",
+			query:          "email=john",
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "sheets error",
+			adminToken:     "secret",
+			authorization:  "Bearer secret",
+			query:          "email=john@example.com",
+			mockError:      errors.New("sheets service error"),
+			expectedStatus: http.StatusBadGateway,
+			expectedResult: services.AuditResultFailed,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			method := tt.method
+			if method == "" {
+				method = http.MethodDelete
+			}
+			req := httptest.NewRequest(method, "/api/applicants?"+tt.query, nil)
+			if tt.authorization != "" {
+				req.Header.Set("Authorization", tt.authorization)
+			}
+			rr := httptest.NewRecorder()
+			mockService := &mockSheetsService{
+				updateStatusErr: tt.mockError,
+				erasedRows:      map[string]int{"Sheet1": 2, "Archive": 1},
+			}
+			audit := services.NewAuditLog("")
+
+			cfg := &config.Config{AdminToken: config.Secret(tt.adminToken)}
+			handler := RequireAdminToken(cfg.AdminToken.Reveal(), testLogger())(EraseApplicantHandler(cfg, testLogger(), Dependencies{Audit: audit}))
+			ctx := context.WithValue(req.Context(), "sheetsService", mockService)
+			handler.ServeHTTP(rr, req.WithContext(ctx))
+
+			if rr.Code != tt.expectedStatus {
+				t.Fatalf("handler returned wrong status code: got %v want %v", rr.Code, tt.expectedStatus)
+			}
+			if rr.Code == http.StatusUnauthorized && rr.Header().Get("WWW-Authenticate") != "Bearer" {
+				t.Errorf("expected a WWW-Authenticate challenge")
+			}
+
+			entries, _ := audit.List(services.AuditFilter{})
+			if tt.expectedResult == "" {
+				if len(entries) != 0 || len(mockService.erased) != 0 {
+					t.Errorf("expected nothing erased or audited, got %v and %v", mockService.erased, entries)
+				}
+				return
+			}
+			if len(entries) != 1 {
+				t.Fatalf("expected one audit entry, got %v", entries)
+			}
+			entry := entries[0]
+			if entry.Action != services.AuditApplicantErased || entry.Result != tt.expectedResult ||
+				entry.Email != "" || entry.Details["emailHash"] != services.EmailHash("John@Example.com") {
+				t.Errorf("unexpected audit entry: %+v", entry)
+			}
+			if tt.expectedStatus != http.StatusOK {
+				return
+			}
+
+			var response EraseApplicantResponse
+			if err := json.NewDecoder(rr.Body).Decode(&response); err != nil {
+				t.Fatalf("Failed to decode response body: %v", err)
+			}
+			if response.Erased != 3 || !reflect.DeepEqual(response.Tabs, mockService.erasedRows) {
+				t.Errorf("unexpected response: %+v", response)
+			}
+			if entry.Details["rows"] != "3" || !reflect.DeepEqual(mockService.erased, []string{"john@example.com"}) {
+				t.Errorf("unexpected erasure: %+v of %v", entry, mockService.erased)
+			}
+		})
+	}
+}
+
+func TestEraseApplicantHandler_Stores(t *testing.T) {
+	dir := t.TempDir()
+	cfg := &config.Config{
+		AdminToken:        config.Secret("secret"),
+		EmailOutboxFile:   filepath.Join(dir, "outbox.json"),
+		SheetsOutboxFile:  filepath.Join(dir, "sheets-outbox.json"),
+		EmailDedupeWindow: time.Hour,
+		ReminderLogFile:   filepath.Join(dir, "reminders.jsonl"),
+	}
+
+	audit := services.NewAuditLog("")
+	if err := audit.Record(services.AuditEntry{Action: services.AuditStatusChanged, Email: "john@example.com", Status: services.StatusSent}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	outbox, err := services.OpenOutbox(cfg.EmailOutboxFile, cfg.EmailDedupeWindow)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, _, err := outbox.Enqueue(services.EmailMessage{Recipients: services.Recipients{To: []string{"john@example.com"}}, Subject: "Welcome"}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	// The sheets tool keeps its outbox open while the server erases from it
+	sheetsOutbox, err := services.OpenOutbox(cfg.SheetsOutboxFile, cfg.EmailDedupeWindow)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, _, err := sheetsOutbox.Enqueue(services.EmailMessage{Recipients: services.Recipients{To: []string{"ops@example.com"}}, Subject: "Stale invites", Body: "john@example.com"}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	deliveries := services.NewDeliveryLog("")
+	payload, _ := json.Marshal(services.WebhookPayload{Data: services.InviteEvent{Email: "john@example.com"}})
+	if err := deliveries.Record(services.WebhookDelivery{ID: "d1", Status: services.DeliveryDeadLetter, Payload: payload}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := services.NewReminderLog(cfg.ReminderLogFile).Record(services.ReminderRecord{EmailHash: services.EmailHash("john@example.com")}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// The server has not opened the outbox, so the handler opens the file
+	deps := Dependencies{Audit: audit, Webhooks: services.NewWebhookDispatcher(nil, deliveries, testLogger())}
+	handler := RequireAdminToken(cfg.AdminToken.Reveal(), testLogger())(EraseApplicantHandler(cfg, testLogger(), deps))
+	req := httptest.NewRequest(http.MethodDelete, "/api/applicants?email=John@Example.com", nil)
+	req.Header.Set("Authorization", "Bearer secret")
+	mockService := &mockSheetsService{erasedRows: map[string]int{"Sheet1": 1}}
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req.WithContext(context.WithValue(req.Context(), "sheetsService", mockService)))
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusOK)
+	}
+	var response EraseApplicantResponse
+	if err := json.NewDecoder(rr.Body).Decode(&response); err != nil {
+		t.Fatalf("Failed to decode response body: %v", err)
+	}
+	expected := map[string]int{"audit": 1, "outbox": 2, "webhookDeliveries": 1, "reminders": 1}
+	if !reflect.DeepEqual(response.Records, expected) {
+		t.Errorf("expected records %v, got %v", expected, response.Records)
+	}
+
+	if entries, _ := audit.List(services.AuditFilter{Email: "john@example.com"}); len(entries) != 0 {
+		t.Errorf("expected the applicant's audit entries to be pseudonymized, got %+v", entries)
+	}
+	entries, _ := audit.List(services.AuditFilter{})
+	if len(entries) != 2 || entries[0].Action != services.AuditApplicantErased || entries[1].Details["emailHash"] != services.EmailHash("john@example.com") {
+		t.Errorf("expected the erasure and the pseudonymized entry, got %+v", entries)
+	}
+	reopened, err := services.OpenOutbox(cfg.EmailOutboxFile, cfg.EmailDedupeWindow)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if messages := reopened.List(""); len(messages) != 0 {
+		t.Errorf("expected the queued email to be removed, got %+v", messages)
+	}
+	// The sheets tool's next write keeps the erasure
+	if _, _, err := sheetsOutbox.Enqueue(services.EmailMessage{Recipients: services.Recipients{To: []string{"ops@example.com"}}, Subject: "Report"}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if messages := sheetsOutbox.List(""); len(messages) != 1 || messages[0].Subject != "Report" {
+		t.Errorf("expected only the new email in the sheets outbox, got %+v", messages)
+	}
+	if records, _ := deliveries.List("", 0); len(records) != 1 || len(records[0].Payload) != 0 {
+		t.Errorf("expected the dead letter payload to be dropped, got %+v", records)
+	}
+}
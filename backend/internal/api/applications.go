@@ -0,0 +1,164 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"math"
+	"net/http"
+	"net/mail"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/stevebennett/slack-invite-mgr/backend/internal/config"
+	"github.com/stevebennett/slack-invite-mgr/backend/internal/services"
+)
+
+// maxApplicationBytes caps the size of an application request body
+const maxApplicationBytes = 64 * 1024
+
+// ApplicationRequest is an application submitted through the intake form
+type ApplicationRequest struct {
+	Name            string `json:"name"`
+	Role            string `json:"role"`
+	Email           string `json:"email"`
+	Company         string `json:"company"`
+	YearsExperience string `json:"yearsExperience"`
+	Reasons         string `json:"reasons"`
+	Source          string `json:"source"`
+	// Website is a honeypot: the form hides it, so only bots fill it in
+	Website string `json:"website"`
+}
+
+// ApplicationResponse is returned once an application has been received
+type ApplicationResponse struct {
+	Status string `json:"status"`
+}
+
+// applicationField describes the validation rules for one form field
+type applicationField struct {
+	name     string
+	value    *string
+	required bool
+	maxLen   int
+}
+
+// Validate trims every field and returns a FieldError for each one that is
+// missing or too long, plus one for a malformed email address
+func (req *ApplicationRequest) Validate() []FieldError {
+	fields := []applicationField{
+		{"name", &req.Name, true, 200},
+		{"role", &req.Role, true, 200},
+		{"email", &req.Email, true, 254},
+		{"company", &req.Company, true, 200},
+		{"yearsExperience", &req.YearsExperience, true, 50},
+		{"reasons", &req.Reasons, true, 5000},
+		{"source", &req.Source, false, 200},
+	}
+
+	var errs []FieldError
+	for _, f := range fields {
+		*f.value = strings.TrimSpace(*f.value)
+		switch {
+		case f.required && *f.value == "":
+			errs = append(errs, FieldError{Field: f.name, Message: "is required"})
+		case len(*f.value) > f.maxLen:
+			errs = append(errs, FieldError{Field: f.name, Message: fmt.Sprintf("must be at most %d characters", f.maxLen)})
+		}
+	}
+
+	if req.Email != "" && len(req.Email) <= 254 {
+		addr, err := mail.ParseAddress(req.Email)
+		if err != nil || addr.Address != req.Email {
+			errs = append(errs, FieldError{Field: "email", Message: "must be a valid email address"})
+		}
+	}
+
+	return errs
+}
+
+// SubmitApplicationHandler handles applications from the public intake form.
+// Each application is appended to the sheet as a new pending row. Requests are
+// rate limited per client IP, and submissions that fill in the honeypot field
+// are accepted but silently discarded.
+func SubmitApplicationHandler(cfg *config.Config, logger *slog.Logger, limiter *RateLimiter) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// Get request-scoped logger from context
+		log := LoggerFromContext(r.Context(), logger)
+
+		if r.Method != http.MethodPost {
+			writeMethodNotAllowed(w, r)
+			return
+		}
+
+		ip := clientIP(r, cfg.TrustProxyHeaders)
+		if allowed, retryAfter := limiter.Allow(ip); !allowed {
+			log.Warn("application rate limit exceeded", slog.String("client_ip", ip))
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+			writeError(w, r, http.StatusTooManyRequests, ErrCodeRateLimited, "Too many applications, please try again later")
+			return
+		}
+
+		// Parse request body
+		var req ApplicationRequest
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxApplicationBytes)).Decode(&req); err != nil {
+			log.Warn("invalid application body", slog.String("error", err.Error()))
+			writeError(w, r, http.StatusBadRequest, ErrCodeInvalidRequest, "Invalid request body")
+			return
+		}
+
+		if req.Website != "" {
+			log.Warn("application honeypot triggered", slog.String("client_ip", ip))
+			writeApplicationReceived(w)
+			return
+		}
+
+		if fieldErrs := req.Validate(); len(fieldErrs) > 0 {
+			log.Warn("invalid application", slog.Int("field_errors", len(fieldErrs)))
+			writeError(w, r, http.StatusBadRequest, ErrCodeValidationFailed, "Application validation failed", fieldErrs...)
+			return
+		}
+
+		// Get sheets service from context
+		sheetsService, ok := r.Context().Value("sheetsService").(services.SheetsServiceInterface)
+		if !ok {
+			// Create sheets service if not in context
+			var err error
+			sheetsService, err = services.NewSheetsService(r.Context(), cfg.SheetsConfig())
+			if err != nil {
+				log.Error("failed to create sheets service", slog.String("error", err.Error()))
+				writeError(w, r, http.StatusInternalServerError, ErrCodeInternal, "Failed to create sheets service")
+				return
+			}
+		}
+
+		invite := services.Invite{
+			SubmittedAt:     time.Now().In(cfg.SheetLocation()).Format(services.SubmittedAtLayout),
+			Name:            req.Name,
+			Role:            req.Role,
+			Email:           req.Email,
+			Company:         req.Company,
+			YearsExperience: req.YearsExperience,
+			Reasons:         req.Reasons,
+			Source:          req.Source,
+			Status:          services.StatusPending,
+		}
+		if err := sheetsService.AppendInvite(r.Context(), invite); err != nil {
+			log.Error("failed to append application", slog.String("error", err.Error()))
+			writeUpstreamError(w, r, err, "Failed to save application")
+			return
+		}
+
+		log.Info("application received", slog.String("source", req.Source))
+		writeApplicationReceived(w)
+	}
+}
+
+// writeApplicationReceived acknowledges an application
+func writeApplicationReceived(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(ApplicationResponse{Status: "received"})
+}
@@ -0,0 +1,218 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stevebennett/slack-invite-mgr/backend/internal/config"
+	"github.com/stevebennett/slack-invite-mgr/backend/internal/services"
+)
+
+func validApplication() ApplicationRequest {
+	return ApplicationRequest{
+		Name:            " Jane Doe ",
+		Role:            "Engineer",
+		Email:           "jane@example.com",
+		Company:         "Acme",
+		YearsExperience: "5-10",
+		Reasons:         "To learn from others",
+		Source:          "Twitter",
+	}
+}
+
+func TestSubmitApplicationHandler(t *testing.T) {
+	tests := []struct {
+		name           string
+		modify         func(*ApplicationRequest)
+		rawBody        string
+		expectedStatus int
+		expectedFields []string
+		expectAppend   bool
+	}{
+		{
+			name:           "valid application",
+			expectedStatus: http.StatusCreated,
+			expectAppend:   true,
+		},
+		{
+			name:           "missing required fields",
+			modify:         func(req *ApplicationRequest) { req.Name = "  "; req.Company = "" },
+			expectedStatus: http.StatusBadRequest,
+			expectedFields: []string{"name", "company"},
+		},
+		{
+			name:           "invalid email",
+			modify:         func(req *ApplicationRequest) { req.Email = "Jane <jane@example.com>" },
+			expectedStatus: http.StatusBadRequest,
+			expectedFields: []string{"email"},
+		},
+		{
+			name:           "honeypot filled in",
+			modify:         func(req *ApplicationRequest) { req.Website = "http://spam.example.com" },
+			expectedStatus: http.StatusCreated,
+			expectAppend:   false,
+		},
+		{
+			name:           "malformed body",
+			rawBody:        "{not json",
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body := []byte(tt.rawBody)
+			if tt.rawBody == "" {
+				app := validApplication()
+				if tt.modify != nil {
+					tt.modify(&app)
+				}
+				body, _ = json.Marshal(app)
+			}
+
+			mockService := &mockSheetsService{}
+			req := httptest.NewRequest(http.MethodPost, "/api/applications", bytes.NewReader(body))
+			req = req.WithContext(context.WithValue(req.Context(), "sheetsService", mockService))
+			rr := httptest.NewRecorder()
+
+			cfg := &config.Config{SheetTimeZone: "Pacific/Auckland"}
+			SubmitApplicationHandler(cfg, testLogger(), NewRateLimiter(10, time.Hour))(rr, req)
+
+			if rr.Code != tt.expectedStatus {
+				t.Fatalf("Expected status %d, got %d: %s", tt.expectedStatus, rr.Code, rr.Body.String())
+			}
+
+			if tt.expectedFields != nil {
+				var resp ErrorResponse
+				if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+					t.Fatalf("Failed to decode error: %v", err)
+				}
+				if len(resp.Error.Fields) != len(tt.expectedFields) {
+					t.Fatalf("Expected field errors for %v, got %+v", tt.expectedFields, resp.Error.Fields)
+				}
+				for i, field := range tt.expectedFields {
+					if resp.Error.Fields[i].Field != field {
+						t.Errorf("Expected field error %d for %s, got %s", i, field, resp.Error.Fields[i].Field)
+					}
+				}
+			}
+
+			if appended := len(mockService.appended) == 1; appended != tt.expectAppend {
+				t.Fatalf("Expected append %v, got %d rows", tt.expectAppend, len(mockService.appended))
+			}
+			if tt.expectAppend {
+				invite := mockService.appended[0]
+				if invite.Name != "Jane Doe" || invite.Status != services.StatusPending {
+					t.Errorf("Unexpected appended invite: %+v", invite)
+				}
+				// Column A is written in the sheet's time zone
+				submitted, ok := services.ParseTimestampInLocation(invite.SubmittedAt, cfg.SheetLocation())
+				if !ok || time.Since(submitted).Abs() > time.Minute {
+					t.Errorf("Expected the submission time in the sheet's time zone, got %q", invite.SubmittedAt)
+				}
+			}
+		})
+	}
+}
+
+func TestSubmitApplicationHandler_RateLimit(t *testing.T) {
+	limiter := NewRateLimiter(2, time.Hour)
+	now := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	limiter.now = func() time.Time { return now }
+	handler := SubmitApplicationHandler(&config.Config{}, testLogger(), limiter)
+	body, _ := json.Marshal(validApplication())
+
+	submit := func(remoteAddr string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/applications", bytes.NewReader(body))
+		req.RemoteAddr = remoteAddr
+		req = req.WithContext(context.WithValue(req.Context(), "sheetsService", &mockSheetsService{}))
+		rr := httptest.NewRecorder()
+		handler(rr, req)
+		return rr
+	}
+
+	for i := 0; i < 2; i++ {
+		if rr := submit("192.0.2.1:1234"); rr.Code != http.StatusCreated {
+			t.Fatalf("Submission %d: expected 201, got %d", i+1, rr.Code)
+		}
+	}
+
+	rr := submit("192.0.2.1:5678")
+	if rr.Code != http.StatusTooManyRequests {
+		t.Fatalf("Expected 429, got %d", rr.Code)
+	}
+	if rr.Header().Get("Retry-After") != "3600" {
+		t.Errorf("Expected Retry-After 3600, got %q", rr.Header().Get("Retry-After"))
+	}
+
+	if rr := submit("198.51.100.7:1234"); rr.Code != http.StatusCreated {
+		t.Errorf("Expected another IP to be allowed, got %d", rr.Code)
+	}
+
+	now = now.Add(time.Hour)
+	if rr := submit("192.0.2.1:1234"); rr.Code != http.StatusCreated {
+		t.Errorf("Expected submission after the window to be allowed, got %d", rr.Code)
+	}
+}
+
+func TestSubmitApplicationHandler_RateLimitSpoofedForwardedFor(t *testing.T) {
+	limiter := NewRateLimiter(1, time.Hour)
+	handler := SubmitApplicationHandler(&config.Config{TrustProxyHeaders: true}, testLogger(), limiter)
+	body, _ := json.Marshal(validApplication())
+
+	// The client sends its own X-Forwarded-For, which the proxy appends to
+	submit := func(spoofed string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/applications", bytes.NewReader(body))
+		req.RemoteAddr = "10.0.0.2:1234"
+		req.Header.Set("X-Forwarded-For", spoofed+", 192.0.2.1")
+		req = req.WithContext(context.WithValue(req.Context(), "sheetsService", &mockSheetsService{}))
+		rr := httptest.NewRecorder()
+		handler(rr, req)
+		return rr
+	}
+
+	if rr := submit("198.51.100.1"); rr.Code != http.StatusCreated {
+		t.Fatalf("Expected the first submission to be allowed, got %d", rr.Code)
+	}
+	if rr := submit("198.51.100.2"); rr.Code != http.StatusTooManyRequests {
+		t.Errorf("Expected a spoofed X-Forwarded-For not to avoid the limit, got %d", rr.Code)
+	}
+}
+
+func TestClientIP(t *testing.T) {
+	tests := []struct {
+		name      string
+		forwarded []string
+		realIP    string
+		trust     bool
+		expected  string
+	}{
+		{name: "remote address", expected: "10.0.0.2"},
+		{name: "proxy headers ignored", forwarded: []string{"192.0.2.1"}, expected: "10.0.0.2"},
+		{name: "single entry", forwarded: []string{"192.0.2.1"}, trust: true, expected: "192.0.2.1"},
+		{name: "last entry", forwarded: []string{"198.51.100.1, 192.0.2.1"}, trust: true, expected: "192.0.2.1"},
+		{name: "last header", forwarded: []string{"198.51.100.1", "192.0.2.1"}, trust: true, expected: "192.0.2.1"},
+		{name: "real ip", realIP: "192.0.2.1", trust: true, expected: "192.0.2.1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.RemoteAddr = "10.0.0.2:1234"
+			for _, value := range tt.forwarded {
+				req.Header.Add("X-Forwarded-For", value)
+			}
+			if tt.realIP != "" {
+				req.Header.Set("X-Real-IP", tt.realIP)
+			}
+			if got := clientIP(req, tt.trust); got != tt.expected {
+				t.Errorf("clientIP() = %q, want %q", got, tt.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,63 @@
+package api
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+
+	"github.com/stevebennett/slack-invite-mgr/backend/internal/services"
+)
+
+const (
+	defaultAuditLimit = 100
+	maxAuditLimit     = 1000
+)
+
+// AuditLogResponse is the envelope returned when listing the audit trail
+type AuditLogResponse struct {
+	Entries []services.AuditEntry `json:"entries"`
+}
+
+// AuditLogHandler lists audit trail entries, newest first, optionally
+// filtered by applicant email and action
+func AuditLogHandler(audit *services.AuditLog, logger *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// Get request-scoped logger from context
+		log := LoggerFromContext(r.Context(), logger)
+
+		if r.Method != http.MethodGet {
+			writeMethodNotAllowed(w, r)
+			return
+		}
+
+		values := r.URL.Query()
+		limit := defaultAuditLimit
+		if values.Get("limit") != "" {
+			var err error
+			if limit, err = parseIntParam(values, "limit"); err != nil {
+				writeValidationError(w, r, err)
+				return
+			}
+			if limit < 1 || limit > maxAuditLimit {
+				writeValidationError(w, r, FieldError{Field: "limit", Message: "must be between 1 and 1000"})
+				return
+			}
+		}
+
+		entries, err := audit.List(services.AuditFilter{
+			Email:  values.Get("email"),
+			Action: values.Get("action"),
+			Limit:  limit,
+		})
+		if err != nil {
+			log.Error("failed to read audit log", slog.String("error", err.Error()))
+			writeError(w, r, http.StatusInternalServerError, ErrCodeInternal, "Failed to read audit log")
+			return
+		}
+
+		// Set response headers
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Access-Control-Allow-Origin", "*")
+		json.NewEncoder(w).Encode(AuditLogResponse{Entries: entries})
+	}
+}
@@ -0,0 +1,154 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"strconv"
+
+	"google.golang.org/api/googleapi"
+)
+
+// Error codes returned in the error envelope
+const (
+	ErrCodeInvalidRequest        = "invalid_request"
+	ErrCodeValidationFailed      = "validation_failed"
+	ErrCodeMethodNotAllowed      = "method_not_allowed"
+	ErrCodeNotFound              = "not_found"
+	ErrCodeUnknownEmails         = "unknown_emails"
+	ErrCodeIdempotencyKeyReused  = "idempotency_key_reused"
+	ErrCodeIdempotencyInProgress = "idempotency_key_in_progress"
+	ErrCodePreconditionRequired  = "precondition_required"
+	ErrCodePreconditionFailed    = "precondition_failed"
+	ErrCodeRateLimited           = "rate_limited"
+	ErrCodeUnauthorized          = "unauthorized"
+	ErrCodeForbidden             = "forbidden"
+	ErrCodeInternal              = "internal_error"
+	ErrCodeUpstreamError         = "upstream_error"
+	ErrCodeUpstreamUnavailable   = "upstream_unavailable"
+	ErrCodeUpstreamTimeout       = "upstream_timeout"
+	ErrCodeQuotaExhausted        = "quota_exhausted"
+)
+
+// defaultRetryAfter is the Retry-After value, in seconds, sent when the Sheets
+// quota is exhausted and Google did not tell us how long to wait
+const defaultRetryAfter = 60
+
+// ErrorResponse is the envelope returned for every API error
+type ErrorResponse struct {
+	Error APIError `json:"error"`
+}
+
+// APIError describes a single API error
+type APIError struct {
+	Code      string       `json:"code"`
+	Message   string       `json:"message"`
+	RequestID string       `json:"requestId,omitempty"`
+	Fields    []FieldError `json:"fields,omitempty"`
+}
+
+// FieldError describes a validation failure for a single request field
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+func (e FieldError) Error() string {
+	return e.Field + ": " + e.Message
+}
+
+// writeError writes an error envelope with the given status and code
+func writeError(w http.ResponseWriter, r *http.Request, status int, code, message string, fields ...FieldError) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(ErrorResponse{
+		Error: APIError{
+			Code:      code,
+			Message:   message,
+			RequestID: RequestIDFromContext(r.Context()),
+			Fields:    fields,
+		},
+	})
+}
+
+// writeMethodNotAllowed writes a 405 error envelope
+func writeMethodNotAllowed(w http.ResponseWriter, r *http.Request) {
+	writeError(w, r, http.StatusMethodNotAllowed, ErrCodeMethodNotAllowed, "Method not allowed")
+}
+
+// writeValidationError writes a 400 error envelope. Field errors are listed
+// individually; any other error is reported as a malformed request.
+func writeValidationError(w http.ResponseWriter, r *http.Request, err error) {
+	var fieldErr FieldError
+	if errors.As(err, &fieldErr) {
+		writeError(w, r, http.StatusBadRequest, ErrCodeValidationFailed, "Request validation failed", fieldErr)
+		return
+	}
+	writeError(w, r, http.StatusBadRequest, ErrCodeInvalidRequest, err.Error())
+}
+
+// writeUpstreamError maps a failed call to Google Sheets onto a gateway error.
+// Quota exhaustion becomes 503 with Retry-After, timeouts become 504, Google
+// outages become 503 and anything else the upstream rejected becomes 502.
+func writeUpstreamError(w http.ResponseWriter, r *http.Request, err error, message string) {
+	if errors.Is(err, context.DeadlineExceeded) {
+		writeError(w, r, http.StatusGatewayTimeout, ErrCodeUpstreamTimeout, message)
+		return
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		writeError(w, r, http.StatusGatewayTimeout, ErrCodeUpstreamTimeout, message)
+		return
+	}
+
+	var apiErr *googleapi.Error
+	if errors.As(err, &apiErr) {
+		switch {
+		case isQuotaError(apiErr):
+			w.Header().Set("Retry-After", retryAfter(apiErr))
+			writeError(w, r, http.StatusServiceUnavailable, ErrCodeQuotaExhausted, message)
+			return
+		case apiErr.Code == http.StatusServiceUnavailable:
+			if value := apiErr.Header.Get("Retry-After"); value != "" {
+				w.Header().Set("Retry-After", value)
+			}
+			writeError(w, r, http.StatusServiceUnavailable, ErrCodeUpstreamUnavailable, message)
+			return
+		case apiErr.Code == http.StatusGatewayTimeout:
+			writeError(w, r, http.StatusGatewayTimeout, ErrCodeUpstreamTimeout, message)
+			return
+		}
+	}
+
+	writeError(w, r, http.StatusBadGateway, ErrCodeUpstreamError, message)
+}
+
+// isQuotaError reports whether Google rejected the call because a rate limit
+// or quota was exhausted. Sheets uses 429, but older endpoints report 403 with
+// a rate-limit reason.
+func isQuotaError(apiErr *googleapi.Error) bool {
+	if apiErr.Code == http.StatusTooManyRequests {
+		return true
+	}
+	if apiErr.Code != http.StatusForbidden {
+		return false
+	}
+	for _, item := range apiErr.Errors {
+		switch item.Reason {
+		case "rateLimitExceeded", "userRateLimitExceeded", "quotaExceeded":
+			return true
+		}
+	}
+	return false
+}
+
+// retryAfter returns the Retry-After value Google sent, or the default
+func retryAfter(apiErr *googleapi.Error) string {
+	if value := apiErr.Header.Get("Retry-After"); value != "" {
+		return value
+	}
+	return strconv.Itoa(defaultRetryAfter)
+}
@@ -0,0 +1,119 @@
+package api
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/stevebennett/slack-invite-mgr/backend/internal/config"
+	"github.com/stevebennett/slack-invite-mgr/backend/internal/services"
+)
+
+// ExportInvitesHandler streams the invites matching the list endpoint's
+// filters as CSV, XLSX or JSON. Rows are written in sheet order as they are
+// read, so sort and pagination parameters are ignored.
+func ExportInvitesHandler(cfg *config.Config, logger *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// Get request-scoped logger from context
+		log := LoggerFromContext(r.Context(), logger)
+
+		if r.Method != http.MethodGet {
+			writeMethodNotAllowed(w, r)
+			return
+		}
+
+		values := r.URL.Query()
+		format := values.Get("format")
+		if format == "" {
+			format = services.ExportCSV
+		}
+		if _, ok := services.ExportContentTypes[format]; !ok {
+			writeValidationError(w, r, FieldError{Field: "format", Message: fmt.Sprintf("unknown format %q, expected csv, xlsx or json", format)})
+			return
+		}
+		// Pagination does not apply to exports
+		values.Del("limit")
+		values.Del("offset")
+		query, err := ParseInviteQuery(values, cfg.SheetLocation())
+		if err != nil {
+			log.Warn("invalid query parameters", slog.String("error", err.Error()))
+			writeValidationError(w, r, err)
+			return
+		}
+
+		// Get sheets service from context
+		sheetsService, ok := r.Context().Value("sheetsService").(services.SheetsServiceInterface)
+		if !ok {
+			// Create sheets service if not in context
+			sheetsService, err = services.NewSheetsService(r.Context(), cfg.SheetsConfig())
+			if err != nil {
+				log.Error("failed to create sheets service", slog.String("error", err.Error()))
+				writeError(w, r, http.StatusInternalServerError, ErrCodeInternal, "Failed to create sheets service")
+				return
+			}
+		}
+
+		// The response starts with the first row, so errors reading the
+		// first page can still be reported as an error envelope
+		var writer services.InviteWriter
+		start := func() error {
+			w.Header().Set("Content-Type", services.ExportContentTypes[format])
+			w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="invites-%s.%s"`, time.Now().UTC().Format("20060102"), format))
+			w.Header().Set("Access-Control-Allow-Origin", "*")
+			writer, err = services.NewInviteWriter(w, format)
+			return err
+		}
+
+		exported := 0
+		write := func(row []interface{}, archived bool) error {
+			invite := services.InviteFromRow(row)
+			if !services.IsKnownStatus(invite.Status) || !query.Matches(invite) {
+				return nil // Skip the header row, hand-edited statuses and filtered rows
+			}
+			invite.Archived = archived
+			if writer == nil {
+				if err := start(); err != nil {
+					return err
+				}
+			}
+			exported++
+			return writer.Write(invite)
+		}
+		err = sheetsService.EachRow(r.Context(), func(row []interface{}) error {
+			return write(row, false)
+		})
+		if err == nil && query.Archived {
+			var archived [][]interface{}
+			if archived, err = sheetsService.GetArchivedSheetData(r.Context()); err == nil {
+				for _, row := range archived {
+					if err = write(row, true); err != nil {
+						break
+					}
+				}
+			}
+		}
+		if err != nil {
+			if writer == nil {
+				log.Error("failed to get sheet data", slog.String("error", err.Error()))
+				writeUpstreamError(w, r, err, "Failed to get sheet data")
+				return
+			}
+			// Headers are already sent; the truncated file is the only signal
+			log.Error("export interrupted", slog.Int("exported", exported), slog.String("error", err.Error()))
+			return
+		}
+
+		if writer == nil {
+			if err := start(); err != nil {
+				log.Error("failed to start export", slog.String("error", err.Error()))
+				return
+			}
+		}
+		if err := writer.Close(); err != nil {
+			log.Error("failed to finish export", slog.String("error", err.Error()))
+			return
+		}
+		log.Debug("exported invites", slog.String("format", format), slog.Int("count", exported))
+	}
+}
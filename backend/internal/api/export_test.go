@@ -0,0 +1,114 @@
+package api
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stevebennett/slack-invite-mgr/backend/internal/config"
+)
+
+func TestExportInvitesHandler(t *testing.T) {
+	data := [][]interface{}{
+		{"Timestamp", "Name", "Role", "Email", "", "Company", "Years", "Reasons", "Source", "Status", "Updated"},
+		{"3/4/2024 10:00:00", "Jane", "Engineer", "jane@example.com", "", "Acme", "5", "Reasons", "Meetup", "Sent", "2024-03-05 10:00:00"},
+		{"3/12/2024 10:00:00", "John", "Engineer", "john@example.com", "", "Acme", "1", "Reasons", "Meetup", "", ""},
+		{"4/2/2024 10:00:00", "Jill", "Engineer", "jill@example.com", "", "Acme", "8", "Reasons", "Twitter", "", ""},
+	}
+	archived := [][]interface{}{
+		{"Timestamp", "Name", "Role", "Email", "", "Company", "Years", "Reasons", "Source", "Status", "Updated"},
+		{"1/4/2023 10:00:00", "Ann", "Engineer", "ann@example.com", "", "Acme", "5", "Reasons", "Meetup", "Sent", "2023-01-05 10:00:00"},
+	}
+
+	tests := []struct {
+		name           string
+		query          string
+		err            error
+		expectedStatus int
+		expectedEmails []string
+	}{
+		{
+			name:           "pending by default",
+			expectedStatus: http.StatusOK,
+			expectedEmails: []string{"john@example.com", "jill@example.com"},
+		},
+		{
+			name:           "same filters as the list endpoint",
+			query:          "?status=all&source=meetup",
+			expectedStatus: http.StatusOK,
+			expectedEmails: []string{"jane@example.com", "john@example.com"},
+		},
+		{
+			name:           "pagination is ignored",
+			query:          "?limit=1&offset=1",
+			expectedStatus: http.StatusOK,
+			expectedEmails: []string{"john@example.com", "jill@example.com"},
+		},
+		{
+			name:           "archived invites on request",
+			query:          "?status=sent&archived=true",
+			expectedStatus: http.StatusOK,
+			expectedEmails: []string{"jane@example.com", "ann@example.com"},
+		},
+		{
+			name:           "no matches",
+			query:          "?q=nobody",
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "unknown format",
+			query:          "?format=pdf",
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "invalid filter",
+			query:          "?status=approved",
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "sheet unavailable",
+			err:            errors.New("sheet unavailable"),
+			expectedStatus: http.StatusBadGateway,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := &mockSheetsService{data: data, archived: archived, updateStatusErr: tt.err}
+			req := httptest.NewRequest(http.MethodGet, "/api/invites/export"+tt.query, nil)
+			req = req.WithContext(context.WithValue(req.Context(), "sheetsService", mockService))
+			rr := httptest.NewRecorder()
+
+			ExportInvitesHandler(&config.Config{}, testLogger())(rr, req)
+
+			if rr.Code != tt.expectedStatus {
+				t.Fatalf("Expected status %d, got %d: %s", tt.expectedStatus, rr.Code, rr.Body.String())
+			}
+			if tt.expectedStatus != http.StatusOK {
+				return
+			}
+			if got := rr.Header().Get("Content-Type"); got != "text/csv; charset=utf-8" {
+				t.Errorf("Expected CSV content type, got %q", got)
+			}
+			if got := rr.Header().Get("Content-Disposition"); !strings.HasPrefix(got, `attachment; filename="invites-`) {
+				t.Errorf("Expected an attachment, got %q", got)
+			}
+
+			records, err := csv.NewReader(rr.Body).ReadAll()
+			if err != nil {
+				t.Fatalf("Failed to parse CSV: %v", err)
+			}
+			var emails []string
+			for _, record := range records[1:] {
+				emails = append(emails, record[3])
+			}
+			if strings.Join(emails, ",") != strings.Join(tt.expectedEmails, ",") {
+				t.Errorf("Expected emails %v, got %v", tt.expectedEmails, emails)
+			}
+		})
+	}
+}
@@ -2,29 +2,42 @@ package api
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
 	"log/slog"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/stevebennett/slack-invite-mgr/backend/internal/config"
 	"github.com/stevebennett/slack-invite-mgr/backend/internal/services"
 )
 
-// Invite represents a single invite from the spreadsheet
-type Invite struct {
-	Name            string `json:"name"`
-	Role            string `json:"role"`
-	Email           string `json:"email"`
-	Company         string `json:"company"`
-	YearsExperience string `json:"yearsExperience"`
-	Reasons         string `json:"reasons"`
-	Source          string `json:"source"`
+// InviteListResponse is the envelope returned when listing invites
+type InviteListResponse struct {
+	Invites      []services.Invite `json:"invites"`
+	Total        int               `json:"total"`
+	Count        int               `json:"count"`
+	Limit        int               `json:"limit"`
+	Offset       int               `json:"offset"`
+	StatusCounts map[string]int    `json:"statusCounts"`
 }
 
 // UpdateInviteStatusRequest represents the request to update invite statuses
 type UpdateInviteStatusRequest struct {
 	Emails []string `json:"emails"`
 	Status string   `json:"status"`
+	// Atomic fails the whole request if any email is not in the sheet
+	Atomic bool `json:"atomic,omitempty"`
+}
+
+// UpdateInviteStatusResponse reports the outcome of a bulk status update.
+// Status is "success" when every email ended up in the requested status and
+// "partial" otherwise.
+type UpdateInviteStatusResponse struct {
+	Status  string                        `json:"status"`
+	Results []services.StatusUpdateResult `json:"results"`
+	Summary map[string]int                `json:"summary"`
 }
 
 // GetOutstandingInvitesHandler handles requests to get outstanding invites
@@ -34,7 +47,15 @@ func GetOutstandingInvitesHandler(cfg *config.Config, logger *slog.Logger) http.
 		log := LoggerFromContext(r.Context(), logger)
 
 		if r.Method != http.MethodGet {
-			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			writeMethodNotAllowed(w, r)
+			return
+		}
+
+		// Parse filters, sort order and pagination
+		query, err := ParseInviteQuery(r.URL.Query(), cfg.SheetLocation())
+		if err != nil {
+			log.Warn("invalid query parameters", slog.String("error", err.Error()))
+			writeValidationError(w, r, err)
 			return
 		}
 
@@ -42,70 +63,87 @@ func GetOutstandingInvitesHandler(cfg *config.Config, logger *slog.Logger) http.
 		sheetsService, ok := r.Context().Value("sheetsService").(services.SheetsServiceInterface)
 		if !ok {
 			// Create sheets service if not in context
-			var err error
-			sheetsService, err = services.NewSheetsService(r.Context(), &config.SheetsConfig{
-				CredentialsFile: cfg.GoogleCredentialsFile,
-				TokenFile:       cfg.GoogleTokenFile,
-				SpreadsheetID:   cfg.GoogleSpreadsheetID,
-				SheetName:       cfg.GoogleSheetName,
-			})
+			sheetsService, err = services.NewSheetsService(r.Context(), cfg.SheetsConfig())
 			if err != nil {
 				log.Error("failed to create sheets service", slog.String("error", err.Error()))
-				http.Error(w, "Failed to create sheets service", http.StatusInternalServerError)
+				writeError(w, r, http.StatusInternalServerError, ErrCodeInternal, "Failed to create sheets service")
 				return
 			}
 		}
 
-		// Get sheet data
-		data, err := sheetsService.GetSheetData(r.Context())
+		// Get sheet data, including processed rows so they can be filtered by status
+		data, err := sheetsService.GetAllSheetData(r.Context())
 		if err != nil {
 			log.Error("failed to get sheet data", slog.String("error", err.Error()))
-			http.Error(w, "Failed to get sheet data", http.StatusInternalServerError)
+			writeUpstreamError(w, r, err, "Failed to get sheet data")
 			return
 		}
+		var archived [][]interface{}
+		if query.Archived {
+			archived, err = sheetsService.GetArchivedSheetData(r.Context())
+			if err != nil {
+				log.Error("failed to get archived sheet data", slog.String("error", err.Error()))
+				writeUpstreamError(w, r, err, "Failed to get archived sheet data")
+				return
+			}
+		}
 
-		// Convert data to invites
-		var invites []Invite
-		for _, row := range data {
+		// Convert data to invites and count them by status
+		var invites []services.Invite
+		statusCounts := make(map[string]int)
+		for i, row := range append(data, archived...) {
 			if len(row) < 9 {
 				continue
 			}
 
-			invite := Invite{
-				Name:            getString(row, 1), // Column B
-				Role:            getString(row, 2), // Column C
-				Email:           getString(row, 3), // Column D
-				Company:         getString(row, 5), // Column F
-				YearsExperience: getString(row, 6), // Column G
-				Reasons:         getString(row, 7), // Column H
-				Source:          getString(row, 8), // Column I
+			invite := services.InviteFromRow(row)
+			if !services.IsKnownStatus(invite.Status) {
+				continue // Skip the header row and hand-edited statuses
 			}
+			invite.Archived = i >= len(data)
+			statusCounts[invite.Status]++
 			invites = append(invites, invite)
 		}
 
-		log.Debug("retrieved invites", slog.Int("count", len(invites)))
+		page, total := query.Apply(invites)
+
+		log.Debug("retrieved invites",
+			slog.Int("total", total),
+			slog.Int("count", len(page)),
+		)
 
 		// Set response headers
 		w.Header().Set("Content-Type", "application/json")
 		w.Header().Set("Access-Control-Allow-Origin", "*")
 
 		// Write response
-		if err := json.NewEncoder(w).Encode(invites); err != nil {
+		response := InviteListResponse{
+			Invites:      page,
+			Total:        total,
+			Count:        len(page),
+			Limit:        query.Limit,
+			Offset:       query.Offset,
+			StatusCounts: statusCounts,
+		}
+		if err := json.NewEncoder(w).Encode(response); err != nil {
 			log.Error("failed to encode response", slog.String("error", err.Error()))
-			http.Error(w, "Failed to encode response", http.StatusInternalServerError)
+			writeError(w, r, http.StatusInternalServerError, ErrCodeInternal, "Failed to encode response")
 			return
 		}
 	}
 }
 
-// UpdateInviteStatusHandler handles requests to update invite statuses
-func UpdateInviteStatusHandler(cfg *config.Config, logger *slog.Logger) http.HandlerFunc {
+// UpdateInviteStatusHandler handles requests to update invite statuses.
+// Successful changes are recorded in the audit trail, published to the event
+// broker and webhook dispatcher, and emailed to applicants by the decision
+// mailer; any of the dependencies may be nil.
+func UpdateInviteStatusHandler(cfg *config.Config, logger *slog.Logger, deps Dependencies) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		// Get request-scoped logger from context
 		log := LoggerFromContext(r.Context(), logger)
 
 		if r.Method != http.MethodPatch {
-			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			writeMethodNotAllowed(w, r)
 			return
 		}
 
@@ -113,13 +151,31 @@ func UpdateInviteStatusHandler(cfg *config.Config, logger *slog.Logger) http.Han
 		var req UpdateInviteStatusRequest
 		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 			log.Warn("invalid request body", slog.String("error", err.Error()))
-			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			writeError(w, r, http.StatusBadRequest, ErrCodeInvalidRequest, "Invalid request body")
+			return
+		}
+
+		req.Status = strings.ToLower(strings.TrimSpace(req.Status))
+		if !services.IsKnownStatus(req.Status) {
+			log.Warn("invalid invite status", slog.String("status", req.Status))
+			writeValidationError(w, r, FieldError{Field: "status", Message: fmt.Sprintf("unknown status %q", req.Status)})
 			return
 		}
 
+		// Require the versions the reviewer loaded, so concurrent edits are not overwritten
+		ifMatch := r.Header.Get("If-Match")
+		if ifMatch == "" {
+			log.Warn("missing If-Match header")
+			writeError(w, r, http.StatusPreconditionRequired, ErrCodePreconditionRequired,
+				"If-Match header with the invite versions is required")
+			return
+		}
+		expectedVersions := parseIfMatch(ifMatch)
+
 		log.Info("updating invite statuses",
 			slog.Int("email_count", len(req.Emails)),
 			slog.String("status", req.Status),
+			slog.Bool("atomic", req.Atomic),
 		)
 
 		// Get sheets service from context
@@ -127,49 +183,143 @@ func UpdateInviteStatusHandler(cfg *config.Config, logger *slog.Logger) http.Han
 		if !ok {
 			// Create sheets service if not in context
 			var err error
-			sheetsService, err = services.NewSheetsService(r.Context(), &config.SheetsConfig{
-				CredentialsFile: cfg.GoogleCredentialsFile,
-				TokenFile:       cfg.GoogleTokenFile,
-				SpreadsheetID:   cfg.GoogleSpreadsheetID,
-				SheetName:       cfg.GoogleSheetName,
-			})
+			sheetsService, err = services.NewSheetsService(r.Context(), cfg.SheetsConfig())
 			if err != nil {
 				log.Error("failed to create sheets service", slog.String("error", err.Error()))
-				http.Error(w, "Failed to create sheets service", http.StatusInternalServerError)
+				writeError(w, r, http.StatusInternalServerError, ErrCodeInternal, "Failed to create sheets service")
 				return
 			}
 		}
 
 		// Update the status for each email
-		timestamp := time.Now().Format("2006-01-02 15:04:05")
-		if err := sheetsService.UpdateInviteStatus(r.Context(), req.Emails, req.Status, timestamp); err != nil {
+		timestamp := time.Now().In(cfg.SheetLocation()).Format("2006-01-02 15:04:05")
+		results, err := sheetsService.UpdateInviteStatus(r.Context(), services.StatusUpdate{
+			Emails:           req.Emails,
+			Status:           req.Status,
+			Timestamp:        timestamp,
+			Atomic:           req.Atomic,
+			ExpectedVersions: expectedVersions,
+		})
+		if errors.Is(err, services.ErrVersionMismatch) {
+			var fields []FieldError
+			for _, result := range results {
+				if result.Result == services.UpdateResultVersionMismatch {
+					fields = append(fields, FieldError{Field: "emails", Message: fmt.Sprintf("%s has changed since it was loaded", result.Email)})
+				}
+			}
+			log.Warn("rejected update of changed invites", slog.Int("changed_count", len(fields)))
+			writeError(w, r, http.StatusPreconditionFailed, ErrCodePreconditionFailed, "One or more invites have changed since they were loaded", fields...)
+			return
+		}
+		if errors.Is(err, services.ErrUnknownEmails) {
+			var fields []FieldError
+			for _, result := range results {
+				if result.Result == services.UpdateResultNotFound {
+					fields = append(fields, FieldError{Field: "emails", Message: fmt.Sprintf("%s was not found", result.Email)})
+				}
+			}
+			log.Warn("rejected atomic update with unknown emails", slog.Int("unknown_count", len(fields)))
+			writeError(w, r, http.StatusUnprocessableEntity, ErrCodeUnknownEmails, "One or more emails were not found", fields...)
+			return
+		}
+		if err != nil {
 			log.Error("failed to update invite statuses", slog.String("error", err.Error()))
-			http.Error(w, "Failed to update invite statuses", http.StatusInternalServerError)
+			writeUpstreamError(w, r, err, "Failed to update invite statuses")
 			return
 		}
 
-		log.Info("invite statuses updated successfully",
+		response := UpdateInviteStatusResponse{
+			Status:  "success",
+			Results: results,
+			Summary: make(map[string]int),
+		}
+		if response.Results == nil {
+			response.Results = []services.StatusUpdateResult{}
+		}
+		for _, result := range results {
+			response.Summary[result.Result]++
+			if result.Result != services.UpdateResultUpdated && result.Result != services.UpdateResultAlreadyInStatus {
+				response.Status = "partial"
+			}
+		}
+
+		// Record the rows we changed and tell event stream subscribers,
+		// webhooks and applicants about them
+		requestID := RequestIDFromContext(r.Context())
+		for _, result := range results {
+			if result.Result != services.UpdateResultUpdated {
+				continue
+			}
+			eventType := services.EventStatusChanged
+			if req.Status == services.StatusDuplicate {
+				eventType = services.EventDuplicateMarked
+			}
+			event := services.InviteEvent{
+				Type:           eventType,
+				Email:          result.Email,
+				Status:         req.Status,
+				PreviousStatus: result.PreviousStatus,
+			}
+			if result.Invite != nil {
+				event.SubmittedAt = result.Invite.SubmittedAt
+			}
+			if deps.Audit != nil {
+				if err := deps.Audit.Record(services.AuditEntry{
+					Action:         services.AuditStatusChanged,
+					Email:          result.Email,
+					Status:         req.Status,
+					PreviousStatus: result.PreviousStatus,
+					RequestID:      requestID,
+					Result:         services.AuditResultSuccess,
+				}); err != nil {
+					log.Error("failed to record status change", slog.String("error", err.Error()))
+				}
+			}
+			if deps.Events != nil {
+				if published, ok := deps.Events.Publish(event); ok {
+					event = published
+				}
+			}
+			if deps.Webhooks != nil {
+				deps.Webhooks.Dispatch(event)
+			}
+			if deps.Mailer != nil && result.Invite != nil {
+				deps.Mailer.Notify(*result.Invite, requestID)
+			}
+		}
+
+		log.Info("invite statuses updated",
 			slog.Int("email_count", len(req.Emails)),
 			slog.String("status", req.Status),
+			slog.Int("updated", response.Summary[services.UpdateResultUpdated]),
+			slog.Int("not_found", response.Summary[services.UpdateResultNotFound]),
+			slog.Int("invalid_transition", response.Summary[services.UpdateResultInvalidTransition]),
 		)
 
 		// Set response headers
 		w.Header().Set("Content-Type", "application/json")
 		w.Header().Set("Access-Control-Allow-Origin", "*")
 		w.WriteHeader(http.StatusOK)
-		json.NewEncoder(w).Encode(map[string]string{"status": "success"})
+		json.NewEncoder(w).Encode(response)
 	}
 }
 
-// Helper function to safely get string values from interface slice
-func getString(row []interface{}, index int) string {
-	if len(row) <= index {
-		return ""
-	}
-	if str, ok := row[index].(string); ok {
-		return str
+// parseIfMatch returns the entity tags listed in an If-Match header, or nil
+// for "*", which matches any version
+func parseIfMatch(header string) []string {
+	versions := []string{}
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "*" {
+			return nil
+		}
+		tag = strings.TrimPrefix(tag, "W/")
+		tag = strings.Trim(tag, `"`)
+		if tag != "" {
+			versions = append(versions, tag)
+		}
 	}
-	return ""
+	return versions
 }
 
 // FrontendLogEntry represents a log entry from the frontend
@@ -186,20 +336,21 @@ func FrontendLogsHandler(logger *slog.Logger) http.HandlerFunc {
 
 	return func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != http.MethodPost {
-			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			writeMethodNotAllowed(w, r)
 			return
 		}
 
 		// Parse request body
 		var entry FrontendLogEntry
 		if err := json.NewDecoder(r.Body).Decode(&entry); err != nil {
-			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			writeError(w, r, http.StatusBadRequest, ErrCodeInvalidRequest, "Invalid request body")
 			return
 		}
 
 		// Validate required fields
 		if entry.Message == "" {
-			http.Error(w, "Message is required", http.StatusBadRequest)
+			writeError(w, r, http.StatusBadRequest, ErrCodeValidationFailed, "Request validation failed",
+				FieldError{Field: "message", Message: "is required"})
 			return
 		}
 
@@ -5,11 +5,16 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"io"
 	"log/slog"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
+
+	"google.golang.org/api/googleapi"
 
 	"github.com/stevebennett/slack-invite-mgr/backend/internal/config"
 	"github.com/stevebennett/slack-invite-mgr/backend/internal/services"
@@ -23,15 +28,25 @@ func testLogger() *slog.Logger {
 // SheetsServiceInterface defines the methods we need from the sheets service
 type SheetsServiceInterface interface {
 	GetSheetData(ctx context.Context) ([][]interface{}, error)
-	UpdateInviteStatus(ctx context.Context, emails []string, status string, timestamp string) error
-	UpdateDuplicateRequests(ctx context.Context, timestamp string) error
+	GetAllSheetData(ctx context.Context) ([][]interface{}, error)
+	UpdateInviteStatus(ctx context.Context, update services.StatusUpdate) ([]services.StatusUpdateResult, error)
+	UpdateDuplicateRequests(ctx context.Context, timestamp string) ([]services.StatusUpdateResult, error)
 	GetNewInvites(ctx context.Context) (int, error)
+	AppendInvite(ctx context.Context, invite services.Invite) error
+	AppendInvites(ctx context.Context, invites []services.Invite) error
+	EachRow(ctx context.Context, fn func(row []interface{}) error) error
 }
 
 // mockSheetsService implements services.SheetsServiceInterface for testing
 type mockSheetsService struct {
 	updateStatusErr error
 	data            [][]interface{}
+	updateResults   []services.StatusUpdateResult
+	lastUpdate      services.StatusUpdate
+	appended        []services.Invite
+	archived        [][]interface{}
+	erasedRows      map[string]int
+	erased          []string
 }
 
 func (m *mockSheetsService) GetSheetData(ctx context.Context) ([][]interface{}, error) {
@@ -41,18 +56,92 @@ func (m *mockSheetsService) GetSheetData(ctx context.Context) ([][]interface{},
 	return m.data, nil
 }
 
-func (m *mockSheetsService) UpdateInviteStatus(ctx context.Context, emails []string, status string, timestamp string) error {
-	return m.updateStatusErr
+func (m *mockSheetsService) GetAllSheetData(ctx context.Context) ([][]interface{}, error) {
+	if m.updateStatusErr != nil {
+		return nil, m.updateStatusErr
+	}
+	return m.data, nil
+}
+
+func (m *mockSheetsService) UpdateInviteStatus(ctx context.Context, update services.StatusUpdate) ([]services.StatusUpdateResult, error) {
+	m.lastUpdate = update
+	if m.updateResults != nil {
+		return m.updateResults, m.updateStatusErr
+	}
+	if m.updateStatusErr != nil {
+		return nil, m.updateStatusErr
+	}
+	var results []services.StatusUpdateResult
+	for _, email := range update.Emails {
+		invite := services.Invite{Name: "Test", Email: email, Status: update.Status}
+		results = append(results, services.StatusUpdateResult{Email: email, Result: services.UpdateResultUpdated, Invite: &invite})
+	}
+	return results, nil
 }
 
-func (m *mockSheetsService) UpdateDuplicateRequests(ctx context.Context, timestamp string) error {
+func (m *mockSheetsService) EachRow(ctx context.Context, fn func(row []interface{}) error) error {
+	if m.updateStatusErr != nil {
+		return m.updateStatusErr
+	}
+	for _, row := range m.data {
+		if err := fn(row); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
+func (m *mockSheetsService) UpdateDuplicateRequests(ctx context.Context, timestamp string) ([]services.StatusUpdateResult, error) {
+	return nil, nil
+}
+
 func (m *mockSheetsService) GetNewInvites(ctx context.Context) (int, error) {
 	return 0, nil
 }
 
+func (m *mockSheetsService) AppendInvite(ctx context.Context, invite services.Invite) error {
+	if m.updateStatusErr != nil {
+		return m.updateStatusErr
+	}
+	m.appended = append(m.appended, invite)
+	return nil
+}
+
+func (m *mockSheetsService) AppendInvites(ctx context.Context, invites []services.Invite) error {
+	if m.updateStatusErr != nil {
+		return m.updateStatusErr
+	}
+	m.appended = append(m.appended, invites...)
+	return nil
+}
+
+func (m *mockSheetsService) ApplyFormatting(ctx context.Context, formatting services.SheetFormatting) error {
+	return m.updateStatusErr
+}
+
+func (m *mockSheetsService) ArchiveInvites(ctx context.Context, opts services.ArchiveOptions) (map[string]int, error) {
+	return nil, m.updateStatusErr
+}
+
+func (m *mockSheetsService) GetArchivedSheetData(ctx context.Context) ([][]interface{}, error) {
+	if m.updateStatusErr != nil {
+		return nil, m.updateStatusErr
+	}
+	return m.archived, nil
+}
+
+func (m *mockSheetsService) RedactInvites(ctx context.Context, before time.Time, dryRun bool) (map[string]int, error) {
+	return nil, m.updateStatusErr
+}
+
+func (m *mockSheetsService) EraseApplicant(ctx context.Context, email string) (map[string]int, error) {
+	if m.updateStatusErr != nil {
+		return nil, m.updateStatusErr
+	}
+	m.erased = append(m.erased, email)
+	return m.erasedRows, nil
+}
+
 // NewSheetsService is a mock factory function
 func NewSheetsService(ctx context.Context, cfg *config.SheetsConfig) (services.SheetsServiceInterface, error) {
 	return &mockSheetsService{
@@ -104,7 +193,7 @@ func TestUpdateInviteStatusHandler(t *testing.T) {
 				Status: "sent",
 			},
 			mockError:      errors.New("sheets service error"),
-			expectedStatus: http.StatusInternalServerError,
+			expectedStatus: http.StatusBadGateway,
 		},
 		{
 			name: "invalid request body",
@@ -113,7 +202,7 @@ func TestUpdateInviteStatusHandler(t *testing.T) {
 				Status: "invalidstatus",
 			},
 			mockError:      nil,
-			expectedStatus: http.StatusOK, // We don't validate the status or email format in the handler
+			expectedStatus: http.StatusBadRequest, // Unknown statuses are rejected before touching the sheet
 		},
 	}
 
@@ -136,6 +225,7 @@ func TestUpdateInviteStatusHandler(t *testing.T) {
 			// Create request
 			req := httptest.NewRequest(http.MethodPatch, "/api/invites", bytes.NewBuffer(body))
 			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set("If-Match", "*")
 
 			// Create response recorder
 			rr := httptest.NewRecorder()
@@ -152,7 +242,7 @@ func TestUpdateInviteStatusHandler(t *testing.T) {
 			handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 				// Create a new context with the mock service
 				ctx := context.WithValue(r.Context(), "sheetsService", mockService)
-				UpdateInviteStatusHandler(cfg, testLogger())(w, r.WithContext(ctx))
+				UpdateInviteStatusHandler(cfg, testLogger(), Dependencies{})(w, r.WithContext(ctx))
 			})
 
 			// Serve request
@@ -166,13 +256,17 @@ func TestUpdateInviteStatusHandler(t *testing.T) {
 
 			// For successful requests, check response body
 			if tt.expectedStatus == http.StatusOK {
-				var response map[string]string
+				var response UpdateInviteStatusResponse
 				if err := json.NewDecoder(rr.Body).Decode(&response); err != nil {
 					t.Fatalf("Failed to decode response body: %v", err)
 				}
-				if response["status"] != "success" {
+				if response.Status != "success" {
 					t.Errorf("handler returned unexpected response: got %v want %v",
-						response["status"], "success")
+						response.Status, "success")
+				}
+				if len(response.Results) != len(tt.requestBody.Emails) {
+					t.Errorf("handler returned unexpected number of results: got %v want %v",
+						len(response.Results), len(tt.requestBody.Emails))
 				}
 			}
 		})
@@ -207,7 +301,7 @@ func TestGetOutstandingInvitesHandler(t *testing.T) {
 			name:           "sheets service error",
 			mockData:       nil,
 			mockError:      errors.New("sheets service error"),
-			expectedStatus: http.StatusInternalServerError,
+			expectedStatus: http.StatusBadGateway,
 			expectedCount:  0,
 		},
 		{
@@ -270,15 +364,585 @@ func TestGetOutstandingInvitesHandler(t *testing.T) {
 
 			// For successful requests, check response body
 			if tt.expectedStatus == http.StatusOK {
-				var response []Invite
+				var response InviteListResponse
 				if err := json.NewDecoder(rr.Body).Decode(&response); err != nil {
 					t.Fatalf("Failed to decode response body: %v", err)
 				}
-				if len(response) != tt.expectedCount {
+				if len(response.Invites) != tt.expectedCount {
 					t.Errorf("handler returned unexpected number of invites: got %v want %v",
-						len(response), tt.expectedCount)
+						len(response.Invites), tt.expectedCount)
 				}
 			}
 		})
 	}
 }
+
+func TestGetOutstandingInvitesHandler_Query(t *testing.T) {
+	mockData := [][]interface{}{
+		{"Timestamp", "Name", "Role", "Email", "", "Company", "Years", "Reasons", "Source", "Status", "Updated"},
+		{"1/5/2024 10:00:00", "Alice", "Dev", "alice@example.com", "", "Acme", "5", "Learning Go", "Meetup", "", ""},
+		{"1/10/2024 10:00:00", "Bob", "PM", "bob@example.com", "", "Globex", "3", "Networking", "Twitter", "sent", "2024-01-12 09:00:00"},
+		{"1/15/2024 10:00:00", "Carol", "Dev", "carol@example.com", "", "Acme", "8", "Mentoring", "Meetup", "denied", "2024-01-16 09:00:00"},
+		{"1/20/2024 10:00:00", "Dave", "QA", "dave@example.com", "", "Initech", "1", "Job hunting", "meetup", "", ""},
+		{"1/25/2024 10:00:00", "Alice", "Dev", "alice@example.com", "", "Acme", "5", "Learning Go", "Meetup", "Duplicate", "2024-01-26 09:00:00"},
+	}
+	archivedData := [][]interface{}{
+		{"Timestamp", "Name", "Role", "Email", "", "Company", "Years", "Reasons", "Source", "Status", "Updated"},
+		{"6/5/2023 10:00:00", "Erin", "Dev", "erin@example.com", "", "Acme", "2", "Learning Go", "Meetup", "sent", "2023-06-06 09:00:00"},
+	}
+
+	tests := []struct {
+		name           string
+		query          string
+		timeZone       string
+		expectedStatus int
+		expectedNames  []string
+		expectedTotal  int
+	}{
+		{
+			name:           "defaults to pending invites",
+			query:          "",
+			expectedStatus: http.StatusOK,
+			expectedNames:  []string{"Alice", "Dave"},
+			expectedTotal:  2,
+		},
+		{
+			name:           "multiple statuses",
+			query:          "status=sent,denied",
+			expectedStatus: http.StatusOK,
+			expectedNames:  []string{"Bob", "Carol"},
+			expectedTotal:  2,
+		},
+		{
+			name:           "all statuses excludes header row",
+			query:          "status=all",
+			expectedStatus: http.StatusOK,
+			expectedNames:  []string{"Alice", "Bob", "Carol", "Dave", "Alice"},
+			expectedTotal:  5,
+		},
+		{
+			name:           "free-text search over company",
+			query:          "status=all&q=acme",
+			expectedStatus: http.StatusOK,
+			expectedNames:  []string{"Alice", "Carol", "Alice"},
+			expectedTotal:  3,
+		},
+		{
+			name:           "source filter is case-insensitive",
+			query:          "status=all&source=MEETUP",
+			expectedStatus: http.StatusOK,
+			expectedNames:  []string{"Alice", "Carol", "Dave", "Alice"},
+			expectedTotal:  4,
+		},
+		{
+			name:           "submitted date range",
+			query:          "status=all&submitted_after=2024-01-10&submitted_before=2024-01-20",
+			expectedStatus: http.StatusOK,
+			expectedNames:  []string{"Bob", "Carol"},
+			expectedTotal:  2,
+		},
+		{
+			// Bob's 10:00 in Berlin is 09:00 UTC
+			name:           "timestamps read in the sheet time zone",
+			query:          "status=all&submitted_after=2024-01-10T09:30:00Z&submitted_before=2024-01-16",
+			timeZone:       "Europe/Berlin",
+			expectedStatus: http.StatusOK,
+			expectedNames:  []string{"Carol"},
+			expectedTotal:  1,
+		},
+		{
+			name:           "sort descending with pagination",
+			query:          "status=all&sort=-submitted&limit=2&offset=1",
+			expectedStatus: http.StatusOK,
+			expectedNames:  []string{"Dave", "Carol"},
+			expectedTotal:  5,
+		},
+		{
+			name:           "offset beyond results",
+			query:          "status=all&offset=10",
+			expectedStatus: http.StatusOK,
+			expectedNames:  []string{},
+			expectedTotal:  5,
+		},
+		{
+			name:           "archived invites are excluded by default",
+			query:          "status=sent",
+			expectedStatus: http.StatusOK,
+			expectedNames:  []string{"Bob"},
+			expectedTotal:  1,
+		},
+		{
+			name:           "archived invites on request",
+			query:          "status=sent&archived=true",
+			expectedStatus: http.StatusOK,
+			expectedNames:  []string{"Bob", "Erin"},
+			expectedTotal:  2,
+		},
+		{
+			name:           "invalid archived flag",
+			query:          "archived=maybe",
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "unknown status",
+			query:          "status=archived",
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "invalid limit",
+			query:          "limit=-1",
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "unknown sort field",
+			query:          "sort=reasons",
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "invalid date",
+			query:          "submitted_after=yesterday",
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.Config{GoogleSpreadsheetID: "test-spreadsheet-id", GoogleSheetName: "test-sheet", SheetTimeZone: tt.timeZone}
+			req := httptest.NewRequest(http.MethodGet, "/api/invites?"+tt.query, nil)
+			rr := httptest.NewRecorder()
+			mockService := &mockSheetsService{data: mockData, archived: archivedData}
+
+			ctx := context.WithValue(req.Context(), "sheetsService", mockService)
+			GetOutstandingInvitesHandler(cfg, testLogger())(rr, req.WithContext(ctx))
+
+			if rr.Code != tt.expectedStatus {
+				t.Fatalf("handler returned wrong status code: got %v want %v", rr.Code, tt.expectedStatus)
+			}
+			if tt.expectedStatus != http.StatusOK {
+				return
+			}
+
+			var response InviteListResponse
+			if err := json.NewDecoder(rr.Body).Decode(&response); err != nil {
+				t.Fatalf("Failed to decode response body: %v", err)
+			}
+			if response.Total != tt.expectedTotal {
+				t.Errorf("unexpected total: got %v want %v", response.Total, tt.expectedTotal)
+			}
+			if response.Count != len(response.Invites) {
+				t.Errorf("count %v does not match invites length %v", response.Count, len(response.Invites))
+			}
+			names := make([]string, 0, len(response.Invites))
+			for _, invite := range response.Invites {
+				names = append(names, invite.Name)
+			}
+			if len(names) != len(tt.expectedNames) {
+				t.Fatalf("unexpected invites: got %v want %v", names, tt.expectedNames)
+			}
+			for i := range names {
+				if names[i] != tt.expectedNames[i] {
+					t.Errorf("unexpected invites: got %v want %v", names, tt.expectedNames)
+					break
+				}
+			}
+			for _, invite := range response.Invites {
+				if invite.Version == "" {
+					t.Errorf("invite %v has no version", invite.Email)
+				}
+				if invite.Archived != (invite.Name == "Erin") {
+					t.Errorf("invite %v has archived %v", invite.Email, invite.Archived)
+				}
+			}
+			if response.StatusCounts["pending"] != 2 || response.StatusCounts["duplicate"] != 1 {
+				t.Errorf("unexpected status counts: %v", response.StatusCounts)
+			}
+		})
+	}
+}
+
+func TestWriteUpstreamError(t *testing.T) {
+	tests := []struct {
+		name               string
+		err                error
+		expectedStatus     int
+		expectedCode       string
+		expectedRetryAfter string
+	}{
+		{
+			name:           "generic error",
+			err:            errors.New("boom"),
+			expectedStatus: http.StatusBadGateway,
+			expectedCode:   ErrCodeUpstreamError,
+		},
+		{
+			name:               "quota exhausted",
+			err:                fmt.Errorf("failed to retrieve sheet data: %w", &googleapi.Error{Code: http.StatusTooManyRequests}),
+			expectedStatus:     http.StatusServiceUnavailable,
+			expectedCode:       ErrCodeQuotaExhausted,
+			expectedRetryAfter: "60",
+		},
+		{
+			name: "quota exhausted with upstream retry-after",
+			err: &googleapi.Error{
+				Code:   http.StatusTooManyRequests,
+				Header: http.Header{"Retry-After": []string{"17"}},
+			},
+			expectedStatus:     http.StatusServiceUnavailable,
+			expectedCode:       ErrCodeQuotaExhausted,
+			expectedRetryAfter: "17",
+		},
+		{
+			name: "legacy rate limit reason",
+			err: &googleapi.Error{
+				Code:   http.StatusForbidden,
+				Errors: []googleapi.ErrorItem{{Reason: "userRateLimitExceeded"}},
+			},
+			expectedStatus:     http.StatusServiceUnavailable,
+			expectedCode:       ErrCodeQuotaExhausted,
+			expectedRetryAfter: "60",
+		},
+		{
+			name:           "permission denied",
+			err:            &googleapi.Error{Code: http.StatusForbidden},
+			expectedStatus: http.StatusBadGateway,
+			expectedCode:   ErrCodeUpstreamError,
+		},
+		{
+			name:           "upstream unavailable",
+			err:            &googleapi.Error{Code: http.StatusServiceUnavailable},
+			expectedStatus: http.StatusServiceUnavailable,
+			expectedCode:   ErrCodeUpstreamUnavailable,
+		},
+		{
+			name:           "deadline exceeded",
+			err:            fmt.Errorf("failed to retrieve sheet data: %w", context.DeadlineExceeded),
+			expectedStatus: http.StatusGatewayTimeout,
+			expectedCode:   ErrCodeUpstreamTimeout,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/api/invites", nil)
+			req = req.WithContext(context.WithValue(req.Context(), RequestIDKey, "req-123"))
+			rr := httptest.NewRecorder()
+
+			writeUpstreamError(rr, req, tt.err, "Failed to get sheet data")
+
+			if rr.Code != tt.expectedStatus {
+				t.Errorf("unexpected status code: got %v want %v", rr.Code, tt.expectedStatus)
+			}
+			if got := rr.Header().Get("Retry-After"); got != tt.expectedRetryAfter {
+				t.Errorf("unexpected Retry-After: got %q want %q", got, tt.expectedRetryAfter)
+			}
+
+			var response ErrorResponse
+			if err := json.NewDecoder(rr.Body).Decode(&response); err != nil {
+				t.Fatalf("Failed to decode response body: %v", err)
+			}
+			if response.Error.Code != tt.expectedCode {
+				t.Errorf("unexpected error code: got %v want %v", response.Error.Code, tt.expectedCode)
+			}
+			if response.Error.RequestID != "req-123" {
+				t.Errorf("unexpected request ID: got %v want %v", response.Error.RequestID, "req-123")
+			}
+		})
+	}
+}
+
+func TestValidationErrorEnvelope(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/api/invites?limit=abc", nil)
+	rr := httptest.NewRecorder()
+	ctx := context.WithValue(req.Context(), "sheetsService", &mockSheetsService{})
+
+	GetOutstandingInvitesHandler(&config.Config{}, testLogger())(rr, req.WithContext(ctx))
+
+	if rr.Code != http.StatusBadRequest {
+		t.Fatalf("unexpected status code: got %v want %v", rr.Code, http.StatusBadRequest)
+	}
+	var response ErrorResponse
+	if err := json.NewDecoder(rr.Body).Decode(&response); err != nil {
+		t.Fatalf("Failed to decode response body: %v", err)
+	}
+	if response.Error.Code != ErrCodeValidationFailed {
+		t.Errorf("unexpected error code: got %v want %v", response.Error.Code, ErrCodeValidationFailed)
+	}
+	if len(response.Error.Fields) != 1 || response.Error.Fields[0].Field != "limit" {
+		t.Errorf("unexpected field errors: %v", response.Error.Fields)
+	}
+}
+
+func TestUpdateInviteStatusHandler_PartialResults(t *testing.T) {
+	tests := []struct {
+		name            string
+		requestBody     UpdateInviteStatusRequest
+		mockResults     []services.StatusUpdateResult
+		mockError       error
+		expectedStatus  int
+		expectedOutcome string
+		expectedSummary map[string]int
+		expectedCode    string
+	}{
+		{
+			name:        "partial success",
+			requestBody: UpdateInviteStatusRequest{Emails: []string{"a@example.com", "b@example.com", "c@example.com"}, Status: "sent"},
+			mockResults: []services.StatusUpdateResult{
+				{Email: "a@example.com", Result: services.UpdateResultUpdated, PreviousStatus: "pending"},
+				{Email: "b@example.com", Result: services.UpdateResultNotFound},
+				{Email: "c@example.com", Result: services.UpdateResultInvalidTransition, PreviousStatus: "sent"},
+			},
+			expectedStatus:  http.StatusOK,
+			expectedOutcome: "partial",
+			expectedSummary: map[string]int{"updated": 1, "not_found": 1, "invalid_transition": 1},
+		},
+		{
+			name:        "already in status counts as success",
+			requestBody: UpdateInviteStatusRequest{Emails: []string{"a@example.com"}, Status: "denied"},
+			mockResults: []services.StatusUpdateResult{
+				{Email: "a@example.com", Result: services.UpdateResultAlreadyInStatus, PreviousStatus: "denied"},
+			},
+			expectedStatus:  http.StatusOK,
+			expectedOutcome: "success",
+			expectedSummary: map[string]int{"already_in_status": 1},
+		},
+		{
+			name:        "atomic update with unknown email",
+			requestBody: UpdateInviteStatusRequest{Emails: []string{"a@example.com", "typo@example.com"}, Status: "sent", Atomic: true},
+			mockResults: []services.StatusUpdateResult{
+				{Email: "a@example.com", Result: services.UpdateResultUpdated, PreviousStatus: "pending"},
+				{Email: "typo@example.com", Result: services.UpdateResultNotFound},
+			},
+			mockError:      services.ErrUnknownEmails,
+			expectedStatus: http.StatusUnprocessableEntity,
+			expectedCode:   ErrCodeUnknownEmails,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body, err := json.Marshal(tt.requestBody)
+			if err != nil {
+				t.Fatalf("Failed to marshal request body: %v", err)
+			}
+			req := httptest.NewRequest(http.MethodPatch, "/api/invites", bytes.NewBuffer(body))
+			req.Header.Set("If-Match", "*")
+			rr := httptest.NewRecorder()
+			mockService := &mockSheetsService{updateResults: tt.mockResults, updateStatusErr: tt.mockError}
+
+			ctx := context.WithValue(req.Context(), "sheetsService", mockService)
+			UpdateInviteStatusHandler(&config.Config{}, testLogger(), Dependencies{})(rr, req.WithContext(ctx))
+
+			if rr.Code != tt.expectedStatus {
+				t.Fatalf("handler returned wrong status code: got %v want %v", rr.Code, tt.expectedStatus)
+			}
+
+			if tt.expectedCode != "" {
+				var response ErrorResponse
+				if err := json.NewDecoder(rr.Body).Decode(&response); err != nil {
+					t.Fatalf("Failed to decode response body: %v", err)
+				}
+				if response.Error.Code != tt.expectedCode {
+					t.Errorf("unexpected error code: got %v want %v", response.Error.Code, tt.expectedCode)
+				}
+				if len(response.Error.Fields) != 1 {
+					t.Errorf("expected one field error, got %v", response.Error.Fields)
+				}
+				return
+			}
+
+			var response UpdateInviteStatusResponse
+			if err := json.NewDecoder(rr.Body).Decode(&response); err != nil {
+				t.Fatalf("Failed to decode response body: %v", err)
+			}
+			if response.Status != tt.expectedOutcome {
+				t.Errorf("unexpected status: got %v want %v", response.Status, tt.expectedOutcome)
+			}
+			if len(response.Summary) != len(tt.expectedSummary) {
+				t.Errorf("unexpected summary: got %v want %v", response.Summary, tt.expectedSummary)
+			}
+			for result, count := range tt.expectedSummary {
+				if response.Summary[result] != count {
+					t.Errorf("unexpected summary: got %v want %v", response.Summary, tt.expectedSummary)
+				}
+			}
+		})
+	}
+}
+
+func TestUpdateInviteStatusHandler_IfMatch(t *testing.T) {
+	tests := []struct {
+		name             string
+		ifMatch          string
+		mockResults      []services.StatusUpdateResult
+		mockError        error
+		expectedStatus   int
+		expectedVersions []string
+	}{
+		{
+			name:           "missing If-Match",
+			ifMatch:        "",
+			expectedStatus: http.StatusPreconditionRequired,
+		},
+		{
+			name:             "wildcard skips version checks",
+			ifMatch:          "*",
+			expectedStatus:   http.StatusOK,
+			expectedVersions: nil,
+		},
+		{
+			name:             "versions are passed to the service",
+			ifMatch:          `"abc", W/"def"`,
+			expectedStatus:   http.StatusOK,
+			expectedVersions: []string{"abc", "def"},
+		},
+		{
+			name:    "changed row",
+			ifMatch: `"abc"`,
+			mockResults: []services.StatusUpdateResult{
+				{Email: "a@example.com", Result: services.UpdateResultVersionMismatch, PreviousStatus: "pending"},
+			},
+			mockError:        services.ErrVersionMismatch,
+			expectedStatus:   http.StatusPreconditionFailed,
+			expectedVersions: []string{"abc"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body := bytes.NewBufferString(`{"emails":["a@example.com"],"status":"sent"}`)
+			req := httptest.NewRequest(http.MethodPatch, "/api/invites", body)
+			if tt.ifMatch != "" {
+				req.Header.Set("If-Match", tt.ifMatch)
+			}
+			rr := httptest.NewRecorder()
+			mockService := &mockSheetsService{updateResults: tt.mockResults, updateStatusErr: tt.mockError}
+
+			ctx := context.WithValue(req.Context(), "sheetsService", mockService)
+			UpdateInviteStatusHandler(&config.Config{}, testLogger(), Dependencies{})(rr, req.WithContext(ctx))
+
+			if rr.Code != tt.expectedStatus {
+				t.Fatalf("handler returned wrong status code: got %v want %v", rr.Code, tt.expectedStatus)
+			}
+			if tt.expectedStatus == http.StatusPreconditionRequired {
+				return
+			}
+			got := mockService.lastUpdate.ExpectedVersions
+			if (got == nil) != (tt.expectedVersions == nil) || len(got) != len(tt.expectedVersions) {
+				t.Fatalf("unexpected expected versions: got %#v want %#v", got, tt.expectedVersions)
+			}
+			for i := range got {
+				if got[i] != tt.expectedVersions[i] {
+					t.Errorf("unexpected expected versions: got %#v want %#v", got, tt.expectedVersions)
+				}
+			}
+		})
+	}
+}
+
+func TestInviteStreamHandler(t *testing.T) {
+	events := services.NewEventBroker(10)
+	events.Publish(services.InviteEvent{Type: services.EventInviteAdded, Email: "a@example.com", Status: services.StatusPending})
+	events.Publish(services.InviteEvent{Type: services.EventStatusChanged, Email: "a@example.com", Status: services.StatusSent})
+
+	tests := []struct {
+		name             string
+		lastEventID      string
+		expectedStatus   int
+		expectedContains []string
+		expectedMissing  []string
+	}{
+		{
+			name:             "resumes after Last-Event-ID",
+			lastEventID:      "1",
+			expectedStatus:   http.StatusOK,
+			expectedContains: []string{"retry: 5000", "id: 2\nevent: status-changed\n"},
+			expectedMissing:  []string{"id: 1\n"},
+		},
+		{
+			name:            "new stream has no backlog",
+			expectedStatus:  http.StatusOK,
+			expectedMissing: []string{"id: 1\n", "id: 2\n"},
+		},
+		{
+			name:           "invalid Last-Event-ID",
+			lastEventID:    "abc",
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Cancel up front so the handler returns after writing the backlog
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+			req := httptest.NewRequest(http.MethodGet, "/api/invites/stream", nil).WithContext(ctx)
+			if tt.lastEventID != "" {
+				req.Header.Set("Last-Event-ID", tt.lastEventID)
+			}
+			rr := httptest.NewRecorder()
+
+			InviteStreamHandler(events, time.Minute, testLogger())(rr, req)
+
+			if rr.Code != tt.expectedStatus {
+				t.Fatalf("handler returned wrong status code: got %v want %v", rr.Code, tt.expectedStatus)
+			}
+			body := rr.Body.String()
+			for _, want := range tt.expectedContains {
+				if !strings.Contains(body, want) {
+					t.Errorf("expected stream to contain %q, got %q", want, body)
+				}
+			}
+			for _, unwanted := range tt.expectedMissing {
+				if strings.Contains(body, unwanted) {
+					t.Errorf("expected stream not to contain %q, got %q", unwanted, body)
+				}
+			}
+		})
+	}
+}
+
+type recordingEmailSender struct {
+	recipients []string
+}
+
+func (s *recordingEmailSender) SendTo(ctx context.Context, to, subject, body string) error {
+	s.recipients = append(s.recipients, to)
+	return nil
+}
+
+func TestUpdateInviteStatusHandler_AuditAndEmails(t *testing.T) {
+	audit := services.NewAuditLog("")
+	sender := &recordingEmailSender{}
+	mailer, err := services.NewDecisionMailer(map[string]config.ApplicantEmailTemplate{
+		services.StatusDenied: {Enabled: true, Subject: "About your application", Body: "<p>Hi {{.Name}}</p>"},
+	}, sender, audit, testLogger())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	deps := Dependencies{Audit: audit, Mailer: mailer}
+
+	body := `{"emails":["a@example.com","b@example.com"],"status":"denied"}`
+	req := httptest.NewRequest(http.MethodPatch, "/api/invites", strings.NewReader(body))
+	req.Header.Set("If-Match", "*")
+	ctx := context.WithValue(req.Context(), "sheetsService", &mockSheetsService{})
+	rr := httptest.NewRecorder()
+
+	UpdateInviteStatusHandler(&config.Config{}, testLogger(), deps)(rr, req.WithContext(ctx))
+	mailer.Wait()
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if len(sender.recipients) != 2 {
+		t.Errorf("Expected 2 decision emails, got %v", sender.recipients)
+	}
+
+	changes, _ := audit.List(services.AuditFilter{Action: services.AuditStatusChanged})
+	emails, _ := audit.List(services.AuditFilter{Action: services.AuditDecisionEmail, Email: "A@example.com"})
+	if len(changes) != 2 {
+		t.Errorf("Expected 2 status changes in the audit trail, got %+v", changes)
+	}
+	if len(emails) != 1 || emails[0].Result != services.AuditResultSuccess {
+		t.Errorf("Expected a successful decision email for a@example.com, got %+v", emails)
+	}
+}
@@ -0,0 +1,265 @@
+package api
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// IdempotencyKeyHeader is the request header carrying the client's idempotency key
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+// maxIdempotencyKeyLength bounds the size of keys we are willing to store
+const maxIdempotencyKeyLength = 255
+
+var (
+	// ErrIdempotencyKeyReused is returned when a key is replayed with a different request
+	ErrIdempotencyKeyReused = errors.New("idempotency key was used with a different request")
+	// ErrIdempotencyKeyInProgress is returned when a key is replayed before the first request finished
+	ErrIdempotencyKeyInProgress = errors.New("a request with this idempotency key is still in progress")
+)
+
+// IdempotentResponse is a stored response that can be replayed
+type IdempotentResponse struct {
+	Status int
+	Header http.Header
+	Body   []byte
+}
+
+// IdempotencyStore records responses to idempotent requests
+type IdempotencyStore interface {
+	// Reserve claims key for a request with the given fingerprint. It returns
+	// the stored response when the key has already completed, or nil when the
+	// caller should process the request and then call Complete or Release.
+	Reserve(key, fingerprint string, ttl time.Duration) (*IdempotentResponse, error)
+	// Complete stores the response for a reserved key
+	Complete(key string, resp *IdempotentResponse)
+	// Release forgets a reserved key so the request can be retried
+	Release(key string)
+}
+
+type idempotencyRecord struct {
+	fingerprint string
+	response    *IdempotentResponse
+	expiresAt   time.Time
+}
+
+// MemoryIdempotencyStore is an in-process IdempotencyStore
+type MemoryIdempotencyStore struct {
+	mu      sync.Mutex
+	records map[string]*idempotencyRecord
+	now     func() time.Time
+}
+
+// NewMemoryIdempotencyStore creates an empty in-memory idempotency store
+func NewMemoryIdempotencyStore() *MemoryIdempotencyStore {
+	return &MemoryIdempotencyStore{
+		records: make(map[string]*idempotencyRecord),
+		now:     time.Now,
+	}
+}
+
+// Reserve implements IdempotencyStore
+func (s *MemoryIdempotencyStore) Reserve(key, fingerprint string, ttl time.Duration) (*IdempotentResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	s.evictExpired(now)
+
+	if record, exists := s.records[key]; exists {
+		if record.fingerprint != fingerprint {
+			return nil, ErrIdempotencyKeyReused
+		}
+		if record.response == nil {
+			return nil, ErrIdempotencyKeyInProgress
+		}
+		return record.response, nil
+	}
+
+	s.records[key] = &idempotencyRecord{
+		fingerprint: fingerprint,
+		expiresAt:   now.Add(ttl),
+	}
+	return nil, nil
+}
+
+// Complete implements IdempotencyStore
+func (s *MemoryIdempotencyStore) Complete(key string, resp *IdempotentResponse) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if record, exists := s.records[key]; exists {
+		record.response = resp
+	}
+}
+
+// Release implements IdempotencyStore
+func (s *MemoryIdempotencyStore) Release(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.records, key)
+}
+
+// evictExpired drops records past their expiry. Callers must hold s.mu.
+func (s *MemoryIdempotencyStore) evictExpired(now time.Time) {
+	for key, record := range s.records {
+		if now.After(record.expiresAt) {
+			delete(s.records, key)
+		}
+	}
+}
+
+// recordingWriter passes a response through while keeping a copy of it
+type recordingWriter struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (rw *recordingWriter) WriteHeader(code int) {
+	rw.status = code
+	rw.ResponseWriter.WriteHeader(code)
+}
+
+func (rw *recordingWriter) Write(b []byte) (int, error) {
+	rw.body.Write(b)
+	return rw.ResponseWriter.Write(b)
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer
+func (rw *recordingWriter) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}
+
+// IdempotencyMiddleware replays stored responses for mutating requests that
+// repeat an Idempotency-Key within ttl. Keys are scoped to the caller
+// returned by caller, which may be nil when every caller is the same, so one
+// caller never sees another's responses. Apply it inside any authentication.
+// Reusing a key with a different method, path, body or If-Match is rejected
+// with 409. Server errors, authentication failures, failed preconditions and
+// rate limits are not stored, so the request can be retried with the same key
+// once the problem is fixed.
+func IdempotencyMiddleware(store IdempotencyStore, ttl time.Duration, caller func(*http.Request) string, logger *slog.Logger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key := r.Header.Get(IdempotencyKeyHeader)
+			if key == "" || !isMutatingMethod(r.Method) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			log := LoggerFromContext(r.Context(), logger)
+
+			if len(key) > maxIdempotencyKeyLength {
+				writeValidationError(w, r, FieldError{Field: IdempotencyKeyHeader, Message: "must not exceed 255 characters"})
+				return
+			}
+
+			// Read the body so it can be fingerprinted and then handed on
+			body, err := io.ReadAll(r.Body)
+			if err != nil {
+				writeError(w, r, http.StatusBadRequest, ErrCodeInvalidRequest, "Failed to read request body")
+				return
+			}
+			r.Body = io.NopCloser(bytes.NewReader(body))
+
+			// Scope the key to the caller, without keeping credentials in the store
+			storeKey := key
+			if caller != nil {
+				scope := sha256.Sum256([]byte(caller(r)))
+				storeKey = hex.EncodeToString(scope[:]) + ":" + key
+			}
+
+			stored, err := store.Reserve(storeKey, requestFingerprint(r, body), ttl)
+			switch {
+			case errors.Is(err, ErrIdempotencyKeyReused):
+				log.Warn("idempotency key reused with a different request", slog.String("idempotency_key", key))
+				writeError(w, r, http.StatusConflict, ErrCodeIdempotencyKeyReused, "Idempotency key was already used for a different request")
+				return
+			case errors.Is(err, ErrIdempotencyKeyInProgress):
+				log.Warn("idempotency key still in progress", slog.String("idempotency_key", key))
+				writeError(w, r, http.StatusConflict, ErrCodeIdempotencyInProgress, "A request with this idempotency key is still in progress")
+				return
+			case err != nil:
+				log.Error("failed to reserve idempotency key", slog.String("error", err.Error()))
+				writeError(w, r, http.StatusInternalServerError, ErrCodeInternal, "Failed to check idempotency key")
+				return
+			}
+
+			if stored != nil {
+				log.Info("replaying idempotent response", slog.String("idempotency_key", key))
+				for name, values := range stored.Header {
+					w.Header()[name] = values
+				}
+				w.Header().Set("Idempotent-Replayed", "true")
+				w.WriteHeader(stored.Status)
+				w.Write(stored.Body)
+				return
+			}
+
+			// Release the key if the handler panics so the client can retry
+			completed := false
+			defer func() {
+				if !completed {
+					store.Release(storeKey)
+				}
+			}()
+
+			recorder := &recordingWriter{ResponseWriter: w, status: http.StatusOK}
+			next.ServeHTTP(recorder, r)
+
+			if !storableStatus(recorder.status) {
+				return
+			}
+			completed = true
+			// The replay gets its own request ID from the logging middleware
+			header := w.Header().Clone()
+			header.Del("X-Request-ID")
+			store.Complete(storeKey, &IdempotentResponse{
+				Status: recorder.status,
+				Header: header,
+				Body:   recorder.body.Bytes(),
+			})
+		})
+	}
+}
+
+// isMutatingMethod reports whether requests with the method change state
+func isMutatingMethod(method string) bool {
+	switch method {
+	case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+		return true
+	default:
+		return false
+	}
+}
+
+// storableStatus reports whether a response with the status can be replayed.
+// Responses that depend on credentials, versions or timing are not kept.
+func storableStatus(status int) bool {
+	switch status {
+	case http.StatusUnauthorized, http.StatusForbidden, http.StatusPreconditionFailed,
+		http.StatusPreconditionRequired, http.StatusTooManyRequests:
+		return false
+	default:
+		return status < http.StatusInternalServerError
+	}
+}
+
+// requestFingerprint identifies a request by its method, path, If-Match
+// versions and body
+func requestFingerprint(r *http.Request, body []byte) string {
+	hash := sha256.New()
+	io.WriteString(hash, r.Method+" "+r.URL.RequestURI()+"\n")
+	io.WriteString(hash, r.Header.Get("If-Match")+"\n")
+	hash.Write(body)
+	return hex.EncodeToString(hash.Sum(nil))
+}
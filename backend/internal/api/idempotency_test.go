@@ -0,0 +1,221 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stevebennett/slack-invite-mgr/backend/internal/config"
+)
+
+func TestIdempotencyMiddleware(t *testing.T) {
+	type step struct {
+		method         string
+		key            string
+		body           string
+		ifMatch        string
+		caller         string
+		expectedStatus int
+		expectedCalls  int
+		expectReplay   bool
+	}
+
+	tests := []struct {
+		name          string
+		handlerStatus int
+		steps         []step
+	}{
+		{
+			name:          "repeat request is replayed",
+			handlerStatus: http.StatusOK,
+			steps: []step{
+				{method: http.MethodPatch, key: "k1", body: `{"status":"sent"}`, expectedStatus: http.StatusOK, expectedCalls: 1},
+				{method: http.MethodPatch, key: "k1", body: `{"status":"sent"}`, expectedStatus: http.StatusOK, expectedCalls: 1, expectReplay: true},
+			},
+		},
+		{
+			name:          "same key with different body conflicts",
+			handlerStatus: http.StatusOK,
+			steps: []step{
+				{method: http.MethodPatch, key: "k1", body: `{"status":"sent"}`, expectedStatus: http.StatusOK, expectedCalls: 1},
+				{method: http.MethodPatch, key: "k1", body: `{"status":"denied"}`, expectedStatus: http.StatusConflict, expectedCalls: 1},
+			},
+		},
+		{
+			name:          "different keys are processed separately",
+			handlerStatus: http.StatusOK,
+			steps: []step{
+				{method: http.MethodPatch, key: "k1", body: `{}`, expectedStatus: http.StatusOK, expectedCalls: 1},
+				{method: http.MethodPatch, key: "k2", body: `{}`, expectedStatus: http.StatusOK, expectedCalls: 2},
+			},
+		},
+		{
+			name:          "requests without a key are not deduplicated",
+			handlerStatus: http.StatusOK,
+			steps: []step{
+				{method: http.MethodPatch, body: `{}`, expectedStatus: http.StatusOK, expectedCalls: 1},
+				{method: http.MethodPatch, body: `{}`, expectedStatus: http.StatusOK, expectedCalls: 2},
+			},
+		},
+		{
+			name:          "safe methods ignore the key",
+			handlerStatus: http.StatusOK,
+			steps: []step{
+				{method: http.MethodGet, key: "k1", expectedStatus: http.StatusOK, expectedCalls: 1},
+				{method: http.MethodGet, key: "k1", expectedStatus: http.StatusOK, expectedCalls: 2},
+			},
+		},
+		{
+			name:          "server errors are not stored",
+			handlerStatus: http.StatusBadGateway,
+			steps: []step{
+				{method: http.MethodPatch, key: "k1", body: `{}`, expectedStatus: http.StatusBadGateway, expectedCalls: 1},
+				{method: http.MethodPatch, key: "k1", body: `{}`, expectedStatus: http.StatusBadGateway, expectedCalls: 2},
+			},
+		},
+		{
+			name:          "same key with different versions conflicts",
+			handlerStatus: http.StatusOK,
+			steps: []step{
+				{method: http.MethodPatch, key: "k1", body: `{}`, ifMatch: `"v1"`, expectedStatus: http.StatusOK, expectedCalls: 1},
+				{method: http.MethodPatch, key: "k1", body: `{}`, ifMatch: `"v2"`, expectedStatus: http.StatusConflict, expectedCalls: 1},
+			},
+		},
+		{
+			name:          "keys are scoped to the caller",
+			handlerStatus: http.StatusOK,
+			steps: []step{
+				{method: http.MethodPatch, key: "k1", body: `{}`, caller: "alice", expectedStatus: http.StatusOK, expectedCalls: 1},
+				{method: http.MethodPatch, key: "k1", body: `{}`, caller: "mallory", expectedStatus: http.StatusOK, expectedCalls: 2},
+				{method: http.MethodPatch, key: "k1", body: `{}`, caller: "alice", expectedStatus: http.StatusOK, expectedCalls: 2, expectReplay: true},
+			},
+		},
+		{
+			name:          "failed preconditions are not stored",
+			handlerStatus: http.StatusPreconditionFailed,
+			steps: []step{
+				{method: http.MethodPatch, key: "k1", body: `{}`, expectedStatus: http.StatusPreconditionFailed, expectedCalls: 1},
+				{method: http.MethodPatch, key: "k1", body: `{}`, expectedStatus: http.StatusPreconditionFailed, expectedCalls: 2},
+			},
+		},
+		{
+			name:          "authentication failures are not stored",
+			handlerStatus: http.StatusUnauthorized,
+			steps: []step{
+				{method: http.MethodPatch, key: "k1", body: `{}`, expectedStatus: http.StatusUnauthorized, expectedCalls: 1},
+				{method: http.MethodPatch, key: "k1", body: `{}`, expectedStatus: http.StatusUnauthorized, expectedCalls: 2},
+			},
+		},
+		{
+			name:          "oversized key is rejected",
+			handlerStatus: http.StatusOK,
+			steps: []step{
+				{method: http.MethodPatch, key: strings.Repeat("k", 256), body: `{}`, expectedStatus: http.StatusBadRequest, expectedCalls: 0},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := 0
+			next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				calls++
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(tt.handlerStatus)
+				fmt.Fprintf(w, `{"call":%d}`, calls)
+			})
+			caller := func(r *http.Request) string { return r.Header.Get("X-Test-Caller") }
+			handler := IdempotencyMiddleware(NewMemoryIdempotencyStore(), time.Hour, caller, testLogger())(next)
+
+			var firstBody string
+			for i, s := range tt.steps {
+				req := httptest.NewRequest(s.method, "/api/invites", strings.NewReader(s.body))
+				if s.key != "" {
+					req.Header.Set(IdempotencyKeyHeader, s.key)
+				}
+				if s.ifMatch != "" {
+					req.Header.Set("If-Match", s.ifMatch)
+				}
+				req.Header.Set("X-Test-Caller", s.caller)
+				rr := httptest.NewRecorder()
+				handler.ServeHTTP(rr, req)
+
+				if rr.Code != s.expectedStatus {
+					t.Errorf("step %d: unexpected status code: got %v want %v", i, rr.Code, s.expectedStatus)
+				}
+				if calls != s.expectedCalls {
+					t.Errorf("step %d: unexpected handler calls: got %v want %v", i, calls, s.expectedCalls)
+				}
+				replayed := rr.Header().Get("Idempotent-Replayed") == "true"
+				if replayed != s.expectReplay {
+					t.Errorf("step %d: unexpected replay header: got %v want %v", i, replayed, s.expectReplay)
+				}
+				if i == 0 {
+					firstBody = rr.Body.String()
+				} else if s.expectReplay && rr.Body.String() != firstBody {
+					t.Errorf("step %d: replayed body %q does not match original %q", i, rr.Body.String(), firstBody)
+				}
+			}
+		})
+	}
+}
+
+func TestNewRouter_IdempotencyAfterAuthentication(t *testing.T) {
+	cfg := &config.Config{AdminToken: config.Secret("secret")}
+	router := NewRouter(cfg, testLogger(), Dependencies{})
+	mockService := &mockSheetsService{erasedRows: map[string]int{"Sheet1": 1}}
+
+	erase := func(authorization string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodDelete, "/api/applicants?email=john@example.com", nil)
+		req.Header.Set(IdempotencyKeyHeader, "k1")
+		if authorization != "" {
+			req.Header.Set("Authorization", authorization)
+		}
+		req = req.WithContext(context.WithValue(req.Context(), "sheetsService", mockService))
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+
+	if rr := erase("Bearer secret"); rr.Code != http.StatusOK {
+		t.Fatalf("Expected the erasure to succeed, got %d: %s", rr.Code, rr.Body.String())
+	}
+	for _, authorization := range []string{"", "Bearer guess"} {
+		if rr := erase(authorization); rr.Code != http.StatusUnauthorized || rr.Header().Get("Idempotent-Replayed") != "" {
+			t.Errorf("Expected %q to be refused rather than replayed, got %d", authorization, rr.Code)
+		}
+	}
+	if rr := erase("Bearer secret"); rr.Header().Get("Idempotent-Replayed") != "true" {
+		t.Errorf("Expected the admin's retry to be replayed, got %d", rr.Code)
+	}
+	if len(mockService.erased) != 1 {
+		t.Errorf("Expected one erasure, got %v", mockService.erased)
+	}
+}
+
+func TestMemoryIdempotencyStore_Expiry(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	store := NewMemoryIdempotencyStore()
+	store.now = func() time.Time { return now }
+
+	if _, err := store.Reserve("k1", "fp1", time.Minute); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := store.Reserve("k1", "fp1", time.Minute); err != ErrIdempotencyKeyInProgress {
+		t.Fatalf("expected in-progress error, got %v", err)
+	}
+	store.Complete("k1", &IdempotentResponse{Status: http.StatusOK})
+
+	now = now.Add(2 * time.Minute)
+	stored, err := store.Reserve("k1", "fp2", time.Minute)
+	if err != nil {
+		t.Fatalf("expected expired key to be reusable, got %v", err)
+	}
+	if stored != nil {
+		t.Fatalf("expected no stored response after expiry, got %v", stored)
+	}
+}
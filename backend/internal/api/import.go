@@ -0,0 +1,372 @@
+package api
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/stevebennett/slack-invite-mgr/backend/internal/config"
+	"github.com/stevebennett/slack-invite-mgr/backend/internal/services"
+)
+
+// Import limits
+const (
+	maxImportBytes = 5 << 20
+	MaxImportRows  = 5000
+)
+
+// DefaultImportSource tags imported rows that name no source of their own
+const DefaultImportSource = "import"
+
+// Import row results
+const (
+	ImportResultNew       = "new"
+	ImportResultDuplicate = "duplicate"
+	ImportResultInvalid   = "invalid"
+)
+
+// importColumns maps normalized CSV header names onto application fields.
+// Headers are compared without case, spaces or punctuation, so files written
+// by the export endpoint and common sign-up list headings are both accepted.
+var importColumns = map[string]string{
+	"name":              "name",
+	"fullname":          "name",
+	"role":              "role",
+	"title":             "role",
+	"jobtitle":          "role",
+	"email":             "email",
+	"emailaddress":      "email",
+	"company":           "company",
+	"organisation":      "company",
+	"organization":      "company",
+	"yearsexperience":   "yearsExperience",
+	"yearsofexperience": "yearsExperience",
+	"experience":        "yearsExperience",
+	"reasons":           "reasons",
+	"reason":            "reasons",
+	"source":            "source",
+}
+
+// ImportRow is one application read from an import file
+type ImportRow struct {
+	// Line is the line of the file the row starts on
+	Line        int
+	Application ApplicationRequest
+}
+
+// ImportOptions control how rows are imported
+type ImportOptions struct {
+	// Source tags every imported row, overriding any source column
+	Source string
+	// DryRun reports what would be imported without writing to the sheet
+	DryRun bool
+	// Now is the submission time recorded for imported rows, and should be in
+	// the sheet's time zone; zero is the current time in UTC
+	Now time.Time
+}
+
+// ImportRowResult reports what happened to one row of an import file
+type ImportRowResult struct {
+	Line   int    `json:"line"`
+	Email  string `json:"email,omitempty"`
+	Result string `json:"result"`
+	// ExistingRow is the sheet row a duplicate matches
+	ExistingRow int `json:"existingRow,omitempty"`
+	// Archived is set when a duplicate matches an invite in the archive tabs
+	Archived bool `json:"archived,omitempty"`
+	// DuplicateOfLine is the earlier line of the file a duplicate matches
+	DuplicateOfLine int          `json:"duplicateOfLine,omitempty"`
+	Errors          []FieldError `json:"errors,omitempty"`
+}
+
+// ImportReport summarises an import, row by row
+type ImportReport struct {
+	DryRun     bool              `json:"dryRun"`
+	New        int               `json:"new"`
+	Duplicates int               `json:"duplicates"`
+	Invalid    int               `json:"invalid"`
+	Rows       []ImportRowResult `json:"rows"`
+}
+
+// ReadImportFile parses a CSV file with a header row into applications.
+// Unknown columns are ignored, but an email column is required. A malformed
+// file is reported as a FieldError for the "file" field.
+func ReadImportFile(r io.Reader) ([]ImportRow, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, FieldError{Field: "file", Message: "is empty"}
+	}
+	if err != nil {
+		return nil, importReadError(err)
+	}
+
+	columns := make(map[string]int)
+	for i, name := range header {
+		field, ok := importColumns[importColumnKey(name)]
+		if !ok {
+			continue
+		}
+		if _, seen := columns[field]; !seen {
+			columns[field] = i
+		}
+	}
+	if _, ok := columns["email"]; !ok {
+		return nil, FieldError{Field: "file", Message: "has no email column"}
+	}
+
+	var rows []ImportRow
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, importReadError(err)
+		}
+		if len(rows) == MaxImportRows {
+			return nil, FieldError{Field: "file", Message: fmt.Sprintf("must have at most %d rows", MaxImportRows)}
+		}
+		if blankRecord(record) {
+			continue
+		}
+
+		value := func(field string) string {
+			if i, ok := columns[field]; ok && i < len(record) {
+				return restoreFormulaText(record[i])
+			}
+			return ""
+		}
+		line, _ := reader.FieldPos(0)
+		rows = append(rows, ImportRow{
+			Line: line,
+			Application: ApplicationRequest{
+				Name:            value("name"),
+				Role:            value("role"),
+				Email:           value("email"),
+				Company:         value("company"),
+				YearsExperience: value("yearsExperience"),
+				Reasons:         value("reasons"),
+				Source:          value("source"),
+			},
+		})
+	}
+	return rows, nil
+}
+
+// ImportInvites validates each row, skips any whose email is already in the
+// sheet, its archive tabs or earlier in the file, and appends the rest as pending invites in a
+// single request. Emails are compared the same way duplicate marking does.
+func ImportInvites(ctx context.Context, sheetsService services.SheetsServiceInterface, rows []ImportRow, opts ImportOptions) (ImportReport, error) {
+	existing, err := sheetsService.GetAllSheetData(ctx)
+	if err != nil {
+		return ImportReport{}, err
+	}
+	// Sheet row numbers are 1-based and include the header row
+	existingRows := make(map[string]int)
+	for i, row := range existing {
+		email := services.NormalizeEmail(services.InviteFromRow(row).Email)
+		if _, seen := existingRows[email]; email != "" && !seen {
+			existingRows[email] = i + 1
+		}
+	}
+	archived, err := sheetsService.GetArchivedSheetData(ctx)
+	if err != nil {
+		return ImportReport{}, err
+	}
+	archivedEmails := make(map[string]bool)
+	for _, row := range archived {
+		if invite := services.InviteFromRow(row); services.IsKnownStatus(invite.Status) {
+			archivedEmails[services.NormalizeEmail(invite.Email)] = true
+		}
+	}
+
+	if opts.Now.IsZero() {
+		opts.Now = time.Now().UTC()
+	}
+	report := ImportReport{DryRun: opts.DryRun, Rows: make([]ImportRowResult, 0, len(rows))}
+	fileLines := make(map[string]int)
+	var invites []services.Invite
+	for _, row := range rows {
+		req := row.Application
+		result := ImportRowResult{Line: row.Line}
+		fieldErrs := req.Validate()
+		result.Email = req.Email
+		email := services.NormalizeEmail(req.Email)
+
+		switch {
+		case len(fieldErrs) > 0:
+			result.Result = ImportResultInvalid
+			result.Errors = fieldErrs
+			report.Invalid++
+		case existingRows[email] > 0:
+			result.Result = ImportResultDuplicate
+			result.ExistingRow = existingRows[email]
+			report.Duplicates++
+		case archivedEmails[email]:
+			result.Result = ImportResultDuplicate
+			result.Archived = true
+			report.Duplicates++
+		case fileLines[email] > 0:
+			result.Result = ImportResultDuplicate
+			result.DuplicateOfLine = fileLines[email]
+			report.Duplicates++
+		default:
+			result.Result = ImportResultNew
+			fileLines[email] = row.Line
+			report.New++
+
+			source := opts.Source
+			if source == "" {
+				source = req.Source
+			}
+			if source == "" {
+				source = DefaultImportSource
+			}
+			invites = append(invites, services.Invite{
+				SubmittedAt:     opts.Now.Format(services.SubmittedAtLayout),
+				Name:            req.Name,
+				Role:            req.Role,
+				Email:           req.Email,
+				Company:         req.Company,
+				YearsExperience: req.YearsExperience,
+				Reasons:         req.Reasons,
+				Source:          source,
+				Status:          services.StatusPending,
+			})
+		}
+		report.Rows = append(report.Rows, result)
+	}
+
+	if !opts.DryRun {
+		if err := sheetsService.AppendInvites(ctx, invites); err != nil {
+			return ImportReport{}, err
+		}
+	}
+	return report, nil
+}
+
+// ImportInvitesHandler appends the applications in a CSV request body to the
+// sheet and returns a per-row report. With dry_run=true nothing is written.
+func ImportInvitesHandler(cfg *config.Config, logger *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// Get request-scoped logger from context
+		log := LoggerFromContext(r.Context(), logger)
+
+		if r.Method != http.MethodPost {
+			writeMethodNotAllowed(w, r)
+			return
+		}
+
+		opts := ImportOptions{Source: strings.TrimSpace(r.URL.Query().Get("source")), Now: time.Now().In(cfg.SheetLocation())}
+		if len(opts.Source) > 200 {
+			writeValidationError(w, r, FieldError{Field: "source", Message: "must be at most 200 characters"})
+			return
+		}
+		if value := r.URL.Query().Get("dry_run"); value != "" {
+			dryRun, err := strconv.ParseBool(value)
+			if err != nil {
+				writeValidationError(w, r, FieldError{Field: "dry_run", Message: "must be true or false"})
+				return
+			}
+			opts.DryRun = dryRun
+		}
+
+		rows, err := ReadImportFile(http.MaxBytesReader(w, r.Body, maxImportBytes))
+		if err != nil {
+			var maxErr *http.MaxBytesError
+			if errors.As(err, &maxErr) {
+				err = FieldError{Field: "file", Message: fmt.Sprintf("must be at most %d bytes", maxImportBytes)}
+			}
+			log.Warn("invalid import file", slog.String("error", err.Error()))
+			writeValidationError(w, r, err)
+			return
+		}
+
+		// Get sheets service from context
+		sheetsService, ok := r.Context().Value("sheetsService").(services.SheetsServiceInterface)
+		if !ok {
+			// Create sheets service if not in context
+			sheetsService, err = services.NewSheetsService(r.Context(), cfg.SheetsConfig())
+			if err != nil {
+				log.Error("failed to create sheets service", slog.String("error", err.Error()))
+				writeError(w, r, http.StatusInternalServerError, ErrCodeInternal, "Failed to create sheets service")
+				return
+			}
+		}
+
+		report, err := ImportInvites(r.Context(), sheetsService, rows, opts)
+		if err != nil {
+			log.Error("failed to import invites", slog.String("error", err.Error()))
+			writeUpstreamError(w, r, err, "Failed to import invites")
+			return
+		}
+
+		log.Info("imported invites",
+			slog.Bool("dry_run", report.DryRun),
+			slog.Int("new", report.New),
+			slog.Int("duplicates", report.Duplicates),
+			slog.Int("invalid", report.Invalid),
+		)
+		status := http.StatusOK
+		if !report.DryRun && report.New > 0 {
+			status = http.StatusCreated
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Access-Control-Allow-Origin", "*")
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(report)
+	}
+}
+
+// importColumnKey normalizes a header name for lookup in importColumns
+func importColumnKey(name string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(name) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// restoreFormulaText removes the quote an export adds in front of text a
+// spreadsheet would otherwise evaluate as a formula
+func restoreFormulaText(value string) string {
+	if len(value) > 1 && value[0] == '\'' && strings.ContainsRune("=+-@\t\r", rune(value[1])) {
+		return value[1:]
+	}
+	return value
+}
+
+// importReadError reports CSV syntax errors against the "file" field and
+// passes read errors, such as an oversized request body, through unchanged
+func importReadError(err error) error {
+	var parseErr *csv.ParseError
+	if errors.As(err, &parseErr) {
+		return FieldError{Field: "file", Message: fmt.Sprintf("is not valid CSV: %v", parseErr)}
+	}
+	return err
+}
+
+// blankRecord reports whether every field of a CSV record is empty
+func blankRecord(record []string) bool {
+	for _, value := range record {
+		if strings.TrimSpace(value) != "" {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,157 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/stevebennett/slack-invite-mgr/backend/internal/config"
+	"github.com/stevebennett/slack-invite-mgr/backend/internal/services"
+)
+
+func TestImportInvitesHandler(t *testing.T) {
+	data := [][]interface{}{
+		{"Timestamp", "Name", "Role", "Email", "", "Company", "Years", "Reasons", "Source", "Status", "Updated"},
+		{"3/4/2024 10:00:00", "Jane", "Engineer", "jane@example.com", "", "Acme", "5", "Reasons", "Form", "Sent", "2024-03-05 10:00:00"},
+	}
+	archived := [][]interface{}{
+		{"Timestamp", "Name", "Role", "Email", "", "Company", "Years", "Reasons", "Source", "Status", "Updated"},
+		{"1/4/2023 10:00:00", "Ann", "Engineer", "ann@example.com", "", "Acme", "5", "Reasons", "Form", "Denied", "2023-01-05 10:00:00"},
+	}
+	file := "Full Name,Job Title,E-mail,Organisation,Years of experience,Reasons,Notes\n" +
+		"Bob,Engineer,bob@example.com,Acme,3,Met at the meetup,table 4\n" +
+		"Jane,Engineer, JANE@example.com ,Acme,5,Again,\n" +
+		",,\n" +
+		"Bad,Engineer,not-an-email,Acme,3,Reasons,\n" +
+		"Bobby,Engineer,Bob@Example.com,Acme,3,Twice,\n" +
+		"Eve,Engineer,eve@example.com,Acme,2,'=cmd,\n"
+
+	tests := []struct {
+		name           string
+		query          string
+		body           string
+		err            error
+		expectedStatus int
+		expectedRows   []ImportRowResult
+		expectedNames  []string
+		expectedSource string
+	}{
+		{
+			name:           "imports new rows and reports the rest",
+			query:          "?source=Meetup",
+			body:           file,
+			expectedStatus: http.StatusCreated,
+			expectedRows: []ImportRowResult{
+				{Line: 2, Email: "bob@example.com", Result: ImportResultNew},
+				{Line: 3, Email: "JANE@example.com", Result: ImportResultDuplicate, ExistingRow: 2},
+				{Line: 5, Email: "not-an-email", Result: ImportResultInvalid, Errors: []FieldError{{Field: "email", Message: "must be a valid email address"}}},
+				{Line: 6, Email: "Bob@Example.com", Result: ImportResultDuplicate, DuplicateOfLine: 2},
+				{Line: 7, Email: "eve@example.com", Result: ImportResultNew},
+			},
+			expectedNames:  []string{"Bob", "Eve"},
+			expectedSource: "Meetup",
+		},
+		{
+			name:           "dry run writes nothing",
+			query:          "?dry_run=true",
+			body:           "email,name,role,company,yearsExperience,reasons\nbob@example.com,Bob,Engineer,Acme,3,Reasons\n",
+			expectedStatus: http.StatusOK,
+			expectedRows:   []ImportRowResult{{Line: 2, Email: "bob@example.com", Result: ImportResultNew}},
+		},
+		{
+			name:           "source column used without a source tag",
+			body:           "email,name,role,company,yearsExperience,reasons,source\nbob@example.com,Bob,Engineer,Acme,3,Reasons,Conference\n",
+			expectedStatus: http.StatusCreated,
+			expectedRows:   []ImportRowResult{{Line: 2, Email: "bob@example.com", Result: ImportResultNew}},
+			expectedNames:  []string{"Bob"},
+			expectedSource: "Conference",
+		},
+		{
+			name:           "duplicate of an archived invite",
+			body:           "email,name,role,company,yearsExperience,reasons\nANN@example.com,Ann,Engineer,Acme,5,Reasons\n",
+			expectedStatus: http.StatusOK,
+			expectedRows:   []ImportRowResult{{Line: 2, Email: "ANN@example.com", Result: ImportResultDuplicate, Archived: true}},
+		},
+		{
+			name:           "missing email column",
+			body:           "name,company\nBob,Acme\n",
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "malformed csv",
+			body:           "email,name\n\"bob@example.com,Bob\n",
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "empty file",
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "invalid dry run flag",
+			query:          "?dry_run=maybe",
+			body:           file,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "sheet unavailable",
+			body:           file,
+			err:            errors.New("sheet unavailable"),
+			expectedStatus: http.StatusBadGateway,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := &mockSheetsService{data: data, archived: archived, updateStatusErr: tt.err}
+			req := httptest.NewRequest(http.MethodPost, "/api/invites/import"+tt.query, strings.NewReader(tt.body))
+			req = req.WithContext(context.WithValue(req.Context(), "sheetsService", mockService))
+			rr := httptest.NewRecorder()
+
+			ImportInvitesHandler(&config.Config{}, testLogger())(rr, req)
+
+			if rr.Code != tt.expectedStatus {
+				t.Fatalf("Expected status %d, got %d: %s", tt.expectedStatus, rr.Code, rr.Body.String())
+			}
+			if tt.expectedRows == nil {
+				return
+			}
+
+			var report ImportReport
+			if err := json.NewDecoder(rr.Body).Decode(&report); err != nil {
+				t.Fatalf("Failed to decode response: %v", err)
+			}
+			if !reflect.DeepEqual(report.Rows, tt.expectedRows) {
+				t.Errorf("Expected rows %+v, got %+v", tt.expectedRows, report.Rows)
+			}
+
+			var names []string
+			for _, invite := range mockService.appended {
+				names = append(names, invite.Name)
+				if invite.Source != tt.expectedSource {
+					t.Errorf("Expected source %q, got %q", tt.expectedSource, invite.Source)
+				}
+				if invite.Status != services.StatusPending {
+					t.Errorf("Expected pending status, got %q", invite.Status)
+				}
+			}
+			if !reflect.DeepEqual(names, tt.expectedNames) {
+				t.Errorf("Expected appended %v, got %v", tt.expectedNames, names)
+			}
+		})
+	}
+}
+
+func TestReadImportFileRestoresExportedFormulas(t *testing.T) {
+	rows, err := ReadImportFile(strings.NewReader("\ufeffemail,reasons\nbob@example.com,'=SUM(1)\n"))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(rows) != 1 || rows[0].Application.Reasons != "=SUM(1)" {
+		t.Errorf("Expected reasons =SUM(1), got %+v", rows)
+	}
+}
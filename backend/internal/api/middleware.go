@@ -2,8 +2,10 @@ package api
 
 import (
 	"context"
+	"crypto/subtle"
 	"log/slog"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
@@ -36,6 +38,11 @@ func (rw *responseWriter) Write(b []byte) (int, error) {
 	return n, err
 }
 
+// Unwrap lets http.ResponseController reach the underlying writer, e.g. to flush event streams
+func (rw *responseWriter) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}
+
 // LoggingMiddleware creates HTTP request/response logging middleware
 func LoggingMiddleware(logger *slog.Logger) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
@@ -98,3 +105,73 @@ func RequestIDFromContext(ctx context.Context) string {
 	}
 	return ""
 }
+
+// RequireAdminToken only lets through requests carrying the admin token as a
+// bearer token. With no token configured every request is refused, so admin
+// endpoints stay off until ADMIN_API_TOKEN is set.
+func RequireAdminToken(token string, logger *slog.Logger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			log := LoggerFromContext(r.Context(), logger)
+			if token == "" {
+				log.Warn("admin endpoint called without ADMIN_API_TOKEN configured")
+				writeError(w, r, http.StatusForbidden, ErrCodeForbidden, "Admin endpoints are disabled")
+				return
+			}
+			given, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if !ok || subtle.ConstantTimeCompare([]byte(given), []byte(token)) != 1 {
+				log.Warn("admin endpoint called without a valid token")
+				w.Header().Set("WWW-Authenticate", "Bearer")
+				writeError(w, r, http.StatusUnauthorized, ErrCodeUnauthorized, "A valid admin token is required")
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// reviewerEmail returns the signed-in reviewer's email from header, in
+// lowercase, or an empty string if there is none. Unless the proxy is trusted
+// the header may have come from the client, so it is ignored.
+func reviewerEmail(r *http.Request, header string, trustProxy bool) string {
+	if header == "" || !trustProxy {
+		return ""
+	}
+	// Identity-Aware Proxy prefixes the address with its issuer
+	email := r.Header.Get(header)
+	if _, address, ok := strings.Cut(email, ":"); ok {
+		email = address
+	}
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// RequireReviewer only lets through requests from the reviewers, identified
+// by the email an authenticating proxy passes in header. The header is only
+// read with trustProxy, so without it every request is refused. With no
+// reviewers configured every request is let through.
+func RequireReviewer(reviewers []string, header string, trustProxy bool, logger *slog.Logger) func(http.Handler) http.Handler {
+	allowed := make(map[string]bool, len(reviewers))
+	for _, reviewer := range reviewers {
+		allowed[strings.ToLower(reviewer)] = true
+	}
+	return func(next http.Handler) http.Handler {
+		if len(allowed) == 0 {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			log := LoggerFromContext(r.Context(), logger)
+			email := reviewerEmail(r, header, trustProxy)
+			if email == "" {
+				log.Warn("reviewer endpoint called without a signed-in reviewer", slog.String("header", header))
+				writeError(w, r, http.StatusUnauthorized, ErrCodeUnauthorized, "Sign in as a reviewer")
+				return
+			}
+			if !allowed[email] {
+				log.Warn("reviewer endpoint called by someone not on the reviewer list", slog.String("reviewer", email))
+				writeError(w, r, http.StatusForbidden, ErrCodeForbidden, "You are not a reviewer for this community")
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
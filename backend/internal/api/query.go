@@ -0,0 +1,220 @@
+package api
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/stevebennett/slack-invite-mgr/backend/internal/services"
+)
+
+// maxPageLimit caps the number of invites returned in a single page
+const maxPageLimit = 500
+
+// inviteSortFields maps sort parameter names to comparison functions, which
+// read timestamps in the sheet's time zone loc
+var inviteSortFields = map[string]func(a, b services.Invite, loc *time.Location) bool{
+	"submitted": func(a, b services.Invite, loc *time.Location) bool {
+		return submittedAt(a, loc).Before(submittedAt(b, loc))
+	},
+	"name": func(a, b services.Invite, _ *time.Location) bool {
+		return strings.ToLower(a.Name) < strings.ToLower(b.Name)
+	},
+	"company": func(a, b services.Invite, _ *time.Location) bool {
+		return strings.ToLower(a.Company) < strings.ToLower(b.Company)
+	},
+	"email": func(a, b services.Invite, _ *time.Location) bool {
+		return strings.ToLower(a.Email) < strings.ToLower(b.Email)
+	},
+}
+
+// InviteQuery holds the filters, sort order and pagination for listing invites
+type InviteQuery struct {
+	Statuses        []string
+	Search          string
+	Source          string
+	SubmittedAfter  time.Time
+	SubmittedBefore time.Time
+	Sort            string
+	Descending      bool
+	Limit           int
+	Offset          int
+	// Archived includes invites moved to the archive tabs
+	Archived bool
+	// Location is the sheet's time zone, which column A timestamps and plain
+	// dates in the submission range are read in
+	Location *time.Location
+}
+
+// ParseInviteQuery builds an InviteQuery from request query parameters, with
+// dates read in loc, the sheet's time zone. Without a status parameter only
+// pending invites are returned.
+func ParseInviteQuery(values url.Values, loc *time.Location) (InviteQuery, error) {
+	q := InviteQuery{
+		Statuses: []string{services.StatusPending},
+		Search:   strings.ToLower(strings.TrimSpace(values.Get("q"))),
+		Source:   strings.ToLower(strings.TrimSpace(values.Get("source"))),
+		Location: loc,
+	}
+
+	if raw := values.Get("status"); raw != "" {
+		q.Statuses = nil
+		for _, status := range strings.Split(raw, ",") {
+			status = strings.ToLower(strings.TrimSpace(status))
+			if status == "all" {
+				q.Statuses = services.KnownStatuses
+				break
+			}
+			if !services.IsKnownStatus(status) {
+				return q, FieldError{Field: "status", Message: fmt.Sprintf("unknown status %q", status)}
+			}
+			q.Statuses = append(q.Statuses, status)
+		}
+	}
+
+	var err error
+	if q.SubmittedAfter, err = parseDateParam(values, "submitted_after", loc); err != nil {
+		return q, err
+	}
+	if q.SubmittedBefore, err = parseDateParam(values, "submitted_before", loc); err != nil {
+		return q, err
+	}
+
+	if raw := values.Get("sort"); raw != "" {
+		q.Descending = strings.HasPrefix(raw, "-")
+		q.Sort = strings.TrimPrefix(raw, "-")
+		if _, ok := inviteSortFields[q.Sort]; !ok {
+			return q, FieldError{Field: "sort", Message: fmt.Sprintf("unknown sort field %q", q.Sort)}
+		}
+	}
+
+	if q.Limit, err = parseIntParam(values, "limit"); err != nil {
+		return q, err
+	}
+	if q.Limit > maxPageLimit {
+		return q, FieldError{Field: "limit", Message: fmt.Sprintf("must not exceed %d", maxPageLimit)}
+	}
+	if q.Offset, err = parseIntParam(values, "offset"); err != nil {
+		return q, err
+	}
+	if raw := values.Get("archived"); raw != "" {
+		if q.Archived, err = strconv.ParseBool(raw); err != nil {
+			return q, FieldError{Field: "archived", Message: "must be true or false"}
+		}
+	}
+
+	return q, nil
+}
+
+// Matches reports whether an invite satisfies every filter in the query
+func (q InviteQuery) Matches(invite services.Invite) bool {
+	statusMatch := false
+	for _, status := range q.Statuses {
+		if invite.Status == status {
+			statusMatch = true
+			break
+		}
+	}
+	if !statusMatch {
+		return false
+	}
+
+	if q.Search != "" {
+		haystack := strings.ToLower(invite.Name + "\n" + invite.Company + "\n" + invite.Reasons)
+		if !strings.Contains(haystack, q.Search) {
+			return false
+		}
+	}
+
+	if q.Source != "" && strings.ToLower(strings.TrimSpace(invite.Source)) != q.Source {
+		return false
+	}
+
+	if !q.SubmittedAfter.IsZero() || !q.SubmittedBefore.IsZero() {
+		submitted, ok := services.ParseTimestampInLocation(invite.SubmittedAt, q.Location)
+		if !ok {
+			return false
+		}
+		if !q.SubmittedAfter.IsZero() && submitted.Before(q.SubmittedAfter) {
+			return false
+		}
+		if !q.SubmittedBefore.IsZero() && !submitted.Before(q.SubmittedBefore) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Apply filters and sorts invites, returning the requested page and the total
+// number of matches before pagination
+func (q InviteQuery) Apply(invites []services.Invite) ([]services.Invite, int) {
+	matched := make([]services.Invite, 0, len(invites))
+	for _, invite := range invites {
+		if q.Matches(invite) {
+			matched = append(matched, invite)
+		}
+	}
+
+	if less, ok := inviteSortFields[q.Sort]; ok {
+		sort.SliceStable(matched, func(i, j int) bool {
+			if q.Descending {
+				return less(matched[j], matched[i], q.Location)
+			}
+			return less(matched[i], matched[j], q.Location)
+		})
+	}
+
+	total := len(matched)
+	if q.Offset >= total {
+		return []services.Invite{}, total
+	}
+	page := matched[q.Offset:]
+	if q.Limit > 0 && q.Limit < len(page) {
+		page = page[:q.Limit]
+	}
+	return page, total
+}
+
+// parseDateParam parses an optional date query parameter. Plain dates are
+// interpreted as midnight in loc, or UTC if loc is nil.
+func parseDateParam(values url.Values, name string, loc *time.Location) (time.Time, error) {
+	raw := values.Get(name)
+	if raw == "" {
+		return time.Time{}, nil
+	}
+	if loc == nil {
+		loc = time.UTC
+	}
+	if t, err := time.ParseInLocation("2006-01-02", raw, loc); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse(time.RFC3339, raw)
+	if err != nil {
+		return time.Time{}, FieldError{Field: name, Message: "must be a date (YYYY-MM-DD) or RFC 3339 timestamp"}
+	}
+	return t, nil
+}
+
+// parseIntParam parses an optional non-negative integer query parameter
+func parseIntParam(values url.Values, name string) (int, error) {
+	raw := values.Get(name)
+	if raw == "" {
+		return 0, nil
+	}
+	n, err := strconv.Atoi(raw)
+	if err != nil || n < 0 {
+		return 0, FieldError{Field: name, Message: "must be a non-negative integer"}
+	}
+	return n, nil
+}
+
+// submittedAt returns the submission time read in loc, or the zero time when
+// the column A value cannot be parsed
+func submittedAt(invite services.Invite, loc *time.Location) time.Time {
+	t, _ := services.ParseTimestampInLocation(invite.SubmittedAt, loc)
+	return t
+}
@@ -5,33 +5,118 @@ import (
 	"net/http"
 
 	"github.com/stevebennett/slack-invite-mgr/backend/internal/config"
+	"github.com/stevebennett/slack-invite-mgr/backend/internal/services"
 )
 
+// Dependencies holds the long-lived services shared by the handlers
+type Dependencies struct {
+	// Events receives invite lifecycle events and feeds the event stream
+	Events *services.EventBroker
+	// Webhooks delivers invite lifecycle events to subscribers
+	Webhooks *services.WebhookDispatcher
+	// Audit records status changes and the emails sent about them
+	Audit *services.AuditLog
+	// Mailer emails applicants about decisions; nil disables applicant emails
+	Mailer *services.DecisionMailer
+	// Outbox queues the server's email, so erasures can remove queued messages
+	Outbox *services.Outbox
+}
+
 // NewRouter creates a new HTTP router with all routes configured
-func NewRouter(cfg *config.Config, logger *slog.Logger) http.Handler {
+func NewRouter(cfg *config.Config, logger *slog.Logger, deps Dependencies) http.Handler {
 	mux := http.NewServeMux()
 
+	if deps.Events == nil {
+		deps.Events = services.NewEventBroker(config.DefaultEventHistorySize)
+	}
+	if deps.Audit == nil {
+		deps.Audit = services.NewAuditLog(cfg.AuditLogFile)
+	}
+	if deps.Webhooks == nil {
+		deps.Webhooks = services.NewWebhookDispatcher(cfg.Webhooks, services.NewDeliveryLog(cfg.WebhookDeliveryLog), logger)
+	}
+
 	// Health check endpoint (no logging to reduce noise)
 	mux.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusOK)
 		w.Write([]byte("OK"))
 	})
 
+	// Endpoints for reviewers, limited to the tenant's reviewers if it has any
+	reviewer := RequireReviewer(cfg.Reviewers, cfg.ReviewerEmailHeader, cfg.TrustProxyHeaders, logger)
+
+	// Replay responses for repeated mutating requests, applied inside any
+	// authentication so replays are only given to callers who pass it
+	idempotencyTTL := cfg.IdempotencyTTL
+	if idempotencyTTL == 0 {
+		idempotencyTTL = config.DefaultIdempotencyTTL
+	}
+	idempotent := IdempotencyMiddleware(NewMemoryIdempotencyStore(), idempotencyTTL, idempotencyCaller(cfg), logger)
+
 	// Invites endpoints
-	mux.HandleFunc("/api/invites", func(w http.ResponseWriter, r *http.Request) {
+	mux.Handle("/api/invites", reviewer(idempotent(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		switch r.Method {
 		case http.MethodGet:
 			GetOutstandingInvitesHandler(cfg, logger)(w, r)
 		case http.MethodPatch:
-			UpdateInviteStatusHandler(cfg, logger)(w, r)
+			UpdateInviteStatusHandler(cfg, logger, deps)(w, r)
 		default:
-			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			writeMethodNotAllowed(w, r)
 		}
-	})
+	}))))
+
+	// Invite export
+	mux.Handle("/api/invites/export", reviewer(ExportInvitesHandler(cfg, logger)))
+
+	// Bulk import from CSV
+	mux.Handle("/api/invites/import", reviewer(idempotent(ImportInvitesHandler(cfg, logger))))
+
+	// Funnel statistics
+	mux.Handle("/api/stats", reviewer(StatsHandler(cfg, logger)))
+
+	// Public application intake
+	rateLimit, rateWindow := cfg.ApplicationRateLimit, cfg.ApplicationRateWindow
+	if rateLimit == 0 {
+		rateLimit = config.DefaultApplicationRateLimit
+	}
+	if rateWindow == 0 {
+		rateWindow = config.DefaultApplicationRateWindow
+	}
+	mux.Handle("/api/applications", idempotent(SubmitApplicationHandler(cfg, logger, NewRateLimiter(rateLimit, rateWindow))))
+
+	// Real-time invite events
+	heartbeat := cfg.EventsHeartbeatInterval
+	if heartbeat == 0 {
+		heartbeat = config.DefaultEventsHeartbeatInterval
+	}
+	mux.Handle("/api/invites/stream", reviewer(InviteStreamHandler(deps.Events, heartbeat, logger)))
+
+	// Webhook delivery log
+	mux.Handle("/api/webhooks/deliveries", reviewer(WebhookDeliveriesHandler(deps.Webhooks.Deliveries(), logger)))
+
+	// Audit trail
+	mux.Handle("/api/audit", reviewer(AuditLogHandler(deps.Audit, logger)))
+
+	// Applicant erasure, for admins only
+	mux.Handle("/api/applicants", RequireAdminToken(cfg.AdminToken.Reveal(), logger)(idempotent(EraseApplicantHandler(cfg, logger, deps))))
 
 	// Frontend logs endpoint
-	mux.HandleFunc("/api/logs", FrontendLogsHandler(logger))
+	mux.Handle("/api/logs", idempotent(FrontendLogsHandler(logger)))
 
 	// Apply logging middleware
 	return LoggingMiddleware(logger)(mux)
 }
+
+// idempotencyCaller identifies the caller an idempotency key belongs to: the
+// admin token or signed-in reviewer when there is one, or else the client IP
+func idempotencyCaller(cfg *config.Config) func(*http.Request) string {
+	return func(r *http.Request) string {
+		if auth := r.Header.Get("Authorization"); auth != "" {
+			return "authorization " + auth
+		}
+		if email := reviewerEmail(r, cfg.ReviewerEmailHeader, cfg.TrustProxyHeaders); email != "" {
+			return "reviewer " + email
+		}
+		return "ip " + clientIP(r, cfg.TrustProxyHeaders)
+	}
+}
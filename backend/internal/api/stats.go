@@ -0,0 +1,128 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/stevebennett/slack-invite-mgr/backend/internal/config"
+	"github.com/stevebennett/slack-invite-mgr/backend/internal/services"
+)
+
+// Report output formats
+const (
+	ReportFormatJSON = "json"
+	ReportFormatCSV  = "csv"
+	ReportFormatHTML = "html"
+)
+
+// StatsHandler reports funnel statistics per week or month, by source and by
+// years of experience, as JSON, CSV or HTML in the email template
+func StatsHandler(cfg *config.Config, logger *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// Get request-scoped logger from context
+		log := LoggerFromContext(r.Context(), logger)
+
+		if r.Method != http.MethodGet {
+			writeMethodNotAllowed(w, r)
+			return
+		}
+
+		opts, format, err := parseStatsQuery(r, cfg.SheetLocation())
+		if err != nil {
+			log.Warn("invalid query parameters", slog.String("error", err.Error()))
+			writeValidationError(w, r, err)
+			return
+		}
+
+		// Get sheets service from context
+		sheetsService, ok := r.Context().Value("sheetsService").(services.SheetsServiceInterface)
+		if !ok {
+			// Create sheets service if not in context
+			sheetsService, err = services.NewSheetsService(r.Context(), cfg.SheetsConfig())
+			if err != nil {
+				log.Error("failed to create sheets service", slog.String("error", err.Error()))
+				writeError(w, r, http.StatusInternalServerError, ErrCodeInternal, "Failed to create sheets service")
+				return
+			}
+		}
+
+		data, err := services.InviteHistory(r.Context(), sheetsService)
+		if err != nil {
+			log.Error("failed to get sheet data", slog.String("error", err.Error()))
+			writeUpstreamError(w, r, err, "Failed to get sheet data")
+			return
+		}
+
+		report, err := services.BuildReport(services.InvitesFromRows(data), opts, time.Now().UTC())
+		if err != nil {
+			writeValidationError(w, r, FieldError{Field: "period", Message: err.Error()})
+			return
+		}
+
+		w.Header().Set("Access-Control-Allow-Origin", "*")
+		switch format {
+		case ReportFormatCSV:
+			w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+			w.Header().Set("Content-Disposition", `attachment; filename="invite-stats.csv"`)
+			if err := services.WriteReportCSV(w, report); err != nil {
+				log.Error("failed to write report", slog.String("error", err.Error()))
+			}
+		case ReportFormatHTML:
+			body, err := renderReportEmail(cfg, report)
+			if err != nil {
+				log.Error("failed to render report", slog.String("error", err.Error()))
+				writeError(w, r, http.StatusInternalServerError, ErrCodeInternal, "Failed to render report")
+				return
+			}
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			w.Write([]byte(body))
+		default:
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(report)
+		}
+	}
+}
+
+// parseStatsQuery reads the period, submission range and output format, with
+// dates in loc, the sheet's time zone
+func parseStatsQuery(r *http.Request, loc *time.Location) (services.ReportOptions, string, error) {
+	values := r.URL.Query()
+	opts := services.ReportOptions{Period: values.Get("period"), Location: loc}
+	if opts.Period != "" && opts.Period != services.PeriodWeek && opts.Period != services.PeriodMonth {
+		return opts, "", FieldError{Field: "period", Message: "must be week or month"}
+	}
+
+	var err error
+	if opts.SubmittedAfter, err = parseDateParam(values, "submitted_after", opts.Location); err != nil {
+		return opts, "", err
+	}
+	if opts.SubmittedBefore, err = parseDateParam(values, "submitted_before", opts.Location); err != nil {
+		return opts, "", err
+	}
+
+	format := values.Get("format")
+	switch format {
+	case "":
+		format = ReportFormatJSON
+	case ReportFormatJSON, ReportFormatCSV, ReportFormatHTML:
+	default:
+		return opts, "", FieldError{Field: "format", Message: fmt.Sprintf("unknown format %q, expected json, csv or html", format)}
+	}
+	return opts, format, nil
+}
+
+// renderReportEmail renders the report in the email template, if one is configured
+func renderReportEmail(cfg *config.Config, report services.Report) (string, error) {
+	body, err := services.RenderReportHTML(report)
+	if err != nil {
+		return "", err
+	}
+	template, err := services.LoadEmailTemplate(cfg.EmailTemplate, cfg.DashboardURL)
+	if err != nil {
+		return "", err
+	}
+	return services.ApplyEmailTemplate(template, body), nil
+}
@@ -0,0 +1,110 @@
+package api
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stevebennett/slack-invite-mgr/backend/internal/config"
+	"github.com/stevebennett/slack-invite-mgr/backend/internal/services"
+)
+
+func TestStatsHandler(t *testing.T) {
+	data := [][]interface{}{
+		{"Timestamp", "Name", "Role", "Email", "", "Company", "Years", "Reasons", "Source", "Status", "Updated"},
+		{"3/4/2024 10:00:00", "Jane", "Engineer", "jane@example.com", "", "Acme", "5", "Reasons", "Meetup", "Sent", "2024-03-05 10:00:00"},
+		{"4/2/2024 10:00:00", "Jill", "Engineer", "jill@example.com", "", "Acme", "8", "Reasons", "Twitter", "", ""},
+	}
+	// Archived invites still count
+	archived := [][]interface{}{
+		{"Timestamp", "Name", "Role", "Email", "", "Company", "Years", "Reasons", "Source", "Status", "Updated"},
+		{"3/12/2024 10:00:00", "John", "Engineer", "john@example.com", "", "Acme", "1", "Reasons", "Meetup", "Denied", "2024-03-12 12:00:00"},
+	}
+
+	tests := []struct {
+		name                string
+		query               string
+		expectedStatus      int
+		expectedContentType string
+	}{
+		{
+			name:                "json by default",
+			expectedStatus:      http.StatusOK,
+			expectedContentType: "application/json",
+		},
+		{
+			name:                "csv",
+			query:               "?format=csv&period=week",
+			expectedStatus:      http.StatusOK,
+			expectedContentType: "text/csv; charset=utf-8",
+		},
+		{
+			name:                "html",
+			query:               "?format=html",
+			expectedStatus:      http.StatusOK,
+			expectedContentType: "text/html; charset=utf-8",
+		},
+		{
+			name:           "unknown period",
+			query:          "?period=year",
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "unknown format",
+			query:          "?format=xml",
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "invalid date",
+			query:          "?submitted_after=yesterday",
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/api/stats"+tt.query, nil)
+			req = req.WithContext(context.WithValue(req.Context(), "sheetsService", &mockSheetsService{data: data, archived: archived}))
+			rr := httptest.NewRecorder()
+
+			StatsHandler(&config.Config{}, testLogger())(rr, req)
+
+			if rr.Code != tt.expectedStatus {
+				t.Fatalf("Expected status %d, got %d: %s", tt.expectedStatus, rr.Code, rr.Body.String())
+			}
+			if tt.expectedContentType == "" {
+				return
+			}
+			if got := rr.Header().Get("Content-Type"); got != tt.expectedContentType {
+				t.Errorf("Expected Content-Type %q, got %q", tt.expectedContentType, got)
+			}
+
+			switch tt.expectedContentType {
+			case "application/json":
+				var report services.Report
+				if err := json.NewDecoder(rr.Body).Decode(&report); err != nil {
+					t.Fatalf("Failed to decode report: %v", err)
+				}
+				if report.Totals.Received != 3 || report.Totals.ApprovalRate != 0.5 || len(report.Periods) != 2 {
+					t.Errorf("Unexpected report: %+v", report)
+				}
+			case "text/csv; charset=utf-8":
+				records, err := csv.NewReader(rr.Body).ReadAll()
+				if err != nil {
+					t.Fatalf("Failed to parse CSV: %v", err)
+				}
+				if records[0][0] != "section" || records[2][0] != "week" {
+					t.Errorf("Unexpected CSV: %v", records)
+				}
+			default:
+				if !strings.Contains(rr.Body.String(), "Invite report by month") {
+					t.Errorf("Unexpected HTML: %s", rr.Body.String())
+				}
+			}
+		})
+	}
+}
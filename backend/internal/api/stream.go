@@ -0,0 +1,123 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/stevebennett/slack-invite-mgr/backend/internal/services"
+)
+
+// streamRetryMillis is the reconnect delay suggested to EventSource clients
+const streamRetryMillis = 5000
+
+// InviteStreamHandler streams invite events to the client as Server-Sent
+// Events. Clients resume after a reconnect by sending the Last-Event-ID header
+// (or the lastEventId query parameter), and a comment is sent every heartbeat
+// interval to keep proxies from closing idle connections.
+func InviteStreamHandler(events *services.EventBroker, heartbeat time.Duration, logger *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// Get request-scoped logger from context
+		log := LoggerFromContext(r.Context(), logger)
+
+		if r.Method != http.MethodGet {
+			writeMethodNotAllowed(w, r)
+			return
+		}
+
+		lastEventID, err := parseLastEventID(r)
+		if err != nil {
+			writeValidationError(w, r, err)
+			return
+		}
+
+		controller := http.NewResponseController(w)
+
+		// Set response headers
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.Header().Set("Connection", "keep-alive")
+		w.Header().Set("X-Accel-Buffering", "no") // Disable nginx buffering
+		w.Header().Set("Access-Control-Allow-Origin", "*")
+		w.WriteHeader(http.StatusOK)
+
+		ch, backlog, unsubscribe := events.Subscribe(lastEventID)
+		defer unsubscribe()
+
+		log.Info("event stream opened", slog.Int64("last_event_id", lastEventID), slog.Int("backlog", len(backlog)))
+
+		fmt.Fprintf(w, "retry: %d\n\n", streamRetryMillis)
+		for _, event := range backlog {
+			if err := writeEvent(w, event); err != nil {
+				return
+			}
+		}
+		if err := controller.Flush(); err != nil {
+			log.Error("event stream does not support flushing", slog.String("error", err.Error()))
+			return
+		}
+
+		ticker := time.NewTicker(heartbeat)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-r.Context().Done():
+				log.Info("event stream closed by client")
+				return
+			case event, ok := <-ch:
+				if !ok {
+					// Dropped for falling behind; the client will reconnect and resume
+					log.Warn("event stream subscriber dropped")
+					return
+				}
+				if err := writeEvent(w, event); err != nil {
+					return
+				}
+			case <-ticker.C:
+				if _, err := fmt.Fprint(w, ": heartbeat\n\n"); err != nil {
+					return
+				}
+			}
+			if err := controller.Flush(); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// writeEvent writes a single event in text/event-stream format
+func writeEvent(w http.ResponseWriter, event services.InviteEvent) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	if event.ID > 0 {
+		if _, err := fmt.Fprintf(w, "id: %d\n", event.ID); err != nil {
+			return err
+		}
+	}
+	_, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event.Type, data)
+	return err
+}
+
+// parseLastEventID reads the ID of the last event the client received
+func parseLastEventID(r *http.Request) (int64, error) {
+	raw := r.Header.Get("Last-Event-ID")
+	field := "Last-Event-ID"
+	if raw == "" {
+		raw = r.URL.Query().Get("lastEventId")
+		field = "lastEventId"
+	}
+	if raw == "" {
+		return 0, nil
+	}
+	id, err := strconv.ParseInt(raw, 10, 64)
+	if err != nil || id < 0 {
+		return 0, FieldError{Field: field, Message: "must be a non-negative integer"}
+	}
+	return id, nil
+}
@@ -0,0 +1,65 @@
+package api
+
+import (
+	"log/slog"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// TenantPathPrefix starts the path of requests for a named tenant, as in
+// /t/gophers/api/invites
+const TenantPathPrefix = "/t/"
+
+// Tenant is one tenant's router and the hosts it is served on
+type Tenant struct {
+	Name    string
+	Hosts   []string
+	Handler http.Handler
+}
+
+// NewTenantRouter sends each request to its tenant's router, chosen by a
+// /t/{tenant} path prefix, which is removed before the router sees the
+// request, or else by the request's host. Requests for no tenant get a 404,
+// apart from the health check.
+func NewTenantRouter(tenants []Tenant, logger *slog.Logger) http.Handler {
+	mux := http.NewServeMux()
+	hosts := make(map[string]http.Handler)
+	for _, tenant := range tenants {
+		prefix := TenantPathPrefix + tenant.Name
+		mux.Handle(prefix+"/", http.StripPrefix(prefix, tenant.Handler))
+		for _, host := range tenant.Hosts {
+			hosts[strings.ToLower(host)] = tenant.Handler
+		}
+	}
+
+	// Health check endpoint (no logging to reduce noise)
+	mux.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("OK"))
+	})
+
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		LoggerFromContext(r.Context(), logger).Debug("request for no tenant", slog.String("host", r.Host), slog.String("path", r.URL.Path))
+		writeError(w, r, http.StatusNotFound, ErrCodeNotFound, "No community is served here; use its host or a "+TenantPathPrefix+"{name} path")
+	})
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.URL.Path, TenantPathPrefix) && r.URL.Path != "/health" {
+			if handler, ok := hosts[requestHostname(r)]; ok {
+				handler.ServeHTTP(w, r)
+				return
+			}
+		}
+		mux.ServeHTTP(w, r)
+	})
+}
+
+// requestHostname returns the request's host without a port, in lowercase
+func requestHostname(r *http.Request) string {
+	host := r.Host
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	return strings.ToLower(host)
+}
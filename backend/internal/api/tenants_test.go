@@ -0,0 +1,154 @@
+package api
+
+import (
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// tenantEcho answers with the tenant's name and the path it was given
+func tenantEcho(name string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(name + " " + r.URL.Path))
+	})
+}
+
+func TestNewTenantRouter(t *testing.T) {
+	router := NewTenantRouter([]Tenant{
+		{Name: "gophers", Hosts: []string{"invites.gophers.example"}, Handler: tenantEcho("gophers")},
+		{Name: "rustaceans", Handler: tenantEcho("rustaceans")},
+	}, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	tests := []struct {
+		name           string
+		host           string
+		path           string
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:           "path prefix",
+			host:           "localhost:8080",
+			path:           "/t/rustaceans/api/invites",
+			expectedStatus: http.StatusOK,
+			expectedBody:   "rustaceans /api/invites",
+		},
+		{
+			name:           "host",
+			host:           "Invites.Gophers.example:443",
+			path:           "/api/invites",
+			expectedStatus: http.StatusOK,
+			expectedBody:   "gophers /api/invites",
+		},
+		{
+			name:           "path prefix wins over host",
+			host:           "invites.gophers.example",
+			path:           "/t/rustaceans/api/stats",
+			expectedStatus: http.StatusOK,
+			expectedBody:   "rustaceans /api/stats",
+		},
+		{
+			name:           "unknown tenant",
+			host:           "localhost",
+			path:           "/t/crabs/api/invites",
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name:           "no tenant",
+			host:           "localhost",
+			path:           "/api/invites",
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name:           "health check",
+			host:           "localhost",
+			path:           "/health",
+			expectedStatus: http.StatusOK,
+			expectedBody:   "OK",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			req.Host = tt.host
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != tt.expectedStatus {
+				t.Fatalf("expected status %d, got %d: %s", tt.expectedStatus, w.Code, w.Body.String())
+			}
+			if tt.expectedBody != "" && w.Body.String() != tt.expectedBody {
+				t.Errorf("expected body %q, got %q", tt.expectedBody, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestRequireReviewer(t *testing.T) {
+	const header = "X-Goog-Authenticated-User-Email"
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	tests := []struct {
+		name           string
+		reviewers      []string
+		email          string
+		untrusted      bool
+		expectedStatus int
+	}{
+		{
+			name:           "no reviewers configured",
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "not signed in",
+			reviewers:      []string{"jane@example.com"},
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           "not a reviewer",
+			reviewers:      []string{"jane@example.com"},
+			email:          "john@example.com",
+			expectedStatus: http.StatusForbidden,
+		},
+		{
+			name:           "reviewer",
+			reviewers:      []string{"Jane@example.com"},
+			email:          "jane@example.com",
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "header ignored without a trusted proxy",
+			reviewers:      []string{"jane@example.com"},
+			email:          "jane@example.com",
+			untrusted:      true,
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           "reviewer with issuer prefix",
+			reviewers:      []string{"jane@example.com"},
+			email:          "accounts.google.com:JANE@example.com",
+			expectedStatus: http.StatusOK,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := RequireReviewer(tt.reviewers, header, !tt.untrusted, slog.New(slog.NewTextHandler(io.Discard, nil)))(ok)
+			req := httptest.NewRequest(http.MethodGet, "/api/invites", nil)
+			if tt.email != "" {
+				req.Header.Set(header, tt.email)
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+
+			if w.Code != tt.expectedStatus {
+				t.Errorf("expected status %d, got %d: %s", tt.expectedStatus, w.Code, w.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,67 @@
+package api
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+
+	"github.com/stevebennett/slack-invite-mgr/backend/internal/services"
+)
+
+const (
+	defaultDeliveryLimit = 100
+	maxDeliveryLimit     = 1000
+)
+
+// WebhookDeliveriesResponse is the envelope returned when listing webhook deliveries
+type WebhookDeliveriesResponse struct {
+	Deliveries []services.WebhookDelivery `json:"deliveries"`
+}
+
+// WebhookDeliveriesHandler lists webhook delivery attempts, newest first.
+// The status query parameter filters by delivery status, e.g. dead_letter.
+func WebhookDeliveriesHandler(deliveries *services.DeliveryLog, logger *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// Get request-scoped logger from context
+		log := LoggerFromContext(r.Context(), logger)
+
+		if r.Method != http.MethodGet {
+			writeMethodNotAllowed(w, r)
+			return
+		}
+
+		values := r.URL.Query()
+		status := values.Get("status")
+		switch status {
+		case "", services.DeliveryDelivered, services.DeliveryRetrying, services.DeliveryDeadLetter:
+		default:
+			writeValidationError(w, r, FieldError{Field: "status", Message: "must be one of delivered, retrying, dead_letter"})
+			return
+		}
+
+		limit := defaultDeliveryLimit
+		if values.Get("limit") != "" {
+			var err error
+			if limit, err = parseIntParam(values, "limit"); err != nil {
+				writeValidationError(w, r, err)
+				return
+			}
+			if limit < 1 || limit > maxDeliveryLimit {
+				writeValidationError(w, r, FieldError{Field: "limit", Message: "must be between 1 and 1000"})
+				return
+			}
+		}
+
+		records, err := deliveries.List(status, limit)
+		if err != nil {
+			log.Error("failed to read webhook deliveries", slog.String("error", err.Error()))
+			writeError(w, r, http.StatusInternalServerError, ErrCodeInternal, "Failed to read webhook deliveries")
+			return
+		}
+
+		// Set response headers
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Access-Control-Allow-Origin", "*")
+		json.NewEncoder(w).Encode(WebhookDeliveriesResponse{Deliveries: records})
+	}
+}
@@ -0,0 +1,61 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// ApplicantEmailTemplate is the email sent to an applicant when their
+// invite moves to a particular status
+type ApplicantEmailTemplate struct {
+	Enabled bool   `json:"enabled"`
+	Subject string `json:"subject"`
+	// Body is an HTML template; BodyFile names a file holding it instead,
+	// relative to the templates file
+	Body     string `json:"body"`
+	BodyFile string `json:"bodyFile"`
+}
+
+// LoadApplicantEmailTemplates reads applicant email templates, keyed by
+// status, from a JSON file. An empty path means no applicant emails.
+func LoadApplicantEmailTemplates(path string) (map[string]ApplicantEmailTemplate, error) {
+	if path == "" {
+		return nil, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read applicant emails file: %w", err)
+	}
+
+	var templates map[string]ApplicantEmailTemplate
+	if err := json.Unmarshal(data, &templates); err != nil {
+		return nil, fmt.Errorf("failed to parse applicant emails file: %w", err)
+	}
+
+	for status, tmpl := range templates {
+		if tmpl.BodyFile != "" {
+			if tmpl.Body != "" {
+				return nil, fmt.Errorf("applicant email %q: set body or bodyFile, not both", status)
+			}
+			bodyPath := tmpl.BodyFile
+			if !filepath.IsAbs(bodyPath) {
+				bodyPath = filepath.Join(filepath.Dir(path), bodyPath)
+			}
+			body, err := os.ReadFile(bodyPath)
+			if err != nil {
+				return nil, fmt.Errorf("applicant email %q: failed to read body file: %w", status, err)
+			}
+			tmpl.Body = string(body)
+			tmpl.BodyFile = ""
+		}
+		if tmpl.Enabled && (tmpl.Subject == "" || tmpl.Body == "") {
+			return nil, fmt.Errorf("applicant email %q: subject and body are required", status)
+		}
+		templates[status] = tmpl
+	}
+
+	return templates, nil
+}
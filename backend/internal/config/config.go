@@ -1,23 +1,231 @@
 package config
 
 import (
+	"fmt"
 	"os"
+	"strings"
+	"time"
+	// SHEET_TIME_ZONE is resolved without relying on the container's zoneinfo
+	_ "time/tzdata"
 )
 
-// Config holds all configuration for the application
+// Configuration defaults
+const (
+	// DefaultIdempotencyTTL is how long idempotent responses are kept
+	DefaultIdempotencyTTL = 24 * time.Hour
+	// DefaultEventsPollInterval is how often the server polls the sheet for changes
+	DefaultEventsPollInterval = 30 * time.Second
+	// DefaultEventsHeartbeatInterval is how often idle event streams receive a heartbeat
+	DefaultEventsHeartbeatInterval = 15 * time.Second
+	// DefaultEventHistorySize is how many events are kept for resuming streams
+	DefaultEventHistorySize = 500
+	// DefaultApplicationRateLimit is how many applications one IP may submit per window
+	DefaultApplicationRateLimit = 5
+	// DefaultApplicationRateWindow is the window DefaultApplicationRateLimit applies to
+	DefaultApplicationRateWindow = time.Hour
+	// DefaultEmailDedupeWindow is how long identical queued emails are suppressed
+	DefaultEmailDedupeWindow = time.Hour
+	// DefaultArchiveSheetName is the tab processed invites are archived to
+	DefaultArchiveSheetName = "Archive"
+	// DefaultPort is the port the API server listens on
+	DefaultPort = "8080"
+)
+
+// Config holds all configuration for the application. Every setting can be
+// given in the config file under its yaml key and overridden by the
+// environment variable in its env tag; Load starts from Defaults.
+//
+// A Config is also the configuration of one tenant, a community with its own
+// spreadsheet served by the same deployment. Tenants are configured in the
+// file's tenants section and inherit every setting they do not override.
 type Config struct {
-	GoogleCredentialsFile string
-	GoogleTokenFile       string
-	GoogleSpreadsheetID   string
-	GoogleSheetName       string
+	// Name is the tenant's name, from its key in the tenants section; it is
+	// empty when no tenants are configured
+	Name string `yaml:"-"`
+	// Hosts are the hostnames that select the tenant in API requests, besides
+	// its /t/{name} path prefix
+	Hosts []string `yaml:"hosts"`
+	// SlackWorkspace is the Slack workspace invites are for, shown in the
+	// subject of notification emails
+	SlackWorkspace string `yaml:"slack_workspace" env:"SLACK_WORKSPACE"`
+
+	GoogleCredentialsFile string `yaml:"google_credentials_file" env:"GOOGLE_CREDENTIALS_FILE"`
+	GoogleTokenFile       string `yaml:"google_token_file" env:"GOOGLE_TOKEN_FILE"`
+	// GoogleAuthMode is how Sheets API requests are authorized; empty detects it
+	GoogleAuthMode      string `yaml:"google_auth_mode" env:"GOOGLE_AUTH_MODE"`
+	GoogleSpreadsheetID string `yaml:"google_spreadsheet_id" env:"GOOGLE_SPREADSHEET_ID"`
+	GoogleSheetName     string `yaml:"google_sheet_name" env:"GOOGLE_SHEET_NAME"`
+	// GoogleSheetsEndpoint overrides the Sheets API base URL, such as a local fake server
+	GoogleSheetsEndpoint string `yaml:"google_sheets_endpoint" env:"GOOGLE_SHEETS_ENDPOINT"`
+	// ArchiveSheetName is the tab, or prefix of the per-year tabs, holding archived invites
+	ArchiveSheetName string `yaml:"archive_sheet_name" env:"ARCHIVE_SHEET_NAME"`
+	// SheetTimeZone is the IANA time zone, such as Europe/Berlin, of the
+	// timestamps in columns A and K. Set it to the spreadsheet's own time
+	// zone, which Google Forms writes in; empty is UTC.
+	SheetTimeZone string `yaml:"sheet_time_zone" env:"SHEET_TIME_ZONE"`
+
+	// Port is the port the API server listens on
+	Port           string        `yaml:"port" env:"PORT"`
+	IdempotencyTTL time.Duration `yaml:"idempotency_ttl" env:"IDEMPOTENCY_TTL"`
+	// EventsPollInterval is how often the sheet is polled for changes
+	EventsPollInterval      time.Duration `yaml:"events_poll_interval" env:"EVENTS_POLL_INTERVAL"`
+	EventsHeartbeatInterval time.Duration `yaml:"events_heartbeat_interval" env:"EVENTS_HEARTBEAT_INTERVAL"`
+	// ApplicationRateLimit caps submissions per client IP every ApplicationRateWindow
+	ApplicationRateLimit  int           `yaml:"application_rate_limit" env:"APPLICATION_RATE_LIMIT"`
+	ApplicationRateWindow time.Duration `yaml:"application_rate_window" env:"APPLICATION_RATE_WINDOW"`
+	// TrustProxyHeaders trusts the headers set by the proxy in front of the
+	// server: the client IP in X-Forwarded-For and the reviewer email in
+	// ReviewerEmailHeader. The server must then only be reachable through the
+	// proxy, since any client can set these headers itself.
+	TrustProxyHeaders bool `yaml:"trust_proxy_headers" env:"TRUST_PROXY_HEADERS"`
+	// AuditLogFile is where the audit trail is kept; empty keeps it in memory
+	AuditLogFile        string `yaml:"audit_log_file" env:"AUDIT_LOG_FILE"`
+	ApplicantEmailsFile string `yaml:"applicant_emails_file" env:"APPLICANT_EMAILS_FILE"`
+	// AdminToken is the bearer token required by admin endpoints such as
+	// applicant erasure; empty disables them
+	AdminToken Secret `yaml:"admin_api_token" env:"ADMIN_API_TOKEN"`
+	// Reviewers may use the reviewer endpoints; empty leaves them open
+	Reviewers []string `yaml:"reviewer_emails" env:"REVIEWER_EMAILS"`
+	// ReviewerEmailHeader is the request header in which an authenticating
+	// proxy passes the signed-in reviewer's email, such as X-Forwarded-Email.
+	// It is only read with TrustProxyHeaders.
+	ReviewerEmailHeader string `yaml:"reviewer_email_header" env:"REVIEWER_EMAIL_HEADER"`
+
+	WebhooksFile       string `yaml:"webhooks_config_file" env:"WEBHOOKS_CONFIG_FILE"`
+	WebhookDeliveryLog string `yaml:"webhook_delivery_log" env:"WEBHOOK_DELIVERY_LOG"`
+
+	SMTPFromEmail string `yaml:"smtp2go_from_email" env:"SMTP2GO_FROM_EMAIL"`
+	SMTPUsername  string `yaml:"smtp2go_username" env:"SMTP2GO_USERNAME"`
+	SMTPPassword  Secret `yaml:"smtp2go_password" env:"SMTP2GO_PASSWORD"`
+	// DashboardURL fills the {{DASHBOARD_URL}} placeholder of the email template
+	DashboardURL string `yaml:"dashboard_url" env:"DASHBOARD_URL"`
+	// EmailRecipient is a comma-separated list of addresses that receive
+	// notifications not routed by EmailRoutingFile
+	EmailRecipient   string `yaml:"email_recipient" env:"EMAIL_RECIPIENT"`
+	EmailRoutingFile string `yaml:"email_routing_file" env:"EMAIL_ROUTING_FILE"`
+	// EmailTemplate is the HTML template notifications and reports are rendered in
+	EmailTemplate string `yaml:"email_template_path" env:"EMAIL_TEMPLATE_PATH"`
+	// EmailOutboxFile is where the API server keeps queued email, and
+	// SheetsOutboxFile where the sheets tool does; empty keeps it in memory
+	EmailOutboxFile   string        `yaml:"email_outbox_file" env:"EMAIL_OUTBOX_FILE"`
+	SheetsOutboxFile  string        `yaml:"sheets_email_outbox_file" env:"SHEETS_EMAIL_OUTBOX_FILE"`
+	EmailDedupeWindow time.Duration `yaml:"email_dedupe_window" env:"EMAIL_DEDUPE_WINDOW"`
+
+	// StaleThresholds are the ages in days at which pending invites trigger
+	// reminders; empty disables them
+	StaleThresholds []int  `yaml:"stale_invite_thresholds" env:"STALE_INVITE_THRESHOLDS"`
+	ReminderLogFile string `yaml:"stale_reminder_log" env:"STALE_REMINDER_LOG"`
+	// RetentionDays is how long after a decision personal data is kept; 0 disables redaction
+	RetentionDays int `yaml:"retention_days" env:"RETENTION_DAYS"`
+	// SheetAdmins may edit the protected status columns J and K
+	SheetAdmins []string `yaml:"sheet_admin_emails" env:"SHEET_ADMIN_EMAILS"`
+
+	// Webhooks are the subscriptions loaded from WebhooksFile
+	Webhooks []WebhookSubscription `yaml:"-"`
+	// ApplicantEmails are the decision email templates loaded from ApplicantEmailsFile
+	ApplicantEmails map[string]ApplicantEmailTemplate `yaml:"-"`
+	// Tenants are the configured tenants, in name order
+	Tenants []*Config `yaml:"-"`
 }
 
-// Load loads configuration from environment variables
-func Load() (*Config, error) {
+// Defaults returns the configuration used for settings that are not set
+func Defaults() *Config {
 	return &Config{
-		GoogleCredentialsFile: os.Getenv("GOOGLE_CREDENTIALS_FILE"),
-		GoogleTokenFile:       os.Getenv("GOOGLE_TOKEN_FILE"),
-		GoogleSpreadsheetID:   os.Getenv("GOOGLE_SPREADSHEET_ID"),
-		GoogleSheetName:       os.Getenv("GOOGLE_SHEET_NAME"),
-	}, nil
+		ArchiveSheetName:        DefaultArchiveSheetName,
+		Port:                    DefaultPort,
+		IdempotencyTTL:          DefaultIdempotencyTTL,
+		EventsPollInterval:      DefaultEventsPollInterval,
+		EventsHeartbeatInterval: DefaultEventsHeartbeatInterval,
+		ApplicationRateLimit:    DefaultApplicationRateLimit,
+		ApplicationRateWindow:   DefaultApplicationRateWindow,
+		EmailDedupeWindow:       DefaultEmailDedupeWindow,
+	}
+}
+
+// Load loads configuration from the file named by CONFIG_FILE, if any, and
+// environment variables
+func Load() (*Config, error) {
+	return LoadFile(os.Getenv("CONFIG_FILE"), os.Getenv)
+}
+
+// TenantConfigs returns the configuration of every tenant, or just c when no
+// tenants are configured
+func (c *Config) TenantConfigs() []*Config {
+	if len(c.Tenants) == 0 {
+		return []*Config{c}
+	}
+	return c.Tenants
+}
+
+// Tenant returns the configuration of the named tenant. An empty name
+// selects the only tenant, or c itself when no tenants are configured.
+func (c *Config) Tenant(name string) (*Config, error) {
+	tenants := c.TenantConfigs()
+	if name == "" {
+		if len(tenants) > 1 {
+			return nil, fmt.Errorf("choose one of the tenants %s", strings.Join(c.TenantNames(), ", "))
+		}
+		return tenants[0], nil
+	}
+	for _, tenant := range c.Tenants {
+		if tenant.Name == name {
+			return tenant, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown tenant %q", name)
+}
+
+// TenantNames returns the names of the configured tenants
+func (c *Config) TenantNames() []string {
+	var names []string
+	for _, tenant := range c.Tenants {
+		names = append(names, tenant.Name)
+	}
+	return names
+}
+
+// SheetsConfig returns the Google Sheets settings for the server and the
+// sheets commands
+func (c *Config) SheetsConfig() *SheetsConfig {
+	return &SheetsConfig{
+		CredentialsFile:    c.GoogleCredentialsFile,
+		TokenFile:          c.GoogleTokenFile,
+		AuthMode:           c.GoogleAuthMode,
+		SpreadsheetID:      c.GoogleSpreadsheetID,
+		SheetName:          c.GoogleSheetName,
+		Endpoint:           c.GoogleSheetsEndpoint,
+		ArchiveSheetName:   c.ArchiveSheetName,
+		Location:           c.SheetLocation(),
+		SlackWorkspace:     c.SlackWorkspace,
+		SMTP:               c.SMTPConfig(),
+		DashboardURL:       c.DashboardURL,
+		EmailRecipient:     c.EmailRecipient,
+		EmailRoutingFile:   c.EmailRoutingFile,
+		EmailTemplate:      c.EmailTemplate,
+		Webhooks:           c.Webhooks,
+		WebhookDeliveryLog: c.WebhookDeliveryLog,
+		EmailOutboxFile:    c.SheetsOutboxFile,
+		EmailDedupeWindow:  c.EmailDedupeWindow,
+		StaleThresholds:    c.StaleThresholds,
+		ReminderLogFile:    c.ReminderLogFile,
+		RetentionDays:      c.RetentionDays,
+		AuditLogFile:       c.AuditLogFile,
+		ServerOutboxFile:   c.EmailOutboxFile,
+		SheetAdmins:        c.SheetAdmins,
+	}
+}
+
+// SheetLocation returns the location of SheetTimeZone, or UTC if it is empty
+// or unknown
+func (c *Config) SheetLocation() *time.Location {
+	location, err := time.LoadLocation(c.SheetTimeZone)
+	if err != nil {
+		return time.UTC
+	}
+	return location
+}
+
+// SMTPConfig returns the SMTP2Go account email is sent through
+func (c *Config) SMTPConfig() SMTPConfig {
+	return SMTPConfig{FromEmail: c.SMTPFromEmail, Username: c.SMTPUsername, Password: c.SMTPPassword}
 }
@@ -0,0 +1,171 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/mail"
+	"os"
+	"strings"
+)
+
+// Notification email events
+const (
+	EmailEventNewApplications = "new_applications"
+	EmailEventErrors          = "errors"
+	EmailEventWeeklyReport    = "weekly_report"
+	// EmailEventStaleReminder and EmailEventStaleEscalation are sent for
+	// pending invites past the early and final stale thresholds
+	EmailEventStaleReminder   = "stale_reminder"
+	EmailEventStaleEscalation = "stale_escalation"
+	// EmailEventDefault routes any event without a route of its own
+	EmailEventDefault = "default"
+)
+
+// EmailEvents lists the events notification emails can be routed by
+var EmailEvents = []string{
+	EmailEventNewApplications,
+	EmailEventErrors,
+	EmailEventWeeklyReport,
+	EmailEventStaleReminder,
+	EmailEventStaleEscalation,
+	EmailEventDefault,
+}
+
+// EmailRoute lists who receives an event. Each entry is an email address or
+// the name of a recipient group.
+type EmailRoute struct {
+	To  []string `json:"to"`
+	CC  []string `json:"cc"`
+	BCC []string `json:"bcc"`
+}
+
+// EmailRouting maps notification events to their recipients
+type EmailRouting struct {
+	// Groups are named lists of addresses, such as "ops" or "moderators"
+	Groups map[string][]string   `json:"groups"`
+	Routes map[string]EmailRoute `json:"routes"`
+}
+
+// LoadEmailRouting reads email routing from a JSON file. Without a file,
+// or when the file has no default route, every event goes to the
+// comma-separated addresses in fallback.
+func LoadEmailRouting(path string, fallback string) (*EmailRouting, error) {
+	routing := &EmailRouting{}
+	if path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read email routing file: %w", err)
+		}
+		if err := json.Unmarshal(data, routing); err != nil {
+			return nil, fmt.Errorf("failed to parse email routing file: %w", err)
+		}
+	}
+	if routing.Routes == nil {
+		routing.Routes = make(map[string]EmailRoute)
+	}
+
+	if _, ok := routing.Routes[EmailEventDefault]; !ok && strings.TrimSpace(fallback) != "" {
+		routing.Routes[EmailEventDefault] = EmailRoute{To: splitList(fallback)}
+	}
+
+	if err := routing.validate(); err != nil {
+		return nil, err
+	}
+	return routing, nil
+}
+
+// validate checks that every group member is an address, every route names
+// a known event and every route entry is an address or a known group
+func (r *EmailRouting) validate() error {
+	for name, members := range r.Groups {
+		if strings.Contains(name, "@") {
+			return fmt.Errorf("email group %q: group names may not contain @", name)
+		}
+		for _, member := range members {
+			if err := validateAddress(member); err != nil {
+				return fmt.Errorf("email group %q: %w", name, err)
+			}
+		}
+	}
+
+	for event, route := range r.Routes {
+		if !isEmailEvent(event) {
+			return fmt.Errorf("email route for unknown event %q", event)
+		}
+		for _, entries := range [][]string{route.To, route.CC, route.BCC} {
+			for _, entry := range entries {
+				if strings.Contains(entry, "@") {
+					if err := validateAddress(entry); err != nil {
+						return fmt.Errorf("email route %q: %w", event, err)
+					}
+				} else if _, ok := r.Groups[entry]; !ok {
+					return fmt.Errorf("email route %q: unknown group %q", event, entry)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// Route returns the route for event, expanded into addresses, falling back
+// to the default route. Each address appears at most once across To, CC and
+// BCC. ok is false when the event has no recipients.
+func (r *EmailRouting) Route(event string) (to, cc, bcc []string, ok bool) {
+	route, found := r.Routes[event]
+	if !found {
+		route, found = r.Routes[EmailEventDefault]
+	}
+	if !found {
+		return nil, nil, nil, false
+	}
+
+	seen := make(map[string]bool)
+	expand := func(entries []string) []string {
+		var addresses []string
+		for _, entry := range entries {
+			members := []string{entry}
+			if !strings.Contains(entry, "@") {
+				members = r.Groups[entry]
+			}
+			for _, address := range members {
+				key := strings.ToLower(address)
+				if !seen[key] {
+					seen[key] = true
+					addresses = append(addresses, address)
+				}
+			}
+		}
+		return addresses
+	}
+	to, cc, bcc = expand(route.To), expand(route.CC), expand(route.BCC)
+	return to, cc, bcc, len(to)+len(cc)+len(bcc) > 0
+}
+
+func isEmailEvent(event string) bool {
+	for _, known := range EmailEvents {
+		if event == known {
+			return true
+		}
+	}
+	return false
+}
+
+// validateAddress checks that value is a bare email address
+func validateAddress(value string) error {
+	addr, err := mail.ParseAddress(value)
+	if err != nil || addr.Address != value {
+		return fmt.Errorf("%q is not a valid email address", value)
+	}
+	return nil
+}
+
+// splitList splits a comma-separated list, dropping empty entries
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
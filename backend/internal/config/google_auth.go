@@ -0,0 +1,229 @@
+package config
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+
+	"cloud.google.com/go/compute/metadata"
+	"github.com/stevebennett/slack-invite-mgr/backend/internal/googleauth"
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/google"
+	"google.golang.org/api/sheets/v4"
+)
+
+// Google auth modes, chosen with GOOGLE_AUTH_MODE
+const (
+	// AuthModeServiceAccount signs requests with the service account key in
+	// the credentials file
+	AuthModeServiceAccount = "service_account"
+	// AuthModeOAuth acts as a user, with the OAuth client in the credentials
+	// file and the token saved by "sheets auth login" in the token file
+	AuthModeOAuth = "oauth"
+	// AuthModeADC uses Application Default Credentials: the credentials file
+	// if set, such as a workload identity federation config, otherwise
+	// GOOGLE_APPLICATION_CREDENTIALS, gcloud credentials or the metadata
+	// server of GCE, Cloud Run or GKE workload identity
+	AuthModeADC = "adc"
+)
+
+// emailScope lets Google's tokeninfo endpoint report the email of the user
+// an OAuth token was granted by
+const emailScope = "https://www.googleapis.com/auth/userinfo.email"
+
+// tokenInfoURL is Google's endpoint that describes an access token
+var tokenInfoURL = "https://oauth2.googleapis.com/tokeninfo"
+
+// OAuthClientConfig returns the OAuth client in the credentials file, which
+// must be a desktop ("installed") or web client downloaded from the Google
+// Cloud console
+func OAuthClientConfig(cfg *SheetsConfig) (*oauth2.Config, error) {
+	if cfg.CredentialsFile == "" {
+		return nil, errors.New("GOOGLE_CREDENTIALS_FILE must name an OAuth client file")
+	}
+	credentials, err := os.ReadFile(cfg.CredentialsFile)
+	if err != nil {
+		return nil, err
+	}
+	client, err := google.ConfigFromJSON(credentials, sheets.SpreadsheetsScope, emailScope)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse OAuth client file: %w", err)
+	}
+	return client, nil
+}
+
+// resolveAuthMode returns the configured auth mode, or detects it: the type
+// of the credentials file picks the mode, and without one ADC is used unless
+// an endpoint is set, which returns "" to send requests unauthenticated
+func resolveAuthMode(cfg *SheetsConfig) (string, []byte, error) {
+	var credentials []byte
+	if cfg.CredentialsFile != "" {
+		var err error
+		if credentials, err = os.ReadFile(cfg.CredentialsFile); err != nil {
+			return "", nil, err
+		}
+	}
+	if cfg.AuthMode != "" {
+		return cfg.AuthMode, credentials, nil
+	}
+	if credentials == nil {
+		if cfg.Endpoint != "" {
+			return "", nil, nil
+		}
+		return AuthModeADC, nil, nil
+	}
+
+	var file struct {
+		Type      string          `json:"type"`
+		Installed json.RawMessage `json:"installed"`
+		Web       json.RawMessage `json:"web"`
+	}
+	if err := json.Unmarshal(credentials, &file); err != nil {
+		return "", nil, fmt.Errorf("failed to parse credentials file: %w", err)
+	}
+	switch {
+	case file.Installed != nil || file.Web != nil:
+		return AuthModeOAuth, credentials, nil
+	case file.Type == "service_account":
+		return AuthModeServiceAccount, credentials, nil
+	default:
+		return AuthModeADC, credentials, nil
+	}
+}
+
+// AuthenticatedEmail returns the email of the account Sheets API requests are
+// made as. A service account key or an impersonation config names it;
+// otherwise the metadata server is asked on Google Cloud, or Google's
+// tokeninfo endpoint for the account's access token. It returns "" when
+// requests are unauthenticated or the account is not reported, such as for an
+// OAuth token granted before "sheets auth login" asked for the email scope.
+func AuthenticatedEmail(ctx context.Context, cfg *SheetsConfig) (string, error) {
+	mode, credentials, err := resolveAuthMode(cfg)
+	if err != nil || mode == "" {
+		return "", err
+	}
+	if mode == AuthModeADC && credentials == nil {
+		creds, err := google.FindDefaultCredentials(ctx, sheets.SpreadsheetsScope)
+		if err != nil {
+			return "", fmt.Errorf("failed to find application default credentials: %w", err)
+		}
+		// Only credentials from the metadata server have no JSON
+		if creds.JSON == nil {
+			return metadata.EmailWithContext(ctx, "default")
+		}
+		credentials = creds.JSON
+	}
+	if mode != AuthModeOAuth && credentials != nil {
+		var file struct {
+			ClientEmail      string `json:"client_email"`
+			ImpersonationURL string `json:"service_account_impersonation_url"`
+		}
+		if err := json.Unmarshal(credentials, &file); err != nil {
+			return "", fmt.Errorf("failed to parse credentials file: %w", err)
+		}
+		if file.ClientEmail != "" {
+			return file.ClientEmail, nil
+		}
+		// .../serviceAccounts/<email>:generateAccessToken
+		if _, account, ok := strings.Cut(file.ImpersonationURL, "/serviceAccounts/"); ok {
+			account, _, _ = strings.Cut(account, ":")
+			return account, nil
+		}
+	}
+
+	tokens, err := googleTokenSource(ctx, cfg, mode, credentials)
+	if err != nil {
+		return "", err
+	}
+	return tokenInfoEmail(ctx, tokens)
+}
+
+// tokenInfoEmail returns the email that Google's tokeninfo endpoint reports
+// for an access token from tokens, or "" if it reports none
+func tokenInfoEmail(ctx context.Context, tokens oauth2.TokenSource) (string, error) {
+	token, err := tokens.Token()
+	if err != nil {
+		return "", fmt.Errorf("failed to get an access token: %w", err)
+	}
+	form := url.Values{"access_token": {token.AccessToken}}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, tokenInfoURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to look up the access token: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("failed to look up the access token: %s", resp.Status)
+	}
+	var info struct {
+		Email string `json:"email"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&info); err != nil {
+		return "", fmt.Errorf("failed to parse token info: %w", err)
+	}
+	return info.Email, nil
+}
+
+// googleHTTPClient returns an HTTP client that authorizes Sheets API requests
+// in the given mode
+func googleHTTPClient(ctx context.Context, cfg *SheetsConfig, mode string, credentials []byte) (*http.Client, error) {
+	tokens, err := googleTokenSource(ctx, cfg, mode, credentials)
+	if err != nil {
+		return nil, err
+	}
+	return oauth2.NewClient(ctx, tokens), nil
+}
+
+// googleTokenSource returns the access tokens for Sheets API requests in the
+// given mode
+func googleTokenSource(ctx context.Context, cfg *SheetsConfig, mode string, credentials []byte) (oauth2.TokenSource, error) {
+	switch mode {
+	case AuthModeServiceAccount:
+		if credentials == nil {
+			return nil, errors.New("GOOGLE_CREDENTIALS_FILE must name a service account key")
+		}
+		jwt, err := google.JWTConfigFromJSON(credentials, sheets.SpreadsheetsScope)
+		if err != nil {
+			return nil, err
+		}
+		return jwt.TokenSource(ctx), nil
+	case AuthModeOAuth:
+		if cfg.TokenFile == "" {
+			return nil, errors.New("GOOGLE_TOKEN_FILE must be set to use OAuth user credentials")
+		}
+		client, err := OAuthClientConfig(cfg)
+		if err != nil {
+			return nil, err
+		}
+		tokens, err := googleauth.FileTokenSource(ctx, client, cfg.TokenFile)
+		if errors.Is(err, googleauth.ErrNoToken) {
+			return nil, fmt.Errorf("%w; run \"sheets auth login\" first", err)
+		}
+		if err != nil {
+			return nil, err
+		}
+		return tokens, nil
+	case AuthModeADC:
+		var creds *google.Credentials
+		var err error
+		if credentials != nil {
+			creds, err = google.CredentialsFromJSON(ctx, credentials, sheets.SpreadsheetsScope)
+		} else {
+			creds, err = google.FindDefaultCredentials(ctx, sheets.SpreadsheetsScope)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to find application default credentials: %w", err)
+		}
+		return creds.TokenSource, nil
+	}
+	return nil, fmt.Errorf("unknown auth mode %q", mode)
+}
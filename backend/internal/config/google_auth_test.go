@@ -0,0 +1,84 @@
+package config
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stevebennett/slack-invite-mgr/backend/internal/googleauth"
+	"golang.org/x/oauth2"
+)
+
+func TestAuthenticatedEmail(t *testing.T) {
+	// tokeninfo reports the email only for the token granted with the email scope
+	tokenInfo := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.FormValue("access_token") == "" {
+			http.Error(w, "invalid_token", http.StatusBadRequest)
+			return
+		}
+		info := map[string]string{"scope": "https://www.googleapis.com/auth/spreadsheets"}
+		if r.FormValue("access_token") == "with-email" {
+			info["email"] = "jane@example.com"
+		}
+		json.NewEncoder(w).Encode(info)
+	}))
+	defer tokenInfo.Close()
+	defer func(previous string) { tokenInfoURL = previous }(tokenInfoURL)
+	tokenInfoURL = tokenInfo.URL
+
+	oauthClient := writeFile(t, "client.json", `{"installed": {"client_id": "id", "client_secret": "secret", "auth_uri": "https://accounts.example/auth", "token_uri": "https://accounts.example/token", "redirect_uris": ["http://localhost"]}}`)
+	oauthToken := func(access string) string {
+		path := filepath.Join(t.TempDir(), "token.json")
+		if err := googleauth.SaveToken(path, &oauth2.Token{AccessToken: access, Expiry: time.Now().Add(time.Hour)}); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	tests := []struct {
+		name     string
+		cfg      *SheetsConfig
+		expected string
+	}{
+		{
+			name:     "service account key",
+			cfg:      &SheetsConfig{CredentialsFile: writeFile(t, "key.json", `{"type": "service_account", "client_email": "sync@project.iam.gserviceaccount.com"}`)},
+			expected: "sync@project.iam.gserviceaccount.com",
+		},
+		{
+			name: "impersonated service account",
+			cfg: &SheetsConfig{AuthMode: AuthModeADC, CredentialsFile: writeFile(t, "federation.json", `{"type": "external_account",
+				"service_account_impersonation_url": "https://iamcredentials.googleapis.com/v1/projects/-/serviceAccounts/sync@project.iam.gserviceaccount.com:generateAccessToken"}`)},
+			expected: "sync@project.iam.gserviceaccount.com",
+		},
+		{
+			name:     "OAuth user from tokeninfo",
+			cfg:      &SheetsConfig{CredentialsFile: oauthClient, TokenFile: oauthToken("with-email")},
+			expected: "jane@example.com",
+		},
+		{
+			name: "OAuth token without the email scope",
+			cfg:  &SheetsConfig{CredentialsFile: oauthClient, TokenFile: oauthToken("without-email")},
+		},
+		{
+			name: "unauthenticated endpoint",
+			cfg:  &SheetsConfig{Endpoint: "http://localhost:9999"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			email, err := AuthenticatedEmail(context.Background(), tt.cfg)
+			if err != nil {
+				t.Fatalf("AuthenticatedEmail() failed: %v", err)
+			}
+			if email != tt.expected {
+				t.Errorf("AuthenticatedEmail() = %q, want %q", email, tt.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,428 @@
+package config
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+var (
+	durationType = reflect.TypeOf(time.Duration(0))
+	secretType   = reflect.TypeOf(Secret(""))
+)
+
+// tenantName is the form of tenant names, which appear in URL paths
+var tenantName = regexp.MustCompile(`^[a-z0-9][a-z0-9-]*$`)
+
+// configFile is the layout of a config file: settings for the whole
+// deployment, which tenants inherit, and each tenant's overrides
+type configFile struct {
+	Config  `yaml:",inline"`
+	Tenants map[string]yaml.Node `yaml:"tenants"`
+}
+
+// strictConfigFile decodes the same layout, to report unknown keys
+type strictConfigFile struct {
+	Config  `yaml:",inline"`
+	Tenants map[string]Config `yaml:"tenants"`
+}
+
+// LoadFile loads configuration from Defaults, then the YAML file at path,
+// if path is not empty, then the environment variables read with getenv.
+// Secrets are resolved as described by Secrets, and secrets in the file may
+// be provider references. Each tenant in the file starts from the result and
+// applies its own settings, with "{tenant}" in its file paths replaced by its
+// name. Every problem found is reported in one error, one line each.
+func LoadFile(path string, getenv func(string) string) (*Config, error) {
+	cfg := Defaults()
+	var tenants map[string]yaml.Node
+	if path != "" {
+		var err error
+		if tenants, err = cfg.readFile(path); err != nil {
+			return nil, err
+		}
+	}
+	secrets, err := NewSecrets(getenv)
+	if err != nil {
+		return nil, err
+	}
+	ctx := context.Background()
+
+	problems := cfg.applyEnv(ctx, getenv, secrets)
+	if len(tenants) == 0 {
+		problems = append(problems, cfg.finish(ctx, secrets)...)
+	} else {
+		names := make([]string, 0, len(tenants))
+		for name := range tenants {
+			names = append(names, name)
+		}
+		slices.Sort(names)
+		for _, name := range names {
+			node := tenants[name]
+			tenant, err := cfg.tenant(ctx, name, &node, secrets)
+			if err != nil {
+				problems = append(problems, fmt.Errorf("tenant %s: %w", name, err))
+				continue
+			}
+			for _, problem := range tenant.finish(ctx, secrets) {
+				problems = append(problems, fmt.Errorf("tenant %s: %w", name, problem))
+			}
+			cfg.Tenants = append(cfg.Tenants, tenant)
+		}
+		problems = append(problems, validateTenants(cfg.Tenants)...)
+	}
+	if len(problems) > 0 {
+		return nil, errors.Join(problems...)
+	}
+	return cfg, nil
+}
+
+// readFile decodes a YAML config file over cfg, rejecting unknown keys, and
+// returns the tenants section undecoded
+func (c *Config) readFile(path string) (map[string]yaml.Node, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+	strict := yaml.NewDecoder(bytes.NewReader(data))
+	strict.KnownFields(true)
+	if err := strict.Decode(&strictConfigFile{}); err != nil && err != io.EOF {
+		return nil, fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+	file := configFile{Config: *c}
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+	*c = file.Config
+	return file.Tenants, nil
+}
+
+// tenant returns the configuration of a tenant: a copy of c with the
+// tenant's settings from node applied
+func (c *Config) tenant(ctx context.Context, name string, node *yaml.Node, secrets *Secrets) (*Config, error) {
+	if !tenantName.MatchString(name) {
+		return nil, errors.New("name must be lowercase letters, digits and dashes")
+	}
+	tenant := *c
+	tenant.Tenants = nil
+	if err := node.Decode(&tenant); err != nil {
+		return nil, err
+	}
+	tenant.Name = name
+
+	v, inherited := reflect.ValueOf(&tenant).Elem(), reflect.ValueOf(c).Elem()
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Field(i)
+		switch {
+		case field.Type() == secretType && field.String() != inherited.Field(i).String():
+			// Set by the tenant, so it may be a reference
+			value, err := secrets.Resolve(ctx, field.String())
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", v.Type().Field(i).Tag.Get("env"), err)
+			}
+			field.SetString(string(value))
+		case field.Kind() == reflect.String && field.Type() != secretType:
+			field.SetString(strings.ReplaceAll(field.String(), "{tenant}", name))
+		}
+	}
+	return &tenant, nil
+}
+
+// finish normalizes the settings, loads the files they name, resolving the
+// secrets in them, and returns every problem found
+func (c *Config) finish(ctx context.Context, secrets *Secrets) []error {
+	var problems []error
+	c.StaleThresholds = slices.Compact(slices.Sorted(slices.Values(c.StaleThresholds)))
+	var err error
+	if c.Webhooks, err = LoadWebhookSubscriptions(ctx, c.WebhooksFile, secrets); err != nil {
+		problems = append(problems, err)
+	}
+	if c.ApplicantEmails, err = LoadApplicantEmailTemplates(c.ApplicantEmailsFile); err != nil {
+		problems = append(problems, err)
+	}
+	return append(problems, c.validate()...)
+}
+
+// validateTenants checks what tenants must not share: hosts, and the files
+// that hold each tenant's state
+func validateTenants(tenants []*Config) []error {
+	var problems []error
+	hosts := make(map[string]string)
+	for _, tenant := range tenants {
+		for _, host := range tenant.Hosts {
+			host = strings.ToLower(host)
+			if other, ok := hosts[host]; ok {
+				problems = append(problems, fmt.Errorf("tenants %s and %s both use host %q", other, tenant.Name, host))
+			}
+			hosts[host] = tenant.Name
+		}
+	}
+	for _, file := range []struct {
+		name string
+		path func(*Config) string
+	}{
+		{"AUDIT_LOG_FILE", func(c *Config) string { return c.AuditLogFile }},
+		{"EMAIL_OUTBOX_FILE", func(c *Config) string { return c.EmailOutboxFile }},
+		{"SHEETS_EMAIL_OUTBOX_FILE", func(c *Config) string { return c.SheetsOutboxFile }},
+		{"WEBHOOK_DELIVERY_LOG", func(c *Config) string { return c.WebhookDeliveryLog }},
+		{"STALE_REMINDER_LOG", func(c *Config) string { return c.ReminderLogFile }},
+	} {
+		owners := make(map[string]string)
+		for _, tenant := range tenants {
+			path := file.path(tenant)
+			if path == "" {
+				continue
+			}
+			if other, ok := owners[path]; ok {
+				problems = append(problems, fmt.Errorf("tenants %s and %s share %s %q; include {tenant} in the path", other, tenant.Name, file.name, path))
+			}
+			owners[path] = tenant.Name
+		}
+	}
+	return problems
+}
+
+// applyEnv overrides settings with the environment variables in their env
+// tags and resolves secrets, returning a problem for each invalid value
+func (c *Config) applyEnv(ctx context.Context, getenv func(string) string, secrets *Secrets) []error {
+	var problems []error
+	v := reflect.ValueOf(c).Elem()
+	for i := 0; i < v.NumField(); i++ {
+		name := v.Type().Field(i).Tag.Get("env")
+		if name == "" {
+			continue
+		}
+		field := v.Field(i)
+		if field.Type() == secretType {
+			value, err := secrets.Lookup(ctx, name)
+			if err == nil && value == "" {
+				// Not set in the environment, so keep the file's value,
+				// which may itself be a reference
+				value, err = secrets.Resolve(ctx, field.String())
+			}
+			if err != nil {
+				problems = append(problems, err)
+				continue
+			}
+			field.SetString(string(value))
+			continue
+		}
+		if value := getenv(name); value != "" {
+			if err := setFromString(field, value); err != nil {
+				problems = append(problems, fmt.Errorf("%s %s: %q", name, err, value))
+			}
+		}
+	}
+	return problems
+}
+
+// setFromString sets a setting from the text of an environment variable.
+// The error completes a sentence starting with the variable's name.
+func setFromString(field reflect.Value, value string) error {
+	switch {
+	case field.Type() == durationType:
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return errors.New("must be a duration such as 24h")
+		}
+		field.SetInt(int64(d))
+	case field.Kind() == reflect.String:
+		field.SetString(value)
+	case field.Kind() == reflect.Int:
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return errors.New("must be a whole number")
+		}
+		field.SetInt(int64(n))
+	case field.Kind() == reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return errors.New("must be true or false")
+		}
+		field.SetBool(b)
+	case field.Type() == reflect.TypeOf([]string(nil)):
+		field.Set(reflect.ValueOf(splitList(value)))
+	case field.Type() == reflect.TypeOf([]int(nil)):
+		var numbers []int
+		for _, item := range splitList(value) {
+			n, err := strconv.Atoi(item)
+			if err != nil {
+				return errors.New("must be a comma-separated list of whole numbers")
+			}
+			numbers = append(numbers, n)
+		}
+		field.Set(reflect.ValueOf(numbers))
+	default:
+		return fmt.Errorf("has unsupported type %s", field.Type())
+	}
+	return nil
+}
+
+// Validate checks every setting and returns all the problems found, one
+// line each
+func (c *Config) Validate() error {
+	return errors.Join(c.validate()...)
+}
+
+// validate returns a problem for each invalid setting
+func (c *Config) validate() []error {
+	var problems []error
+	problem := func(format string, args ...interface{}) {
+		problems = append(problems, fmt.Errorf(format, args...))
+	}
+
+	if c.GoogleSpreadsheetID == "" {
+		problem("GOOGLE_SPREADSHEET_ID is required")
+	}
+	if c.GoogleSheetName == "" {
+		problem("GOOGLE_SHEET_NAME is required")
+	}
+	switch c.GoogleAuthMode {
+	case "", AuthModeServiceAccount, AuthModeOAuth, AuthModeADC:
+	default:
+		problem("GOOGLE_AUTH_MODE must be %s, %s or %s: %q", AuthModeServiceAccount, AuthModeOAuth, AuthModeADC, c.GoogleAuthMode)
+	}
+	if c.GoogleAuthMode == AuthModeOAuth && c.GoogleTokenFile == "" {
+		problem("GOOGLE_TOKEN_FILE is required when GOOGLE_AUTH_MODE is %s", AuthModeOAuth)
+	}
+	if c.GoogleSheetsEndpoint != "" {
+		if u, err := url.Parse(c.GoogleSheetsEndpoint); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			problem("GOOGLE_SHEETS_ENDPOINT must be an absolute http(s) URL: %q", c.GoogleSheetsEndpoint)
+		}
+	}
+	if c.ArchiveSheetName == "" {
+		problem("ARCHIVE_SHEET_NAME must not be empty")
+	} else if c.ArchiveSheetName == c.GoogleSheetName {
+		problem("ARCHIVE_SHEET_NAME must differ from GOOGLE_SHEET_NAME: %q", c.ArchiveSheetName)
+	}
+	if _, err := time.LoadLocation(c.SheetTimeZone); err != nil {
+		problem("SHEET_TIME_ZONE must be an IANA time zone such as Europe/Berlin: %q", c.SheetTimeZone)
+	}
+
+	if port, err := strconv.Atoi(c.Port); err != nil || port < 1 || port > 65535 {
+		problem("PORT must be a port number: %q", c.Port)
+	}
+	for _, d := range []struct {
+		name  string
+		value time.Duration
+	}{
+		{"IDEMPOTENCY_TTL", c.IdempotencyTTL},
+		{"EVENTS_POLL_INTERVAL", c.EventsPollInterval},
+		{"EVENTS_HEARTBEAT_INTERVAL", c.EventsHeartbeatInterval},
+		{"APPLICATION_RATE_WINDOW", c.ApplicationRateWindow},
+		{"EMAIL_DEDUPE_WINDOW", c.EmailDedupeWindow},
+	} {
+		if d.value <= 0 {
+			problem("%s must be a positive duration such as 24h: %s", d.name, d.value)
+		}
+	}
+	if c.ApplicationRateLimit <= 0 {
+		problem("APPLICATION_RATE_LIMIT must be a positive number: %d", c.ApplicationRateLimit)
+	}
+	if c.RetentionDays < 0 {
+		problem("RETENTION_DAYS must not be negative: %d", c.RetentionDays)
+	}
+	for _, days := range c.StaleThresholds {
+		if days <= 0 {
+			problem("STALE_INVITE_THRESHOLDS must be positive day counts: %d", days)
+		}
+	}
+	// The server and the sheets tool each rewrite their outbox whole
+	if c.EmailOutboxFile != "" && filepath.Clean(c.EmailOutboxFile) == filepath.Clean(c.SheetsOutboxFile) {
+		problem("SHEETS_EMAIL_OUTBOX_FILE must differ from EMAIL_OUTBOX_FILE: %q", c.SheetsOutboxFile)
+	}
+
+	if c.SMTPFromEmail != "" {
+		if err := validateAddress(c.SMTPFromEmail); err != nil {
+			problem("SMTP2GO_FROM_EMAIL: %w", err)
+		}
+	}
+	for _, admin := range c.SheetAdmins {
+		if err := validateAddress(admin); err != nil {
+			problem("SHEET_ADMIN_EMAILS: %w", err)
+		}
+	}
+	for _, reviewer := range c.Reviewers {
+		if err := validateAddress(reviewer); err != nil {
+			problem("REVIEWER_EMAILS: %w", err)
+		}
+	}
+	if len(c.Reviewers) > 0 && c.ReviewerEmailHeader == "" {
+		problem("REVIEWER_EMAIL_HEADER is required to check REVIEWER_EMAILS")
+	}
+	if len(c.Reviewers) > 0 && !c.TrustProxyHeaders {
+		problem("TRUST_PROXY_HEADERS must be true to check REVIEWER_EMAILS, which come from a proxy header")
+	}
+	if _, err := LoadEmailRouting(c.EmailRoutingFile, c.EmailRecipient); err != nil {
+		problems = append(problems, err)
+	}
+	return problems
+}
+
+// WriteYAML writes the configuration as a YAML config file, in field order,
+// followed by each tenant's full settings. With redact, secrets are written
+// as "[redacted]".
+func (c *Config) WriteYAML(w io.Writer, redact bool) error {
+	doc, err := c.yamlNode(redact)
+	if err != nil {
+		return err
+	}
+	if len(c.Tenants) > 0 {
+		tenants := &yaml.Node{Kind: yaml.MappingNode}
+		for _, tenant := range c.Tenants {
+			node, err := tenant.yamlNode(redact)
+			if err != nil {
+				return err
+			}
+			tenants.Content = append(tenants.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: tenant.Name}, node)
+		}
+		doc.Content = append(doc.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: "tenants"}, tenants)
+	}
+	encoder := yaml.NewEncoder(w)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(doc); err != nil {
+		return err
+	}
+	return encoder.Close()
+}
+
+// yamlNode returns the settings as a YAML mapping in field order
+func (c *Config) yamlNode(redact bool) (*yaml.Node, error) {
+	doc := &yaml.Node{Kind: yaml.MappingNode}
+	v := reflect.ValueOf(c).Elem()
+	for i := 0; i < v.NumField(); i++ {
+		key := v.Type().Field(i).Tag.Get("yaml")
+		if key == "" || key == "-" {
+			continue
+		}
+		var value interface{} = v.Field(i).Interface()
+		switch field := value.(type) {
+		case Secret:
+			value = field.Reveal()
+			if redact {
+				value = field.String()
+			}
+		case time.Duration:
+			value = field.String()
+		}
+		var node yaml.Node
+		if err := node.Encode(value); err != nil {
+			return nil, fmt.Errorf("failed to encode %s: %w", key, err)
+		}
+		doc.Content = append(doc.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: key}, &node)
+	}
+	return doc, nil
+}
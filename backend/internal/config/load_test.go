@@ -0,0 +1,257 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLoadFile(t *testing.T) {
+	path := writeFile(t, "config.yaml", `
+google_spreadsheet_id: from-file
+google_sheet_name: Invites
+idempotency_ttl: 12h
+application_rate_limit: 10
+stale_invite_thresholds: [14, 3, 7, 3]
+sheet_admin_emails: [alice@example.com]
+admin_api_token: file-token
+smtp2go_password: stub://smtp
+`)
+	t.Run("file, env and defaults", func(t *testing.T) {
+		RegisterSecretProvider("stub", stubProvider{"smtp": "from-provider"})
+		cfg, err := LoadFile(path, envMap(map[string]string{
+			"GOOGLE_SPREADSHEET_ID": "from-env",
+			"TRUST_PROXY_HEADERS":   "true",
+			"SHEET_ADMIN_EMAILS":    "bob@example.com, carol@example.com",
+		}))
+		if err != nil {
+			t.Fatalf("LoadFile() failed: %v", err)
+		}
+		checks := []struct {
+			name      string
+			got, want interface{}
+		}{
+			{"env overrides file", cfg.GoogleSpreadsheetID, "from-env"},
+			{"file value", cfg.GoogleSheetName, "Invites"},
+			{"file duration", cfg.IdempotencyTTL, 12 * time.Hour},
+			{"file int", cfg.ApplicationRateLimit, 10},
+			{"env bool", cfg.TrustProxyHeaders, true},
+			{"env list", cfg.SheetAdmins, []string{"bob@example.com", "carol@example.com"}},
+			{"sorted thresholds", cfg.StaleThresholds, []int{3, 7, 14}},
+			{"file secret", cfg.AdminToken, Secret("file-token")},
+			{"file secret reference", cfg.SMTPPassword, Secret("from-provider")},
+			{"default", cfg.EventsPollInterval, DefaultEventsPollInterval},
+			{"default port", cfg.Port, DefaultPort},
+		}
+		for _, c := range checks {
+			if !reflect.DeepEqual(c.got, c.want) {
+				t.Errorf("%s: got %v, want %v", c.name, c.got, c.want)
+			}
+		}
+	})
+
+	t.Run("secret from env file", func(t *testing.T) {
+		cfg, err := LoadFile(path, envMap(map[string]string{"ADMIN_API_TOKEN_FILE": writeFile(t, "token", "env-token\n")}))
+		if err != nil {
+			t.Fatalf("LoadFile() failed: %v", err)
+		}
+		if cfg.AdminToken != "env-token" {
+			t.Errorf("AdminToken = %q, want env-token", cfg.AdminToken.Reveal())
+		}
+	})
+
+	t.Run("env only", func(t *testing.T) {
+		cfg, err := LoadFile("", envMap(map[string]string{"GOOGLE_SPREADSHEET_ID": "id", "GOOGLE_SHEET_NAME": "Sheet1"}))
+		if err != nil {
+			t.Fatalf("LoadFile() failed: %v", err)
+		}
+		if cfg.IdempotencyTTL != DefaultIdempotencyTTL || cfg.ArchiveSheetName != DefaultArchiveSheetName {
+			t.Errorf("LoadFile() = %+v, want defaults", cfg)
+		}
+	})
+
+	t.Run("unknown key", func(t *testing.T) {
+		_, err := LoadFile(writeFile(t, "config.yaml", "google_sheet: typo\n"), envMap(nil))
+		if err == nil || !strings.Contains(err.Error(), "google_sheet") {
+			t.Errorf("LoadFile() error = %v, want the unknown key named", err)
+		}
+	})
+}
+
+func TestLoadFileReportsEveryProblem(t *testing.T) {
+	path := writeFile(t, "config.yaml", `
+google_sheet_name: Archive
+events_poll_interval: 0s
+stale_invite_thresholds: [0]
+`)
+	_, err := LoadFile(path, envMap(map[string]string{
+		"IDEMPOTENCY_TTL":          "soon",
+		"RETENTION_DAYS":           "-1",
+		"GOOGLE_AUTH_MODE":         "password",
+		"SHEET_ADMIN_EMAILS":       "not-an-address",
+		"WEBHOOKS_CONFIG_FILE":     "/does/not/exist.json",
+		"SHEET_TIME_ZONE":          "Mars/Olympus_Mons",
+		"EMAIL_OUTBOX_FILE":        "outbox.json",
+		"SHEETS_EMAIL_OUTBOX_FILE": "./outbox.json",
+	}))
+	if err == nil {
+		t.Fatal("LoadFile() succeeded, want an error")
+	}
+	for _, want := range []string{
+		"IDEMPOTENCY_TTL must be a duration",
+		"GOOGLE_SPREADSHEET_ID is required",
+		"GOOGLE_AUTH_MODE must be",
+		"ARCHIVE_SHEET_NAME must differ",
+		"EVENTS_POLL_INTERVAL must be a positive duration",
+		"RETENTION_DAYS must not be negative",
+		"STALE_INVITE_THRESHOLDS must be positive",
+		"SHEET_ADMIN_EMAILS",
+		"SHEET_TIME_ZONE must be an IANA time zone",
+		"SHEETS_EMAIL_OUTBOX_FILE must differ from EMAIL_OUTBOX_FILE",
+		"failed to read webhooks file",
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error does not report %q:\n%v", want, err)
+		}
+	}
+	var joined interface{ Unwrap() []error }
+	if !errors.As(err, &joined) {
+		t.Error("error is not a joined list of problems")
+	}
+}
+
+func TestWriteYAML(t *testing.T) {
+	cfg := Defaults()
+	cfg.GoogleSpreadsheetID = "id"
+	cfg.GoogleSheetName = "Sheet1"
+	cfg.AdminToken = "hunter2"
+	cfg.StaleThresholds = []int{3, 7}
+
+	var redactedOut bytes.Buffer
+	if err := cfg.WriteYAML(&redactedOut, true); err != nil {
+		t.Fatalf("WriteYAML() failed: %v", err)
+	}
+	if strings.Contains(redactedOut.String(), "hunter2") || !strings.Contains(redactedOut.String(), "admin_api_token: '[redacted]'") {
+		t.Errorf("redacted output shows the secret:\n%s", redactedOut.String())
+	}
+
+	// Unredacted output is a config file that loads back to the same settings
+	var out bytes.Buffer
+	if err := cfg.WriteYAML(&out, false); err != nil {
+		t.Fatalf("WriteYAML() failed: %v", err)
+	}
+	loaded, err := LoadFile(writeFile(t, "config.yaml", out.String()), envMap(nil))
+	if err != nil {
+		t.Fatalf("LoadFile() of printed config failed: %v\n%s", err, out.String())
+	}
+	if loaded.AdminToken != "hunter2" || loaded.IdempotencyTTL != cfg.IdempotencyTTL || !reflect.DeepEqual(loaded.StaleThresholds, cfg.StaleThresholds) {
+		t.Errorf("printed config loaded as %+v, want %+v", loaded, cfg)
+	}
+}
+
+func TestLoadFileTenants(t *testing.T) {
+	tokenFile := writeFile(t, "token", "rust-token\n")
+	path := writeFile(t, "config.yaml", `
+google_sheet_name: Invites
+admin_api_token: shared-token
+audit_log_file: /var/lib/invites/{tenant}/audit.jsonl
+reviewer_email_header: X-Goog-Authenticated-User-Email
+trust_proxy_headers: true
+tenants:
+  gophers:
+    google_spreadsheet_id: gophers-sheet
+    slack_workspace: Gophers
+    hosts: [invites.gophers.example]
+    reviewer_emails: [jane@example.com]
+  rustaceans:
+    google_spreadsheet_id: rust-sheet
+    google_sheet_name: Requests
+    admin_api_token: file://`+tokenFile+`
+`)
+	cfg, err := LoadFile(path, envMap(map[string]string{"SLACK_WORKSPACE": "Default"}))
+	if err != nil {
+		t.Fatalf("LoadFile() failed: %v", err)
+	}
+	if got := cfg.TenantNames(); !reflect.DeepEqual(got, []string{"gophers", "rustaceans"}) {
+		t.Fatalf("TenantNames() = %v", got)
+	}
+	gophers, err := cfg.Tenant("gophers")
+	if err != nil {
+		t.Fatalf("Tenant(gophers) failed: %v", err)
+	}
+	rustaceans, err := cfg.Tenant("rustaceans")
+	if err != nil {
+		t.Fatalf("Tenant(rustaceans) failed: %v", err)
+	}
+	checks := []struct {
+		name      string
+		got, want interface{}
+	}{
+		{"tenant value", gophers.GoogleSpreadsheetID, "gophers-sheet"},
+		{"tenant overrides env", gophers.SlackWorkspace, "Gophers"},
+		{"inherited env", rustaceans.SlackWorkspace, "Default"},
+		{"inherited file value", gophers.GoogleSheetName, "Invites"},
+		{"tenant overrides file", rustaceans.GoogleSheetName, "Requests"},
+		{"expanded path", rustaceans.AuditLogFile, "/var/lib/invites/rustaceans/audit.jsonl"},
+		{"inherited secret", gophers.AdminToken, Secret("shared-token")},
+		{"file secret reference", rustaceans.AdminToken, Secret("rust-token")},
+		{"hosts", gophers.Hosts, []string{"invites.gophers.example"}},
+		{"reviewers", gophers.Reviewers, []string{"jane@example.com"}},
+		{"no reviewers", len(rustaceans.Reviewers), 0},
+		{"default", rustaceans.Port, DefaultPort},
+	}
+	for _, c := range checks {
+		if !reflect.DeepEqual(c.got, c.want) {
+			t.Errorf("%s: got %v, want %v", c.name, c.got, c.want)
+		}
+	}
+
+	if _, err := cfg.Tenant(""); err == nil {
+		t.Error("Tenant(\"\") succeeded with two tenants, want an error")
+	}
+	if _, err := cfg.Tenant("crabs"); err == nil {
+		t.Error("Tenant(crabs) succeeded, want an error")
+	}
+	single, err := LoadFile("", envMap(map[string]string{"GOOGLE_SPREADSHEET_ID": "id", "GOOGLE_SHEET_NAME": "Sheet1"}))
+	if err != nil {
+		t.Fatalf("LoadFile() failed: %v", err)
+	}
+	if tenant, err := single.Tenant(""); err != nil || tenant != single {
+		t.Errorf("Tenant(\"\") without tenants = %v, %v, want the config itself", tenant, err)
+	}
+}
+
+func TestLoadFileTenantProblems(t *testing.T) {
+	path := writeFile(t, "config.yaml", `
+google_sheet_name: Invites
+email_outbox_file: /var/lib/invites/outbox.jsonl
+tenants:
+  gophers:
+    google_spreadsheet_id: gophers-sheet
+    hosts: [invites.example]
+    reviewer_emails: [jane@example.com]
+  rustaceans:
+    google_spreadsheet_id: rust-sheet
+    hosts: [Invites.example]
+  Bad_Name:
+    google_spreadsheet_id: bad-sheet
+`)
+	_, err := LoadFile(path, envMap(nil))
+	if err == nil {
+		t.Fatal("LoadFile() succeeded, want an error")
+	}
+	for _, want := range []string{
+		"tenant Bad_Name: name must be",
+		"tenant gophers: REVIEWER_EMAIL_HEADER is required",
+		"tenant gophers: TRUST_PROXY_HEADERS must be true",
+		`tenants gophers and rustaceans both use host "invites.example"`,
+		"tenants gophers and rustaceans share EMAIL_OUTBOX_FILE",
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error does not report %q:\n%v", want, err)
+		}
+	}
+}
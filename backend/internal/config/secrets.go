@@ -0,0 +1,273 @@
+package config
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// redacted replaces secret values wherever they are printed
+const redacted = "[redacted]"
+
+// Secret is a credential such as a password or token. It prints, logs and
+// marshals as "[redacted]" so it cannot leak by accident; Reveal returns the
+// value itself.
+type Secret string
+
+// Reveal returns the secret value
+func (s Secret) Reveal() string { return string(s) }
+
+// String redacts the value, leaving an empty secret empty
+func (s Secret) String() string {
+	if s == "" {
+		return ""
+	}
+	return redacted
+}
+
+// GoString redacts the value in %#v
+func (s Secret) GoString() string { return fmt.Sprintf("%q", s.String()) }
+
+// LogValue redacts the value in slog output
+func (s Secret) LogValue() slog.Value { return slog.StringValue(s.String()) }
+
+// MarshalJSON redacts the value in JSON
+func (s Secret) MarshalJSON() ([]byte, error) { return json.Marshal(s.String()) }
+
+// SecretProvider resolves references to secrets held elsewhere, such as
+// HashiCorp Vault or a cloud secret manager. A variable holding
+// "scheme://ref" is resolved by the provider registered for scheme, which is
+// passed ref.
+type SecretProvider interface {
+	GetSecret(ctx context.Context, ref string) (string, error)
+}
+
+var (
+	providersMu     sync.Mutex
+	secretProviders = map[string]SecretProvider{}
+)
+
+// RegisterSecretProvider makes a provider available to every Secrets created
+// afterwards, for references with the given scheme
+func RegisterSecretProvider(scheme string, provider SecretProvider) {
+	providersMu.Lock()
+	defer providersMu.Unlock()
+	secretProviders[scheme] = provider
+}
+
+// secretReference matches values that refer to a secret provider
+var secretReference = regexp.MustCompile(`^([a-z][a-z0-9+.-]*)://(.+)$`)
+
+// Secrets resolves named secrets. For a name such as SMTP2GO_PASSWORD it
+// tries, in order:
+//
+//   - SMTP2GO_PASSWORD_FILE, the path of a file holding the value, as
+//     mounted by Docker and Kubernetes secrets
+//   - SMTP2GO_PASSWORD, holding the value or a "scheme://ref" reference to a
+//     registered SecretProvider, such as "vault://secret/data/app#smtp"
+//   - the entry of that name in the encrypted SECRETS_FILE
+//
+// Values are never logged and errors name the secret, not its value.
+type Secrets struct {
+	getenv    func(string) string
+	providers map[string]SecretProvider
+	sealed    map[string]string
+}
+
+// NewSecrets creates Secrets reading variables with getenv, such as
+// os.Getenv. It decrypts SECRETS_FILE with the key in SECRETS_KEY, and adds
+// a Vault provider for "vault://" references when VAULT_ADDR is set.
+// "file:///run/secrets/name" references read a file, as NAME_FILE does.
+func NewSecrets(getenv func(string) string) (*Secrets, error) {
+	s := &Secrets{getenv: getenv, providers: map[string]SecretProvider{"file": fileProvider{}}}
+	providersMu.Lock()
+	for scheme, provider := range secretProviders {
+		s.providers[scheme] = provider
+	}
+	providersMu.Unlock()
+
+	if addr := getenv("VAULT_ADDR"); addr != "" {
+		token, err := s.fromEnv("VAULT_TOKEN")
+		if err != nil {
+			return nil, err
+		}
+		s.providers["vault"] = &VaultProvider{Address: addr, Token: token}
+	}
+
+	if path := getenv("SECRETS_FILE"); path != "" {
+		key, err := s.fromEnv("SECRETS_KEY")
+		if err != nil {
+			return nil, err
+		}
+		if key == "" {
+			return nil, errors.New("SECRETS_KEY must be set to decrypt SECRETS_FILE")
+		}
+		if s.sealed, err = OpenSecretsFile(path, key); err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+// Register adds a provider for references with the given scheme
+func (s *Secrets) Register(scheme string, provider SecretProvider) {
+	s.providers[scheme] = provider
+}
+
+// Lookup resolves the named secret, returning "" if it is not set anywhere
+func (s *Secrets) Lookup(ctx context.Context, name string) (Secret, error) {
+	value, err := s.fromEnv(name)
+	if err != nil || value != "" {
+		return value, err
+	}
+	if value, err = s.Resolve(ctx, s.getenv(name)); err != nil {
+		return "", fmt.Errorf("%s: %w", name, err)
+	}
+	if value != "" {
+		return value, nil
+	}
+	return Secret(s.sealed[name]), nil
+}
+
+// Resolve returns value itself, or the secret it refers to when it is a
+// "scheme://ref" reference to a provider
+func (s *Secrets) Resolve(ctx context.Context, value string) (Secret, error) {
+	match := secretReference.FindStringSubmatch(value)
+	if match == nil {
+		return Secret(value), nil
+	}
+	provider, ok := s.providers[match[1]]
+	if !ok {
+		return "", fmt.Errorf("no secret provider for %s:// references", match[1])
+	}
+	resolved, err := provider.GetSecret(ctx, match[2])
+	if err != nil {
+		return "", err
+	}
+	return Secret(resolved), nil
+}
+
+// Names lists the secrets held in the encrypted secrets file
+func (s *Secrets) Names() []string {
+	names := make([]string, 0, len(s.sealed))
+	for name := range s.sealed {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// fromEnv reads a secret from NAME_FILE or from NAME itself, leaving
+// provider references in NAME unresolved as ""
+func (s *Secrets) fromEnv(name string) (Secret, error) {
+	value, path := s.getenv(name), s.getenv(name+"_FILE")
+	if path != "" {
+		if value != "" {
+			return "", fmt.Errorf("%s and %s_FILE are both set", name, name)
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return "", fmt.Errorf("failed to read %s_FILE: %w", name, err)
+		}
+		// Secret files usually end with a newline that is not part of the value
+		return Secret(strings.TrimRight(string(data), "\r\n")), nil
+	}
+	if secretReference.MatchString(value) {
+		return "", nil
+	}
+	return Secret(value), nil
+}
+
+// fileProvider resolves "file://" references to the contents of the file
+type fileProvider struct{}
+
+func (fileProvider) GetSecret(ctx context.Context, path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(string(data), "\r\n"), nil
+}
+
+// EnvSecret reads a secret from NAME_FILE or NAME only, for secrets such as
+// SECRETS_KEY that cannot come from the encrypted file or a provider
+func EnvSecret(name string) (Secret, error) {
+	return (&Secrets{getenv: os.Getenv}).fromEnv(name)
+}
+
+// GenerateSecretsKey returns a new random key for SECRETS_KEY
+func GenerateSecretsKey() (string, error) {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(key), nil
+}
+
+// SealSecrets encrypts secret values by name with AES-256-GCM, for saving
+// as SECRETS_FILE. The key is base64, as from GenerateSecretsKey.
+func SealSecrets(values map[string]string, key Secret) ([]byte, error) {
+	aead, err := secretsCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	plaintext, err := json.Marshal(values)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	sealed := aead.Seal(nonce, nonce, plaintext, nil)
+	return []byte(base64.StdEncoding.EncodeToString(sealed) + "\n"), nil
+}
+
+// OpenSecretsFile decrypts a file written from SealSecrets
+func OpenSecretsFile(path string, key Secret) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read secrets file: %w", err)
+	}
+	aead, err := secretsCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	sealed, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(data)))
+	if err != nil || len(sealed) < aead.NonceSize() {
+		return nil, errors.New("secrets file is not a sealed secrets file")
+	}
+	plaintext, err := aead.Open(nil, sealed[:aead.NonceSize()], sealed[aead.NonceSize():], nil)
+	if err != nil {
+		return nil, errors.New("failed to decrypt secrets file: wrong key or corrupted file")
+	}
+	var values map[string]string
+	if err := json.Unmarshal(plaintext, &values); err != nil {
+		return nil, errors.New("failed to parse decrypted secrets file")
+	}
+	return values, nil
+}
+
+// secretsCipher returns the AES-256-GCM cipher for a base64 key
+func secretsCipher(key Secret) (cipher.AEAD, error) {
+	raw, err := base64.StdEncoding.DecodeString(key.Reveal())
+	if err != nil || len(raw) != 32 {
+		return nil, errors.New("SECRETS_KEY must be 32 bytes, base64 encoded")
+	}
+	block, err := aes.NewCipher(raw)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
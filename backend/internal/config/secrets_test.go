@@ -0,0 +1,222 @@
+package config
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// envMap stands in for os.Getenv
+func envMap(vars map[string]string) func(string) string {
+	return func(name string) string { return vars[name] }
+}
+
+// stubProvider resolves references from a map
+type stubProvider map[string]string
+
+func (p stubProvider) GetSecret(ctx context.Context, ref string) (string, error) {
+	value, ok := p[ref]
+	if !ok {
+		return "", fmt.Errorf("no secret at %s", ref)
+	}
+	return value, nil
+}
+
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestSecretsLookup(t *testing.T) {
+	key, err := GenerateSecretsKey()
+	if err != nil {
+		t.Fatalf("GenerateSecretsKey() failed: %v", err)
+	}
+	sealed, err := SealSecrets(map[string]string{"SEALED": "from-sealed", "PLAIN": "shadowed"}, Secret(key))
+	if err != nil {
+		t.Fatalf("SealSecrets() failed: %v", err)
+	}
+	sealedPath := writeFile(t, "secrets.enc", string(sealed))
+
+	tests := []struct {
+		name    string
+		env     map[string]string
+		lookup  string
+		want    Secret
+		wantErr string
+	}{
+		{name: "plain variable", env: map[string]string{"PLAIN": "value"}, lookup: "PLAIN", want: "value"},
+		{name: "file variable", env: map[string]string{"PLAIN_FILE": writeFile(t, "plain", "from-file\n")}, lookup: "PLAIN", want: "from-file"},
+		{name: "both set", env: map[string]string{"PLAIN": "value", "PLAIN_FILE": "/run/secrets/plain"}, lookup: "PLAIN", wantErr: "both set"},
+		{name: "missing file", env: map[string]string{"PLAIN_FILE": "/does/not/exist"}, lookup: "PLAIN", wantErr: "PLAIN_FILE"},
+		{name: "provider reference", env: map[string]string{"PLAIN": "stub://app/smtp"}, lookup: "PLAIN", want: "from-provider"},
+		{name: "provider error", env: map[string]string{"PLAIN": "stub://app/missing"}, lookup: "PLAIN", wantErr: "no secret at app/missing"},
+		{name: "unknown provider", env: map[string]string{"PLAIN": "gcpsm://projects/p/secrets/s"}, lookup: "PLAIN", wantErr: "no secret provider for gcpsm"},
+		{name: "sealed file", env: map[string]string{"SECRETS_FILE": sealedPath, "SECRETS_KEY": key}, lookup: "SEALED", want: "from-sealed"},
+		{name: "variable before sealed file", env: map[string]string{"SECRETS_FILE": sealedPath, "SECRETS_KEY": key, "PLAIN": "value"}, lookup: "PLAIN", want: "value"},
+		{name: "key from file", env: map[string]string{"SECRETS_FILE": sealedPath, "SECRETS_KEY_FILE": writeFile(t, "key", key+"\n")}, lookup: "SEALED", want: "from-sealed"},
+		{name: "unset", env: map[string]string{}, lookup: "PLAIN", want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			secrets, err := NewSecrets(envMap(tt.env))
+			if err != nil {
+				t.Fatalf("NewSecrets() failed: %v", err)
+			}
+			secrets.Register("stub", stubProvider{"app/smtp": "from-provider"})
+			got, err := secrets.Lookup(context.Background(), tt.lookup)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Lookup() error = %v, want it to contain %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Lookup() failed: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Lookup() = %q, want %q", got.Reveal(), tt.want.Reveal())
+			}
+		})
+	}
+}
+
+func TestSealedSecretsFile(t *testing.T) {
+	key, _ := GenerateSecretsKey()
+	other, _ := GenerateSecretsKey()
+	sealed, err := SealSecrets(map[string]string{"B": "2", "A": "1"}, Secret(key))
+	if err != nil {
+		t.Fatalf("SealSecrets() failed: %v", err)
+	}
+	if bytes.Contains(sealed, []byte(`"A"`)) {
+		t.Error("sealed file contains plaintext")
+	}
+	path := writeFile(t, "secrets.enc", string(sealed))
+
+	secrets, err := NewSecrets(envMap(map[string]string{"SECRETS_FILE": path, "SECRETS_KEY": key}))
+	if err != nil {
+		t.Fatalf("NewSecrets() failed: %v", err)
+	}
+	if names := secrets.Names(); strings.Join(names, ",") != "A,B" {
+		t.Errorf("Names() = %v, want [A B]", names)
+	}
+
+	for name, env := range map[string]map[string]string{
+		"wrong key":   {"SECRETS_FILE": path, "SECRETS_KEY": other},
+		"invalid key": {"SECRETS_FILE": path, "SECRETS_KEY": "short"},
+		"missing key": {"SECRETS_FILE": path},
+		"not sealed":  {"SECRETS_FILE": writeFile(t, "plain.json", `{"A":"1"}`), "SECRETS_KEY": key},
+	} {
+		if _, err := NewSecrets(envMap(env)); err == nil {
+			t.Errorf("%s: NewSecrets() succeeded, want an error", name)
+		}
+	}
+}
+
+func TestVaultProvider(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Vault-Token") != "vault-token" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		switch r.URL.Path {
+		case "/v1/secret/data/app":
+			fmt.Fprint(w, `{"data": {"data": {"smtp": "kv2-value"}, "metadata": {"version": 3}}}`)
+		case "/v1/kv/app":
+			fmt.Fprint(w, `{"data": {"smtp": "kv1-value"}}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	secrets, err := NewSecrets(envMap(map[string]string{
+		"VAULT_ADDR":  server.URL,
+		"VAULT_TOKEN": "vault-token",
+		"KV2":         "vault://secret/data/app#smtp",
+		"KV1":         "vault://kv/app#smtp",
+		"NO_KEY":      "vault://kv/app#other",
+		"NOT_FOUND":   "vault://kv/missing#smtp",
+		"BAD_REF":     "vault://kv/app",
+	}))
+	if err != nil {
+		t.Fatalf("NewSecrets() failed: %v", err)
+	}
+	for name, want := range map[string]Secret{"KV2": "kv2-value", "KV1": "kv1-value"} {
+		got, err := secrets.Lookup(context.Background(), name)
+		if err != nil || got != want {
+			t.Errorf("Lookup(%s) = %q, %v, want %q", name, got.Reveal(), err, want.Reveal())
+		}
+	}
+	for _, name := range []string{"NO_KEY", "NOT_FOUND", "BAD_REF"} {
+		if _, err := secrets.Lookup(context.Background(), name); err == nil {
+			t.Errorf("Lookup(%s) succeeded, want an error", name)
+		}
+	}
+}
+
+func TestSecretIsRedacted(t *testing.T) {
+	secret := Secret("hunter2")
+	var logged bytes.Buffer
+	slog.New(slog.NewJSONHandler(&logged, nil)).Info("loaded", slog.Any("password", secret))
+	marshalled, _ := json.Marshal(struct{ Password Secret }{secret})
+
+	for name, out := range map[string]string{
+		"%s":   fmt.Sprintf("%s", secret),
+		"%v":   fmt.Sprintf("%v", secret),
+		"%#v":  fmt.Sprintf("%#v", secret),
+		"slog": logged.String(),
+		"json": string(marshalled),
+	} {
+		if strings.Contains(out, "hunter2") || !strings.Contains(out, redacted) {
+			t.Errorf("%s output = %q, want the value redacted", name, out)
+		}
+	}
+	if secret.Reveal() != "hunter2" {
+		t.Errorf("Reveal() = %q, want hunter2", secret.Reveal())
+	}
+	if Secret("").String() != "" {
+		t.Error("an empty secret should print as empty")
+	}
+}
+
+func TestWebhookSecretReferences(t *testing.T) {
+	secrets, err := NewSecrets(envMap(nil))
+	if err != nil {
+		t.Fatalf("NewSecrets() failed: %v", err)
+	}
+	secrets.Register("stub", stubProvider{"hooks/crm": "from-provider"})
+	secretFile := writeFile(t, "billing-secret", "from-file\n")
+	path := writeFile(t, "webhooks.json", fmt.Sprintf(`[
+		{"name": "crm", "url": "https://crm.example/hook", "secret": "stub://hooks/crm"},
+		{"name": "billing", "url": "https://billing.example/hook", "secret": "file://%s"},
+		{"name": "chat", "url": "https://chat.example/hook", "secret": "plain"}
+	]`, secretFile))
+
+	subscriptions, err := LoadWebhookSubscriptions(context.Background(), path, secrets)
+	if err != nil {
+		t.Fatalf("LoadWebhookSubscriptions() failed: %v", err)
+	}
+	for i, want := range []string{"from-provider", "from-file", "plain"} {
+		if got := subscriptions[i].Secret.Reveal(); got != want {
+			t.Errorf("webhook %s secret = %q, want %q", subscriptions[i].Name, got, want)
+		}
+	}
+
+	missing := writeFile(t, "missing.json", `[{"name": "crm", "url": "https://crm.example/hook", "secret": "stub://hooks/missing"}]`)
+	if _, err := LoadWebhookSubscriptions(context.Background(), missing, secrets); err == nil || !strings.Contains(err.Error(), `webhook "crm": secret`) {
+		t.Errorf("LoadWebhookSubscriptions() error = %v, want the webhook named", err)
+	}
+}
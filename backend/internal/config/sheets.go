@@ -2,56 +2,109 @@ package config
 
 import (
 	"context"
-	"os"
+	"strings"
+	"time"
 
-	"golang.org/x/oauth2/google"
+	"google.golang.org/api/option"
 	"google.golang.org/api/sheets/v4"
 )
 
+// SMTPConfig holds the SMTP2Go account email is sent through
+type SMTPConfig struct {
+	FromEmail string
+	Username  string
+	Password  Secret
+}
+
 // SheetsConfig holds Google Sheets specific configuration
 type SheetsConfig struct {
 	CredentialsFile string
-	TokenFile       string
-	SpreadsheetID   string
-	SheetName       string
-	EmailRecipient  string
-	EmailTemplate   string
+	// TokenFile is where the OAuth user token is kept in AuthModeOAuth
+	TokenFile string
+	// AuthMode is how requests are authorized, one of the AuthMode
+	// constants; empty detects it from the credentials file
+	AuthMode      string
+	SpreadsheetID string
+	SheetName     string
+	// Endpoint overrides the Sheets API base URL, such as a local fake
+	// server; without a credentials file, requests are sent unauthenticated
+	Endpoint string
+	// ArchiveSheetName is the tab archived invites are moved to, or the
+	// prefix of per-year tabs such as "Archive 2024"
+	ArchiveSheetName string
+	// SlackWorkspace is the Slack workspace invites are for, shown in the
+	// subject of notification emails
+	SlackWorkspace string
+	// SMTP is the account email is sent through
+	SMTP SMTPConfig
+	// DashboardURL fills the {{DASHBOARD_URL}} placeholder of EmailTemplate
+	DashboardURL string
+	// EmailRecipient is a comma-separated list of addresses that receive
+	// notifications not routed by EmailRoutingFile
+	EmailRecipient   string
+	EmailRoutingFile string
+	EmailTemplate    string
+	// Webhooks and WebhookDeliveryLog configure webhooks for the dedupe run
+	Webhooks           []WebhookSubscription
+	WebhookDeliveryLog string
+	// EmailOutboxFile is where the sheets tool keeps queued email; empty
+	// keeps it in memory
+	EmailOutboxFile   string
+	EmailDedupeWindow time.Duration
+	// StaleThresholds are the ages in days, ascending, at which pending
+	// invites trigger reminders; the last one escalates. Empty disables them.
+	StaleThresholds []int
+	// ReminderLogFile records reminders already sent so none repeat
+	ReminderLogFile string
+	// Location is the time zone of the timestamps in columns A and K
+	Location *time.Location
+	// RetentionDays is how long after a decision personal data is kept
+	// before "sheets retention" redacts it; 0 disables redaction
+	RetentionDays int
+	// AuditLogFile is the server's audit trail, and ServerOutboxFile its
+	// outbox, which retention also redacts
+	AuditLogFile     string
+	ServerOutboxFile string
+	// SheetAdmins may edit the protected status columns J and K alongside the
+	// service account
+	SheetAdmins []string
 }
 
-// LoadSheetsConfig loads Google Sheets configuration from environment variables
-func LoadSheetsConfig() *SheetsConfig {
-	return &SheetsConfig{
-		CredentialsFile: os.Getenv("GOOGLE_CREDENTIALS_FILE"),
-		TokenFile:       os.Getenv("GOOGLE_TOKEN_FILE"),
-		SpreadsheetID:   os.Getenv("GOOGLE_SPREADSHEET_ID"),
-		SheetName:       os.Getenv("GOOGLE_SHEET_NAME"),
-		EmailRecipient:  os.Getenv("EMAIL_RECIPIENT"),
-		EmailTemplate:   os.Getenv("EMAIL_TEMPLATE_PATH"),
+// LoadSheetsConfig loads the configuration with Load and returns the Google
+// Sheets settings of the named tenant, which may be empty as for
+// Config.Tenant
+func LoadSheetsConfig(tenant string) (*SheetsConfig, error) {
+	cfg, err := Load()
+	if err != nil {
+		return nil, err
+	}
+	tenantCfg, err := cfg.Tenant(tenant)
+	if err != nil {
+		return nil, err
 	}
+	return tenantCfg.SheetsConfig(), nil
 }
 
-// GetSheetsService creates a new Google Sheets service client
+// GetSheetsService creates a new Google Sheets service client, authorized
+// as described by cfg.AuthMode
 func GetSheetsService(ctx context.Context, cfg *SheetsConfig) (*sheets.Service, error) {
-	// Read credentials file
-	credentials, err := os.ReadFile(cfg.CredentialsFile)
-	if err != nil {
-		return nil, err
+	var opts []option.ClientOption
+	if cfg.Endpoint != "" {
+		// The client resolves API paths against the endpoint, which needs a
+		// trailing slash to keep any path prefix
+		opts = append(opts, option.WithEndpoint(strings.TrimSuffix(cfg.Endpoint, "/")+"/"))
 	}
 
-	// Parse credentials
-	config, err := google.JWTConfigFromJSON(credentials, sheets.SpreadsheetsScope)
+	mode, credentials, err := resolveAuthMode(cfg)
 	if err != nil {
 		return nil, err
 	}
-
-	// Create client
-	client := config.Client(ctx)
-
-	// Create service
-	service, err := sheets.New(client)
+	if mode == "" {
+		return sheets.NewService(ctx, append(opts, option.WithoutAuthentication())...)
+	}
+	client, err := googleHTTPClient(ctx, cfg, mode, credentials)
 	if err != nil {
 		return nil, err
 	}
-
-	return service, nil
+	return sheets.NewService(ctx, append(opts, option.WithHTTPClient(client))...)
 }
@@ -0,0 +1,77 @@
+package config
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// VaultProvider reads secrets from HashiCorp Vault's KV secrets engine over
+// its HTTP API. References are "path#key", such as
+// "secret/data/slack-invite-mgr#smtp_password" for KV version 2 or
+// "secret/slack-invite-mgr#smtp_password" for version 1.
+type VaultProvider struct {
+	Address string
+	Token   Secret
+	// Client sends the requests; nil uses a client with a 10 second timeout
+	Client *http.Client
+}
+
+// GetSecret reads the key from the secret at the referenced path
+func (p *VaultProvider) GetSecret(ctx context.Context, ref string) (string, error) {
+	path, key, ok := strings.Cut(ref, "#")
+	if !ok || path == "" || key == "" {
+		return "", fmt.Errorf("vault reference %q must be path#key", ref)
+	}
+	endpoint, err := url.JoinPath(p.Address, "v1", path)
+	if err != nil {
+		return "", fmt.Errorf("invalid VAULT_ADDR: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("X-Vault-Token", p.Token.Reveal())
+
+	client := p.Client
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s from vault: %w", path, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("failed to read %s from vault: status %d", path, resp.StatusCode)
+	}
+
+	// KV version 2 nests the values in data.data; version 1 has them in data
+	var body struct {
+		Data map[string]json.RawMessage `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return "", fmt.Errorf("failed to parse vault response for %s: %w", path, err)
+	}
+	values := body.Data
+	if nested, ok := body.Data["data"]; ok {
+		values = nil
+		if err := json.Unmarshal(nested, &values); err != nil {
+			return "", fmt.Errorf("failed to parse vault response for %s: %w", path, err)
+		}
+	}
+	raw, ok := values[key]
+	if !ok {
+		return "", fmt.Errorf("vault secret %s has no key %q", path, key)
+	}
+	var value string
+	if err := json.Unmarshal(raw, &value); err != nil {
+		return "", errors.New("vault secret " + path + "#" + key + " is not a string")
+	}
+	return value, nil
+}
@@ -0,0 +1,88 @@
+package config
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+)
+
+// WebhookEvents lists the event names a webhook subscription can filter on
+var WebhookEvents = []string{
+	"invite.added",
+	"invite.sent",
+	"invite.denied",
+	"invite.duplicate",
+	"invite.needs_info",
+	"invite.reopened",
+}
+
+// WebhookSubscription describes an endpoint that receives invite events
+type WebhookSubscription struct {
+	Name string `json:"name"`
+	URL  string `json:"url"`
+	// Events filters the events delivered; "*" or an empty list means all
+	Events []string `json:"events"`
+	// Secret signs each delivery with HMAC-SHA256. In the file it may be a
+	// reference resolved by Secrets, such as vault://path#key.
+	Secret Secret `json:"secret"`
+}
+
+// LoadWebhookSubscriptions reads webhook subscriptions from a JSON file
+// containing an array of subscriptions, resolving their secrets with
+// secrets. An empty path means no webhooks.
+func LoadWebhookSubscriptions(ctx context.Context, path string, secrets *Secrets) ([]WebhookSubscription, error) {
+	if path == "" {
+		return nil, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read webhooks file: %w", err)
+	}
+
+	var subscriptions []WebhookSubscription
+	if err := json.Unmarshal(data, &subscriptions); err != nil {
+		return nil, fmt.Errorf("failed to parse webhooks file: %w", err)
+	}
+
+	names := make(map[string]bool)
+	for i := range subscriptions {
+		sub := &subscriptions[i]
+		if sub.Name == "" {
+			return nil, fmt.Errorf("webhook %d: name is required", i)
+		}
+		if names[sub.Name] {
+			return nil, fmt.Errorf("webhook %q: name is used more than once", sub.Name)
+		}
+		names[sub.Name] = true
+
+		u, err := url.Parse(sub.URL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return nil, fmt.Errorf("webhook %q: url must be an absolute http(s) URL", sub.Name)
+		}
+		if sub.Secret == "" {
+			return nil, fmt.Errorf("webhook %q: secret is required", sub.Name)
+		}
+		if sub.Secret, err = secrets.Resolve(ctx, sub.Secret.Reveal()); err != nil {
+			return nil, fmt.Errorf("webhook %q: secret: %w", sub.Name, err)
+		}
+		for _, event := range sub.Events {
+			if event != "*" && !isWebhookEvent(event) {
+				return nil, fmt.Errorf("webhook %q: unknown event %q", sub.Name, event)
+			}
+		}
+	}
+
+	return subscriptions, nil
+}
+
+func isWebhookEvent(event string) bool {
+	for _, known := range WebhookEvents {
+		if event == known {
+			return true
+		}
+	}
+	return false
+}
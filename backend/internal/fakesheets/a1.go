@@ -0,0 +1,148 @@
+package fakesheets
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// cellRange is a parsed A1 range. Rows and columns are 0-based and the ends
+// are exclusive; an end of -1 leaves that side open.
+type cellRange struct {
+	sheet    string
+	hasSheet bool
+	startRow int
+	endRow   int
+	startCol int
+	endCol   int
+}
+
+// parseA1 parses ranges such as "Sheet1", "'My Sheet'!A2:K", "A:K" or
+// "Sheet1!B3"
+func parseA1(a1 string) (cellRange, error) {
+	r := cellRange{endRow: -1, endCol: -1}
+	rest := a1
+	switch {
+	case strings.HasPrefix(a1, "'"):
+		end := 1
+		var name strings.Builder
+		for ; end < len(a1); end++ {
+			if a1[end] != '\'' {
+				name.WriteByte(a1[end])
+				continue
+			}
+			if end+1 < len(a1) && a1[end+1] == '\'' {
+				name.WriteByte('\'')
+				end++
+				continue
+			}
+			break
+		}
+		if end >= len(a1) {
+			return r, fmt.Errorf("unterminated sheet name in %q", a1)
+		}
+		r.sheet, r.hasSheet = name.String(), true
+		rest = a1[end+1:]
+		if rest == "" {
+			return r, nil
+		}
+		if rest[0] != '!' {
+			return r, fmt.Errorf("expected ! after sheet name in %q", a1)
+		}
+		rest = rest[1:]
+	case strings.Contains(a1, "!"):
+		i := strings.Index(a1, "!")
+		r.sheet, r.hasSheet, rest = a1[:i], true, a1[i+1:]
+	default:
+		// A bare name is a whole sheet unless it looks like cells, and a
+		// single reference only looks like cells with a row, so "Foo" is a
+		// sheet but "B3" is not; the server checks for a sheet with the exact
+		// name first
+		start, _, isRange := strings.Cut(a1, ":")
+		if row, _, err := parseCell(start); err != nil || !isRange && row < 0 {
+			r.sheet, r.hasSheet = a1, true
+			return r, nil
+		}
+	}
+
+	start, end, isRange := strings.Cut(rest, ":")
+	row, col, err := parseCell(start)
+	if err != nil {
+		return r, fmt.Errorf("invalid range %q: %w", a1, err)
+	}
+	if row >= 0 {
+		r.startRow = row
+	}
+	if col >= 0 {
+		r.startCol = col
+	}
+	if !isRange {
+		// A single cell, row or column
+		if row >= 0 {
+			r.endRow = row + 1
+		}
+		if col >= 0 {
+			r.endCol = col + 1
+		}
+		return r, nil
+	}
+	row, col, err = parseCell(end)
+	if err != nil {
+		return r, fmt.Errorf("invalid range %q: %w", a1, err)
+	}
+	if row >= 0 {
+		r.endRow = row + 1
+	}
+	if col >= 0 {
+		r.endCol = col + 1
+	}
+	return r, nil
+}
+
+// parseCell parses "B3", "B" or "3" into 0-based row and column indexes,
+// returning -1 for a part that is missing. Column letters must be uppercase.
+func parseCell(ref string) (int, int, error) {
+	i := 0
+	col := 0
+	for i < len(ref) && ref[i] >= 'A' && ref[i] <= 'Z' {
+		col = col*26 + int(ref[i]-'A') + 1
+		i++
+	}
+	letters := i
+	row := -1
+	if i < len(ref) {
+		n, err := strconv.Atoi(ref[i:])
+		if err != nil || n < 1 {
+			return 0, 0, fmt.Errorf("invalid cell %q", ref)
+		}
+		row = n - 1
+	}
+	if letters == 0 && row < 0 {
+		return 0, 0, fmt.Errorf("invalid cell %q", ref)
+	}
+	return row, col - 1, nil
+}
+
+// columnName returns the letters of a 0-based column index, such as "AA"
+func columnName(col int) string {
+	name := ""
+	for col++; col > 0; col = (col - 1) / 26 {
+		name = string(rune('A'+(col-1)%26)) + name
+	}
+	return name
+}
+
+// formatA1 formats a sheet name and bounded 0-based range as A1 notation
+func formatA1(sheet string, startRow, endRow, startCol, endCol int) string {
+	return fmt.Sprintf("%s!%s%d:%s%d", quoteSheetName(sheet), columnName(startCol), startRow+1, columnName(endCol-1), endRow)
+}
+
+// quoteSheetName quotes a sheet name when A1 notation requires it
+func quoteSheetName(name string) string {
+	for _, r := range name {
+		if !(r >= 'A' && r <= 'Z' || r >= 'a' && r <= 'z' || r >= '0' && r <= '9' || r == '_') {
+			return "'" + strings.ReplaceAll(name, "'", "''") + "'"
+		}
+	}
+	return name
+}
@@ -0,0 +1,971 @@
+// Package fakesheets is an in-memory fake of the Google Sheets v4 REST API.
+// It serves the calls this project makes, so the real client code can be run
+// end to end in tests and local development without a Google account.
+package fakesheets
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+
+	"google.golang.org/api/sheets/v4"
+)
+
+// Default grid size of a new sheet, matching Google Sheets
+const (
+	DefaultRowCount    = 1000
+	DefaultColumnCount = 26
+)
+
+// Operation names counted by Calls
+const (
+	OpSpreadsheetsGet         = "spreadsheets.get"
+	OpSpreadsheetsBatchUpdate = "spreadsheets.batchUpdate"
+	OpValuesGet               = "values.get"
+	OpValuesAppend            = "values.append"
+	OpValuesBatchUpdate       = "values.batchUpdate"
+)
+
+// formula is a cell entered as a formula. Formulas are stored, not evaluated.
+type formula string
+
+// sheet is one tab of the spreadsheet. Formatting is kept as requested and
+// only reported back, never applied to values.
+type sheet struct {
+	id                 int64
+	title              string
+	rowCount           int
+	columnCount        int
+	frozenRowCount     int
+	cells              [][]interface{}
+	conditionalFormats []*sheets.ConditionalFormatRule
+	protectedRanges    []*sheets.ProtectedRange
+	validations        []*sheets.SetDataValidationRequest
+}
+
+// Server is a fake spreadsheet served over HTTP. It is safe for concurrent use.
+type Server struct {
+	mu            sync.Mutex
+	spreadsheetID string
+	sheets        []*sheet
+	nextSheetID   int64
+	nextRangeID   int64
+	calls         map[string]int
+}
+
+// New returns a fake serving a single empty spreadsheet with the given ID
+func New(spreadsheetID string) *Server {
+	return &Server{spreadsheetID: spreadsheetID, calls: make(map[string]int)}
+}
+
+// SpreadsheetID returns the ID of the fake spreadsheet
+func (s *Server) SpreadsheetID() string {
+	return s.spreadsheetID
+}
+
+// AddSheet adds a tab holding rows, growing the grid if the rows do not fit,
+// and returns its sheet ID
+func (s *Server) AddSheet(title string, rows [][]interface{}) int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sh := s.newSheet(title)
+	for r, row := range rows {
+		for c, value := range row {
+			sh.set(r, c, value)
+		}
+	}
+	sh.rowCount = max(sh.rowCount, len(rows))
+	for _, row := range rows {
+		sh.columnCount = max(sh.columnCount, len(row))
+	}
+	return sh.id
+}
+
+// Rows returns the formatted values of a tab, as values.get would, or nil if
+// there is no such tab
+func (s *Server) Rows(title string) [][]interface{} {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sh := s.sheetByTitle(title)
+	if sh == nil {
+		return nil
+	}
+	return sh.values(0, len(sh.cells), 0, sh.columnCount, renderFormatted)
+}
+
+// UnformattedRows returns the values of a tab with numbers and booleans
+// typed, as values.get with UNFORMATTED_VALUE would, or nil if there is no
+// such tab
+func (s *Server) UnformattedRows(title string) [][]interface{} {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sh := s.sheetByTitle(title)
+	if sh == nil {
+		return nil
+	}
+	return sh.values(0, len(sh.cells), 0, sh.columnCount, renderUnformatted)
+}
+
+// Sheet returns a tab as spreadsheets.get would describe it, with its
+// conditional formats and protected ranges, or nil if there is no such tab
+func (s *Server) Sheet(title string) *sheets.Sheet {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i, sh := range s.sheets {
+		if strings.EqualFold(sh.title, title) {
+			return sh.describe(i)
+		}
+	}
+	return nil
+}
+
+// DataValidation returns the validation rule of a cell, given as 0-based
+// indexes, or nil if it has none
+func (s *Server) DataValidation(title string, row, col int) *sheets.DataValidationRule {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sh := s.sheetByTitle(title)
+	if sh == nil {
+		return nil
+	}
+	// Later rules replace earlier ones over the cells they cover
+	var rule *sheets.DataValidationRule
+	for _, v := range sh.validations {
+		if gridContains(v.Range, row, col) {
+			rule = v.Rule
+		}
+	}
+	return rule
+}
+
+// Calls returns how many times an operation, such as OpValuesGet, was served
+func (s *Server) Calls(op string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.calls[op]
+}
+
+func (s *Server) newSheet(title string) *sheet {
+	sh := &sheet{id: s.nextSheetID, title: title, rowCount: DefaultRowCount, columnCount: DefaultColumnCount}
+	s.nextSheetID++
+	s.sheets = append(s.sheets, sh)
+	return sh
+}
+
+func (s *Server) sheetByTitle(title string) *sheet {
+	for _, sh := range s.sheets {
+		if strings.EqualFold(sh.title, title) {
+			return sh
+		}
+	}
+	return nil
+}
+
+func (s *Server) sheetByID(id int64) *sheet {
+	for _, sh := range s.sheets {
+		if sh.id == id {
+			return sh
+		}
+	}
+	return nil
+}
+
+// apiError is a failed call, written in Google's error format so the client
+// library returns a *googleapi.Error
+type apiError struct {
+	code    int
+	status  string
+	message string
+}
+
+func (e *apiError) Error() string {
+	return e.message
+}
+
+func invalidArgument(format string, args ...interface{}) *apiError {
+	return &apiError{code: http.StatusBadRequest, status: "INVALID_ARGUMENT", message: fmt.Sprintf(format, args...)}
+}
+
+func notFound() *apiError {
+	return &apiError{code: http.StatusNotFound, status: "NOT_FOUND", message: "Requested entity was not found."}
+}
+
+// ServeHTTP routes Sheets v4 REST calls, under /v4/spreadsheets/, to the fake
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	rest, ok := strings.CutPrefix(r.URL.EscapedPath(), "/v4/spreadsheets/")
+	if !ok {
+		writeError(w, notFound())
+		return
+	}
+	segments := strings.Split(rest, "/")
+	id, method := splitMethod(segments[0])
+	if id, err := url.PathUnescape(id); err != nil || id != s.spreadsheetID {
+		writeError(w, notFound())
+		return
+	}
+
+	var (
+		op       string
+		response interface{}
+		err      *apiError
+	)
+	s.mu.Lock()
+	switch {
+	case len(segments) == 1 && method == "" && r.Method == http.MethodGet:
+		op, response = OpSpreadsheetsGet, s.spreadsheet()
+	case len(segments) == 1 && method == "batchUpdate" && r.Method == http.MethodPost:
+		var req sheets.BatchUpdateSpreadsheetRequest
+		if err = decode(r, &req); err == nil {
+			op = OpSpreadsheetsBatchUpdate
+			response, err = s.batchUpdate(&req)
+		}
+	case len(segments) == 2 && segments[1] == "values:batchUpdate" && r.Method == http.MethodPost:
+		var req sheets.BatchUpdateValuesRequest
+		if err = decode(r, &req); err == nil {
+			op = OpValuesBatchUpdate
+			response, err = s.valuesBatchUpdate(&req)
+		}
+	case len(segments) == 3 && segments[1] == "values":
+		escaped, method := splitMethod(segments[2])
+		a1, unescapeErr := url.PathUnescape(escaped)
+		switch {
+		case unescapeErr != nil:
+			err = invalidArgument("Unable to parse range: %s", escaped)
+		case method == "" && r.Method == http.MethodGet:
+			op = OpValuesGet
+			response, err = s.valuesGet(a1, r.URL.Query().Get("valueRenderOption"))
+		case method == "append" && r.Method == http.MethodPost:
+			var req sheets.ValueRange
+			if err = decode(r, &req); err == nil {
+				op = OpValuesAppend
+				query := r.URL.Query()
+				response, err = s.valuesAppend(a1, &req, query.Get("valueInputOption"), query.Get("insertDataOption"))
+			}
+		default:
+			err = notFound()
+		}
+	default:
+		err = notFound()
+	}
+	if op != "" {
+		s.calls[op]++
+	}
+	s.mu.Unlock()
+
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// splitMethod splits a custom method such as ":append" off a path segment.
+// Colons inside ranges are escaped, so the first literal colon starts it.
+func splitMethod(segment string) (string, string) {
+	name, method, _ := strings.Cut(segment, ":")
+	return name, method
+}
+
+func decode(r *http.Request, v interface{}) *apiError {
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		return invalidArgument("Invalid JSON payload received. %v", err)
+	}
+	return nil
+}
+
+func writeError(w http.ResponseWriter, err *apiError) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(err.code)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"error": map[string]interface{}{
+			"code":    err.code,
+			"message": err.message,
+			"status":  err.status,
+		},
+	})
+}
+
+// spreadsheet describes the spreadsheet and its tabs
+func (s *Server) spreadsheet() *sheets.Spreadsheet {
+	result := &sheets.Spreadsheet{
+		SpreadsheetId: s.spreadsheetID,
+		Properties:    &sheets.SpreadsheetProperties{Title: s.spreadsheetID},
+	}
+	for i, sh := range s.sheets {
+		result.Sheets = append(result.Sheets, sh.describe(i))
+	}
+	return result
+}
+
+// describe returns the tab's metadata for the sheet at index
+func (sh *sheet) describe(index int) *sheets.Sheet {
+	return &sheets.Sheet{
+		Properties:         sh.properties(index),
+		ConditionalFormats: append([]*sheets.ConditionalFormatRule(nil), sh.conditionalFormats...),
+		ProtectedRanges:    append([]*sheets.ProtectedRange(nil), sh.protectedRanges...),
+	}
+}
+
+func (sh *sheet) properties(index int) *sheets.SheetProperties {
+	return &sheets.SheetProperties{
+		SheetId:   sh.id,
+		Title:     sh.title,
+		Index:     int64(index),
+		SheetType: "GRID",
+		GridProperties: &sheets.GridProperties{
+			RowCount:       int64(sh.rowCount),
+			ColumnCount:    int64(sh.columnCount),
+			FrozenRowCount: int64(sh.frozenRowCount),
+		},
+	}
+}
+
+// resolve finds the tab and bounded cells an A1 range refers to. Open ends
+// extend to the edge of the grid.
+func (s *Server) resolve(a1 string) (*sheet, cellRange, *apiError) {
+	if sh := s.sheetByTitle(a1); sh != nil {
+		return sh, cellRange{sheet: sh.title, hasSheet: true, endRow: sh.rowCount, endCol: sh.columnCount}, nil
+	}
+	r, err := parseA1(a1)
+	if err != nil {
+		return nil, r, invalidArgument("Unable to parse range: %s", a1)
+	}
+	var sh *sheet
+	if r.hasSheet {
+		sh = s.sheetByTitle(r.sheet)
+	} else if len(s.sheets) > 0 {
+		sh = s.sheets[0]
+	}
+	if sh == nil {
+		return nil, r, invalidArgument("Unable to parse range: %s", a1)
+	}
+	if r.endRow < 0 {
+		r.endRow = sh.rowCount
+	}
+	if r.endCol < 0 {
+		r.endCol = sh.columnCount
+	}
+	if r.startRow >= r.endRow || r.startCol >= r.endCol {
+		return nil, r, invalidArgument("Unable to parse range: %s", a1)
+	}
+	return sh, r, nil
+}
+
+// valuesGet reads a range. Trailing empty rows and cells are left out, as
+// the real API does.
+func (s *Server) valuesGet(a1, renderOption string) (*sheets.ValueRange, *apiError) {
+	sh, r, err := s.resolve(a1)
+	if err != nil {
+		return nil, err
+	}
+	render := renderFormatted
+	switch renderOption {
+	case "", "FORMATTED_VALUE":
+	case "UNFORMATTED_VALUE", "FORMULA":
+		render = renderUnformatted
+	default:
+		return nil, invalidArgument("Invalid valueRenderOption: %s", renderOption)
+	}
+	endRow := min(r.endRow, sh.rowCount)
+	return &sheets.ValueRange{
+		Range:          formatA1(sh.title, r.startRow, endRow, r.startCol, r.endCol),
+		MajorDimension: "ROWS",
+		Values:         sh.values(r.startRow, endRow, r.startCol, r.endCol, render),
+	}, nil
+}
+
+// valuesAppend writes rows after the last row with data in the range's
+// columns, growing the grid when asked to insert rows
+func (s *Server) valuesAppend(a1 string, req *sheets.ValueRange, inputOption, insertOption string) (*sheets.AppendValuesResponse, *apiError) {
+	if inputOption != "RAW" && inputOption != "USER_ENTERED" {
+		return nil, invalidArgument("Invalid valueInputOption: %q", inputOption)
+	}
+	if insertOption != "" && insertOption != "INSERT_ROWS" && insertOption != "OVERWRITE" {
+		return nil, invalidArgument("Invalid insertDataOption: %q", insertOption)
+	}
+	sh, r, err := s.resolve(a1)
+	if err != nil {
+		return nil, err
+	}
+
+	last := -1
+	for row := range sh.cells {
+		for col := r.startCol; col < r.endCol; col++ {
+			if sh.get(row, col) != nil {
+				last = row
+				break
+			}
+		}
+	}
+	start := max(last+1, r.startRow)
+	width := 0
+	for i, values := range req.Values {
+		for j, value := range values {
+			sh.set(start+i, r.startCol+j, inputValue(value, inputOption))
+		}
+		width = max(width, len(values))
+	}
+	if insertOption == "INSERT_ROWS" {
+		sh.rowCount += len(req.Values)
+	}
+	sh.rowCount = max(sh.rowCount, start+len(req.Values))
+	sh.columnCount = max(sh.columnCount, r.startCol+width)
+
+	result := &sheets.AppendValuesResponse{SpreadsheetId: s.spreadsheetID}
+	if last >= r.startRow {
+		result.TableRange = formatA1(sh.title, r.startRow, last+1, r.startCol, r.endCol)
+	}
+	if len(req.Values) > 0 && width > 0 {
+		result.Updates = &sheets.UpdateValuesResponse{
+			SpreadsheetId:  s.spreadsheetID,
+			UpdatedRange:   formatA1(sh.title, start, start+len(req.Values), r.startCol, r.startCol+width),
+			UpdatedRows:    int64(len(req.Values)),
+			UpdatedColumns: int64(width),
+			UpdatedCells:   int64(countCells(req.Values)),
+		}
+	}
+	return result, nil
+}
+
+// valuesBatchUpdate writes each value range from its top-left cell. Values
+// must fit inside bounded ranges.
+func (s *Server) valuesBatchUpdate(req *sheets.BatchUpdateValuesRequest) (*sheets.BatchUpdateValuesResponse, *apiError) {
+	if req.ValueInputOption != "RAW" && req.ValueInputOption != "USER_ENTERED" {
+		return nil, invalidArgument("Invalid valueInputOption: %q", req.ValueInputOption)
+	}
+
+	type write struct {
+		sh *sheet
+		r  cellRange
+		vr *sheets.ValueRange
+	}
+	writes := make([]write, 0, len(req.Data))
+	for _, vr := range req.Data {
+		sh, r, err := s.resolve(vr.Range)
+		if err != nil {
+			return nil, err
+		}
+		if len(vr.Values) > r.endRow-r.startRow {
+			return nil, invalidArgument("Requested writing within range [%s], but tried writing to row [%d]", vr.Range, r.startRow+len(vr.Values))
+		}
+		for _, values := range vr.Values {
+			if len(values) > r.endCol-r.startCol {
+				return nil, invalidArgument("Requested writing within range [%s], but tried writing to column [%s]", vr.Range, columnName(r.startCol+len(values)-1))
+			}
+		}
+		writes = append(writes, write{sh, r, vr})
+	}
+
+	result := &sheets.BatchUpdateValuesResponse{SpreadsheetId: s.spreadsheetID}
+	updatedSheets := make(map[int64]bool)
+	for _, wr := range writes {
+		width := 0
+		for i, values := range wr.vr.Values {
+			for j, value := range values {
+				wr.sh.set(wr.r.startRow+i, wr.r.startCol+j, inputValue(value, req.ValueInputOption))
+			}
+			width = max(width, len(values))
+		}
+		wr.sh.rowCount = max(wr.sh.rowCount, wr.r.startRow+len(wr.vr.Values))
+		cells := countCells(wr.vr.Values)
+		result.Responses = append(result.Responses, &sheets.UpdateValuesResponse{
+			SpreadsheetId:  s.spreadsheetID,
+			UpdatedRange:   formatA1(wr.sh.title, wr.r.startRow, wr.r.startRow+len(wr.vr.Values), wr.r.startCol, wr.r.startCol+max(width, 1)),
+			UpdatedRows:    int64(len(wr.vr.Values)),
+			UpdatedColumns: int64(width),
+			UpdatedCells:   int64(cells),
+		})
+		result.TotalUpdatedRows += int64(len(wr.vr.Values))
+		result.TotalUpdatedColumns += int64(width)
+		result.TotalUpdatedCells += int64(cells)
+		updatedSheets[wr.sh.id] = true
+	}
+	result.TotalUpdatedSheets = int64(len(updatedSheets))
+	return result, nil
+}
+
+// batchUpdate applies spreadsheet requests in order. As with the real API,
+// either every request is applied or none are.
+func (s *Server) batchUpdate(req *sheets.BatchUpdateSpreadsheetRequest) (*sheets.BatchUpdateSpreadsheetResponse, *apiError) {
+	saved, savedNextID, savedRangeID := s.snapshot(), s.nextSheetID, s.nextRangeID
+	result := &sheets.BatchUpdateSpreadsheetResponse{SpreadsheetId: s.spreadsheetID}
+	for i, request := range req.Requests {
+		reply, err := s.apply(request)
+		if err != nil {
+			s.sheets, s.nextSheetID, s.nextRangeID = saved, savedNextID, savedRangeID
+			err.message = fmt.Sprintf("Invalid requests[%d]: %s", i, err.message)
+			return nil, err
+		}
+		result.Replies = append(result.Replies, reply)
+	}
+	return result, nil
+}
+
+// apply applies one spreadsheet request
+func (s *Server) apply(request *sheets.Request) (*sheets.Response, *apiError) {
+	switch {
+	case request.UpdateCells != nil:
+		return &sheets.Response{}, s.updateCells(request.UpdateCells)
+	case request.AddSheet != nil:
+		props := request.AddSheet.Properties
+		if props == nil || props.Title == "" {
+			return nil, invalidArgument("addSheet: a sheet title is required")
+		}
+		if s.sheetByTitle(props.Title) != nil {
+			return nil, invalidArgument("A sheet with the name %q already exists. Please enter another name.", props.Title)
+		}
+		sh := s.newSheet(props.Title)
+		if grid := props.GridProperties; grid != nil {
+			if grid.RowCount > 0 {
+				sh.rowCount = int(grid.RowCount)
+			}
+			if grid.ColumnCount > 0 {
+				sh.columnCount = int(grid.ColumnCount)
+			}
+			sh.frozenRowCount = int(grid.FrozenRowCount)
+		}
+		return &sheets.Response{AddSheet: &sheets.AddSheetResponse{Properties: sh.properties(len(s.sheets) - 1)}}, nil
+	case request.DeleteDimension != nil:
+		return &sheets.Response{}, s.deleteDimension(request.DeleteDimension)
+	case request.AppendDimension != nil:
+		return &sheets.Response{}, s.appendDimension(request.AppendDimension)
+	case request.CopyPaste != nil:
+		return &sheets.Response{}, s.copyPaste(request.CopyPaste)
+	case request.UpdateSheetProperties != nil:
+		return &sheets.Response{}, s.updateSheetProperties(request.UpdateSheetProperties)
+	case request.AddConditionalFormatRule != nil:
+		return &sheets.Response{}, s.addConditionalFormatRule(request.AddConditionalFormatRule)
+	case request.DeleteConditionalFormatRule != nil:
+		return &sheets.Response{}, s.deleteConditionalFormatRule(request.DeleteConditionalFormatRule)
+	case request.SetDataValidation != nil:
+		return &sheets.Response{}, s.setDataValidation(request.SetDataValidation)
+	case request.AddProtectedRange != nil:
+		return s.addProtectedRange(request.AddProtectedRange)
+	case request.DeleteProtectedRange != nil:
+		return &sheets.Response{}, s.deleteProtectedRange(request.DeleteProtectedRange)
+	default:
+		data, _ := json.Marshal(request)
+		return nil, invalidArgument("unsupported request: %s", data)
+	}
+}
+
+// updateCells writes the userEnteredValue of cells starting at the range or
+// start coordinate. Cells of a bounded range not covered by rows are cleared.
+func (s *Server) updateCells(req *sheets.UpdateCellsRequest) *apiError {
+	if req.Fields != "*" && !strings.Contains(req.Fields, "userEnteredValue") {
+		if req.Fields == "" {
+			return invalidArgument("updateCells: fields is required")
+		}
+		return nil // Only values are modelled
+	}
+
+	var sheetID int64
+	var startRow, startCol, endRow, endCol int
+	switch {
+	case req.Range != nil:
+		sheetID = req.Range.SheetId
+		startRow, startCol = int(req.Range.StartRowIndex), int(req.Range.StartColumnIndex)
+		endRow, endCol = int(req.Range.EndRowIndex), int(req.Range.EndColumnIndex)
+	case req.Start != nil:
+		sheetID = req.Start.SheetId
+		startRow, startCol = int(req.Start.RowIndex), int(req.Start.ColumnIndex)
+	default:
+		return invalidArgument("updateCells: range or start is required")
+	}
+	sh := s.sheetByID(sheetID)
+	if sh == nil {
+		return invalidArgument("No grid with id: %d", sheetID)
+	}
+	bounded := endRow > 0 && endCol > 0
+	if !bounded {
+		endRow, endCol = startRow+len(req.Rows), startCol
+		for _, row := range req.Rows {
+			endCol = max(endCol, startCol+len(row.Values))
+		}
+	}
+	if endRow > sh.rowCount || endCol > sh.columnCount {
+		return invalidArgument("Range (%s) exceeds grid limits. Max rows: %d, max columns: %d",
+			formatA1(sh.title, startRow, endRow, startCol, endCol), sh.rowCount, sh.columnCount)
+	}
+	if startRow >= endRow && len(req.Rows) > 0 || bounded && startCol >= endCol {
+		return invalidArgument("updateCells: empty range")
+	}
+
+	for r := startRow; r < endRow; r++ {
+		var values []*sheets.CellData
+		if i := r - startRow; i < len(req.Rows) {
+			values = req.Rows[i].Values
+		}
+		for c := startCol; c < endCol; c++ {
+			var value interface{}
+			if j := c - startCol; j < len(values) {
+				value = extendedValue(values[j].UserEnteredValue)
+			} else if !bounded {
+				continue
+			}
+			sh.set(r, c, value)
+		}
+	}
+	return nil
+}
+
+// deleteDimension removes whole rows; the grid shrinks by the same amount
+func (s *Server) deleteDimension(req *sheets.DeleteDimensionRequest) *apiError {
+	if req.Range == nil {
+		return invalidArgument("deleteDimension: range is required")
+	}
+	sh := s.sheetByID(req.Range.SheetId)
+	if sh == nil {
+		return invalidArgument("No grid with id: %d", req.Range.SheetId)
+	}
+	if req.Range.Dimension != "ROWS" {
+		return invalidArgument("deleteDimension: only ROWS is supported")
+	}
+	start, end := int(req.Range.StartIndex), int(req.Range.EndIndex)
+	if start < 0 || end <= start || end > sh.rowCount {
+		return invalidArgument("deleteDimension: invalid range %d:%d", start, end)
+	}
+	if end-start >= sh.rowCount {
+		return invalidArgument("You can't delete all the rows on the sheet.")
+	}
+	if start < len(sh.cells) {
+		sh.cells = append(sh.cells[:start], sh.cells[min(end, len(sh.cells)):]...)
+	}
+	sh.rowCount -= end - start
+	return nil
+}
+
+// appendDimension grows the grid by rows or columns at the end
+func (s *Server) appendDimension(req *sheets.AppendDimensionRequest) *apiError {
+	sh := s.sheetByID(req.SheetId)
+	if sh == nil {
+		return invalidArgument("No grid with id: %d", req.SheetId)
+	}
+	if req.Length <= 0 {
+		return invalidArgument("appendDimension: length must be positive")
+	}
+	switch req.Dimension {
+	case "ROWS":
+		sh.rowCount += int(req.Length)
+	case "COLUMNS":
+		sh.columnCount += int(req.Length)
+	default:
+		return invalidArgument("appendDimension: dimension must be ROWS or COLUMNS")
+	}
+	return nil
+}
+
+// copyPaste copies the values of a source range to the top-left of the
+// destination, which may be on another sheet. Formats are not modelled.
+func (s *Server) copyPaste(req *sheets.CopyPasteRequest) *apiError {
+	if req.Source == nil || req.Destination == nil {
+		return invalidArgument("copyPaste: source and destination are required")
+	}
+	if req.PasteType != "" && req.PasteType != "PASTE_NORMAL" && req.PasteType != "PASTE_VALUES" {
+		return invalidArgument("copyPaste: unsupported paste type %s", req.PasteType)
+	}
+	src, err := s.gridSheet(req.Source)
+	if err != nil {
+		return err
+	}
+	dst, err := s.gridSheet(req.Destination)
+	if err != nil {
+		return err
+	}
+	g := req.Source
+	startRow, endRow := int(g.StartRowIndex), int(g.EndRowIndex)
+	startCol, endCol := int(g.StartColumnIndex), int(g.EndColumnIndex)
+	if g.EndRowIndex == 0 {
+		endRow = src.rowCount
+	}
+	if g.EndColumnIndex == 0 {
+		endCol = src.columnCount
+	}
+	dstRow, dstCol := int(req.Destination.StartRowIndex), int(req.Destination.StartColumnIndex)
+	if dstRow+endRow-startRow > dst.rowCount || dstCol+endCol-startCol > dst.columnCount {
+		return invalidArgument("Range (%s) exceeds grid limits. Max rows: %d, max columns: %d",
+			formatA1(dst.title, dstRow, dstRow+endRow-startRow, dstCol, dstCol+endCol-startCol), dst.rowCount, dst.columnCount)
+	}
+	// Read everything first so overlapping ranges copy the original values
+	values := make([][]interface{}, endRow-startRow)
+	for r := range values {
+		values[r] = make([]interface{}, endCol-startCol)
+		for c := range values[r] {
+			values[r][c] = src.get(startRow+r, startCol+c)
+		}
+	}
+	for r, row := range values {
+		for c, value := range row {
+			dst.set(dstRow+r, dstCol+c, value)
+		}
+	}
+	return nil
+}
+
+// updateSheetProperties changes the frozen row count; other properties are
+// not modelled
+func (s *Server) updateSheetProperties(req *sheets.UpdateSheetPropertiesRequest) *apiError {
+	if req.Properties == nil || req.Fields == "" {
+		return invalidArgument("updateSheetProperties: properties and fields are required")
+	}
+	sh := s.sheetByID(req.Properties.SheetId)
+	if sh == nil {
+		return invalidArgument("No grid with id: %d", req.Properties.SheetId)
+	}
+	for _, field := range strings.Split(req.Fields, ",") {
+		if strings.TrimSpace(field) != "gridProperties.frozenRowCount" {
+			return invalidArgument("updateSheetProperties: unsupported field %q", field)
+		}
+		frozen := 0
+		if req.Properties.GridProperties != nil {
+			frozen = int(req.Properties.GridProperties.FrozenRowCount)
+		}
+		if frozen < 0 || frozen >= sh.rowCount {
+			return invalidArgument("You can't freeze all visible rows on the sheet.")
+		}
+		sh.frozenRowCount = frozen
+	}
+	return nil
+}
+
+// addConditionalFormatRule inserts a rule at its index in the sheet of its
+// first range
+func (s *Server) addConditionalFormatRule(req *sheets.AddConditionalFormatRuleRequest) *apiError {
+	rule := req.Rule
+	if rule == nil || len(rule.Ranges) == 0 || (rule.BooleanRule == nil) == (rule.GradientRule == nil) {
+		return invalidArgument("addConditionalFormatRule: a rule with ranges and one of booleanRule or gradientRule is required")
+	}
+	sh, err := s.gridSheet(rule.Ranges...)
+	if err != nil {
+		return err
+	}
+	index := int(req.Index)
+	if index < 0 || index > len(sh.conditionalFormats) {
+		return invalidArgument("addConditionalFormatRule: invalid index %d", index)
+	}
+	sh.conditionalFormats = append(sh.conditionalFormats[:index], append([]*sheets.ConditionalFormatRule{rule}, sh.conditionalFormats[index:]...)...)
+	return nil
+}
+
+// deleteConditionalFormatRule removes the rule at an index
+func (s *Server) deleteConditionalFormatRule(req *sheets.DeleteConditionalFormatRuleRequest) *apiError {
+	sh := s.sheetByID(req.SheetId)
+	if sh == nil {
+		return invalidArgument("No grid with id: %d", req.SheetId)
+	}
+	index := int(req.Index)
+	if index < 0 || index >= len(sh.conditionalFormats) {
+		return invalidArgument("No conditional format on sheet: %d at index: %d", req.SheetId, index)
+	}
+	sh.conditionalFormats = append(sh.conditionalFormats[:index], sh.conditionalFormats[index+1:]...)
+	return nil
+}
+
+// setDataValidation records a validation rule, or its removal when the rule
+// is empty, over a range
+func (s *Server) setDataValidation(req *sheets.SetDataValidationRequest) *apiError {
+	if req.Range == nil {
+		return invalidArgument("setDataValidation: range is required")
+	}
+	sh, err := s.gridSheet(req.Range)
+	if err != nil {
+		return err
+	}
+	if rule := req.Rule; rule != nil && (rule.Condition == nil || rule.Condition.Type == "") {
+		return invalidArgument("setDataValidation: a condition is required")
+	}
+	sh.validations = append(sh.validations, req)
+	return nil
+}
+
+// addProtectedRange protects a range and replies with its new ID
+func (s *Server) addProtectedRange(req *sheets.AddProtectedRangeRequest) (*sheets.Response, *apiError) {
+	if req.ProtectedRange == nil || req.ProtectedRange.Range == nil {
+		return nil, invalidArgument("addProtectedRange: a range is required")
+	}
+	sh, err := s.gridSheet(req.ProtectedRange.Range)
+	if err != nil {
+		return nil, err
+	}
+	protected := *req.ProtectedRange
+	s.nextRangeID++
+	protected.ProtectedRangeId = s.nextRangeID
+	sh.protectedRanges = append(sh.protectedRanges, &protected)
+	return &sheets.Response{AddProtectedRange: &sheets.AddProtectedRangeResponse{ProtectedRange: &protected}}, nil
+}
+
+// deleteProtectedRange removes a protected range by ID
+func (s *Server) deleteProtectedRange(req *sheets.DeleteProtectedRangeRequest) *apiError {
+	for _, sh := range s.sheets {
+		for i, protected := range sh.protectedRanges {
+			if protected.ProtectedRangeId == req.ProtectedRangeId {
+				sh.protectedRanges = append(sh.protectedRanges[:i], sh.protectedRanges[i+1:]...)
+				return nil
+			}
+		}
+	}
+	return invalidArgument("No protected range with id: %d", req.ProtectedRangeId)
+}
+
+// gridSheet returns the sheet that grid ranges are on, checking they fit in
+// its grid
+func (s *Server) gridSheet(ranges ...*sheets.GridRange) (*sheet, *apiError) {
+	var sh *sheet
+	for _, g := range ranges {
+		if sh = s.sheetByID(g.SheetId); sh == nil {
+			return nil, invalidArgument("No grid with id: %d", g.SheetId)
+		}
+		if int(g.EndRowIndex) > sh.rowCount || int(g.EndColumnIndex) > sh.columnCount {
+			return nil, invalidArgument("Range exceeds grid limits. Max rows: %d, max columns: %d", sh.rowCount, sh.columnCount)
+		}
+	}
+	return sh, nil
+}
+
+// gridContains reports whether a grid range covers a cell; an end of 0
+// leaves that side open
+func gridContains(g *sheets.GridRange, row, col int) bool {
+	return row >= int(g.StartRowIndex) && (g.EndRowIndex == 0 || row < int(g.EndRowIndex)) &&
+		col >= int(g.StartColumnIndex) && (g.EndColumnIndex == 0 || col < int(g.EndColumnIndex))
+}
+
+// snapshot deep copies the sheets so a failed batch can be rolled back
+func (s *Server) snapshot() []*sheet {
+	saved := make([]*sheet, len(s.sheets))
+	for i, sh := range s.sheets {
+		copied := *sh
+		copied.conditionalFormats = append([]*sheets.ConditionalFormatRule(nil), sh.conditionalFormats...)
+		copied.protectedRanges = append([]*sheets.ProtectedRange(nil), sh.protectedRanges...)
+		copied.validations = append([]*sheets.SetDataValidationRequest(nil), sh.validations...)
+		copied.cells = make([][]interface{}, len(sh.cells))
+		for r, row := range sh.cells {
+			copied.cells[r] = append([]interface{}(nil), row...)
+		}
+		saved[i] = &copied
+	}
+	return saved
+}
+
+func (sh *sheet) get(row, col int) interface{} {
+	if row < len(sh.cells) && col < len(sh.cells[row]) {
+		return sh.cells[row][col]
+	}
+	return nil
+}
+
+func (sh *sheet) set(row, col int, value interface{}) {
+	if value == nil && sh.get(row, col) == nil {
+		return
+	}
+	for len(sh.cells) <= row {
+		sh.cells = append(sh.cells, nil)
+	}
+	for len(sh.cells[row]) <= col {
+		sh.cells[row] = append(sh.cells[row], nil)
+	}
+	sh.cells[row][col] = value
+}
+
+// values renders a block of cells, trimming trailing empty cells and rows
+func (sh *sheet) values(startRow, endRow, startCol, endCol int, render func(interface{}) interface{}) [][]interface{} {
+	var rows [][]interface{}
+	for r := startRow; r < endRow && r < len(sh.cells); r++ {
+		var row []interface{}
+		for c := startCol; c < endCol && c < len(sh.cells[r]); c++ {
+			row = append(row, render(sh.cells[r][c]))
+		}
+		for len(row) > 0 && row[len(row)-1] == "" {
+			row = row[:len(row)-1]
+		}
+		if row == nil {
+			row = []interface{}{}
+		}
+		rows = append(rows, row)
+	}
+	for len(rows) > 0 && len(rows[len(rows)-1]) == 0 {
+		rows = rows[:len(rows)-1]
+	}
+	return rows
+}
+
+// renderFormatted renders a cell as the text a user would see. Formulas are
+// not evaluated, so they render as their text.
+func renderFormatted(value interface{}) interface{} {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		return strings.ToUpper(strconv.FormatBool(v))
+	case formula:
+		return string(v)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// renderUnformatted keeps numbers and booleans typed
+func renderUnformatted(value interface{}) interface{} {
+	switch value.(type) {
+	case float64, bool:
+		return value
+	default:
+		return renderFormatted(value)
+	}
+}
+
+// inputValue converts a written value. USER_ENTERED text starting with "="
+// is a formula and numeric text becomes a number; RAW keeps values as sent.
+func inputValue(value interface{}, inputOption string) interface{} {
+	text, ok := value.(string)
+	if !ok || inputOption != "USER_ENTERED" {
+		if ok && text == "" {
+			return nil
+		}
+		return value
+	}
+	switch {
+	case text == "":
+		return nil
+	case strings.HasPrefix(text, "="):
+		return formula(text)
+	}
+	if n, err := strconv.ParseFloat(text, 64); err == nil {
+		return n
+	}
+	return text
+}
+
+// extendedValue converts a cell's userEnteredValue
+func extendedValue(v *sheets.ExtendedValue) interface{} {
+	switch {
+	case v == nil:
+		return nil
+	case v.StringValue != nil:
+		if *v.StringValue == "" {
+			return nil
+		}
+		return *v.StringValue
+	case v.NumberValue != nil:
+		return *v.NumberValue
+	case v.BoolValue != nil:
+		return *v.BoolValue
+	case v.FormulaValue != nil:
+		return formula(*v.FormulaValue)
+	}
+	return nil
+}
+
+func countCells(values [][]interface{}) int {
+	n := 0
+	for _, row := range values {
+		n += len(row)
+	}
+	return n
+}
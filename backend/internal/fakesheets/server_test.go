@@ -0,0 +1,108 @@
+package fakesheets
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"google.golang.org/api/googleapi"
+	"google.golang.org/api/option"
+	"google.golang.org/api/sheets/v4"
+)
+
+func TestParseA1(t *testing.T) {
+	tests := []struct {
+		a1      string
+		want    cellRange
+		wantErr bool
+	}{
+		{"Sheet1!A1:K1000", cellRange{sheet: "Sheet1", hasSheet: true, startRow: 0, endRow: 1000, startCol: 0, endCol: 11}, false},
+		{"Sheet1!A:K", cellRange{sheet: "Sheet1", hasSheet: true, endRow: -1, endCol: 11}, false},
+		{"'It''s here'!J5:K", cellRange{sheet: "It's here", hasSheet: true, startRow: 4, endRow: -1, startCol: 9, endCol: 11}, false},
+		{"'My Sheet'", cellRange{sheet: "My Sheet", hasSheet: true, endRow: -1, endCol: -1}, false},
+		{"AA10", cellRange{startRow: 9, endRow: 10, startCol: 26, endCol: 27}, false},
+		{"Sheet1!2:5", cellRange{sheet: "Sheet1", hasSheet: true, startRow: 1, endRow: 5, endCol: -1}, false},
+		{"Form Responses", cellRange{sheet: "Form Responses", hasSheet: true, endRow: -1, endCol: -1}, false},
+		{"Foo", cellRange{sheet: "Foo", hasSheet: true, endRow: -1, endCol: -1}, false},
+		{"Archive", cellRange{sheet: "Archive", hasSheet: true, endRow: -1, endCol: -1}, false},
+		{"A:K", cellRange{endRow: -1, endCol: 11}, false},
+		{"b2", cellRange{sheet: "b2", hasSheet: true, endRow: -1, endCol: -1}, false},
+		{"Sheet1!b2", cellRange{}, true},
+		{"Sheet1!A0", cellRange{}, true},
+		{"'Unterminated!A1", cellRange{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.a1, func(t *testing.T) {
+			got, err := parseA1(tt.a1)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseA1(%q) error = %v, wantErr %v", tt.a1, err, tt.wantErr)
+			}
+			if !tt.wantErr && got != tt.want {
+				t.Errorf("parseA1(%q) = %+v, want %+v", tt.a1, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestServer(t *testing.T) {
+	fake := New("spreadsheet-id")
+	sheetID := fake.AddSheet("My Sheet", [][]interface{}{
+		{"Name", "Count"},
+		{"a", "1"},
+		{"b", "2"},
+	})
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	ctx := context.Background()
+	svc, err := sheets.NewService(ctx, option.WithEndpoint(server.URL+"/"), option.WithoutAuthentication())
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+
+	// Values written as USER_ENTERED are typed
+	_, err = svc.Spreadsheets.Values.BatchUpdate("spreadsheet-id", &sheets.BatchUpdateValuesRequest{
+		ValueInputOption: "USER_ENTERED",
+		Data:             []*sheets.ValueRange{{Range: "'My Sheet'!B2:B3", Values: [][]interface{}{{"10"}, {"=B2*2"}}}},
+	}).Context(ctx).Do()
+	if err != nil {
+		t.Fatalf("values.batchUpdate failed: %v", err)
+	}
+	resp, err := svc.Spreadsheets.Values.Get("spreadsheet-id", "'My Sheet'!A2:B").ValueRenderOption("UNFORMATTED_VALUE").Context(ctx).Do()
+	if err != nil {
+		t.Fatalf("values.get failed: %v", err)
+	}
+	want := [][]interface{}{{"a", float64(10)}, {"b", "=B2*2"}}
+	if !reflect.DeepEqual(resp.Values, want) || resp.Range != "'My Sheet'!A2:B1000" {
+		t.Errorf("Expected %v in 'My Sheet'!A2:B1000, got %v in %s", want, resp.Values, resp.Range)
+	}
+
+	// A failing request rolls back the whole batch
+	name := "c"
+	_, err = svc.Spreadsheets.BatchUpdate("spreadsheet-id", &sheets.BatchUpdateSpreadsheetRequest{
+		Requests: []*sheets.Request{
+			{UpdateCells: &sheets.UpdateCellsRequest{
+				Range:  &sheets.GridRange{SheetId: sheetID, StartRowIndex: 3, EndRowIndex: 4, StartColumnIndex: 0, EndColumnIndex: 1},
+				Rows:   []*sheets.RowData{{Values: []*sheets.CellData{{UserEnteredValue: &sheets.ExtendedValue{StringValue: &name}}}}},
+				Fields: "userEnteredValue",
+			}},
+			{UpdateCells: &sheets.UpdateCellsRequest{
+				Range:  &sheets.GridRange{SheetId: sheetID, StartRowIndex: 0, EndRowIndex: 1, StartColumnIndex: 0, EndColumnIndex: 30},
+				Fields: "userEnteredValue",
+			}},
+		},
+	}).Context(ctx).Do()
+	var apiErr *googleapi.Error
+	if !errors.As(err, &apiErr) || apiErr.Code != http.StatusBadRequest {
+		t.Fatalf("Expected a 400 for a range outside the grid, got %v", err)
+	}
+	if rows := fake.Rows("My Sheet"); len(rows) != 3 {
+		t.Errorf("Expected the batch rolled back, got %v", rows)
+	}
+	if calls := fake.Calls(OpSpreadsheetsBatchUpdate); calls != 1 {
+		t.Errorf("Expected 1 batchUpdate call, got %d", calls)
+	}
+}
@@ -0,0 +1,94 @@
+package googleauth
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+
+	"golang.org/x/oauth2"
+)
+
+// Login runs the installed-app consent flow. It listens on a loopback port
+// for the redirect, calls open with the URL the user must visit, and
+// exchanges the authorization code for a token once they consent. The
+// exchange uses PKCE and asks for offline access so the token can be
+// refreshed.
+func Login(ctx context.Context, cfg *oauth2.Config, open func(url string) error) (*oauth2.Token, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen for the OAuth redirect: %w", err)
+	}
+	defer listener.Close()
+
+	flow := *cfg
+	flow.RedirectURL = "http://" + listener.Addr().String() + "/"
+	state, err := randomState()
+	if err != nil {
+		return nil, err
+	}
+	verifier := oauth2.GenerateVerifier()
+
+	type result struct {
+		code string
+		err  error
+	}
+	results := make(chan result, 1)
+	server := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		if r.URL.Path != "/" || query.Get("state") != state {
+			http.Error(w, "Unexpected request", http.StatusBadRequest)
+			return
+		}
+		var res result
+		switch {
+		case query.Get("error") != "":
+			res.err = fmt.Errorf("authorization denied: %s", query.Get("error"))
+			http.Error(w, "Authorization was denied. You can close this window.", http.StatusForbidden)
+		case query.Get("code") == "":
+			res.err = errors.New("authorization response has no code")
+			http.Error(w, "Authorization failed. You can close this window.", http.StatusBadRequest)
+		default:
+			res.code = query.Get("code")
+			fmt.Fprintln(w, "Authorization complete. You can close this window.")
+		}
+		select {
+		case results <- res:
+		default:
+		}
+	})}
+	go server.Serve(listener)
+	defer server.Close()
+
+	authURL := flow.AuthCodeURL(state, oauth2.AccessTypeOffline, oauth2.ApprovalForce, oauth2.S256ChallengeOption(verifier))
+	if err := open(authURL); err != nil {
+		return nil, err
+	}
+
+	var res result
+	select {
+	case res = <-results:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	if res.err != nil {
+		return nil, res.err
+	}
+	token, err := flow.Exchange(ctx, res.code, oauth2.VerifierOption(verifier))
+	if err != nil {
+		return nil, fmt.Errorf("failed to exchange authorization code: %w", err)
+	}
+	return token, nil
+}
+
+// randomState returns an unguessable value tying the redirect to this login
+func randomState() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,86 @@
+package googleauth
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/oauth2"
+)
+
+// consent returns an open func that plays the user's browser: it follows the
+// auth URL straight to the redirect with the given query values
+func consent(t *testing.T, values func(auth url.Values) url.Values) func(string) error {
+	return func(authURL string) error {
+		parsed, err := url.Parse(authURL)
+		if err != nil {
+			return err
+		}
+		auth := parsed.Query()
+		go func() {
+			resp, err := http.Get(auth.Get("redirect_uri") + "?" + values(auth).Encode())
+			if err != nil {
+				t.Errorf("redirect failed: %v", err)
+				return
+			}
+			resp.Body.Close()
+		}()
+		return nil
+	}
+}
+
+func TestLogin(t *testing.T) {
+	server, _ := newTokenServer(t)
+	cfg := &oauth2.Config{
+		ClientID: "client",
+		Endpoint: oauth2.Endpoint{AuthURL: "https://accounts.example.com/auth", TokenURL: server.URL},
+		Scopes:   []string{"scope"},
+	}
+
+	t.Run("exchanges the code", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		var auth url.Values
+		token, err := Login(ctx, cfg, consent(t, func(a url.Values) url.Values {
+			auth = a
+			return url.Values{"state": {a.Get("state")}, "code": {"code"}}
+		}))
+		if err != nil {
+			t.Fatalf("Login() failed: %v", err)
+		}
+		if token.AccessToken == "" || token.RefreshToken != "refresh-code" {
+			t.Errorf("Login() = %+v, want a token for the code", token)
+		}
+		if !strings.HasPrefix(auth.Get("redirect_uri"), "http://127.0.0.1:") {
+			t.Errorf("redirect_uri = %q, want a loopback address", auth.Get("redirect_uri"))
+		}
+		if auth.Get("access_type") != "offline" || auth.Get("code_challenge_method") != "S256" {
+			t.Errorf("auth URL = %v, want offline access with PKCE", auth)
+		}
+	})
+
+	t.Run("rejects denied consent", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_, err := Login(ctx, cfg, consent(t, func(a url.Values) url.Values {
+			return url.Values{"state": {a.Get("state")}, "error": {"access_denied"}}
+		}))
+		if err == nil || !strings.Contains(err.Error(), "access_denied") {
+			t.Errorf("Login() error = %v, want access_denied", err)
+		}
+	})
+
+	t.Run("ignores a redirect with the wrong state", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+		defer cancel()
+		_, err := Login(ctx, cfg, consent(t, func(a url.Values) url.Values {
+			return url.Values{"state": {"forged"}, "code": {"code"}}
+		}))
+		if err != context.DeadlineExceeded {
+			t.Errorf("Login() error = %v, want the deadline to pass", err)
+		}
+	})
+}
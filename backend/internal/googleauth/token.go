@@ -0,0 +1,101 @@
+// Package googleauth handles OAuth user credentials for the Google APIs: the
+// installed-app consent flow and tokens persisted to a file that are
+// refreshed automatically.
+package googleauth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"golang.org/x/oauth2"
+)
+
+// ErrNoToken is returned when the token file does not exist yet
+var ErrNoToken = errors.New("no OAuth token saved")
+
+// ReadToken reads a token saved by SaveToken
+func ReadToken(path string) (*oauth2.Token, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("%w in %s", ErrNoToken, path)
+	}
+	if err != nil {
+		return nil, err
+	}
+	var token oauth2.Token
+	if err := json.Unmarshal(data, &token); err != nil {
+		return nil, fmt.Errorf("failed to parse token file %s: %w", path, err)
+	}
+	return &token, nil
+}
+
+// SaveToken writes a token readable only by the current user. The file is
+// replaced atomically so concurrent readers never see a partial token.
+func SaveToken(path string, token *oauth2.Token) error {
+	data, err := json.MarshalIndent(token, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if err := tmp.Chmod(0o600); err != nil {
+		tmp.Close()
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// FileTokenSource returns a token source for the token saved at path. Expired
+// tokens are refreshed with cfg and the refreshed token is saved back, so the
+// consent step is only needed once.
+func FileTokenSource(ctx context.Context, cfg *oauth2.Config, path string) (oauth2.TokenSource, error) {
+	token, err := ReadToken(path)
+	if err != nil {
+		return nil, err
+	}
+	if token.RefreshToken == "" && !token.Valid() {
+		return nil, fmt.Errorf("token in %s has expired and cannot be refreshed", path)
+	}
+	saving := &savingTokenSource{path: path, base: cfg.TokenSource(ctx, token), last: token}
+	return oauth2.ReuseTokenSource(token, saving), nil
+}
+
+// savingTokenSource saves each new token its base source returns
+type savingTokenSource struct {
+	mu   sync.Mutex
+	path string
+	base oauth2.TokenSource
+	last *oauth2.Token
+}
+
+func (s *savingTokenSource) Token() (*oauth2.Token, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	token, err := s.base.Token()
+	if err != nil {
+		return nil, err
+	}
+	if token.AccessToken != s.last.AccessToken {
+		if err := SaveToken(s.path, token); err != nil {
+			return nil, fmt.Errorf("failed to save refreshed token: %w", err)
+		}
+		s.last = token
+	}
+	return token, nil
+}
@@ -0,0 +1,131 @@
+package googleauth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"golang.org/x/oauth2"
+)
+
+// newTokenServer serves an OAuth token endpoint that issues access tokens
+// numbered by request
+func newTokenServer(t *testing.T) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+	var issued atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		n := issued.Add(1)
+		response := map[string]interface{}{
+			"access_token": "access-" + strconv.Itoa(int(n)),
+			"token_type":   "Bearer",
+			"expires_in":   3600,
+		}
+		// Refreshes keep the refresh token; exchanges issue one for the code
+		if code := r.Form.Get("code"); code != "" {
+			response["refresh_token"] = "refresh-" + code
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(response)
+	}))
+	t.Cleanup(server.Close)
+	return server, &issued
+}
+
+func TestSaveAndReadToken(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "token.json")
+	if _, err := ReadToken(path); !errors.Is(err, ErrNoToken) {
+		t.Fatalf("ReadToken() of a missing file = %v, want ErrNoToken", err)
+	}
+
+	token := &oauth2.Token{AccessToken: "access", RefreshToken: "refresh", Expiry: time.Now().Add(time.Hour).Round(time.Second)}
+	if err := SaveToken(path, token); err != nil {
+		t.Fatalf("SaveToken() failed: %v", err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("Stat() failed: %v", err)
+	}
+	if perm := info.Mode().Perm(); perm != 0o600 {
+		t.Errorf("token file mode = %v, want 0600", perm)
+	}
+	got, err := ReadToken(path)
+	if err != nil {
+		t.Fatalf("ReadToken() failed: %v", err)
+	}
+	if got.AccessToken != token.AccessToken || got.RefreshToken != token.RefreshToken || !got.Expiry.Equal(token.Expiry) {
+		t.Errorf("ReadToken() = %+v, want %+v", got, token)
+	}
+}
+
+func TestFileTokenSourceSavesRefreshedTokens(t *testing.T) {
+	server, issued := newTokenServer(t)
+	cfg := &oauth2.Config{ClientID: "client", Endpoint: oauth2.Endpoint{TokenURL: server.URL}}
+	path := filepath.Join(t.TempDir(), "token.json")
+
+	t.Run("valid token is used as is", func(t *testing.T) {
+		if err := SaveToken(path, &oauth2.Token{AccessToken: "saved", RefreshToken: "refresh", Expiry: time.Now().Add(time.Hour)}); err != nil {
+			t.Fatal(err)
+		}
+		source, err := FileTokenSource(context.Background(), cfg, path)
+		if err != nil {
+			t.Fatalf("FileTokenSource() failed: %v", err)
+		}
+		token, err := source.Token()
+		if err != nil {
+			t.Fatalf("Token() failed: %v", err)
+		}
+		if token.AccessToken != "saved" || issued.Load() != 0 {
+			t.Errorf("Token() = %q after %d refreshes, want the saved token", token.AccessToken, issued.Load())
+		}
+	})
+
+	t.Run("expired token is refreshed and saved", func(t *testing.T) {
+		if err := SaveToken(path, &oauth2.Token{AccessToken: "saved", RefreshToken: "refresh", Expiry: time.Now().Add(-time.Minute)}); err != nil {
+			t.Fatal(err)
+		}
+		source, err := FileTokenSource(context.Background(), cfg, path)
+		if err != nil {
+			t.Fatalf("FileTokenSource() failed: %v", err)
+		}
+		for i := 0; i < 2; i++ {
+			token, err := source.Token()
+			if err != nil {
+				t.Fatalf("Token() failed: %v", err)
+			}
+			if token.AccessToken != "access-1" {
+				t.Errorf("Token() = %q, want access-1", token.AccessToken)
+			}
+		}
+		if issued.Load() != 1 {
+			t.Errorf("token endpoint called %d times, want 1", issued.Load())
+		}
+		saved, err := ReadToken(path)
+		if err != nil {
+			t.Fatalf("ReadToken() failed: %v", err)
+		}
+		if saved.AccessToken != "access-1" || saved.RefreshToken != "refresh" {
+			t.Errorf("saved token = %+v, want access-1 with the refresh token kept", saved)
+		}
+	})
+
+	t.Run("expired token without a refresh token is rejected", func(t *testing.T) {
+		if err := SaveToken(path, &oauth2.Token{AccessToken: "saved", Expiry: time.Now().Add(-time.Minute)}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := FileTokenSource(context.Background(), cfg, path); err == nil {
+			t.Error("FileTokenSource() succeeded, want an error")
+		}
+	})
+}
@@ -0,0 +1,138 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"google.golang.org/api/sheets/v4"
+)
+
+// Invite sheet columns, as 0-based indexes
+const (
+	columnEmail         = 3  // Column D
+	columnStatus        = 9  // Column J
+	columnStatusUpdated = 10 // Column K
+	inviteColumnCount   = 11 // Columns A-K
+)
+
+// CellRange is a block of cells in one sheet that can be written in A1
+// notation or as a GridRange. Indexes are 0-based and ends are exclusive. As in
+// a GridRange, an end of 0 leaves that side open, so "A2:K" has EndRow 0.
+type CellRange struct {
+	Sheet       string
+	StartRow    int
+	EndRow      int
+	StartColumn int
+	EndColumn   int
+}
+
+// A1 formats the range in A1 notation, quoting the sheet name when needed.
+// A range open on both ends is written as the whole sheet.
+func (r CellRange) A1() string {
+	var start, end string
+	switch {
+	case r.EndColumn == 0 && r.EndRow == 0:
+		return QuoteSheetName(r.Sheet)
+	case r.EndColumn == 0:
+		// Whole rows, such as "3:5"
+		start, end = strconv.Itoa(r.StartRow+1), strconv.Itoa(r.EndRow)
+	case r.EndRow == 0 && r.StartRow == 0:
+		// Whole columns, such as "A:K"
+		start, end = ColumnName(r.StartColumn), ColumnName(r.EndColumn-1)
+	case r.EndRow == 0:
+		// Columns from a row down, such as "A2:K"
+		start, end = ColumnName(r.StartColumn)+strconv.Itoa(r.StartRow+1), ColumnName(r.EndColumn-1)
+	default:
+		start = ColumnName(r.StartColumn) + strconv.Itoa(r.StartRow+1)
+		end = ColumnName(r.EndColumn-1) + strconv.Itoa(r.EndRow)
+	}
+	cells := start + ":" + end
+	if r.Sheet == "" {
+		return cells
+	}
+	return QuoteSheetName(r.Sheet) + "!" + cells
+}
+
+// GridRange converts the range to a GridRange on the sheet with the given ID
+func (r CellRange) GridRange(sheetID int64) *sheets.GridRange {
+	return &sheets.GridRange{
+		SheetId:          sheetID,
+		StartRowIndex:    int64(r.StartRow),
+		EndRowIndex:      int64(r.EndRow),
+		StartColumnIndex: int64(r.StartColumn),
+		EndColumnIndex:   int64(r.EndColumn),
+	}
+}
+
+// ColumnName returns the letters of a 0-based column index, so 0 is "A",
+// 25 is "Z" and 26 is "AA"
+func ColumnName(index int) string {
+	var name []byte
+	for n := index + 1; n > 0; n = (n - 1) / 26 {
+		name = append([]byte{byte('A' + (n-1)%26)}, name...)
+	}
+	return string(name)
+}
+
+// ColumnIndex returns the 0-based index of uppercase column letters such as "AA"
+func ColumnIndex(name string) (int, error) {
+	if name == "" {
+		return 0, errors.New("empty column name")
+	}
+	index := 0
+	for _, c := range name {
+		if c < 'A' || c > 'Z' {
+			return 0, fmt.Errorf("invalid column name %q", name)
+		}
+		index = index*26 + int(c-'A') + 1
+		if index > 18278 { // ZZZ, the widest column Google Sheets allows
+			return 0, fmt.Errorf("column %q is out of range", name)
+		}
+	}
+	return index - 1, nil
+}
+
+// QuoteSheetName quotes a sheet name for A1 notation unless it is made only
+// of letters, digits and underscores. Apostrophes are doubled.
+func QuoteSheetName(name string) string {
+	for _, c := range name {
+		if !(c >= 'A' && c <= 'Z' || c >= 'a' && c <= 'z' || c >= '0' && c <= '9' || c == '_') {
+			return "'" + strings.ReplaceAll(name, "'", "''") + "'"
+		}
+	}
+	if row, column, err := parseCellRef(strings.ToUpper(name)); err == nil && row >= 0 && column >= 0 {
+		// Names like "A1" or "q3" would be read as cells
+		return "'" + name + "'"
+	}
+	return name
+}
+
+// parseCellRef parses a cell reference such as "B3", a column such as "B" or
+// a row such as "3" into 0-based indexes, with -1 for a missing part. Column
+// letters must be uppercase.
+func parseCellRef(ref string) (int, int, error) {
+	letters := 0
+	for letters < len(ref) && ref[letters] >= 'A' && ref[letters] <= 'Z' {
+		letters++
+	}
+	row, column := -1, -1
+	if letters > 0 {
+		var err error
+		if column, err = ColumnIndex(ref[:letters]); err != nil {
+			return 0, 0, err
+		}
+	}
+	if digits := ref[letters:]; digits != "" {
+		n, err := strconv.Atoi(digits)
+		if err != nil || n < 1 || digits[0] == '+' {
+			return 0, 0, fmt.Errorf("invalid cell reference %q", ref)
+		}
+		row = n - 1
+	}
+	if row < 0 && column < 0 {
+		return 0, 0, fmt.Errorf("invalid cell reference %q", ref)
+	}
+	return row, column, nil
+}
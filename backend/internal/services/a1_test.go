@@ -0,0 +1,58 @@
+package services
+
+import (
+	"reflect"
+	"testing"
+
+	"google.golang.org/api/sheets/v4"
+)
+
+func TestCellRangeA1(t *testing.T) {
+	tests := []struct {
+		cells CellRange
+		want  string
+	}{
+		{CellRange{Sheet: "Sheet1", EndColumn: 11}, "Sheet1!A:K"},
+		{CellRange{Sheet: "Sheet1", StartRow: 1, EndColumn: 11}, "Sheet1!A2:K"},
+		{CellRange{Sheet: "Sheet1", StartRow: 1000, EndRow: 2000, EndColumn: 11}, "Sheet1!A1001:K2000"},
+		{CellRange{Sheet: "Sheet1", StartRow: 2, EndRow: 5}, "Sheet1!3:5"},
+		{CellRange{Sheet: "Form Responses 1", StartColumn: 701, EndColumn: 704}, "'Form Responses 1'!ZZ:AAB"},
+		{CellRange{Sheet: "Bob's list"}, "'Bob''s list'"},
+		{CellRange{Sheet: "Q3", EndRow: 1, EndColumn: 1}, "'Q3'!A1:A1"},
+		{CellRange{Sheet: "q3"}, "'q3'"},
+		{CellRange{Sheet: "Foo"}, "Foo"},
+		{CellRange{StartRow: 4, EndRow: 5, StartColumn: 9, EndColumn: 11}, "J5:K5"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.want, func(t *testing.T) {
+			if got := tt.cells.A1(); got != tt.want {
+				t.Errorf("A1() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCellRangeGridRange(t *testing.T) {
+	cells := CellRange{Sheet: "My Sheet", StartRow: 9, EndRow: 12, StartColumn: 26, EndColumn: 28}
+	grid := cells.GridRange(42)
+	want := &sheets.GridRange{SheetId: 42, StartRowIndex: 9, EndRowIndex: 12, StartColumnIndex: 26, EndColumnIndex: 28}
+	if !reflect.DeepEqual(grid, want) {
+		t.Errorf("GridRange() = %+v, want %+v", grid, want)
+	}
+}
+
+func TestColumnNames(t *testing.T) {
+	for index, name := range map[int]string{0: "A", 25: "Z", 26: "AA", 51: "AZ", 52: "BA", 701: "ZZ", 702: "AAA", 18277: "ZZZ"} {
+		if got := ColumnName(index); got != name {
+			t.Errorf("ColumnName(%d) = %q, want %q", index, got, name)
+		}
+		if got, err := ColumnIndex(name); err != nil || got != index {
+			t.Errorf("ColumnIndex(%q) = %d, %v, want %d", name, got, err, index)
+		}
+	}
+	for _, name := range []string{"", "A1", "AAAA", "aa"} {
+		if _, err := ColumnIndex(name); err == nil {
+			t.Errorf("ColumnIndex(%q) succeeded, want an error", name)
+		}
+	}
+}
@@ -0,0 +1,295 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/stevebennett/slack-invite-mgr/backend/internal/config"
+	"google.golang.org/api/sheets/v4"
+)
+
+// ArchiveOptions selects the invites ArchiveInvites moves
+type ArchiveOptions struct {
+	// Before archives invites decided, or failing that submitted, before this time
+	Before time.Time
+	// PerYear archives to a tab per decision year, such as "Archive 2024"
+	PerYear bool
+	// DryRun counts the invites that would be archived without moving them
+	DryRun bool
+}
+
+// archiveRun is a block of adjacent rows moving to the same archive tab
+type archiveRun struct {
+	tab        string
+	start, end int
+}
+
+// IsTerminalStatus reports whether an invite in the status needs no further
+// review. Only terminal invites are archived.
+func IsTerminalStatus(status string) bool {
+	return status == StatusSent || status == StatusDenied || status == StatusDuplicate
+}
+
+// ArchiveInvites moves terminal invites older than opts.Before from the sheet
+// to the archive tab, creating it with a copy of the header row if needed.
+// Each row is copied, with its formatting, to the end of the archive and then
+// deleted, in batches that apply atomically. It returns how many invites were
+// archived to each tab, including those in batches sent before an error.
+//
+// Rows are found by position, so nothing else should write to the sheet while
+// invites are being archived. Writes from the same process wait for it.
+func (s *SheetsService) ArchiveInvites(ctx context.Context, opts ArchiveOptions) (map[string]int, error) {
+	if s.isArchiveTab(s.cfg.SheetName) {
+		return nil, fmt.Errorf("cannot archive sheet '%s' to itself", s.cfg.SheetName)
+	}
+	lock := s.rowLock()
+	lock.Lock()
+	defer lock.Unlock()
+
+	spreadsheet, err := s.service.SpreadsheetsGet(ctx, s.cfg.SpreadsheetID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get spreadsheet metadata: %w", err)
+	}
+	tabs := make(map[string]*sheets.SheetProperties)
+	for _, sheet := range spreadsheet.Sheets {
+		if sheet.Properties != nil {
+			tabs[sheet.Properties.Title] = sheet.Properties
+		}
+	}
+	source, ok := tabs[s.cfg.SheetName]
+	if !ok {
+		return nil, fmt.Errorf("sheet with name '%s' not found", s.cfg.SheetName)
+	}
+
+	resp, err := s.service.Get(ctx, s.cfg.SpreadsheetID, CellRange{Sheet: s.cfg.SheetName, EndColumn: inviteColumnCount}.A1())
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve sheet data: %w", err)
+	}
+
+	// Group the rows to archive into runs, skipping the header row
+	counts := make(map[string]int)
+	var runs []archiveRun
+	for i := 1; i < len(resp.Values); i++ {
+		tab, ok := s.archiveTab(resp.Values[i], opts)
+		if !ok {
+			continue
+		}
+		counts[tab]++
+		if n := len(runs); n > 0 && runs[n-1].tab == tab && runs[n-1].end == i {
+			runs[n-1].end++
+			continue
+		}
+		runs = append(runs, archiveRun{tab: tab, start: i, end: i + 1})
+	}
+	if opts.DryRun || len(runs) == 0 {
+		return counts, nil
+	}
+
+	// Create missing archive tabs, sized to take just the header row
+	var missing []string
+	for tab := range counts {
+		if _, ok := tabs[tab]; !ok {
+			missing = append(missing, tab)
+		}
+	}
+	sort.Strings(missing)
+	if len(missing) > 0 {
+		if err := s.addArchiveTabs(ctx, source, missing, tabs); err != nil {
+			return nil, err
+		}
+	}
+
+	// Copy and delete each run, keeping track of the rows already deleted
+	// above later runs and the next free row of each archive tab
+	nextRow := make(map[string]int)
+	for tab := range counts {
+		nextRow[tab] = int(tabs[tab].GridProperties.RowCount)
+	}
+	archived := make(map[string]int)
+	removed := 0
+	runsPerBatch := maxBatchRequests / 3
+	for len(runs) > 0 {
+		batch := runs[:min(runsPerBatch, len(runs))]
+		runs = runs[len(batch):]
+
+		var requests, deletes []*sheets.Request
+		for _, run := range batch {
+			size := run.end - run.start
+			start := run.start - removed
+			target := tabs[run.tab].SheetId
+			requests = append(requests,
+				&sheets.Request{AppendDimension: &sheets.AppendDimensionRequest{SheetId: target, Dimension: "ROWS", Length: int64(size)}},
+				&sheets.Request{CopyPaste: &sheets.CopyPasteRequest{
+					Source:      CellRange{StartRow: start, EndRow: start + size, EndColumn: int(source.GridProperties.ColumnCount)}.GridRange(source.SheetId),
+					Destination: CellRange{StartRow: nextRow[run.tab], EndRow: nextRow[run.tab] + size, EndColumn: int(source.GridProperties.ColumnCount)}.GridRange(target),
+					PasteType:   "PASTE_NORMAL",
+				}},
+			)
+			nextRow[run.tab] += size
+			// Delete from the bottom so earlier runs keep their positions
+			deletes = append([]*sheets.Request{{DeleteDimension: &sheets.DeleteDimensionRequest{
+				Range: &sheets.DimensionRange{SheetId: source.SheetId, Dimension: "ROWS", StartIndex: int64(start), EndIndex: int64(start + size)},
+			}}}, deletes...)
+		}
+		_, err := s.service.BatchUpdate(ctx, s.cfg.SpreadsheetID, &sheets.BatchUpdateSpreadsheetRequest{
+			Requests: append(requests, deletes...),
+		})
+		if err != nil {
+			return archived, fmt.Errorf("failed to archive invites: %w", err)
+		}
+		for _, run := range batch {
+			archived[run.tab] += run.end - run.start
+			removed += run.end - run.start
+		}
+	}
+	return archived, nil
+}
+
+// archiveTab returns the tab a row is archived to, or false if it is not
+// terminal or not older than opts.Before
+func (s *SheetsService) archiveTab(row []interface{}, opts ArchiveOptions) (string, bool) {
+	decided, ok := decidedBefore(row, opts.Before, s.cfg.Location)
+	if !ok {
+		return "", false
+	}
+	if opts.PerYear {
+		return s.archiveSheetName() + " " + strconv.Itoa(decided.Year()), true
+	}
+	return s.archiveSheetName(), true
+}
+
+// decidedBefore returns when a terminal invite was decided, from column K or
+// column A when that is missing, if that was before the cutoff. The columns
+// are read in loc.
+func decidedBefore(row []interface{}, cutoff time.Time, loc *time.Location) (time.Time, bool) {
+	if !IsTerminalStatus(NormalizeStatus(cellString(row, columnStatus))) {
+		return time.Time{}, false
+	}
+	decided, ok := ParseTimestampInLocation(cellString(row, columnStatusUpdated), loc)
+	if !ok {
+		if decided, ok = ParseTimestampInLocation(cellString(row, 0), loc); !ok {
+			return time.Time{}, false
+		}
+	}
+	return decided, decided.Before(cutoff)
+}
+
+// addArchiveTabs creates archive tabs with a frozen copy of the source's
+// header row and adds their properties to tabs
+func (s *SheetsService) addArchiveTabs(ctx context.Context, source *sheets.SheetProperties, names []string, tabs map[string]*sheets.SheetProperties) error {
+	columns := source.GridProperties.ColumnCount
+	var requests []*sheets.Request
+	for _, name := range names {
+		requests = append(requests, &sheets.Request{AddSheet: &sheets.AddSheetRequest{
+			Properties: &sheets.SheetProperties{
+				Title:          name,
+				GridProperties: &sheets.GridProperties{RowCount: 1, ColumnCount: columns, FrozenRowCount: 1},
+			},
+		}})
+	}
+	resp, err := s.service.BatchUpdate(ctx, s.cfg.SpreadsheetID, &sheets.BatchUpdateSpreadsheetRequest{Requests: requests})
+	if err != nil {
+		return fmt.Errorf("failed to create archive tabs: %w", err)
+	}
+
+	requests = nil
+	for _, reply := range resp.Replies {
+		if reply.AddSheet == nil || reply.AddSheet.Properties == nil {
+			return errors.New("failed to create archive tabs: no sheet in reply")
+		}
+		properties := reply.AddSheet.Properties
+		tabs[properties.Title] = properties
+		requests = append(requests, &sheets.Request{CopyPaste: &sheets.CopyPasteRequest{
+			Source:      CellRange{EndRow: 1, EndColumn: int(columns)}.GridRange(source.SheetId),
+			Destination: CellRange{EndRow: 1, EndColumn: int(columns)}.GridRange(properties.SheetId),
+			PasteType:   "PASTE_NORMAL",
+		}})
+	}
+	if _, err := s.service.BatchUpdate(ctx, s.cfg.SpreadsheetID, &sheets.BatchUpdateSpreadsheetRequest{Requests: requests}); err != nil {
+		return fmt.Errorf("failed to copy header to archive tabs: %w", err)
+	}
+	return nil
+}
+
+// GetArchivedSheetData retrieves every row from the archive tab and any
+// per-year archive tabs, padded to columns A-K. Header rows are included.
+func (s *SheetsService) GetArchivedSheetData(ctx context.Context) ([][]interface{}, error) {
+	spreadsheet, err := s.service.SpreadsheetsGet(ctx, s.cfg.SpreadsheetID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get spreadsheet metadata: %w", err)
+	}
+	var rows [][]interface{}
+	_, archives := s.inviteTabs(spreadsheet)
+	for _, tab := range archives {
+		resp, err := s.service.Get(ctx, s.cfg.SpreadsheetID, CellRange{Sheet: tab.Title, EndColumn: inviteColumnCount}.A1())
+		if err != nil {
+			return nil, fmt.Errorf("failed to retrieve archive data: %w", err)
+		}
+		for _, row := range resp.Values {
+			for len(row) < inviteColumnCount {
+				row = append(row, "")
+			}
+			rows = append(rows, row)
+		}
+	}
+	return rows, nil
+}
+
+// InviteHistory returns the rows of the sheet, processed ones included,
+// followed by those of the archive tabs, so that reports and duplicate checks
+// cover every invite ever received
+func InviteHistory(ctx context.Context, s SheetsServiceInterface) ([][]interface{}, error) {
+	rows, err := s.GetAllSheetData(ctx)
+	if err != nil {
+		return nil, err
+	}
+	archived, err := s.GetArchivedSheetData(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return append(rows, archived...), nil
+}
+
+// inviteTabs returns the properties of the sheet, or nil if it does not
+// exist, and of the archive tabs in spreadsheet order
+func (s *SheetsService) inviteTabs(spreadsheet *sheets.Spreadsheet) (*sheets.SheetProperties, []*sheets.SheetProperties) {
+	var sheet *sheets.SheetProperties
+	var archives []*sheets.SheetProperties
+	for _, tab := range spreadsheet.Sheets {
+		switch {
+		case tab.Properties == nil:
+		case tab.Properties.Title == s.cfg.SheetName:
+			sheet = tab.Properties
+		case s.isArchiveTab(tab.Properties.Title):
+			archives = append(archives, tab.Properties)
+		}
+	}
+	return sheet, archives
+}
+
+// isArchiveTab reports whether a tab is the archive tab or a per-year one
+func (s *SheetsService) isArchiveTab(title string) bool {
+	name := s.archiveSheetName()
+	if title == name {
+		return true
+	}
+	year, ok := strings.CutPrefix(title, name+" ")
+	if !ok || len(year) != 4 {
+		return false
+	}
+	_, err := strconv.Atoi(year)
+	return err == nil
+}
+
+// archiveSheetName returns the configured archive tab name
+func (s *SheetsService) archiveSheetName() string {
+	if s.cfg.ArchiveSheetName == "" {
+		return config.DefaultArchiveSheetName
+	}
+	return s.cfg.ArchiveSheetName
+}
@@ -0,0 +1,198 @@
+package services
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Audit actions
+const (
+	// AuditStatusChanged records an invite status change made through the API
+	AuditStatusChanged = "status_changed"
+	// AuditDecisionEmail records an attempt to email an applicant about a decision
+	AuditDecisionEmail = "decision_email"
+	// AuditApplicantErased records a request to erase an applicant's rows.
+	// The entry holds a hash of the email rather than the address itself.
+	AuditApplicantErased = "applicant_erased"
+)
+
+// Audit results
+const (
+	AuditResultSuccess = "success"
+	AuditResultFailed  = "failed"
+	// AuditResultQueued records an email left in the outbox, and
+	// AuditResultDelivered its delivery by the outbox worker
+	AuditResultQueued    = "queued"
+	AuditResultDelivered = "delivered"
+)
+
+// memoryAuditLogSize bounds the audit trail when it is not backed by a file
+const memoryAuditLogSize = 5000
+
+// AuditEntry is a single record in the audit trail
+type AuditEntry struct {
+	ID             string    `json:"id"`
+	Timestamp      time.Time `json:"timestamp"`
+	Action         string    `json:"action"`
+	Email          string    `json:"email,omitempty"`
+	Status         string    `json:"status,omitempty"`
+	PreviousStatus string    `json:"previousStatus,omitempty"`
+	RequestID      string    `json:"requestId,omitempty"`
+	Result         string    `json:"result,omitempty"`
+	Error          string    `json:"error,omitempty"`
+	// Details holds action-specific context, such as an email subject
+	Details map[string]string `json:"details,omitempty"`
+}
+
+// EmailHash returns the hex SHA-256 of a normalized email, which identifies
+// an applicant in the audit trail without keeping their address
+func EmailHash(email string) string {
+	sum := sha256.Sum256([]byte(NormalizeEmail(email)))
+	return hex.EncodeToString(sum[:])
+}
+
+// AuditFilter selects audit entries. Empty fields match everything.
+type AuditFilter struct {
+	Email  string
+	Action string
+	Limit  int
+}
+
+// AuditLog is an append-only record of changes made to invites and of the
+// emails sent about them. Like DeliveryLog it is kept in a JSON lines file
+// when a path is configured, or in memory otherwise.
+type AuditLog struct {
+	mu      sync.Mutex
+	path    string
+	entries []AuditEntry
+	now     func() time.Time
+}
+
+// NewAuditLog creates an audit log backed by path, or kept in memory if path is empty
+func NewAuditLog(path string) *AuditLog {
+	return &AuditLog{path: path, now: time.Now}
+}
+
+// Record appends an entry, filling in its ID and timestamp if they are unset
+func (l *AuditLog) Record(entry AuditEntry) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if entry.ID == "" {
+		entry.ID = uuid.New().String()
+	}
+	if entry.Timestamp.IsZero() {
+		entry.Timestamp = l.now()
+	}
+
+	if l.path == "" {
+		l.entries = append(l.entries, entry)
+		if len(l.entries) > memoryAuditLogSize {
+			l.entries = l.entries[len(l.entries)-memoryAuditLogSize:]
+		}
+		return nil
+	}
+
+	return appendJSONLine(l.path, entry)
+}
+
+// List returns the entries matching filter, newest first
+func (l *AuditLog) List(filter AuditFilter) ([]AuditEntry, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	entries := l.entries
+	if l.path != "" {
+		var err error
+		if entries, err = readJSONLines[AuditEntry](l.path); err != nil {
+			return nil, err
+		}
+	}
+
+	email := NormalizeEmail(filter.Email)
+	result := []AuditEntry{}
+	for i := len(entries) - 1; i >= 0; i-- {
+		entry := entries[i]
+		if email != "" && NormalizeEmail(entry.Email) != email {
+			continue
+		}
+		if filter.Action != "" && !strings.EqualFold(entry.Action, filter.Action) {
+			continue
+		}
+		result = append(result, entry)
+		if filter.Limit > 0 && len(result) == filter.Limit {
+			break
+		}
+	}
+	return result, nil
+}
+
+// Erase pseudonymizes every entry about email, as Redact does, so the
+// applicant can no longer be found in the audit trail. It returns how many
+// entries were changed.
+func (l *AuditLog) Erase(email string) (int, error) {
+	email = NormalizeEmail(email)
+	return l.pseudonymize(false, func(entry AuditEntry) bool {
+		return email != "" && NormalizeEmail(entry.Email) == email
+	})
+}
+
+// Redact pseudonymizes the entries recorded before the cutoff: each email
+// address is replaced by its EmailHash in details.emailHash, and email
+// subjects, which may quote the applicant, are dropped. It returns how many
+// entries were changed, or with dryRun would be.
+func (l *AuditLog) Redact(before time.Time, dryRun bool) (int, error) {
+	return l.pseudonymize(dryRun, func(entry AuditEntry) bool {
+		return entry.Timestamp.Before(before)
+	})
+}
+
+// pseudonymize rewrites the entries with an email address that match
+func (l *AuditLog) pseudonymize(dryRun bool, match func(AuditEntry) bool) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	entries := slices.Clone(l.entries)
+	if l.path != "" {
+		// Hold the file's lock until it is rewritten, so appends wait
+		unlock, err := lockFile(l.path)
+		if err != nil {
+			return 0, err
+		}
+		defer unlock()
+		if entries, err = readJSONLines[AuditEntry](l.path); err != nil {
+			return 0, err
+		}
+	}
+
+	changed := 0
+	for i, entry := range entries {
+		if entry.Email == "" || !match(entry) {
+			continue
+		}
+		changed++
+		details := map[string]string{"emailHash": EmailHash(entry.Email)}
+		for key, value := range entry.Details {
+			if key != "subject" {
+				details[key] = value
+			}
+		}
+		entry.Error = strings.ReplaceAll(entry.Error, entry.Email, RedactedValue)
+		entry.Email, entry.Details = "", details
+		entries[i] = entry
+	}
+	if changed == 0 || dryRun {
+		return changed, nil
+	}
+	if l.path != "" {
+		return changed, writeJSONLines(l.path, entries)
+	}
+	l.entries = entries
+	return changed, nil
+}
@@ -0,0 +1,105 @@
+package services
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestAuditLog_EraseAndRedact(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		path string
+	}{
+		{"in memory", ""},
+		{"file", filepath.Join(t.TempDir(), "audit.jsonl")},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			old := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+			recent := old.AddDate(0, 6, 0)
+			log := NewAuditLog(tc.path)
+			for _, entry := range []AuditEntry{
+				{Timestamp: old, Action: AuditStatusChanged, Email: "john@example.com", Status: StatusSent},
+				{Timestamp: recent, Action: AuditDecisionEmail, Email: "John@Example.com", Result: AuditResultFailed,
+					Error: "rejected John@Example.com", Details: map[string]string{"subject": "Hi John", "template": "approved"}},
+				{Timestamp: old, Action: AuditStatusChanged, Email: "jane@example.com", Status: StatusDenied},
+				{Timestamp: recent, Action: AuditStatusChanged, Email: "sam@example.com", Status: StatusSent},
+			} {
+				if err := log.Record(entry); err != nil {
+					t.Fatalf("Unexpected error: %v", err)
+				}
+			}
+
+			erased, err := log.Erase("JOHN@example.com")
+			if err != nil || erased != 2 {
+				t.Fatalf("Expected 2 entries erased, got %d %v", erased, err)
+			}
+			if entries, _ := log.List(AuditFilter{Email: "john@example.com"}); len(entries) != 0 {
+				t.Errorf("Expected no entries for the erased email, got %+v", entries)
+			}
+			entries, _ := log.List(AuditFilter{Action: AuditDecisionEmail})
+			if len(entries) != 1 {
+				t.Fatalf("Expected the email entry to be kept, got %+v", entries)
+			}
+			entry := entries[0]
+			if entry.Email != "" || entry.Error != "rejected "+RedactedValue {
+				t.Errorf("Expected the email to be removed, got %+v", entry)
+			}
+			if entry.Details["emailHash"] != EmailHash("john@example.com") || entry.Details["template"] != "approved" {
+				t.Errorf("Expected the hash and other details, got %v", entry.Details)
+			}
+			if _, ok := entry.Details["subject"]; ok {
+				t.Errorf("Expected the subject to be dropped, got %v", entry.Details)
+			}
+
+			// Only jane's entry is older than the cutoff and still has an email
+			cutoff := old.AddDate(0, 1, 0)
+			if redacted, err := log.Redact(cutoff, true); err != nil || redacted != 1 {
+				t.Fatalf("Expected 1 entry to redact, got %d %v", redacted, err)
+			}
+			if entries, _ := log.List(AuditFilter{Email: "jane@example.com"}); len(entries) != 1 {
+				t.Errorf("Expected a dry run to keep the entry, got %+v", entries)
+			}
+			if redacted, err := log.Redact(cutoff, false); err != nil || redacted != 1 {
+				t.Fatalf("Expected 1 entry redacted, got %d %v", redacted, err)
+			}
+			if entries, _ := log.List(AuditFilter{Email: "jane@example.com"}); len(entries) != 0 {
+				t.Errorf("Expected the entry to be redacted, got %+v", entries)
+			}
+			if entries, _ := log.List(AuditFilter{Email: "sam@example.com"}); len(entries) != 1 {
+				t.Errorf("Expected the recent entry to be kept, got %+v", entries)
+			}
+			if entries, _ := log.List(AuditFilter{}); len(entries) != 4 {
+				t.Errorf("Expected every entry to be kept, got %d", len(entries))
+			}
+		})
+	}
+}
+
+func TestAuditLog_RecordWaitsForRewrite(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	log := NewAuditLog(path)
+
+	// Another process rewriting the file holds its lock
+	unlock, err := lockFile(path)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	recorded := make(chan error, 1)
+	go func() {
+		recorded <- log.Record(AuditEntry{Action: AuditStatusChanged, Email: "jane@example.com"})
+	}()
+	select {
+	case <-recorded:
+		t.Fatal("Expected Record to wait for the lock")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	unlock()
+	if err := <-recorded; err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if entries, _ := log.List(AuditFilter{}); len(entries) != 1 {
+		t.Errorf("Expected the entry once the lock was released, got %+v", entries)
+	}
+}
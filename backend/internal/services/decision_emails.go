@@ -0,0 +1,162 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	htmltemplate "html/template"
+	"log/slog"
+	"net/mail"
+	"sync"
+	texttemplate "text/template"
+	"time"
+
+	"github.com/stevebennett/slack-invite-mgr/backend/internal/config"
+)
+
+// decisionEmailTimeout bounds how long a single decision email may take to send
+const decisionEmailTimeout = 30 * time.Second
+
+// EmailSender sends a single HTML email
+type EmailSender interface {
+	SendTo(ctx context.Context, to, subject, body string) error
+}
+
+// EmailQueuer is an EmailSender that may queue email in an outbox instead,
+// returning the ID of the queued message, or an empty ID if it was sent
+// immediately. The outbox worker records the delivery as a copy of audit.
+type EmailQueuer interface {
+	QueueTo(ctx context.Context, to, subject, body string, audit AuditEntry) (string, error)
+}
+
+// decisionTemplate is a compiled applicant email template
+type decisionTemplate struct {
+	subject *texttemplate.Template
+	body    *htmltemplate.Template
+}
+
+// DecisionMailer emails applicants when their invite moves to a status with
+// an enabled template, and records each attempt in the audit trail
+type DecisionMailer struct {
+	templates map[string]decisionTemplate
+	sender    EmailSender
+	audit     *AuditLog
+	logger    *slog.Logger
+	wg        sync.WaitGroup
+}
+
+// NewDecisionMailer compiles the enabled templates. Templates are executed
+// with the Invite, so they can refer to fields such as {{.Name}}.
+func NewDecisionMailer(templates map[string]config.ApplicantEmailTemplate, sender EmailSender, audit *AuditLog, logger *slog.Logger) (*DecisionMailer, error) {
+	m := &DecisionMailer{
+		templates: make(map[string]decisionTemplate),
+		sender:    sender,
+		audit:     audit,
+		logger:    logger,
+	}
+
+	for status, tmpl := range templates {
+		if !IsKnownStatus(status) {
+			return nil, fmt.Errorf("applicant email for unknown status %q", status)
+		}
+		if !tmpl.Enabled {
+			continue
+		}
+		subject, err := texttemplate.New(status).Option("missingkey=error").Parse(tmpl.Subject)
+		if err != nil {
+			return nil, fmt.Errorf("applicant email %q: invalid subject: %w", status, err)
+		}
+		body, err := htmltemplate.New(status).Option("missingkey=error").Parse(tmpl.Body)
+		if err != nil {
+			return nil, fmt.Errorf("applicant email %q: invalid body: %w", status, err)
+		}
+		m.templates[status] = decisionTemplate{subject: subject, body: body}
+	}
+
+	return m, nil
+}
+
+// Enabled reports whether applicants are emailed when moved to status
+func (m *DecisionMailer) Enabled(status string) bool {
+	_, ok := m.templates[status]
+	return ok
+}
+
+// Notify emails the applicant about their invite's current status in the
+// background, if that status has an enabled template. requestID links the
+// audit entry to the API request that made the change.
+func (m *DecisionMailer) Notify(invite Invite, requestID string) {
+	tmpl, ok := m.templates[invite.Status]
+	if !ok {
+		return
+	}
+
+	m.wg.Add(1)
+	go func() {
+		defer m.wg.Done()
+		ctx, cancel := context.WithTimeout(context.Background(), decisionEmailTimeout)
+		defer cancel()
+		m.send(ctx, tmpl, invite, requestID)
+	}()
+}
+
+// Wait blocks until every pending email has been sent or has failed
+func (m *DecisionMailer) Wait() {
+	m.wg.Wait()
+}
+
+// send renders and sends one decision email and records the outcome. An
+// email left in an outbox is recorded as queued, and the outbox worker
+// records its delivery.
+func (m *DecisionMailer) send(ctx context.Context, tmpl decisionTemplate, invite Invite, requestID string) {
+	entry := AuditEntry{
+		Action:    AuditDecisionEmail,
+		Email:     invite.Email,
+		Status:    invite.Status,
+		RequestID: requestID,
+	}
+
+	var messageID string
+	subject, body, err := renderDecisionEmail(tmpl, invite)
+	if err == nil {
+		entry.Details = map[string]string{"subject": subject}
+		if _, parseErr := mail.ParseAddress(invite.Email); parseErr != nil {
+			err = fmt.Errorf("invalid applicant email address: %w", parseErr)
+		} else if queuer, ok := m.sender.(EmailQueuer); ok {
+			messageID, err = queuer.QueueTo(ctx, invite.Email, subject, body, entry)
+		} else {
+			err = m.sender.SendTo(ctx, invite.Email, subject, body)
+		}
+	}
+
+	log := m.logger.With(slog.String("status", invite.Status), slog.String("request_id", requestID))
+	switch {
+	case err != nil:
+		entry.Result = AuditResultFailed
+		entry.Error = err.Error()
+		log.Error("failed to send decision email", slog.String("error", err.Error()))
+	case messageID != "":
+		entry.Result = AuditResultQueued
+		entry.Details = map[string]string{"subject": subject, "outboxMessageId": messageID}
+		log.Info("decision email queued", slog.String("message_id", messageID))
+	default:
+		entry.Result = AuditResultSuccess
+		log.Info("decision email sent")
+	}
+
+	if auditErr := m.audit.Record(entry); auditErr != nil {
+		log.Error("failed to record decision email", slog.String("error", auditErr.Error()))
+	}
+}
+
+// renderDecisionEmail returns the rendered subject and body
+func renderDecisionEmail(tmpl decisionTemplate, invite Invite) (string, string, error) {
+	var subject, body bytes.Buffer
+	if err := tmpl.subject.Execute(&subject, invite); err != nil {
+		return "", "", fmt.Errorf("failed to render subject: %w", err)
+	}
+	if err := tmpl.body.Execute(&body, invite); err != nil {
+		return "", "", fmt.Errorf("failed to render body: %w", err)
+	}
+	return subject.String(), body.String(), nil
+}
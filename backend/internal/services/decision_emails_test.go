@@ -0,0 +1,168 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/stevebennett/slack-invite-mgr/backend/internal/config"
+)
+
+type sentEmail struct {
+	to, subject, body string
+}
+
+type mockEmailSender struct {
+	sent []sentEmail
+	err  error
+}
+
+func (m *mockEmailSender) SendTo(ctx context.Context, to, subject, body string) error {
+	if m.err != nil {
+		return m.err
+	}
+	m.sent = append(m.sent, sentEmail{to: to, subject: subject, body: body})
+	return nil
+}
+
+// mockEmailQueuer queues email as an outbox would
+type mockEmailQueuer struct {
+	mockEmailSender
+}
+
+func (m *mockEmailQueuer) QueueTo(ctx context.Context, to, subject, body string, audit AuditEntry) (string, error) {
+	if err := m.SendTo(ctx, to, subject, body); err != nil {
+		return "", err
+	}
+	return "msg-1", nil
+}
+
+func TestDecisionMailer_Notify(t *testing.T) {
+	templates := map[string]config.ApplicantEmailTemplate{
+		StatusDenied: {
+			Enabled: true,
+			Subject: "Your application to join, {{.Name}}",
+			Body:    "<p>Sorry {{.Name}}, we can't invite {{.Company}} right now.</p>",
+		},
+		StatusSent: {
+			Enabled: false,
+			Subject: "Welcome",
+			Body:    "<p>Check your inbox for a Slack invite.</p>",
+		},
+	}
+
+	tests := []struct {
+		name           string
+		invite         Invite
+		sendErr        error
+		queue          bool
+		expectedSent   int
+		expectedResult string
+		expectedBody   string
+	}{
+		{
+			name:           "enabled template is sent and escaped",
+			invite:         Invite{Name: "Jane <script>", Company: "Acme", Email: "jane@example.com", Status: StatusDenied},
+			expectedSent:   1,
+			expectedResult: AuditResultSuccess,
+			expectedBody:   "<p>Sorry Jane &lt;script&gt;, we can't invite Acme right now.</p>",
+		},
+		{
+			name:           "queued email is recorded as queued",
+			invite:         Invite{Name: "Jane", Email: "jane@example.com", Status: StatusDenied},
+			queue:          true,
+			expectedSent:   1,
+			expectedResult: AuditResultQueued,
+		},
+		{
+			name:           "queue failure is recorded",
+			invite:         Invite{Name: "Jane", Email: "jane@example.com", Status: StatusDenied},
+			sendErr:        errors.New("disk full"),
+			queue:          true,
+			expectedResult: AuditResultFailed,
+		},
+		{
+			name:   "disabled template is not sent",
+			invite: Invite{Name: "Jane", Email: "jane@example.com", Status: StatusSent},
+		},
+		{
+			name:   "status without a template is not sent",
+			invite: Invite{Name: "Jane", Email: "jane@example.com", Status: StatusDuplicate},
+		},
+		{
+			name:           "send failure is recorded",
+			invite:         Invite{Name: "Jane", Email: "jane@example.com", Status: StatusDenied},
+			sendErr:        errors.New("smtp unavailable"),
+			expectedResult: AuditResultFailed,
+		},
+		{
+			name:           "invalid address is recorded",
+			invite:         Invite{Name: "Jane", Email: "not an email", Status: StatusDenied},
+			expectedResult: AuditResultFailed,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sender := &mockEmailSender{err: tt.sendErr}
+			var emailSender EmailSender = sender
+			if tt.queue {
+				queuer := &mockEmailQueuer{mockEmailSender{err: tt.sendErr}}
+				sender, emailSender = &queuer.mockEmailSender, queuer
+			}
+			audit := NewAuditLog("")
+			mailer, err := NewDecisionMailer(templates, emailSender, audit, slog.New(slog.NewTextHandler(io.Discard, nil)))
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			mailer.Notify(tt.invite, "req-1")
+			mailer.Wait()
+
+			if len(sender.sent) != tt.expectedSent {
+				t.Fatalf("Expected %d emails, got %d", tt.expectedSent, len(sender.sent))
+			}
+			if tt.expectedBody != "" && sender.sent[0].body != tt.expectedBody {
+				t.Errorf("Expected body %q, got %q", tt.expectedBody, sender.sent[0].body)
+			}
+
+			entries, _ := audit.List(AuditFilter{Action: AuditDecisionEmail})
+			if tt.expectedResult == "" {
+				if len(entries) != 0 {
+					t.Errorf("Expected no audit entries, got %+v", entries)
+				}
+				return
+			}
+			if len(entries) != 1 {
+				t.Fatalf("Expected 1 audit entry, got %+v", entries)
+			}
+			entry := entries[0]
+			if entry.Result != tt.expectedResult || entry.RequestID != "req-1" || entry.Email != tt.invite.Email {
+				t.Errorf("Unexpected audit entry: %+v", entry)
+			}
+			if (entry.Error != "") != (tt.expectedResult == AuditResultFailed) {
+				t.Errorf("Expected an error only for failures, got %q", entry.Error)
+			}
+			if (entry.Details["outboxMessageId"] == "msg-1") != (tt.expectedResult == AuditResultQueued) {
+				t.Errorf("Expected the outbox message only for queued email, got %v", entry.Details)
+			}
+		})
+	}
+}
+
+func TestNewDecisionMailer_InvalidTemplates(t *testing.T) {
+	tests := map[string]config.ApplicantEmailTemplate{
+		"approved":   {Enabled: true, Subject: "Hi", Body: "Hi"},
+		StatusDenied: {Enabled: true, Subject: "{{.Name", Body: "Hi"},
+	}
+
+	for status, tmpl := range tests {
+		_, err := NewDecisionMailer(map[string]config.ApplicantEmailTemplate{status: tmpl}, &mockEmailSender{}, NewAuditLog(""), slog.New(slog.NewTextHandler(io.Discard, nil)))
+		if err == nil || !strings.Contains(err.Error(), status) {
+			t.Errorf("Expected an error naming %q, got %v", status, err)
+		}
+	}
+}
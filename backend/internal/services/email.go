@@ -2,103 +2,190 @@ package services
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net/smtp"
 	"os"
 	"strings"
+	"time"
+
+	"github.com/stevebennett/slack-invite-mgr/backend/internal/config"
 )
 
 // EmailService handles operations related to sending emails
 type EmailService struct {
-	recipient string
-	from      string
-	username  string
-	password  string
-	template  string
+	routing  *config.EmailRouting
+	from     string
+	username string
+	password config.Secret
+	template string
+	// workspace names the Slack workspace in notification subjects
+	workspace string
+	// outbox, if set, queues messages instead of sending them immediately
+	outbox *Outbox
+	now    func() time.Time
 }
 
-// NewEmailService creates a new EmailService instance
-func NewEmailService(recipient string, templatePath string) *EmailService {
-	from := os.Getenv("SMTP2GO_FROM_EMAIL")
-	username := os.Getenv("SMTP2GO_USERNAME")
-	password := os.Getenv("SMTP2GO_PASSWORD")
-
+// LoadEmailService creates an EmailService sending through the SMTP2Go
+// account in cfg.SMTP, with notifications wrapped in cfg.EmailTemplate and
+// their subjects prefixed with cfg.SlackWorkspace, if set.
+// Routing decides who receives each notification event; it may be nil when
+// the service is only used with SendTo.
+func LoadEmailService(routing *config.EmailRouting, cfg *config.SheetsConfig) (*EmailService, error) {
 	// Validate required fields
-	if from == "" {
-		panic("SMTP2GO_FROM_EMAIL environment variable is not set")
-	}
-	if username == "" {
-		panic("SMTP2GO_USERNAME environment variable is not set")
+	if cfg.SMTP.FromEmail == "" {
+		return nil, errors.New("SMTP2GO_FROM_EMAIL is not set")
 	}
-	if password == "" {
-		panic("SMTP2GO_PASSWORD environment variable is not set")
+	if cfg.SMTP.Username == "" {
+		return nil, errors.New("SMTP2GO_USERNAME is not set")
 	}
-	if recipient == "" {
-		panic("recipient email address is required")
+	if cfg.SMTP.Password == "" {
+		return nil, errors.New("SMTP2GO_PASSWORD is not set")
 	}
 
 	// Basic email format validation
-	if !strings.Contains(from, "@") {
-		panic("SMTP2GO_FROM_EMAIL is not a valid email address")
+	if !strings.Contains(cfg.SMTP.FromEmail, "@") {
+		return nil, errors.New("SMTP2GO_FROM_EMAIL is not a valid email address")
 	}
-	if !strings.Contains(recipient, "@") {
-		panic("recipient is not a valid email address")
+
+	template, err := LoadEmailTemplate(cfg.EmailTemplate, cfg.DashboardURL)
+	if err != nil {
+		return nil, err
 	}
 
-	// Load email template if provided
-	var template string
-	if templatePath != "" {
-		templateBytes, err := os.ReadFile(templatePath)
-		if err != nil {
-			panic(fmt.Sprintf("failed to read email template: %v", err))
-		}
-		template = string(templateBytes)
+	return &EmailService{
+		routing:   routing,
+		from:      cfg.SMTP.FromEmail,
+		username:  cfg.SMTP.Username,
+		password:  cfg.SMTP.Password,
+		template:  template,
+		workspace: cfg.SlackWorkspace,
+		now:       time.Now,
+	}, nil
+}
 
-		// Replace dashboard URL placeholder if environment variable is set
-		dashboardURL := os.Getenv("DASHBOARD_URL")
-		if dashboardURL != "" {
-			template = strings.Replace(template, "{{DASHBOARD_URL}}", dashboardURL, -1)
+// SendEmail sends a notification to the recipients routed for event, such as
+// config.EmailEventErrors, with the body wrapped in the email template if one
+// was loaded
+func (s *EmailService) SendEmail(ctx context.Context, event, subject, body string) error {
+	if s.routing == nil {
+		return errors.New("no email recipients configured")
+	}
+	to, cc, bcc, ok := s.routing.Route(event)
+	if !ok {
+		return fmt.Errorf("no email recipients configured for %s", event)
+	}
+
+	if s.workspace != "" {
+		subject = "[" + s.workspace + "] " + subject
+	}
+	return s.Send(ctx, Recipients{To: to, CC: cc, BCC: bcc}, subject, ApplyEmailTemplate(s.template, body))
+}
+
+// LoadEmailTemplate reads the HTML email template at path, which wraps
+// notification bodies at its %s placeholder. The {{DASHBOARD_URL}}
+// placeholder is filled with dashboardURL, if set. An empty path returns an
+// empty template.
+func LoadEmailTemplate(path, dashboardURL string) (string, error) {
+	if path == "" {
+		return "", nil
+	}
+	templateBytes, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read email template: %w", err)
+	}
+	template := string(templateBytes)
+
+	// Replace dashboard URL placeholder if one is configured
+	if dashboardURL != "" {
+		template = strings.Replace(template, "{{DASHBOARD_URL}}", dashboardURL, -1)
+	}
+	return template, nil
+}
+
+// ApplyEmailTemplate wraps body in a template loaded by LoadEmailTemplate, or
+// returns body unchanged if the template is empty
+func ApplyEmailTemplate(template, body string) string {
+	if template == "" {
+		return body
+	}
+	return fmt.Sprintf(template, body)
+}
+
+// UseOutbox makes the service queue messages in outbox rather than sending
+// them immediately. An OutboxWorker must then deliver them.
+func (s *EmailService) UseOutbox(outbox *Outbox) {
+	s.outbox = outbox
+}
+
+// SendTo sends an HTML email to a single address. The body is sent as is.
+func (s *EmailService) SendTo(ctx context.Context, to, subject, body string) error {
+	return s.Send(ctx, Recipients{To: []string{to}}, subject, body)
+}
+
+// Send sends an HTML email to the given recipients. The body is sent as is.
+// When an outbox is in use the message is queued, and identical messages
+// queued within the outbox's dedupe window are dropped.
+func (s *EmailService) Send(ctx context.Context, recipients Recipients, subject, body string) error {
+	if len(recipients.All()) == 0 {
+		return errors.New("email has no recipients")
+	}
+
+	msg := s.message(recipients, subject, body)
+	if s.outbox != nil {
+		if _, _, err := s.outbox.Enqueue(msg); err != nil {
+			return fmt.Errorf("failed to queue email: %w", err)
 		}
+		return nil
 	}
+	return s.Deliver(ctx, msg)
+}
 
-	return &EmailService{
-		recipient: recipient,
-		from:      from,
-		username:  username,
-		password:  password,
-		template:  template,
+// QueueTo is SendTo for email whose outcome is audited. When an outbox is in
+// use, the message is queued with audit and its ID returned, and the
+// OutboxWorker records the delivery; otherwise it is sent immediately and the
+// ID is empty.
+func (s *EmailService) QueueTo(ctx context.Context, to, subject, body string, audit AuditEntry) (string, error) {
+	if s.outbox == nil {
+		return "", s.SendTo(ctx, to, subject, body)
+	}
+	queued, _, err := s.outbox.EnqueueAudited(s.message(Recipients{To: []string{to}}, subject, body), &audit)
+	if err != nil {
+		return "", fmt.Errorf("failed to queue email: %w", err)
 	}
+	return queued.ID, nil
 }
 
-// SendEmail sends an email to the specified address with the given subject and body
-func (s *EmailService) SendEmail(ctx context.Context, subject, body string) error {
+// message builds an email from the service's sender address
+func (s *EmailService) message(recipients Recipients, subject, body string) EmailMessage {
+	return EmailMessage{
+		MessageID:  newMessageID(s.from),
+		Recipients: recipients,
+		Subject:    subject,
+		Body:       body,
+		Date:       s.now(),
+	}
+}
+
+// Deliver sends an HTML email over SMTP immediately, bypassing any outbox
+func (s *EmailService) Deliver(ctx context.Context, msg EmailMessage) error {
 	// SMTP2Go settings
 	host := "mail.smtp2go.com"
 	port := "587"
-	auth := smtp.PlainAuth("", s.username, s.password, host)
+	auth := smtp.PlainAuth("", s.username, s.password.Reveal(), host)
 
-	// Use template if available, otherwise use plain text
-	content := body
-	if s.template != "" {
-		content = fmt.Sprintf(s.template, body)
+	data, err := msg.Format(s.from)
+	if err != nil {
+		return fmt.Errorf("failed to format email: %w", err)
 	}
 
-	// Format the email with HTML content type
-	msg := fmt.Sprintf("From: %s\r\n"+
-		"To: %s\r\n"+
-		"Subject: %s\r\n"+
-		"MIME-Version: 1.0\r\n"+
-		"Content-Type: text/html; charset=UTF-8\r\n"+
-		"\r\n"+
-		"%s\r\n", s.from, s.recipient, subject, content)
-
-	// Send the email
-	err := smtp.SendMail(
+	// Send the email to every recipient, including BCC
+	err = smtp.SendMail(
 		host+":"+port,
 		auth,
 		s.from,
-		[]string{s.recipient},
-		[]byte(msg),
+		msg.All(),
+		data,
 	)
 	if err != nil {
 		return fmt.Errorf("failed to send email: %w", err)
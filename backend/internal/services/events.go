@@ -0,0 +1,147 @@
+package services
+
+import (
+	"sync"
+	"time"
+)
+
+// Invite lifecycle event types
+const (
+	EventInviteAdded     = "invite-added"
+	EventStatusChanged   = "status-changed"
+	EventDuplicateMarked = "duplicate-marked"
+	// EventResync tells a subscriber that events were missed and it should reload
+	EventResync = "resync"
+)
+
+// subscriberBuffer is how many events a subscriber may fall behind before it is dropped
+const subscriberBuffer = 64
+
+// InviteEvent describes a change to an invite. Email and SubmittedAt, the
+// column A form timestamp, identify the submission, since an applicant who
+// applies again has several rows with the same email.
+type InviteEvent struct {
+	ID             int64     `json:"id"`
+	Type           string    `json:"type"`
+	Email          string    `json:"email,omitempty"`
+	SubmittedAt    string    `json:"submittedAt,omitempty"`
+	Status         string    `json:"status,omitempty"`
+	PreviousStatus string    `json:"previousStatus,omitempty"`
+	Invite         *Invite   `json:"invite,omitempty"`
+	OccurredAt     time.Time `json:"occurredAt"`
+}
+
+// EventBroker fans invite events out to subscribers and keeps a bounded
+// history so that reconnecting subscribers can resume where they left off
+type EventBroker struct {
+	mu          sync.Mutex
+	nextID      int64
+	history     []InviteEvent
+	historySize int
+	subscribers map[chan InviteEvent]struct{}
+	// statuses holds the last published status per submission, as built by
+	// submissionKey, so the same change reported by a handler and by the sheet
+	// watcher is only published once
+	statuses map[string]string
+	now      func() time.Time
+}
+
+// NewEventBroker creates an EventBroker that remembers historySize events
+func NewEventBroker(historySize int) *EventBroker {
+	return &EventBroker{
+		nextID:      1,
+		historySize: historySize,
+		subscribers: make(map[chan InviteEvent]struct{}),
+		statuses:    make(map[string]string),
+		now:         time.Now,
+	}
+}
+
+// Publish assigns the event an ID and delivers it to every subscriber. Status
+// events that repeat the last published status for a submission are dropped;
+// the second return value reports whether the event was published.
+func (b *EventBroker) Publish(event InviteEvent) (InviteEvent, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	key := submissionKey(Invite{Email: event.Email, SubmittedAt: event.SubmittedAt})
+	if event.Type == EventStatusChanged || event.Type == EventDuplicateMarked {
+		if b.statuses[key] == event.Status {
+			return event, false
+		}
+	}
+	if event.Email != "" && event.Status != "" {
+		b.statuses[key] = event.Status
+	}
+
+	event.ID = b.nextID
+	b.nextID++
+	if event.OccurredAt.IsZero() {
+		event.OccurredAt = b.now()
+	}
+
+	b.history = append(b.history, event)
+	if len(b.history) > b.historySize {
+		b.history = b.history[len(b.history)-b.historySize:]
+	}
+
+	for ch := range b.subscribers {
+		select {
+		case ch <- event:
+		default:
+			// The subscriber is too far behind; drop it so it reconnects and resumes
+			delete(b.subscribers, ch)
+			close(ch)
+		}
+	}
+
+	return event, true
+}
+
+// Observe records the current status of a submission without publishing an event
+func (b *EventBroker) Observe(invite Invite) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.statuses[submissionKey(invite)] = invite.Status
+}
+
+// Subscribe registers a new subscriber. Events published after lastEventID
+// that are still in the history are returned as a backlog; if some have
+// already been discarded the backlog starts with a resync event. The returned
+// function must be called to unsubscribe.
+func (b *EventBroker) Subscribe(lastEventID int64) (<-chan InviteEvent, []InviteEvent, func()) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var backlog []InviteEvent
+	if lastEventID > 0 {
+		oldest := b.nextID
+		if len(b.history) > 0 {
+			oldest = b.history[0].ID
+		}
+		if lastEventID+1 < oldest || lastEventID >= b.nextID {
+			// Missed events, or an ID from before a restart
+			backlog = append(backlog, InviteEvent{Type: EventResync, OccurredAt: b.now()})
+		}
+		for _, event := range b.history {
+			if event.ID > lastEventID {
+				backlog = append(backlog, event)
+			}
+		}
+	}
+
+	ch := make(chan InviteEvent, subscriberBuffer)
+	b.subscribers[ch] = struct{}{}
+
+	unsubscribe := func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		if _, ok := b.subscribers[ch]; ok {
+			delete(b.subscribers, ch)
+			close(ch)
+		}
+	}
+
+	return ch, backlog, unsubscribe
+}
@@ -0,0 +1,178 @@
+package services
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"testing"
+
+	"github.com/stevebennett/slack-invite-mgr/backend/internal/config"
+)
+
+func TestEventBroker_Subscribe(t *testing.T) {
+	tests := []struct {
+		name          string
+		historySize   int
+		published     int
+		lastEventID   int64
+		expectedTypes []string
+		expectedIDs   []int64
+	}{
+		{
+			name:        "new subscriber gets no backlog",
+			historySize: 10,
+			published:   3,
+			lastEventID: 0,
+		},
+		{
+			name:          "resume after last event",
+			historySize:   10,
+			published:     3,
+			lastEventID:   1,
+			expectedTypes: []string{EventInviteAdded, EventInviteAdded},
+			expectedIDs:   []int64{2, 3},
+		},
+		{
+			name:          "resume after history was trimmed",
+			historySize:   2,
+			published:     5,
+			lastEventID:   1,
+			expectedTypes: []string{EventResync, EventInviteAdded, EventInviteAdded},
+			expectedIDs:   []int64{0, 4, 5},
+		},
+		{
+			name:          "resume with an ID from before a restart",
+			historySize:   10,
+			published:     1,
+			lastEventID:   42,
+			expectedTypes: []string{EventResync},
+			expectedIDs:   []int64{0},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			broker := NewEventBroker(tt.historySize)
+			for i := 0; i < tt.published; i++ {
+				broker.Publish(InviteEvent{Type: EventInviteAdded, Email: "a@example.com", Status: StatusPending})
+			}
+
+			_, backlog, unsubscribe := broker.Subscribe(tt.lastEventID)
+			defer unsubscribe()
+
+			if len(backlog) != len(tt.expectedTypes) {
+				t.Fatalf("Expected %d backlog events, got %v", len(tt.expectedTypes), backlog)
+			}
+			for i, event := range backlog {
+				if event.Type != tt.expectedTypes[i] || event.ID != tt.expectedIDs[i] {
+					t.Errorf("Backlog event %d: expected %s/%d, got %s/%d",
+						i, tt.expectedTypes[i], tt.expectedIDs[i], event.Type, event.ID)
+				}
+			}
+		})
+	}
+}
+
+func TestEventBroker_DropsRepeatedStatus(t *testing.T) {
+	broker := NewEventBroker(10)
+	ch, _, unsubscribe := broker.Subscribe(0)
+	defer unsubscribe()
+
+	if _, ok := broker.Publish(InviteEvent{Type: EventStatusChanged, Email: "a@example.com", Status: StatusSent}); !ok {
+		t.Fatal("Expected first status change to be published")
+	}
+	if _, ok := broker.Publish(InviteEvent{Type: EventStatusChanged, Email: " A@example.com", Status: StatusSent}); ok {
+		t.Fatal("Expected repeated status change to be dropped")
+	}
+	if len(ch) != 1 {
+		t.Errorf("Expected 1 delivered event, got %d", len(ch))
+	}
+}
+
+func TestSheetWatcher_Poll(t *testing.T) {
+	mockService := &mockSheetsService{
+		values: [][]interface{}{
+			{"Timestamp", "Name", "Role", "Email", "", "Company", "Years", "Reasons", "Source", "Status", "Updated"},
+			{"1/1/2024 10:00:00", "Alice", "", "alice@example.com", "", "", "", "", "", "", ""},
+			{"1/2/2024 10:00:00", "Bob", "", "bob@example.com", "", "", "", "", "", "", ""},
+		},
+	}
+	svc := &SheetsService{
+		cfg:     &config.SheetsConfig{SpreadsheetID: "sheetid", SheetName: "Sheet1"},
+		service: mockService,
+	}
+	broker := NewEventBroker(10)
+	watcher := NewSheetWatcher(svc, broker, 0, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	ch, _, unsubscribe := broker.Subscribe(0)
+	defer unsubscribe()
+
+	// The first poll only records the current state
+	if err := watcher.Poll(context.Background()); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(ch) != 0 {
+		t.Fatalf("Expected no events from the first poll, got %d", len(ch))
+	}
+
+	// Bob is sent by the API, which publishes the change itself
+	broker.Publish(InviteEvent{Type: EventStatusChanged, Email: "bob@example.com", SubmittedAt: "1/2/2024 10:00:00", Status: StatusSent, PreviousStatus: StatusPending})
+	<-ch
+
+	mockService.values = [][]interface{}{
+		mockService.values[0],
+		{"1/1/2024 10:00:00", "Alice", "", "alice@example.com", "", "", "", "", "", "Duplicate", "2024-01-03 00:00:00"},
+		{"1/2/2024 10:00:00", "Bob", "", "bob@example.com", "", "", "", "", "", "sent", "2024-01-03 00:00:00"},
+		{"1/3/2024 10:00:00", "Carol", "", "carol@example.com", "", "", "", "", "", "", ""},
+	}
+	if err := watcher.Poll(context.Background()); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	var events []InviteEvent
+	for len(ch) > 0 {
+		events = append(events, <-ch)
+	}
+	if len(events) != 2 {
+		t.Fatalf("Expected 2 events, got %v", events)
+	}
+	if events[0].Type != EventDuplicateMarked || events[0].Email != "alice@example.com" || events[0].SubmittedAt != "1/1/2024 10:00:00" || events[0].PreviousStatus != StatusPending {
+		t.Errorf("Unexpected first event: %+v", events[0])
+	}
+	if events[1].Type != EventInviteAdded || events[1].Invite == nil || events[1].Invite.Name != "Carol" {
+		t.Errorf("Unexpected second event: %+v", events[1])
+	}
+
+	// Carol applies again and the sync marks the new row a duplicate; the
+	// event names the new submission rather than her pending first one
+	again := []interface{}{"1/4/2024 10:00:00", "Carol", "", "carol@example.com", "", "", "", "", "", "", ""}
+	mockService.values = append(mockService.values, again)
+	if err := watcher.Poll(context.Background()); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if event := <-ch; event.Type != EventInviteAdded || event.SubmittedAt != "1/4/2024 10:00:00" {
+		t.Fatalf("Unexpected event: %+v", event)
+	}
+	mockService.values[4] = []interface{}{"1/4/2024 10:00:00", "Carol", "", "carol@example.com", "", "", "", "", "", "Duplicate", "2024-01-04 00:00:00"}
+	if err := watcher.Poll(context.Background()); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(ch) != 1 {
+		t.Fatalf("Expected 1 event, got %d", len(ch))
+	}
+	if event := <-ch; event.Type != EventDuplicateMarked || event.SubmittedAt != "1/4/2024 10:00:00" {
+		t.Errorf("Unexpected event: %+v", event)
+	}
+}
+
+func TestEventBroker_KeysStatusesBySubmission(t *testing.T) {
+	broker := NewEventBroker(10)
+	broker.Observe(Invite{Email: "a@example.com", SubmittedAt: "1/1/2024 10:00:00", Status: StatusDuplicate})
+
+	// Another submission by the same applicant has its own status
+	if _, ok := broker.Publish(InviteEvent{Type: EventDuplicateMarked, Email: "a@example.com", SubmittedAt: "1/2/2024 10:00:00", Status: StatusDuplicate}); !ok {
+		t.Error("Expected the second submission to be published")
+	}
+	if _, ok := broker.Publish(InviteEvent{Type: EventDuplicateMarked, Email: "A@example.com", SubmittedAt: "1/1/2024 10:00:00", Status: StatusDuplicate}); ok {
+		t.Error("Expected the repeated status of the first submission to be dropped")
+	}
+}
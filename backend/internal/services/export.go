@@ -0,0 +1,228 @@
+package services
+
+import (
+	"archive/zip"
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Export formats
+const (
+	ExportCSV  = "csv"
+	ExportXLSX = "xlsx"
+	ExportJSON = "json"
+)
+
+// ExportContentTypes maps each export format to its media type
+var ExportContentTypes = map[string]string{
+	ExportCSV:  "text/csv; charset=utf-8",
+	ExportXLSX: "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+	ExportJSON: "application/json",
+}
+
+// exportHeader names the exported columns, matching the Invite JSON fields
+var exportHeader = []string{
+	"submittedAt", "name", "role", "email", "company", "yearsExperience", "reasons", "source", "status", "statusUpdatedAt",
+}
+
+// exportRecord returns the exported columns of an invite
+func exportRecord(invite Invite) []string {
+	return []string{
+		invite.SubmittedAt, invite.Name, invite.Role, invite.Email, invite.Company,
+		invite.YearsExperience, invite.Reasons, invite.Source, invite.Status, invite.StatusUpdatedAt,
+	}
+}
+
+// InviteWriter writes invites one at a time in an export format. Close must
+// be called to finish the output.
+type InviteWriter interface {
+	Write(invite Invite) error
+	Close() error
+}
+
+// NewInviteWriter returns a writer for the given export format that streams
+// invites to w as they are written
+func NewInviteWriter(w io.Writer, format string) (InviteWriter, error) {
+	switch format {
+	case ExportCSV:
+		return newCSVInviteWriter(w)
+	case ExportXLSX:
+		return newXLSXInviteWriter(w)
+	case ExportJSON:
+		return newJSONInviteWriter(w), nil
+	default:
+		return nil, fmt.Errorf("unknown export format %q", format)
+	}
+}
+
+// csvInviteWriter writes a header row followed by one row per invite
+type csvInviteWriter struct {
+	cw *csv.Writer
+}
+
+func newCSVInviteWriter(w io.Writer) (*csvInviteWriter, error) {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(exportHeader); err != nil {
+		return nil, err
+	}
+	return &csvInviteWriter{cw: cw}, nil
+}
+
+func (c *csvInviteWriter) Write(invite Invite) error {
+	record := exportRecord(invite)
+	for i, value := range record {
+		record[i] = neutralizeFormula(value)
+	}
+	return c.cw.Write(record)
+}
+
+func (c *csvInviteWriter) Close() error {
+	c.cw.Flush()
+	return c.cw.Error()
+}
+
+// neutralizeFormula prefixes applicant-supplied text that a spreadsheet
+// would evaluate as a formula, such as "=HYPERLINK(...)", with a quote
+func neutralizeFormula(value string) string {
+	if value != "" && strings.ContainsRune("=+-@\t\r", rune(value[0])) {
+		return "'" + value
+	}
+	return value
+}
+
+// jsonInviteWriter writes a JSON array of invites
+type jsonInviteWriter struct {
+	w     *bufio.Writer
+	count int
+}
+
+func newJSONInviteWriter(w io.Writer) *jsonInviteWriter {
+	return &jsonInviteWriter{w: bufio.NewWriter(w)}
+}
+
+func (j *jsonInviteWriter) Write(invite Invite) error {
+	data, err := json.Marshal(invite)
+	if err != nil {
+		return err
+	}
+	separator := ",\n"
+	if j.count == 0 {
+		separator = "[\n"
+	}
+	j.count++
+	if _, err := j.w.WriteString(separator); err != nil {
+		return err
+	}
+	_, err = j.w.Write(data)
+	return err
+}
+
+func (j *jsonInviteWriter) Close() error {
+	closing := "\n]\n"
+	if j.count == 0 {
+		closing = "[]\n"
+	}
+	if _, err := j.w.WriteString(closing); err != nil {
+		return err
+	}
+	return j.w.Flush()
+}
+
+// The fixed parts of an XLSX workbook with a single worksheet of inline strings
+const (
+	xlsxContentTypes = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">` +
+		`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>` +
+		`<Default Extension="xml" ContentType="application/xml"/>` +
+		`<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>` +
+		`<Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>` +
+		`</Types>`
+	xlsxRootRels = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+		`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>` +
+		`</Relationships>`
+	xlsxWorkbook = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">` +
+		`<sheets><sheet name="Invites" sheetId="1" r:id="rId1"/></sheets>` +
+		`</workbook>`
+	xlsxWorkbookRels = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+		`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/>` +
+		`</Relationships>`
+	xlsxSheetStart = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`
+	xlsxSheetEnd = `</sheetData></worksheet>`
+)
+
+// xlsxInviteWriter writes a minimal XLSX workbook. The worksheet is the last
+// entry in the zip archive, so rows are streamed into it as they arrive.
+type xlsxInviteWriter struct {
+	zw    *zip.Writer
+	sheet *bufio.Writer
+}
+
+func newXLSXInviteWriter(w io.Writer) (*xlsxInviteWriter, error) {
+	zw := zip.NewWriter(w)
+	parts := []struct{ name, content string }{
+		{"[Content_Types].xml", xlsxContentTypes},
+		{"_rels/.rels", xlsxRootRels},
+		{"xl/workbook.xml", xlsxWorkbook},
+		{"xl/_rels/workbook.xml.rels", xlsxWorkbookRels},
+	}
+	for _, part := range parts {
+		f, err := zw.Create(part.name)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := io.WriteString(f, part.content); err != nil {
+			return nil, err
+		}
+	}
+
+	f, err := zw.Create("xl/worksheets/sheet1.xml")
+	if err != nil {
+		return nil, err
+	}
+	x := &xlsxInviteWriter{zw: zw, sheet: bufio.NewWriter(f)}
+	if _, err := x.sheet.WriteString(xlsxSheetStart); err != nil {
+		return nil, err
+	}
+	if err := x.writeRow(exportHeader); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+func (x *xlsxInviteWriter) Write(invite Invite) error {
+	return x.writeRow(exportRecord(invite))
+}
+
+// writeRow writes a row of inline string cells. Text is stored as strings,
+// so it is never evaluated as a formula.
+func (x *xlsxInviteWriter) writeRow(values []string) error {
+	x.sheet.WriteString("<row>")
+	for _, value := range values {
+		x.sheet.WriteString(`<c t="inlineStr"><is><t xml:space="preserve">`)
+		if err := xml.EscapeText(x.sheet, []byte(value)); err != nil {
+			return err
+		}
+		x.sheet.WriteString("</t></is></c>")
+	}
+	_, err := x.sheet.WriteString("</row>")
+	return err
+}
+
+func (x *xlsxInviteWriter) Close() error {
+	if _, err := x.sheet.WriteString(xlsxSheetEnd); err != nil {
+		return err
+	}
+	if err := x.sheet.Flush(); err != nil {
+		return err
+	}
+	return x.zw.Close()
+}
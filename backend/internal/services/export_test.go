@@ -0,0 +1,134 @@
+package services
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"io"
+	"testing"
+)
+
+func TestInviteWriter(t *testing.T) {
+	invites := []Invite{
+		{SubmittedAt: "3/4/2024 10:00:00", Name: "Jane <Doe>", Email: "jane@example.com", Reasons: "=HYPERLINK(\"http://evil\")", Status: StatusSent, StatusUpdatedAt: "2024-03-05 10:00:00"},
+		{SubmittedAt: "3/5/2024 10:00:00", Name: "John", Email: "john@example.com", Reasons: "Line one\nline two", Status: StatusPending},
+	}
+
+	tests := []struct {
+		format string
+		// read returns the exported rows, including the header
+		read func(t *testing.T, data []byte) [][]string
+	}{
+		{format: ExportCSV, read: readCSVExport},
+		{format: ExportXLSX, read: readXLSXExport},
+		{format: ExportJSON, read: readJSONExport},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			var buf bytes.Buffer
+			writer, err := NewInviteWriter(&buf, tt.format)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			for _, invite := range invites {
+				if err := writer.Write(invite); err != nil {
+					t.Fatalf("Unexpected error: %v", err)
+				}
+			}
+			if err := writer.Close(); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			rows := tt.read(t, buf.Bytes())
+			if len(rows) != 3 {
+				t.Fatalf("Expected a header and 2 rows, got %d rows", len(rows))
+			}
+			if rows[1][1] != "Jane <Doe>" || rows[1][8] != StatusSent || rows[1][9] != "2024-03-05 10:00:00" {
+				t.Errorf("Unexpected first row %q", rows[1])
+			}
+			if rows[2][6] != "Line one\nline two" {
+				t.Errorf("Expected line breaks to be kept, got %q", rows[2][6])
+			}
+
+			// Only CSV needs formulas neutralised; XLSX cells are always text
+			expectedReasons := invites[0].Reasons
+			if tt.format == ExportCSV {
+				expectedReasons = "'" + expectedReasons
+			}
+			if rows[1][6] != expectedReasons {
+				t.Errorf("Expected reasons %q, got %q", expectedReasons, rows[1][6])
+			}
+		})
+	}
+
+	if _, err := NewInviteWriter(io.Discard, "pdf"); err == nil {
+		t.Errorf("Expected an error for an unknown format")
+	}
+}
+
+func readCSVExport(t *testing.T, data []byte) [][]string {
+	rows, err := csv.NewReader(bytes.NewReader(data)).ReadAll()
+	if err != nil {
+		t.Fatalf("Failed to parse CSV: %v", err)
+	}
+	return rows
+}
+
+func readJSONExport(t *testing.T, data []byte) [][]string {
+	var invites []Invite
+	if err := json.Unmarshal(data, &invites); err != nil {
+		t.Fatalf("Failed to parse JSON: %v", err)
+	}
+	rows := [][]string{exportHeader}
+	for _, invite := range invites {
+		rows = append(rows, exportRecord(invite))
+	}
+	return rows
+}
+
+func readXLSXExport(t *testing.T, data []byte) [][]string {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatalf("Failed to open XLSX: %v", err)
+	}
+	var sheet struct {
+		Rows []struct {
+			Cells []struct {
+				Text string `xml:"is>t"`
+			} `xml:"c"`
+		} `xml:"sheetData>row"`
+	}
+	names := make(map[string]bool)
+	for _, f := range zr.File {
+		names[f.Name] = true
+		if f.Name != "xl/worksheets/sheet1.xml" {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatalf("Failed to open worksheet: %v", err)
+		}
+		defer rc.Close()
+		if err := xml.NewDecoder(rc).Decode(&sheet); err != nil {
+			t.Fatalf("Failed to parse worksheet: %v", err)
+		}
+	}
+	for _, name := range []string{"[Content_Types].xml", "_rels/.rels", "xl/workbook.xml", "xl/_rels/workbook.xml.rels"} {
+		if !names[name] {
+			t.Errorf("Expected %s in the workbook", name)
+		}
+	}
+
+	var rows [][]string
+	for _, row := range sheet.Rows {
+		var values []string
+		for _, cell := range row.Cells {
+			values = append(values, cell.Text)
+		}
+		rows = append(rows, values)
+	}
+	return rows
+}
@@ -0,0 +1,75 @@
+package services
+
+// Invite represents a single invite from the spreadsheet
+type Invite struct {
+	SubmittedAt     string `json:"submittedAt,omitempty"`
+	Name            string `json:"name"`
+	Role            string `json:"role"`
+	Email           string `json:"email"`
+	Company         string `json:"company"`
+	YearsExperience string `json:"yearsExperience"`
+	Reasons         string `json:"reasons"`
+	Source          string `json:"source"`
+	Status          string `json:"status"`
+	StatusUpdatedAt string `json:"statusUpdatedAt,omitempty"`
+	// Version changes whenever the row changes; send it back in If-Match
+	Version string `json:"version"`
+	// Archived is set on invites read from an archive tab, which can no
+	// longer be updated
+	Archived bool `json:"archived,omitempty"`
+}
+
+// InviteFromRow converts a sheet row (columns A-K) into an Invite
+func InviteFromRow(row []interface{}) Invite {
+	return Invite{
+		SubmittedAt:     cellString(row, 0),                  // Column A
+		Name:            cellString(row, 1),                  // Column B
+		Role:            cellString(row, 2),                  // Column C
+		Email:           cellString(row, 3),                  // Column D
+		Company:         cellString(row, 5),                  // Column F
+		YearsExperience: cellString(row, 6),                  // Column G
+		Reasons:         cellString(row, 7),                  // Column H
+		Source:          cellString(row, 8),                  // Column I
+		Status:          NormalizeStatus(cellString(row, 9)), // Column J
+		StatusUpdatedAt: cellString(row, 10),                 // Column K
+		Version:         RowVersion(row),
+	}
+}
+
+// SubmittedAtLayout is the column A timestamp format written by Google Forms
+const SubmittedAtLayout = "1/2/2006 15:04:05"
+
+// InviteRow converts an Invite into a sheet row (columns A-K), the inverse of
+// InviteFromRow. Column E is not used by the form and is left empty.
+func InviteRow(invite Invite) []interface{} {
+	return []interface{}{
+		invite.SubmittedAt,
+		invite.Name,
+		invite.Role,
+		invite.Email,
+		"",
+		invite.Company,
+		invite.YearsExperience,
+		invite.Reasons,
+		invite.Source,
+		StatusCellValue(NormalizeStatus(invite.Status)),
+		invite.StatusUpdatedAt,
+	}
+}
+
+// InvitesFromRows converts sheet rows into invites, skipping the header row
+// and rows with hand-edited statuses
+func InvitesFromRows(rows [][]interface{}) []Invite {
+	var invites []Invite
+	for _, row := range rows {
+		if len(row) < 9 {
+			continue
+		}
+		invite := InviteFromRow(row)
+		if !IsKnownStatus(invite.Status) {
+			continue
+		}
+		invites = append(invites, invite)
+	}
+	return invites
+}
@@ -0,0 +1,98 @@
+package services
+
+import (
+	"bytes"
+	"fmt"
+	"mime"
+	"mime/quotedprintable"
+	"net/mail"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Recipients are the addresses an email is sent to. BCC addresses receive
+// the message but are left out of its headers.
+type Recipients struct {
+	To  []string `json:"to"`
+	CC  []string `json:"cc,omitempty"`
+	BCC []string `json:"bcc,omitempty"`
+}
+
+// All returns every recipient address, for the SMTP envelope
+func (r Recipients) All() []string {
+	all := make([]string, 0, len(r.To)+len(r.CC)+len(r.BCC))
+	all = append(all, r.To...)
+	all = append(all, r.CC...)
+	return append(all, r.BCC...)
+}
+
+// EmailMessage is an HTML email ready to be delivered
+type EmailMessage struct {
+	// MessageID is kept across retries so receivers can spot duplicates
+	MessageID string `json:"messageId"`
+	Recipients
+	Subject string    `json:"subject"`
+	Body    string    `json:"body"`
+	Date    time.Time `json:"date"`
+}
+
+// newMessageID returns a unique Message-ID for mail sent from the given address
+func newMessageID(from string) string {
+	domain := "localhost"
+	if at := strings.LastIndex(from, "@"); at >= 0 && at < len(from)-1 {
+		domain = from[at+1:]
+	}
+	return "<" + uuid.New().String() + "@" + domain + ">"
+}
+
+// Format renders the message as RFC 5322 text sent from the given address.
+// The subject is MIME-encoded when it is not plain ASCII and the body is
+// quoted-printable, so no line exceeds the SMTP limit.
+func (m EmailMessage) Format(from string) ([]byte, error) {
+	var buf bytes.Buffer
+	header := func(name, value string) {
+		fmt.Fprintf(&buf, "%s: %s\r\n", name, value)
+	}
+
+	header("From", formatAddressList([]string{from}))
+	if len(m.To) > 0 {
+		header("To", formatAddressList(m.To))
+	} else {
+		// Keep BCC-only recipients hidden from each other
+		header("To", "undisclosed-recipients:;")
+	}
+	if len(m.CC) > 0 {
+		header("Cc", formatAddressList(m.CC))
+	}
+	header("Date", m.Date.Format(time.RFC1123Z))
+	header("Message-ID", m.MessageID)
+
+	// Header values must not contain line breaks
+	subject := strings.NewReplacer("\r", " ", "\n", " ").Replace(m.Subject)
+	header("Subject", mime.QEncoding.Encode("UTF-8", subject))
+	header("MIME-Version", "1.0")
+	header("Content-Type", "text/html; charset=UTF-8")
+	header("Content-Transfer-Encoding", "quoted-printable")
+	buf.WriteString("\r\n")
+
+	qp := quotedprintable.NewWriter(&buf)
+	if _, err := qp.Write([]byte(m.Body)); err != nil {
+		return nil, err
+	}
+	if err := qp.Close(); err != nil {
+		return nil, err
+	}
+	buf.WriteString("\r\n")
+	return buf.Bytes(), nil
+}
+
+// formatAddressList formats addresses for an address header
+func formatAddressList(addresses []string) string {
+	formatted := make([]string, len(addresses))
+	for i, address := range addresses {
+		formatted[i] = (&mail.Address{Address: address}).String()
+	}
+	return strings.Join(formatted, ", ")
+}
@@ -0,0 +1,206 @@
+package services
+
+import (
+	"context"
+	"mime"
+	"net/mail"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stevebennett/slack-invite-mgr/backend/internal/config"
+)
+
+func TestEmailMessage_Format(t *testing.T) {
+	date := time.Date(2024, 3, 1, 9, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		name            string
+		recipients      Recipients
+		subject         string
+		expectedTo      string
+		expectedCc      string
+		expectedSubject string
+	}{
+		{
+			name:            "to and cc",
+			recipients:      Recipients{To: []string{"ops@example.com", "oncall@example.com"}, CC: []string{"lead@example.com"}},
+			subject:         "New Invites Need Processing",
+			expectedTo:      "<ops@example.com>, <oncall@example.com>",
+			expectedCc:      "<lead@example.com>",
+			expectedSubject: "New Invites Need Processing",
+		},
+		{
+			name:            "bcc only",
+			recipients:      Recipients{BCC: []string{"hidden@example.com"}},
+			subject:         "Weekly report",
+			expectedTo:      "undisclosed-recipients:;",
+			expectedSubject: "Weekly report",
+		},
+		{
+			name:            "non-ascii subject with line break",
+			recipients:      Recipients{To: []string{"ops@example.com"}},
+			subject:         "Café\r\nBcc: attacker@example.com",
+			expectedTo:      "<ops@example.com>",
+			expectedSubject: "Café  Bcc: attacker@example.com",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msg := EmailMessage{
+				MessageID:  newMessageID("sender@example.com"),
+				Recipients: tt.recipients,
+				Subject:    tt.subject,
+				Body:       "<p>" + strings.Repeat("x", 2000) + "</p>",
+				Date:       date,
+			}
+			data, err := msg.Format("sender@example.com")
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			parsed, err := mail.ReadMessage(strings.NewReader(string(data)))
+			if err != nil {
+				t.Fatalf("Failed to parse formatted message: %v", err)
+			}
+			header := parsed.Header
+
+			if got := header.Get("To"); got != tt.expectedTo {
+				t.Errorf("Expected To %q, got %q", tt.expectedTo, got)
+			}
+			if got := header.Get("Cc"); got != tt.expectedCc {
+				t.Errorf("Expected Cc %q, got %q", tt.expectedCc, got)
+			}
+			if _, ok := header["Bcc"]; ok {
+				t.Errorf("Expected no Bcc header")
+			}
+			if got, _ := header.Date(); !got.Equal(date) {
+				t.Errorf("Expected Date %v, got %v", date, got)
+			}
+			if got := header.Get("Message-ID"); got != msg.MessageID || !strings.HasSuffix(got, "@example.com>") {
+				t.Errorf("Expected Message-ID %q, got %q", msg.MessageID, got)
+			}
+			subject, err := new(mime.WordDecoder).DecodeHeader(header.Get("Subject"))
+			if err != nil || subject != tt.expectedSubject {
+				t.Errorf("Expected subject %q, got %q (%v)", tt.expectedSubject, subject, err)
+			}
+
+			for _, line := range strings.Split(string(data), "\r\n") {
+				if len(line) > 998 {
+					t.Fatalf("Expected no line longer than 998 characters, got %d", len(line))
+				}
+			}
+		})
+	}
+}
+
+func TestEmailService_SendEmailRoutesByEvent(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "routing.json")
+	routing := `{
+		"groups": {
+			"ops": ["ops@example.com", "oncall@example.com"],
+			"moderators": ["mods@example.com", "ops@example.com"]
+		},
+		"routes": {
+			"errors": {"to": ["ops"]},
+			"new_applications": {"to": ["moderators"], "cc": ["lead@example.com"], "bcc": ["ops"]}
+		}
+	}`
+	if err := os.WriteFile(path, []byte(routing), 0o600); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	cfg, err := config.LoadEmailRouting(path, "fallback@example.com")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		event    string
+		expected Recipients
+	}{
+		{
+			event:    config.EmailEventErrors,
+			expected: Recipients{To: []string{"ops@example.com", "oncall@example.com"}},
+		},
+		{
+			// Addresses in more than one list are only sent to once
+			event: config.EmailEventNewApplications,
+			expected: Recipients{
+				To:  []string{"mods@example.com", "ops@example.com"},
+				CC:  []string{"lead@example.com"},
+				BCC: []string{"oncall@example.com"},
+			},
+		},
+		{
+			event:    config.EmailEventWeeklyReport,
+			expected: Recipients{To: []string{"fallback@example.com"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.event, func(t *testing.T) {
+			outbox, _ := OpenOutbox("", time.Hour)
+			service := &EmailService{routing: cfg, from: "sender@example.com", now: time.Now}
+			service.UseOutbox(outbox)
+
+			if err := service.SendEmail(context.Background(), tt.event, "Subject", "body"); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			queued := outbox.List(OutboxPending)
+			if len(queued) != 1 {
+				t.Fatalf("Expected 1 queued message, got %d", len(queued))
+			}
+			if !reflect.DeepEqual(queued[0].Recipients, tt.expected) {
+				t.Errorf("Expected recipients %+v, got %+v", tt.expected, queued[0].Recipients)
+			}
+		})
+	}
+}
+
+func TestSendEmail_WorkspaceSubject(t *testing.T) {
+	routing, err := config.LoadEmailRouting("", "mods@example.com")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	outbox, _ := OpenOutbox("", time.Hour)
+	service := &EmailService{routing: routing, from: "sender@example.com", workspace: "gophers", now: time.Now}
+	service.UseOutbox(outbox)
+
+	if err := service.SendEmail(context.Background(), config.EmailEventErrors, "Sync failed", "body"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := service.SendTo(context.Background(), "applicant@example.com", "Your invite", "body"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	subjects := map[string]bool{}
+	for _, msg := range outbox.List(OutboxPending) {
+		subjects[msg.Subject] = true
+	}
+	// Only notifications to the team name the workspace
+	if !subjects["[gophers] Sync failed"] || !subjects["Your invite"] {
+		t.Errorf("Expected a prefixed notification and an unprefixed applicant email, got %v", subjects)
+	}
+}
+
+func TestLoadEmailRouting_Invalid(t *testing.T) {
+	tests := map[string]string{
+		"unknown group":   `{"routes": {"errors": {"to": ["ops"]}}}`,
+		"unknown event":   `{"routes": {"signups": {"to": ["ops@example.com"]}}}`,
+		"invalid address": `{"groups": {"ops": ["not an address@"]}}`,
+	}
+	for name, routing := range tests {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "routing.json")
+			if err := os.WriteFile(path, []byte(routing), 0o600); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if _, err := config.LoadEmailRouting(path, ""); err == nil {
+				t.Errorf("Expected an error")
+			}
+		})
+	}
+}
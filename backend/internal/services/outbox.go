@@ -0,0 +1,398 @@
+package services
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Outbox message statuses
+const (
+	OutboxPending = "pending"
+	OutboxSent    = "sent"
+	OutboxFailed  = "failed"
+)
+
+// outboxSentRetention is how long sent messages are kept for inspection;
+// they are kept for at least the dedupe window
+const outboxSentRetention = 7 * 24 * time.Hour
+
+// ErrOutboxMessageNotFound is returned when an outbox message ID is unknown
+var ErrOutboxMessageNotFound = errors.New("outbox message not found")
+
+// OutboxMessage is an email waiting to be sent, or a record of one that was
+type OutboxMessage struct {
+	ID string `json:"id"`
+	EmailMessage
+	Hash          string    `json:"hash"`
+	Status        string    `json:"status"`
+	Attempts      int       `json:"attempts"`
+	LastError     string    `json:"lastError,omitempty"`
+	CreatedAt     time.Time `json:"createdAt"`
+	NextAttemptAt time.Time `json:"nextAttemptAt"`
+	SentAt        time.Time `json:"sentAt,omitzero"`
+	// Audit is recorded again with the outcome once the message is
+	// delivered or has failed for good
+	Audit *AuditEntry `json:"audit,omitempty"`
+}
+
+// Outbox is a durable queue of outgoing email. Messages are kept in a JSON
+// file that is rewritten atomically on every change, so queued email survives
+// SMTP outages and restarts. An empty path keeps the outbox in memory.
+//
+// Each operation takes the file's lock and rereads it, so another process,
+// such as the API server erasing an applicant from the sheets tool's outbox,
+// may change it at the same time.
+type Outbox struct {
+	mu           sync.Mutex
+	path         string
+	dedupeWindow time.Duration
+	messages     []OutboxMessage
+	now          func() time.Time
+}
+
+// OpenOutbox loads the outbox stored at path, creating it on first write.
+// Identical messages enqueued within dedupeWindow of each other are only sent once.
+func OpenOutbox(path string, dedupeWindow time.Duration) (*Outbox, error) {
+	o := &Outbox{path: path, dedupeWindow: dedupeWindow, now: time.Now}
+	if path != "" {
+		if err := o.load(); err != nil {
+			return nil, err
+		}
+	}
+	return o, nil
+}
+
+// load rereads the outbox file. A missing file holds no messages.
+func (o *Outbox) load() error {
+	data, err := os.ReadFile(o.path)
+	if errors.Is(err, os.ErrNotExist) {
+		o.messages = nil
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read outbox: %w", err)
+	}
+	var messages []OutboxMessage
+	if len(data) > 0 {
+		if err := json.Unmarshal(data, &messages); err != nil {
+			return fmt.Errorf("failed to parse outbox: %w", err)
+		}
+	}
+	o.messages = messages
+	return nil
+}
+
+// lockFile takes the outbox file's lock and rereads it, returning a function
+// that releases the lock. o.mu must be held.
+func (o *Outbox) lockFile() (unlock func(), err error) {
+	if o.path == "" {
+		return func() {}, nil
+	}
+	if unlock, err = lockFile(o.path); err != nil {
+		return nil, err
+	}
+	if err := o.load(); err != nil {
+		unlock()
+		return nil, err
+	}
+	return unlock, nil
+}
+
+// Enqueue adds a message to the outbox. If a message with the same recipients,
+// subject and body was enqueued within the dedupe window and has not failed,
+// that message is returned instead and enqueued is false.
+func (o *Outbox) Enqueue(email EmailMessage) (msg OutboxMessage, enqueued bool, err error) {
+	return o.EnqueueAudited(email, nil)
+}
+
+// EnqueueAudited is Enqueue for a message whose outcome the OutboxWorker
+// records in the audit trail as a copy of audit
+func (o *Outbox) EnqueueAudited(email EmailMessage, audit *AuditEntry) (msg OutboxMessage, enqueued bool, err error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	unlock, err := o.lockFile()
+	if err != nil {
+		return OutboxMessage{}, false, err
+	}
+	defer unlock()
+
+	now := o.now()
+	hash := messageHash(email)
+	for _, existing := range o.messages {
+		if existing.Hash == hash && existing.Status != OutboxFailed && now.Sub(existing.CreatedAt) < o.dedupeWindow {
+			return existing, false, nil
+		}
+	}
+
+	msg = OutboxMessage{
+		ID:            uuid.New().String(),
+		EmailMessage:  email,
+		Hash:          hash,
+		Status:        OutboxPending,
+		CreatedAt:     now,
+		NextAttemptAt: now,
+		Audit:         audit,
+	}
+	o.messages = append(o.messages, msg)
+	if err := o.save(); err != nil {
+		o.messages = o.messages[:len(o.messages)-1]
+		return OutboxMessage{}, false, err
+	}
+	return msg, true, nil
+}
+
+// Due returns the pending messages whose next attempt is due
+func (o *Outbox) Due() []OutboxMessage {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	// A file that cannot be read leaves the messages last read
+	if unlock, err := o.lockFile(); err == nil {
+		defer unlock()
+	}
+
+	now := o.now()
+	var due []OutboxMessage
+	for _, msg := range o.messages {
+		if msg.Status == OutboxPending && !msg.NextAttemptAt.After(now) {
+			due = append(due, msg)
+		}
+	}
+	return due
+}
+
+// NextAttempt returns when the earliest pending message is next due, and
+// false if nothing is pending
+func (o *Outbox) NextAttempt() (time.Time, bool) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	// A file that cannot be read leaves the messages last read
+	if unlock, err := o.lockFile(); err == nil {
+		defer unlock()
+	}
+
+	var next time.Time
+	found := false
+	for _, msg := range o.messages {
+		if msg.Status == OutboxPending && (!found || msg.NextAttemptAt.Before(next)) {
+			next = msg.NextAttemptAt
+			found = true
+		}
+	}
+	return next, found
+}
+
+// MarkSent records that a message was delivered
+func (o *Outbox) MarkSent(id string) error {
+	return o.update(id, func(msg *OutboxMessage, now time.Time) {
+		msg.Attempts++
+		msg.Status = OutboxSent
+		msg.LastError = ""
+		msg.SentAt = now
+	})
+}
+
+// MarkAttemptFailed records a failed delivery. The message is retried at
+// retryAt, or marked failed if retryAt is zero.
+func (o *Outbox) MarkAttemptFailed(id string, sendErr error, retryAt time.Time) error {
+	return o.update(id, func(msg *OutboxMessage, now time.Time) {
+		msg.Attempts++
+		msg.LastError = sendErr.Error()
+		if retryAt.IsZero() {
+			msg.Status = OutboxFailed
+			return
+		}
+		msg.NextAttemptAt = retryAt
+	})
+}
+
+// Retry moves a failed message back to pending so it is sent again
+func (o *Outbox) Retry(id string) error {
+	return o.update(id, func(msg *OutboxMessage, now time.Time) {
+		msg.Status = OutboxPending
+		msg.Attempts = 0
+		msg.NextAttemptAt = now
+	})
+}
+
+// List returns the messages with the given status, or every message if
+// status is empty, oldest first
+func (o *Outbox) List(status string) []OutboxMessage {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	// A file that cannot be read leaves the messages last read
+	if unlock, err := o.lockFile(); err == nil {
+		defer unlock()
+	}
+
+	result := []OutboxMessage{}
+	for _, msg := range o.messages {
+		if status == "" || msg.Status == status {
+			result = append(result, msg)
+		}
+	}
+	sort.SliceStable(result, func(i, j int) bool {
+		return result[i].CreatedAt.Before(result[j].CreatedAt)
+	})
+	return result
+}
+
+// Erase removes every message addressed to email or mentioning it, such as
+// decision emails and reminder digests, whatever its status, so nothing more
+// is sent about the applicant. It returns how many messages were removed.
+func (o *Outbox) Erase(email string) (int, error) {
+	email = NormalizeEmail(email)
+	if email == "" {
+		return 0, nil
+	}
+	return o.remove(false, func(msg OutboxMessage) bool {
+		for _, recipients := range [][]string{msg.To, msg.CC, msg.BCC} {
+			for _, recipient := range recipients {
+				if NormalizeEmail(recipient) == email {
+					return true
+				}
+			}
+		}
+		return strings.Contains(strings.ToLower(msg.Subject+"\n"+msg.Body), email)
+	})
+}
+
+// Redact removes the sent and failed messages created before the cutoff. It
+// returns how many were removed, or with dryRun would be.
+func (o *Outbox) Redact(before time.Time, dryRun bool) (int, error) {
+	return o.remove(dryRun, func(msg OutboxMessage) bool {
+		return msg.Status != OutboxPending && msg.CreatedAt.Before(before)
+	})
+}
+
+// remove drops the messages that match and saves the outbox
+func (o *Outbox) remove(dryRun bool, match func(OutboxMessage) bool) (int, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	unlock, err := o.lockFile()
+	if err != nil {
+		return 0, err
+	}
+	defer unlock()
+
+	kept := make([]OutboxMessage, 0, len(o.messages))
+	for _, msg := range o.messages {
+		if !match(msg) {
+			kept = append(kept, msg)
+		}
+	}
+	removed := len(o.messages) - len(kept)
+	if removed == 0 || dryRun {
+		return removed, nil
+	}
+	previous := o.messages
+	o.messages = kept
+	if err := o.save(); err != nil {
+		o.messages = previous
+		return 0, err
+	}
+	return removed, nil
+}
+
+// update applies fn to the message with the given ID and saves the outbox
+func (o *Outbox) update(id string, fn func(msg *OutboxMessage, now time.Time)) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	unlock, err := o.lockFile()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	for i := range o.messages {
+		if o.messages[i].ID == id {
+			previous := o.messages[i]
+			fn(&o.messages[i], o.now())
+			if err := o.save(); err != nil {
+				o.messages[i] = previous
+				return err
+			}
+			return nil
+		}
+	}
+	return ErrOutboxMessageNotFound
+}
+
+// save prunes old sent messages and writes the outbox to disk. The messages
+// in memory are left untouched if writing fails.
+func (o *Outbox) save() error {
+	retention := outboxSentRetention
+	if o.dedupeWindow > retention {
+		retention = o.dedupeWindow
+	}
+	now := o.now()
+	kept := make([]OutboxMessage, 0, len(o.messages))
+	for _, msg := range o.messages {
+		if msg.Status == OutboxSent && now.Sub(msg.SentAt) > retention {
+			continue
+		}
+		kept = append(kept, msg)
+	}
+
+	if o.path != "" {
+		if err := writeOutboxFile(o.path, kept); err != nil {
+			return err
+		}
+	}
+	o.messages = kept
+	return nil
+}
+
+// writeOutboxFile replaces the outbox file with messages
+func writeOutboxFile(path string, messages []OutboxMessage) error {
+	data, err := json.MarshalIndent(messages, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode outbox: %w", err)
+	}
+
+	// Write to a temporary file and rename it so a crash never leaves a
+	// truncated outbox behind
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to write outbox: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write outbox: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write outbox: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to write outbox: %w", err)
+	}
+	return nil
+}
+
+// messageHash identifies identical messages for deduplication. The Message-ID
+// and date are left out as they differ between otherwise identical messages.
+func messageHash(email EmailMessage) string {
+	h := sha256.New()
+	for _, part := range []string{
+		strings.Join(email.To, ","),
+		strings.Join(email.CC, ","),
+		strings.Join(email.BCC, ","),
+		email.Subject,
+		email.Body,
+	} {
+		h.Write([]byte(part))
+		h.Write([]byte{0x1f})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
@@ -0,0 +1,215 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+type mockDeliverer struct {
+	failures  int
+	delivered []string
+}
+
+func (m *mockDeliverer) Deliver(ctx context.Context, msg EmailMessage) error {
+	if m.failures > 0 {
+		m.failures--
+		return errors.New("smtp unavailable")
+	}
+	m.delivered = append(m.delivered, msg.MessageID)
+	return nil
+}
+
+func testEmail(body string) EmailMessage {
+	return EmailMessage{
+		MessageID:  newMessageID("sender@example.com"),
+		Recipients: Recipients{To: []string{"ops@example.com"}},
+		Subject:    "Error",
+		Body:       body,
+	}
+}
+
+func TestOutbox_EnqueueDedupes(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "outbox.json")
+	outbox, err := OpenOutbox(path, time.Hour)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	now := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	outbox.now = func() time.Time { return now }
+
+	first, enqueued, err := outbox.Enqueue(testEmail("body"))
+	if err != nil || !enqueued {
+		t.Fatalf("Expected first message to be enqueued, got %v %v", enqueued, err)
+	}
+
+	now = now.Add(30 * time.Minute)
+	dup, enqueued, _ := outbox.Enqueue(testEmail("body"))
+	if enqueued || dup.ID != first.ID {
+		t.Errorf("Expected identical message within the window to be deduped")
+	}
+	if _, enqueued, _ := outbox.Enqueue(testEmail("other body")); !enqueued {
+		t.Errorf("Expected a different message to be enqueued")
+	}
+
+	now = now.Add(time.Hour)
+	if _, enqueued, _ := outbox.Enqueue(testEmail("body")); !enqueued {
+		t.Errorf("Expected identical message after the window to be enqueued")
+	}
+
+	// Messages survive reopening the outbox
+	reopened, err := OpenOutbox(path, time.Hour)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if pending := reopened.List(OutboxPending); len(pending) != 3 {
+		t.Errorf("Expected 3 pending messages after reopening, got %d", len(pending))
+	}
+}
+
+func TestOutboxWorker_Flush(t *testing.T) {
+	tests := []struct {
+		name             string
+		failures         int
+		flushes          int
+		expectedStatus   string
+		expectedAttempts int
+		expectedAudit    string
+	}{
+		{
+			name:             "sent first time",
+			flushes:          1,
+			expectedStatus:   OutboxSent,
+			expectedAttempts: 1,
+			expectedAudit:    AuditResultDelivered,
+		},
+		{
+			name:             "retried after backoff",
+			failures:         2,
+			flushes:          3,
+			expectedStatus:   OutboxSent,
+			expectedAttempts: 3,
+			expectedAudit:    AuditResultDelivered,
+		},
+		{
+			name:             "failed after max attempts",
+			failures:         10,
+			flushes:          5,
+			expectedStatus:   OutboxFailed,
+			expectedAttempts: 3,
+			expectedAudit:    AuditResultFailed,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			outbox, _ := OpenOutbox("", time.Hour)
+			now := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+			outbox.now = func() time.Time { return now }
+			queued := AuditEntry{Action: AuditDecisionEmail, Email: "ops@example.com", RequestID: "req-1", Result: AuditResultQueued}
+			msg, _, _ := outbox.EnqueueAudited(testEmail("body"), &queued)
+
+			deliverer := &mockDeliverer{failures: tt.failures}
+			audit := NewAuditLog("")
+			worker := NewOutboxWorker(outbox, deliverer, slog.New(slog.NewTextHandler(io.Discard, nil)))
+			worker.UseAuditLog(audit)
+			worker.maxAttempts = 3
+			worker.backoff = time.Minute
+
+			for i := 0; i < tt.flushes; i++ {
+				worker.Flush(context.Background())
+
+				// A retry is not due until its backoff has passed
+				if len(outbox.Due()) > 0 {
+					t.Fatalf("Flush %d: expected nothing due immediately after a flush", i+1)
+				}
+				now = now.Add(time.Duration(1<<i) * time.Minute)
+			}
+
+			got := outbox.List("")[0]
+			if got.ID != msg.ID || got.Status != tt.expectedStatus || got.Attempts != tt.expectedAttempts {
+				t.Errorf("Expected %s after %d attempts, got %s after %d", tt.expectedStatus, tt.expectedAttempts, got.Status, got.Attempts)
+			}
+			if (got.Status == OutboxFailed) != (got.LastError != "") {
+				t.Errorf("Expected last error only on failed messages, got %q", got.LastError)
+			}
+
+			// Only the final outcome is audited, not each retry
+			entries, _ := audit.List(AuditFilter{})
+			if len(entries) != 1 {
+				t.Fatalf("Expected 1 audit entry, got %+v", entries)
+			}
+			entry := entries[0]
+			if entry.Result != tt.expectedAudit || entry.RequestID != "req-1" || entry.Details["outboxMessageId"] != msg.ID {
+				t.Errorf("Expected a %s entry for the message, got %+v", tt.expectedAudit, entry)
+			}
+			if (entry.Error != "") != (tt.expectedAudit == AuditResultFailed) {
+				t.Errorf("Expected an error only for failures, got %q", entry.Error)
+			}
+
+			if got.Status == OutboxFailed {
+				if err := outbox.Retry(got.ID); err != nil {
+					t.Fatalf("Unexpected error: %v", err)
+				}
+				if len(outbox.Due()) != 1 {
+					t.Errorf("Expected retried message to be due")
+				}
+			}
+		})
+	}
+}
+
+func TestOutbox_EraseAndRedact(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "outbox.json")
+	outbox, err := OpenOutbox(path, time.Hour)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	now := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	outbox.now = func() time.Time { return now }
+
+	decision := testEmail("Welcome aboard")
+	decision.To = []string{"John@Example.com"}
+	digest := testEmail("Pending: john@example.com (14 days)")
+	other := testEmail("Pending: jane@example.com (14 days)")
+	for _, msg := range []EmailMessage{decision, digest, other} {
+		if _, _, err := outbox.Enqueue(msg); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+
+	erased, err := outbox.Erase("john@example.com")
+	if err != nil || erased != 2 {
+		t.Fatalf("Expected 2 messages erased, got %d %v", erased, err)
+	}
+	reopened, err := OpenOutbox(path, time.Hour)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	messages := reopened.List("")
+	if len(messages) != 1 || messages[0].Body != other.Body {
+		t.Fatalf("Expected only the other message to be kept, got %+v", messages)
+	}
+
+	// Pending messages are kept whatever their age
+	cutoff := now.Add(time.Hour)
+	if redacted, err := outbox.Redact(cutoff, false); err != nil || redacted != 0 {
+		t.Errorf("Expected the pending message to be kept, got %d %v", redacted, err)
+	}
+	if err := outbox.MarkSent(messages[0].ID); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if redacted, err := outbox.Redact(cutoff, true); err != nil || redacted != 1 || len(outbox.List("")) != 1 {
+		t.Errorf("Expected a dry run to count the sent message and keep it, got %d %v", redacted, err)
+	}
+	if redacted, err := outbox.Redact(now, false); err != nil || redacted != 0 {
+		t.Errorf("Expected a message created at the cutoff to be kept, got %d %v", redacted, err)
+	}
+	if redacted, err := outbox.Redact(cutoff, false); err != nil || redacted != 1 || len(outbox.List("")) != 0 {
+		t.Errorf("Expected the sent message to be removed, got %d %v", redacted, err)
+	}
+}
@@ -0,0 +1,161 @@
+package services
+
+import (
+	"context"
+	"log/slog"
+	"maps"
+	"time"
+)
+
+const (
+	defaultOutboxMaxAttempts = 8
+	defaultOutboxBackoff     = 30 * time.Second
+	maxOutboxBackoff         = time.Hour
+	// DefaultOutboxInterval is how often a running worker checks for due messages
+	DefaultOutboxInterval = 15 * time.Second
+)
+
+// MessageDeliverer sends an email immediately
+type MessageDeliverer interface {
+	Deliver(ctx context.Context, msg EmailMessage) error
+}
+
+// OutboxWorker delivers queued email, retrying failures with exponential
+// backoff until a message has used up its attempts
+type OutboxWorker struct {
+	outbox      *Outbox
+	deliverer   MessageDeliverer
+	audit       *AuditLog
+	logger      *slog.Logger
+	maxAttempts int
+	backoff     time.Duration
+	interval    time.Duration
+}
+
+// NewOutboxWorker creates a worker that delivers messages from outbox
+func NewOutboxWorker(outbox *Outbox, deliverer MessageDeliverer, logger *slog.Logger) *OutboxWorker {
+	return &OutboxWorker{
+		outbox:      outbox,
+		deliverer:   deliverer,
+		logger:      logger,
+		maxAttempts: defaultOutboxMaxAttempts,
+		backoff:     defaultOutboxBackoff,
+		interval:    DefaultOutboxInterval,
+	}
+}
+
+// UseAuditLog makes the worker record the outcome of audited messages, such
+// as decision emails, in audit once they are delivered or have failed for good
+func (w *OutboxWorker) UseAuditLog(audit *AuditLog) {
+	w.audit = audit
+}
+
+// Run delivers due messages until ctx is cancelled
+func (w *OutboxWorker) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		w.Flush(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Drain delivers messages until none are pending or ctx is done, waiting for
+// retries that fall due in the meantime. Short-lived commands call it before
+// exiting; anything still pending is picked up by the next run.
+func (w *OutboxWorker) Drain(ctx context.Context) {
+	for {
+		w.Flush(ctx)
+
+		next, pending := w.outbox.NextAttempt()
+		if !pending {
+			return
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(time.Until(next)):
+		}
+	}
+}
+
+// Flush attempts every message that is due once and returns how many were sent
+func (w *OutboxWorker) Flush(ctx context.Context) int {
+	sent := 0
+	for _, msg := range w.outbox.Due() {
+		if ctx.Err() != nil {
+			break
+		}
+
+		log := w.logger.With(slog.String("message_id", msg.ID), slog.Int("attempt", msg.Attempts+1))
+		err := w.deliverer.Deliver(ctx, msg.EmailMessage)
+		if err == nil {
+			sent++
+			log.Info("queued email sent")
+			// A message erased meanwhile is no longer audited
+			if err := w.outbox.MarkSent(msg.ID); err != nil {
+				log.Error("failed to update outbox", slog.String("error", err.Error()))
+			} else {
+				w.recordOutcome(log, msg, nil)
+			}
+			continue
+		}
+
+		var retryAt time.Time
+		if msg.Attempts+1 < w.maxAttempts {
+			retryAt = w.outbox.now().Add(w.retryDelay(msg.Attempts + 1))
+			log.Warn("failed to send queued email, will retry",
+				slog.Time("retry_at", retryAt),
+				slog.String("error", err.Error()),
+			)
+		} else {
+			log.Error("failed to send queued email, giving up", slog.String("error", err.Error()))
+		}
+		if markErr := w.outbox.MarkAttemptFailed(msg.ID, err, retryAt); markErr != nil {
+			log.Error("failed to update outbox", slog.String("error", markErr.Error()))
+		} else if retryAt.IsZero() {
+			w.recordOutcome(log, msg, err)
+		}
+	}
+	return sent
+}
+
+// recordOutcome records that an audited message was delivered, or failed
+// for good with sendErr
+func (w *OutboxWorker) recordOutcome(log *slog.Logger, msg OutboxMessage, sendErr error) {
+	if w.audit == nil || msg.Audit == nil {
+		return
+	}
+	entry := *msg.Audit
+	entry.ID, entry.Timestamp = "", time.Time{}
+	entry.Details = maps.Clone(entry.Details)
+	if entry.Details == nil {
+		entry.Details = make(map[string]string)
+	}
+	entry.Details["outboxMessageId"] = msg.ID
+	entry.Result, entry.Error = AuditResultDelivered, ""
+	if sendErr != nil {
+		entry.Result, entry.Error = AuditResultFailed, sendErr.Error()
+	}
+	if err := w.audit.Record(entry); err != nil {
+		log.Error("failed to record email delivery", slog.String("error", err.Error()))
+	}
+}
+
+// retryDelay returns the backoff after the given number of failed attempts
+func (w *OutboxWorker) retryDelay(attempts int) time.Duration {
+	delay := w.backoff
+	for i := 1; i < attempts; i++ {
+		delay *= 2
+		if delay >= maxOutboxBackoff {
+			return maxOutboxBackoff
+		}
+	}
+	return delay
+}
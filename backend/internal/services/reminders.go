@@ -0,0 +1,272 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"log/slog"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/stevebennett/slack-invite-mgr/backend/internal/config"
+)
+
+// NotificationSender sends a notification email to the recipients routed for
+// an event
+type NotificationSender interface {
+	SendEmail(ctx context.Context, event, subject, body string) error
+}
+
+// ReminderRecord notes that a reminder went out for an invite at a threshold.
+// The applicant is identified by EmailHash; Email is only set in records
+// written before the hash was kept.
+type ReminderRecord struct {
+	Email         string    `json:"email,omitempty"`
+	EmailHash     string    `json:"emailHash,omitempty"`
+	SubmittedAt   string    `json:"submittedAt"`
+	ThresholdDays int       `json:"thresholdDays"`
+	SentAt        time.Time `json:"sentAt"`
+}
+
+// ReminderLog stores the stale invite reminders that have been sent. When
+// backed by a file, records are appended as JSON lines so they persist
+// between sync runs.
+type ReminderLog struct {
+	mu      sync.Mutex
+	path    string
+	entries []ReminderRecord
+}
+
+// NewReminderLog creates a reminder log backed by path, or kept in memory if path is empty
+func NewReminderLog(path string) *ReminderLog {
+	return &ReminderLog{path: path}
+}
+
+// Record appends reminder records
+func (l *ReminderLog) Record(records ...ReminderRecord) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.path == "" {
+		l.entries = append(l.entries, records...)
+		return nil
+	}
+	for _, record := range records {
+		if err := appendJSONLine(l.path, record); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sent returns the keys of reminders already sent, as built by reminderKey
+func (l *ReminderLog) sent() (map[string]bool, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	entries := l.entries
+	if l.path != "" {
+		var err error
+		if entries, err = readJSONLines[ReminderRecord](l.path); err != nil {
+			return nil, err
+		}
+	}
+
+	sent := make(map[string]bool, len(entries))
+	for _, entry := range entries {
+		sent[entry.key()] = true
+	}
+	return sent, nil
+}
+
+// Erase removes the records of reminders about email. It returns how many
+// records were removed.
+func (l *ReminderLog) Erase(email string) (int, error) {
+	hash := EmailHash(email)
+	return l.rewrite(false, func(records []ReminderRecord) ([]ReminderRecord, int) {
+		kept := records[:0]
+		for _, record := range records {
+			if record.emailHash() != hash {
+				kept = append(kept, record)
+			}
+		}
+		return kept, len(records) - len(kept)
+	})
+}
+
+// Redact replaces the email address in records sent before the cutoff with
+// its hash. It returns how many records were changed, or with dryRun would be.
+func (l *ReminderLog) Redact(before time.Time, dryRun bool) (int, error) {
+	return l.rewrite(dryRun, func(records []ReminderRecord) ([]ReminderRecord, int) {
+		changed := 0
+		for i, record := range records {
+			if record.Email != "" && record.SentAt.Before(before) {
+				records[i].EmailHash, records[i].Email = record.emailHash(), ""
+				changed++
+			}
+		}
+		return records, changed
+	})
+}
+
+// rewrite replaces the records with those returned by fn, unless fn changed
+// none of them or dryRun is set
+func (l *ReminderLog) rewrite(dryRun bool, fn func([]ReminderRecord) ([]ReminderRecord, int)) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	entries := slices.Clone(l.entries)
+	if l.path != "" {
+		// Hold the file's lock until it is rewritten, so appends wait
+		unlock, err := lockFile(l.path)
+		if err != nil {
+			return 0, err
+		}
+		defer unlock()
+		if entries, err = readJSONLines[ReminderRecord](l.path); err != nil {
+			return 0, err
+		}
+	}
+
+	entries, changed := fn(entries)
+	if changed == 0 || dryRun {
+		return changed, nil
+	}
+	if l.path != "" {
+		return changed, writeJSONLines(l.path, entries)
+	}
+	l.entries = entries
+	return changed, nil
+}
+
+// emailHash returns the hash of the record's applicant
+func (r ReminderRecord) emailHash() string {
+	if r.EmailHash != "" {
+		return r.EmailHash
+	}
+	return EmailHash(r.Email)
+}
+
+// key returns the reminderKey of the record
+func (r ReminderRecord) key() string {
+	return fmt.Sprintf("%s|%s|%d", r.emailHash(), r.SubmittedAt, r.ThresholdDays)
+}
+
+// reminderKey identifies a reminder for a submission at a threshold
+func reminderKey(invite Invite, thresholdDays int) string {
+	return ReminderRecord{EmailHash: EmailHash(invite.Email), SubmittedAt: invite.SubmittedAt, ThresholdDays: thresholdDays}.key()
+}
+
+// StaleInviteReminder emails reminders about pending invites that have waited
+// past each threshold. Each invite is reminded at most once per threshold,
+// and the final threshold escalates to its own recipients.
+type StaleInviteReminder struct {
+	// thresholds are in days, ascending
+	thresholds []int
+	reminders  *ReminderLog
+	sender     NotificationSender
+	logger     *slog.Logger
+	location   *time.Location
+	now        func() time.Time
+}
+
+// NewStaleInviteReminder creates a reminder for the given thresholds in days,
+// which must be ascending. Form timestamps are read in location, the sheet's
+// time zone.
+func NewStaleInviteReminder(thresholds []int, location *time.Location, reminders *ReminderLog, sender NotificationSender, logger *slog.Logger) *StaleInviteReminder {
+	return &StaleInviteReminder{
+		thresholds: thresholds,
+		reminders:  reminders,
+		sender:     sender,
+		logger:     logger,
+		location:   location,
+		now:        time.Now,
+	}
+}
+
+// Run sends reminders for the pending invites in rows, one email per
+// threshold, and returns how many invites were reminded about
+func (r *StaleInviteReminder) Run(ctx context.Context, rows [][]interface{}) (int, error) {
+	if len(r.thresholds) == 0 {
+		return 0, nil
+	}
+
+	sent, err := r.reminders.sent()
+	if err != nil {
+		return 0, fmt.Errorf("failed to read reminder log: %w", err)
+	}
+
+	// Each invite is due a reminder for the highest threshold it has passed;
+	// lower thresholds it skipped, such as when reminders were first enabled,
+	// are not sent
+	now := r.now()
+	due := make(map[int][]Invite)
+	for _, row := range rows {
+		invite := InviteFromRow(row)
+		if invite.Status != StatusPending || invite.Email == "" {
+			continue
+		}
+		submitted, ok := ParseTimestampInLocation(invite.SubmittedAt, r.location)
+		if !ok {
+			// Header rows and hand-edited timestamps cannot be aged
+			continue
+		}
+
+		threshold := 0
+		for _, days := range r.thresholds {
+			if now.Sub(submitted) >= time.Duration(days)*24*time.Hour {
+				threshold = days
+			}
+		}
+		if threshold > 0 && !sent[reminderKey(invite, threshold)] {
+			due[threshold] = append(due[threshold], invite)
+		}
+	}
+
+	reminded := 0
+	for _, threshold := range r.thresholds {
+		invites := due[threshold]
+		if len(invites) == 0 {
+			continue
+		}
+
+		final := threshold == r.thresholds[len(r.thresholds)-1]
+		event, subject := config.EmailEventStaleReminder, fmt.Sprintf("%d invites pending for over %d days", len(invites), threshold)
+		if final {
+			event, subject = config.EmailEventStaleEscalation, "Escalation: "+subject
+		}
+		if err := r.sender.SendEmail(ctx, event, subject, staleInvitesBody(invites, threshold)); err != nil {
+			return reminded, fmt.Errorf("failed to send %d day reminder: %w", threshold, err)
+		}
+
+		records := make([]ReminderRecord, len(invites))
+		for i, invite := range invites {
+			records[i] = ReminderRecord{EmailHash: EmailHash(invite.Email), SubmittedAt: invite.SubmittedAt, ThresholdDays: threshold, SentAt: now}
+		}
+		if err := r.reminders.Record(records...); err != nil {
+			return reminded, fmt.Errorf("failed to record %d day reminder: %w", threshold, err)
+		}
+
+		reminded += len(invites)
+		r.logger.Info("sent stale invite reminder",
+			slog.Int("threshold_days", threshold),
+			slog.Int("invites", len(invites)),
+			slog.Bool("escalation", final),
+		)
+	}
+	return reminded, nil
+}
+
+// staleInvitesBody lists the stale invites as HTML
+func staleInvitesBody(invites []Invite, threshold int) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "<p>These invites have been pending for over %d days:</p>\n<ul>\n", threshold)
+	for _, invite := range invites {
+		fmt.Fprintf(&b, "<li>%s &lt;%s&gt;, submitted %s</li>\n",
+			html.EscapeString(invite.Name), html.EscapeString(invite.Email), html.EscapeString(invite.SubmittedAt))
+	}
+	b.WriteString("</ul>\n")
+	return b.String()
+}
@@ -0,0 +1,203 @@
+package services
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stevebennett/slack-invite-mgr/backend/internal/config"
+)
+
+type recordingNotifier struct {
+	events   []string
+	subjects []string
+	bodies   []string
+}
+
+func (n *recordingNotifier) SendEmail(ctx context.Context, event, subject, body string) error {
+	n.events = append(n.events, event)
+	n.subjects = append(n.subjects, subject)
+	n.bodies = append(n.bodies, body)
+	return nil
+}
+
+func TestStaleInviteReminder_Run(t *testing.T) {
+	now := time.Date(2024, 3, 20, 12, 0, 0, 0, time.UTC)
+	submitted := func(daysAgo int) string {
+		return now.Add(-time.Duration(daysAgo) * 24 * time.Hour).Format(SubmittedAtLayout)
+	}
+	row := func(email string, daysAgo int, status string) []interface{} {
+		return []interface{}{submitted(daysAgo), "Name", "Engineer", email, "", "Acme", "5", "Reasons", "Referral", status, ""}
+	}
+
+	initial := [][]interface{}{
+		{"Timestamp", "Name", "Role", "Email", "", "Company", "Years", "Reasons", "Source", "Status", "Updated"},
+		row("fresh@example.com", 1, ""),
+		row("three@example.com", 4, ""),
+		row("seven@example.com", 8, ""),
+		row("old@example.com", 30, ""),
+		row("sent@example.com", 30, "Sent"),
+	}
+
+	// Each run ages the rows by a few days
+	tests := []struct {
+		name             string
+		advance          time.Duration
+		expectedEvents   []string
+		expectedSubjects []string
+		expectedCount    int
+	}{
+		{
+			name:           "first run",
+			expectedEvents: []string{config.EmailEventStaleReminder, config.EmailEventStaleReminder, config.EmailEventStaleEscalation},
+			expectedSubjects: []string{
+				"1 invites pending for over 3 days",
+				"1 invites pending for over 7 days",
+				"Escalation: 1 invites pending for over 14 days",
+			},
+			expectedCount: 3,
+		},
+		{
+			name:          "same day does not repeat",
+			expectedCount: 0,
+		},
+		{
+			name:           "invites reach the next thresholds",
+			advance:        3 * 24 * time.Hour,
+			expectedEvents: []string{config.EmailEventStaleReminder, config.EmailEventStaleReminder},
+			expectedSubjects: []string{
+				"1 invites pending for over 3 days",
+				"1 invites pending for over 7 days",
+			},
+			expectedCount: 2,
+		},
+	}
+
+	reminders := NewReminderLog(filepath.Join(t.TempDir(), "reminders.jsonl"))
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			now = now.Add(tt.advance)
+			notifier := &recordingNotifier{}
+			reminder := NewStaleInviteReminder([]int{3, 7, 14}, time.UTC, reminders, notifier, slog.New(slog.NewTextHandler(io.Discard, nil)))
+			reminder.now = func() time.Time { return now }
+
+			count, err := reminder.Run(context.Background(), initial)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if count != tt.expectedCount {
+				t.Errorf("Expected %d invites reminded, got %d", tt.expectedCount, count)
+			}
+			if !reflect.DeepEqual(notifier.events, tt.expectedEvents) {
+				t.Errorf("Expected events %v, got %v", tt.expectedEvents, notifier.events)
+			}
+			if !reflect.DeepEqual(notifier.subjects, tt.expectedSubjects) {
+				t.Errorf("Expected subjects %v, got %v", tt.expectedSubjects, notifier.subjects)
+			}
+			for _, body := range notifier.bodies {
+				if strings.Contains(body, "sent@example.com") {
+					t.Errorf("Expected only pending invites in reminders, got %q", body)
+				}
+			}
+		})
+	}
+}
+
+func TestStaleInviteReminder_RunTimestampFormats(t *testing.T) {
+	now := time.Date(2024, 3, 20, 12, 0, 0, 0, time.UTC)
+	row := func(email, submittedAt string) []interface{} {
+		return []interface{}{submittedAt, "Name", "Engineer", email, "", "Acme", "5", "Reasons", "Referral", "", ""}
+	}
+	rows := [][]interface{}{
+		row("forms@example.com", "3/16/2024 09:00:00"),
+		row("date@example.com", "2024-03-16"),
+		row("rfc3339@example.com", "2024-03-16T09:00:00Z"),
+		// 20:00 at UTC+10 is 10:00 UTC, just over 3 days ago; read as UTC it
+		// would be under 3 days
+		row("local@example.com", "2024-03-17 20:00:00"),
+		row("recent@example.com", "2024-03-18 09:00:00"),
+		row("unparsed@example.com", "last Tuesday"),
+	}
+
+	notifier := &recordingNotifier{}
+	reminder := NewStaleInviteReminder([]int{3}, time.FixedZone("AEST", 10*60*60), NewReminderLog(""), notifier, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	reminder.now = func() time.Time { return now }
+
+	count, err := reminder.Run(context.Background(), rows)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if count != 4 || len(notifier.bodies) != 1 {
+		t.Fatalf("Expected 4 invites in one reminder, got %d in %d", count, len(notifier.bodies))
+	}
+	for _, email := range []string{"forms@", "date@", "rfc3339@", "local@"} {
+		if !strings.Contains(notifier.bodies[0], email) {
+			t.Errorf("Expected %sexample.com to be reminded, got %q", email, notifier.bodies[0])
+		}
+	}
+}
+
+func TestReminderLog_EraseAndRedact(t *testing.T) {
+	old := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	recent := old.AddDate(0, 6, 0)
+	john := Invite{Email: "john@example.com", SubmittedAt: "2023-12-01 09:00:00"}
+	jane := Invite{Email: "jane@example.com", SubmittedAt: "2023-12-02 09:00:00"}
+
+	reminders := NewReminderLog(filepath.Join(t.TempDir(), "reminders.jsonl"))
+	err := reminders.Record(
+		// Records written before hashes were kept hold the address
+		ReminderRecord{Email: "John@Example.com", SubmittedAt: john.SubmittedAt, ThresholdDays: 7, SentAt: old},
+		ReminderRecord{Email: jane.Email, SubmittedAt: jane.SubmittedAt, ThresholdDays: 7, SentAt: old},
+		ReminderRecord{EmailHash: EmailHash(john.Email), SubmittedAt: john.SubmittedAt, ThresholdDays: 14, SentAt: recent},
+		ReminderRecord{Email: jane.Email, SubmittedAt: jane.SubmittedAt, ThresholdDays: 14, SentAt: recent},
+	)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	sentKeys := func() map[string]bool {
+		sent, err := reminders.sent()
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		return sent
+	}
+	before := sentKeys()
+	for _, invite := range []Invite{john, jane} {
+		if !before[reminderKey(invite, 7)] || !before[reminderKey(invite, 14)] {
+			t.Fatalf("Expected legacy and hashed records to match %s, got %v", invite.Email, before)
+		}
+	}
+
+	cutoff := old.AddDate(0, 1, 0)
+	if redacted, err := reminders.Redact(cutoff, true); err != nil || redacted != 2 {
+		t.Fatalf("Expected 2 records to redact, got %d %v", redacted, err)
+	}
+	if redacted, err := reminders.Redact(cutoff, false); err != nil || redacted != 2 {
+		t.Fatalf("Expected 2 records redacted, got %d %v", redacted, err)
+	}
+	if after := sentKeys(); !reflect.DeepEqual(after, before) {
+		t.Errorf("Expected redaction to keep the reminders sent, got %v", after)
+	}
+	records, err := readJSONLines[ReminderRecord](reminders.path)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	for _, record := range records {
+		if record.Email != "" && record.SentAt.Before(cutoff) {
+			t.Errorf("Expected old records to keep only the hash, got %+v", record)
+		}
+	}
+
+	if erased, err := reminders.Erase("JOHN@example.com"); err != nil || erased != 2 {
+		t.Fatalf("Expected 2 records erased, got %d %v", erased, err)
+	}
+	after := sentKeys()
+	if len(after) != 2 || !after[reminderKey(jane, 7)] || !after[reminderKey(jane, 14)] {
+		t.Errorf("Expected only jane's reminders to be kept, got %v", after)
+	}
+}
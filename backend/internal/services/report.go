@@ -0,0 +1,341 @@
+package services
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	htmltemplate "html/template"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Report periods
+const (
+	PeriodWeek  = "week"
+	PeriodMonth = "month"
+)
+
+// unknownCategory labels invites with no source or years of experience
+const unknownCategory = "unknown"
+
+// experienceBands group the leading number in the years-of-experience answer
+var experienceBands = []struct {
+	name string
+	max  int
+}{
+	{"0-2", 2},
+	{"3-5", 5},
+	{"6-10", 10},
+	{"11-20", 20},
+	{"21+", -1},
+}
+
+// ReportOptions selects the invites a report covers and how they are grouped
+type ReportOptions struct {
+	// Period is PeriodWeek or PeriodMonth
+	Period string
+	// SubmittedAfter and SubmittedBefore bound the submission times covered;
+	// zero values leave that side open
+	SubmittedAfter  time.Time
+	SubmittedBefore time.Time
+	// Location is the time zone of the sheet's timestamps, which periods
+	// follow; nil is UTC
+	Location *time.Location
+}
+
+// ReportStats are the funnel counts for a group of invites. Approving an
+// application sends its invite, so sent invites count as approvals.
+type ReportStats struct {
+	Name string `json:"name"`
+	// Start is when the period began, for per-period stats
+	Start     time.Time `json:"start,omitzero"`
+	Received  int       `json:"received"`
+	Pending   int       `json:"pending"`
+	NeedsInfo int       `json:"needsInfo"`
+	Sent      int       `json:"sent"`
+	Denied    int       `json:"denied"`
+	Duplicate int       `json:"duplicate"`
+	// ApprovalRate is sent / (sent + denied), or 0 before any decisions
+	ApprovalRate float64 `json:"approvalRate"`
+	// MedianDecisionHours is the median time from submission to the invite
+	// being sent or denied, or 0 before any decisions
+	MedianDecisionHours float64 `json:"medianDecisionHours"`
+
+	decisionHours []float64
+}
+
+// Report summarises invites per period, by source and by years of experience
+type Report struct {
+	GeneratedAt     time.Time     `json:"generatedAt"`
+	Period          string        `json:"period"`
+	SubmittedAfter  time.Time     `json:"submittedAfter,omitzero"`
+	SubmittedBefore time.Time     `json:"submittedBefore,omitzero"`
+	Totals          ReportStats   `json:"totals"`
+	Periods         []ReportStats `json:"periods"`
+	Sources         []ReportStats `json:"sources"`
+	ExperienceBands []ReportStats `json:"experienceBands"`
+}
+
+// BuildReport computes a report over invites submitted in the selected range.
+// Invites whose submission time cannot be parsed are left out.
+func BuildReport(invites []Invite, opts ReportOptions, now time.Time) (Report, error) {
+	if opts.Period == "" {
+		opts.Period = PeriodMonth
+	}
+	if opts.Period != PeriodWeek && opts.Period != PeriodMonth {
+		return Report{}, fmt.Errorf("unknown report period %q", opts.Period)
+	}
+
+	report := Report{
+		GeneratedAt:     now,
+		Period:          opts.Period,
+		SubmittedAfter:  opts.SubmittedAfter,
+		SubmittedBefore: opts.SubmittedBefore,
+		Totals:          ReportStats{Name: "total"},
+	}
+	periods := make(map[string]*ReportStats)
+	sources := make(map[string]*ReportStats)
+	bands := make(map[string]*ReportStats)
+
+	for _, invite := range invites {
+		submitted, ok := ParseTimestampInLocation(invite.SubmittedAt, opts.Location)
+		if !ok {
+			continue
+		}
+		if !opts.SubmittedAfter.IsZero() && submitted.Before(opts.SubmittedAfter) {
+			continue
+		}
+		if !opts.SubmittedBefore.IsZero() && !submitted.Before(opts.SubmittedBefore) {
+			continue
+		}
+
+		name, start := periodOf(submitted, opts.Period)
+		period, ok := periods[name]
+		if !ok {
+			period = &ReportStats{Name: name, Start: start}
+			periods[name] = period
+		}
+
+		source := strings.TrimSpace(invite.Source)
+		if source == "" {
+			source = unknownCategory
+		}
+		sourceKey := strings.ToLower(source)
+		sourceStats, ok := sources[sourceKey]
+		if !ok {
+			sourceStats = &ReportStats{Name: source}
+			sources[sourceKey] = sourceStats
+		}
+
+		band := experienceBand(invite.YearsExperience)
+		bandStats, ok := bands[band]
+		if !ok {
+			bandStats = &ReportStats{Name: band}
+			bands[band] = bandStats
+		}
+
+		for _, stats := range []*ReportStats{&report.Totals, period, sourceStats, bandStats} {
+			stats.add(invite, submitted)
+		}
+	}
+
+	report.Totals.finish()
+	report.Periods = sortedStats(periods, func(a, b ReportStats) bool { return a.Start.Before(b.Start) })
+	report.Sources = sortedStats(sources, func(a, b ReportStats) bool {
+		if a.Received != b.Received {
+			return a.Received > b.Received
+		}
+		return a.Name < b.Name
+	})
+	report.ExperienceBands = sortedStats(bands, func(a, b ReportStats) bool {
+		return experienceBandIndex(a.Name) < experienceBandIndex(b.Name)
+	})
+	return report, nil
+}
+
+// add counts an invite submitted at the given time
+func (s *ReportStats) add(invite Invite, submitted time.Time) {
+	s.Received++
+	switch invite.Status {
+	case StatusPending:
+		s.Pending++
+	case StatusNeedsInfo:
+		s.NeedsInfo++
+	case StatusSent:
+		s.Sent++
+	case StatusDenied:
+		s.Denied++
+	case StatusDuplicate:
+		s.Duplicate++
+	}
+
+	if invite.Status == StatusSent || invite.Status == StatusDenied {
+		// Column K is in the same time zone as column A
+		if decided, ok := ParseTimestampInLocation(invite.StatusUpdatedAt, submitted.Location()); ok && !decided.Before(submitted) {
+			s.decisionHours = append(s.decisionHours, decided.Sub(submitted).Hours())
+		}
+	}
+}
+
+// finish computes the approval rate and median decision time
+func (s *ReportStats) finish() {
+	if decided := s.Sent + s.Denied; decided > 0 {
+		s.ApprovalRate = roundTo(float64(s.Sent)/float64(decided), 4)
+	}
+	if n := len(s.decisionHours); n > 0 {
+		sort.Float64s(s.decisionHours)
+		median := s.decisionHours[n/2]
+		if n%2 == 0 {
+			median = (s.decisionHours[n/2-1] + s.decisionHours[n/2]) / 2
+		}
+		s.MedianDecisionHours = roundTo(median, 1)
+	}
+}
+
+// sortedStats finishes each group of stats and returns them in order
+func sortedStats(groups map[string]*ReportStats, less func(a, b ReportStats) bool) []ReportStats {
+	result := make([]ReportStats, 0, len(groups))
+	for _, stats := range groups {
+		stats.finish()
+		result = append(result, *stats)
+	}
+	sort.Slice(result, func(i, j int) bool { return less(result[i], result[j]) })
+	return result
+}
+
+// periodOf returns the name and start of the week or month containing t.
+// Weeks are ISO weeks starting on Monday, named like "2024-W09".
+func periodOf(t time.Time, period string) (string, time.Time) {
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	if period == PeriodWeek {
+		start := day.AddDate(0, 0, -((int(day.Weekday()) + 6) % 7))
+		year, week := start.ISOWeek()
+		return fmt.Sprintf("%d-W%02d", year, week), start
+	}
+	start := time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, t.Location())
+	return start.Format("2006-01"), start
+}
+
+// experienceBand buckets a free-text years-of-experience answer, such as
+// "5", "3-5" or "10+ years", by its first number
+func experienceBand(answer string) string {
+	start := strings.IndexAny(answer, "0123456789")
+	if start < 0 {
+		return unknownCategory
+	}
+	end := start
+	for end < len(answer) && answer[end] >= '0' && answer[end] <= '9' {
+		end++
+	}
+	years, err := strconv.Atoi(answer[start:end])
+	if err != nil {
+		return unknownCategory
+	}
+	for _, band := range experienceBands {
+		if band.max < 0 || years <= band.max {
+			return band.name
+		}
+	}
+	return unknownCategory
+}
+
+// experienceBandIndex orders bands from least to most experience, with
+// unknown answers last
+func experienceBandIndex(name string) int {
+	for i, band := range experienceBands {
+		if band.name == name {
+			return i
+		}
+	}
+	return len(experienceBands)
+}
+
+func roundTo(value float64, places int) float64 {
+	rounded, _ := strconv.ParseFloat(strconv.FormatFloat(value, 'f', places, 64), 64)
+	return rounded
+}
+
+// reportCSVHeader names the columns written by WriteReportCSV
+var reportCSVHeader = []string{
+	"section", "name", "start", "received", "pending", "needs_info", "sent", "denied", "duplicate",
+	"approval_rate", "median_decision_hours",
+}
+
+// WriteReportCSV writes the report as a single CSV table. The section column
+// tells apart the total, period, source and experience rows.
+func WriteReportCSV(w io.Writer, report Report) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(reportCSVHeader); err != nil {
+		return err
+	}
+
+	sections := []struct {
+		name  string
+		stats []ReportStats
+	}{
+		{"total", []ReportStats{report.Totals}},
+		{report.Period, report.Periods},
+		{"source", report.Sources},
+		{"experience", report.ExperienceBands},
+	}
+	for _, section := range sections {
+		for _, stats := range section.stats {
+			start := ""
+			if !stats.Start.IsZero() {
+				start = stats.Start.Format("2006-01-02")
+			}
+			record := []string{
+				section.name,
+				stats.Name,
+				start,
+				strconv.Itoa(stats.Received),
+				strconv.Itoa(stats.Pending),
+				strconv.Itoa(stats.NeedsInfo),
+				strconv.Itoa(stats.Sent),
+				strconv.Itoa(stats.Denied),
+				strconv.Itoa(stats.Duplicate),
+				strconv.FormatFloat(stats.ApprovalRate, 'f', -1, 64),
+				strconv.FormatFloat(stats.MedianDecisionHours, 'f', -1, 64),
+			}
+			if err := cw.Write(record); err != nil {
+				return err
+			}
+		}
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
+
+var reportHTMLTemplate = htmltemplate.Must(htmltemplate.New("report").Funcs(htmltemplate.FuncMap{
+	"percent": func(rate float64) string { return strconv.FormatFloat(rate*100, 'f', 1, 64) + "%" },
+	"rows": func(title string, rows []ReportStats) map[string]interface{} {
+		return map[string]interface{}{"Title": title, "Rows": rows}
+	},
+}).Parse(`<h2>Invite report by {{.Period}}</h2>
+<p>{{.Totals.Received}} applications received, {{.Totals.Sent}} approved and sent, {{.Totals.Denied}} denied and {{.Totals.Duplicate}} duplicates.
+Approval rate {{percent .Totals.ApprovalRate}}, median time to decision {{.Totals.MedianDecisionHours}} hours.</p>
+{{define "table"}}<table cellpadding="4" style="border-collapse: collapse;">
+<tr><th align="left">{{.Title}}</th><th>Received</th><th>Pending</th><th>Needs info</th><th>Sent</th><th>Denied</th><th>Duplicate</th><th>Approval rate</th><th>Median hours to decision</th></tr>
+{{range .Rows}}<tr><td>{{.Name}}</td><td align="right">{{.Received}}</td><td align="right">{{.Pending}}</td><td align="right">{{.NeedsInfo}}</td><td align="right">{{.Sent}}</td><td align="right">{{.Denied}}</td><td align="right">{{.Duplicate}}</td><td align="right">{{percent .ApprovalRate}}</td><td align="right">{{.MedianDecisionHours}}</td></tr>
+{{end}}</table>{{end}}
+<h3>By {{.Period}}</h3>
+{{template "table" (rows "Period" .Periods)}}
+<h3>By source</h3>
+{{template "table" (rows "Source" .Sources)}}
+<h3>By years of experience</h3>
+{{template "table" (rows "Years" .ExperienceBands)}}
+`))
+
+// RenderReportHTML renders the report as an HTML fragment for the body of
+// the email template
+func RenderReportHTML(report Report) (string, error) {
+	var buf bytes.Buffer
+	if err := reportHTMLTemplate.Execute(&buf, report); err != nil {
+		return "", fmt.Errorf("failed to render report: %w", err)
+	}
+	return buf.String(), nil
+}
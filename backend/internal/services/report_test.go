@@ -0,0 +1,133 @@
+package services
+
+import (
+	"bytes"
+	"encoding/csv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBuildReport(t *testing.T) {
+	invites := []Invite{
+		{SubmittedAt: "3/4/2024 10:00:00", Source: "Meetup", YearsExperience: "2", Status: StatusSent, StatusUpdatedAt: "2024-03-05 10:00:00"},
+		{SubmittedAt: "3/6/2024 09:00:00", Source: "meetup ", YearsExperience: "10+ years", Status: StatusDenied, StatusUpdatedAt: "2024-03-09 09:00:00"},
+		{SubmittedAt: "3/12/2024 12:00:00", Source: "Twitter", YearsExperience: "4-6", Status: StatusSent, StatusUpdatedAt: "2024-03-12 18:00:00"},
+		{SubmittedAt: "3/13/2024 12:00:00", Source: "", YearsExperience: "lots", Status: StatusPending},
+		{SubmittedAt: "4/1/2024 08:00:00", Source: "Twitter", YearsExperience: "25", Status: StatusDuplicate},
+		{SubmittedAt: "not a date", Source: "Twitter", Status: StatusSent},
+	}
+	now := time.Date(2024, 4, 2, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name            string
+		opts            ReportOptions
+		expectedPeriods []string
+		expectedTotals  ReportStats
+	}{
+		{
+			name:            "monthly",
+			opts:            ReportOptions{Period: PeriodMonth},
+			expectedPeriods: []string{"2024-03", "2024-04"},
+			expectedTotals: ReportStats{
+				Name: "total", Received: 5, Pending: 1, Sent: 2, Denied: 1, Duplicate: 1,
+				ApprovalRate: 0.6667, MedianDecisionHours: 24,
+			},
+		},
+		{
+			name:            "weekly within a range",
+			opts:            ReportOptions{Period: PeriodWeek, SubmittedBefore: time.Date(2024, 3, 13, 0, 0, 0, 0, time.UTC)},
+			expectedPeriods: []string{"2024-W10", "2024-W11"},
+			expectedTotals: ReportStats{
+				Name: "total", Received: 3, Sent: 2, Denied: 1,
+				ApprovalRate: 0.6667, MedianDecisionHours: 24,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			report, err := BuildReport(invites, tt.opts, now)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			totals := report.Totals
+			totals.decisionHours = nil
+			if totals.Name != tt.expectedTotals.Name || totals.Received != tt.expectedTotals.Received ||
+				totals.Pending != tt.expectedTotals.Pending || totals.Sent != tt.expectedTotals.Sent ||
+				totals.Denied != tt.expectedTotals.Denied || totals.Duplicate != tt.expectedTotals.Duplicate ||
+				totals.ApprovalRate != tt.expectedTotals.ApprovalRate || totals.MedianDecisionHours != tt.expectedTotals.MedianDecisionHours {
+				t.Errorf("Expected totals %+v, got %+v", tt.expectedTotals, totals)
+			}
+
+			var periods []string
+			for _, period := range report.Periods {
+				periods = append(periods, period.Name)
+			}
+			if strings.Join(periods, ",") != strings.Join(tt.expectedPeriods, ",") {
+				t.Errorf("Expected periods %v, got %v", tt.expectedPeriods, periods)
+			}
+		})
+	}
+
+	report, _ := BuildReport(invites, ReportOptions{}, now)
+
+	// Sources are grouped case-insensitively, most applications first
+	var sources []string
+	for _, source := range report.Sources {
+		sources = append(sources, source.Name)
+	}
+	if got := strings.Join(sources, ","); got != "Meetup,Twitter,unknown" {
+		t.Errorf("Expected sources Meetup,Twitter,unknown, got %s", got)
+	}
+
+	var bands []string
+	for _, band := range report.ExperienceBands {
+		bands = append(bands, band.Name)
+	}
+	if got := strings.Join(bands, ","); got != "0-2,3-5,6-10,21+,unknown" {
+		t.Errorf("Expected bands 0-2,3-5,6-10,21+,unknown, got %s", got)
+	}
+
+	if _, err := BuildReport(invites, ReportOptions{Period: "year"}, now); err == nil {
+		t.Errorf("Expected an error for an unknown period")
+	}
+}
+
+func TestReportOutputs(t *testing.T) {
+	invites := []Invite{
+		{SubmittedAt: "3/4/2024 10:00:00", Name: "<b>Jane</b>", Source: "<script>", YearsExperience: "2", Status: StatusSent, StatusUpdatedAt: "2024-03-05 10:00:00"},
+	}
+	report, err := BuildReport(invites, ReportOptions{Period: PeriodMonth}, time.Now())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := WriteReportCSV(&buf, report); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	records, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatalf("Failed to parse CSV: %v", err)
+	}
+	// Header, total, one month, one source and one band
+	if len(records) != 5 {
+		t.Fatalf("Expected 5 CSV records, got %d", len(records))
+	}
+	if got := strings.Join(records[2][:4], ","); got != "month,2024-03,2024-03-01,1" {
+		t.Errorf("Unexpected period row %q", got)
+	}
+
+	html, err := RenderReportHTML(report)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if strings.Contains(html, "<script>") || !strings.Contains(html, "&lt;script&gt;") {
+		t.Errorf("Expected sources to be escaped in HTML")
+	}
+	if !strings.Contains(html, "100.0%") {
+		t.Errorf("Expected the approval rate as a percentage in HTML")
+	}
+}
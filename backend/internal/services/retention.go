@@ -0,0 +1,199 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"google.golang.org/api/sheets/v4"
+)
+
+// RedactedValue replaces the name, company and reasons of redacted invites
+const RedactedValue = "[redacted]"
+
+// piiColumns are the columns holding personal data: name, email, company and
+// reasons. The timestamp, role, experience, source and status are kept so
+// redacted invites still count towards reports.
+var piiColumns = []int{1, columnEmail, 5, 7}
+
+// RedactInvites replaces the personal data of terminal invites decided before
+// the cutoff, on the sheet and the archive tabs. Emails are cleared so
+// redacted rows never match an applicant. It returns how many invites were
+// redacted, or with dryRun would be, on each tab.
+func (s *SheetsService) RedactInvites(ctx context.Context, before time.Time, dryRun bool) (map[string]int, error) {
+	lock := s.rowLock()
+	lock.RLock()
+	defer lock.RUnlock()
+
+	tabs, rows, err := s.readInviteTabs(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	counts := make(map[string]int)
+	var requests []*sheets.Request
+	for t, tab := range tabs {
+		for i, row := range rows[t] {
+			if i == 0 || cellString(row, 1) == RedactedValue {
+				continue // Skip the header row and rows already redacted
+			}
+			if _, ok := decidedBefore(row, before, s.cfg.Location); !ok {
+				continue
+			}
+			counts[tab.Title]++
+			for _, column := range piiColumns {
+				value := RedactedValue
+				if column == columnEmail {
+					value = ""
+				}
+				requests = append(requests, cellRequest(tab.SheetId, i, column, value))
+			}
+		}
+	}
+	if dryRun {
+		return counts, nil
+	}
+	if _, err := s.batchUpdate(ctx, requests); err != nil {
+		return nil, fmt.Errorf("failed to redact invites: %w", err)
+	}
+	return counts, nil
+}
+
+// EraseApplicant deletes every row for an email, compared as duplicate
+// marking compares them, from the sheet and the archive tabs. It returns how
+// many rows were deleted from each tab.
+func (s *SheetsService) EraseApplicant(ctx context.Context, email string) (map[string]int, error) {
+	email = NormalizeEmail(email)
+	if email == "" {
+		return nil, errors.New("an email is required")
+	}
+	lock := s.rowLock()
+	lock.Lock()
+	defer lock.Unlock()
+
+	tabs, rows, err := s.readInviteTabs(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	counts := make(map[string]int)
+	var requests []*sheets.Request
+	for t, tab := range tabs {
+		// Delete from the bottom so earlier rows keep their positions
+		for i := len(rows[t]) - 1; i >= 0; i-- {
+			if NormalizeEmail(cellString(rows[t][i], columnEmail)) != email {
+				continue
+			}
+			counts[tab.Title]++
+			requests = append(requests, &sheets.Request{DeleteDimension: &sheets.DeleteDimensionRequest{
+				Range: &sheets.DimensionRange{SheetId: tab.SheetId, Dimension: "ROWS", StartIndex: int64(i), EndIndex: int64(i + 1)},
+			}})
+		}
+	}
+	if _, err := s.batchUpdate(ctx, requests); err != nil {
+		return nil, fmt.Errorf("failed to erase applicant: %w", err)
+	}
+	return counts, nil
+}
+
+// PersonalDataStores are the stores outside the sheet that can hold an
+// applicant's email address. Nil stores are skipped.
+type PersonalDataStores struct {
+	Audit *AuditLog
+	// Outboxes are the API server's and the sheets tool's, counted together
+	Outboxes   []*Outbox
+	Deliveries *DeliveryLog
+	Reminders  *ReminderLog
+}
+
+// Erase removes or pseudonymizes every record about email in the stores. It
+// returns how many records were changed in each store, by the names used in
+// EraseApplicant responses, and stops at the first store that fails.
+func (p PersonalDataStores) Erase(email string) (map[string]int, error) {
+	return p.each(func(store personalDataStore) (int, error) { return store.Erase(email) })
+}
+
+// Redact removes or pseudonymizes the records written before the cutoff. It
+// returns how many records were changed, or with dryRun would be, in each store.
+func (p PersonalDataStores) Redact(before time.Time, dryRun bool) (map[string]int, error) {
+	return p.each(func(store personalDataStore) (int, error) { return store.Redact(before, dryRun) })
+}
+
+// personalDataStore is implemented by each of the PersonalDataStores
+type personalDataStore interface {
+	Erase(email string) (int, error)
+	Redact(before time.Time, dryRun bool) (int, error)
+}
+
+func (p PersonalDataStores) each(fn func(personalDataStore) (int, error)) (map[string]int, error) {
+	type namedStore struct {
+		name  string
+		store personalDataStore
+	}
+	var stores []namedStore
+	if p.Audit != nil {
+		stores = append(stores, namedStore{"audit", p.Audit})
+	}
+	for _, outbox := range p.Outboxes {
+		if outbox != nil {
+			stores = append(stores, namedStore{"outbox", outbox})
+		}
+	}
+	if p.Deliveries != nil {
+		stores = append(stores, namedStore{"webhookDeliveries", p.Deliveries})
+	}
+	if p.Reminders != nil {
+		stores = append(stores, namedStore{"reminders", p.Reminders})
+	}
+
+	counts := make(map[string]int)
+	for _, store := range stores {
+		count, err := fn(store.store)
+		if err != nil {
+			return counts, fmt.Errorf("failed to update the %s store: %w", store.name, err)
+		}
+		counts[store.name] += count
+	}
+	return counts, nil
+}
+
+// readInviteTabs reads columns A-K of the sheet and the archive tabs
+func (s *SheetsService) readInviteTabs(ctx context.Context) ([]*sheets.SheetProperties, [][][]interface{}, error) {
+	spreadsheet, err := s.service.SpreadsheetsGet(ctx, s.cfg.SpreadsheetID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get spreadsheet metadata: %w", err)
+	}
+	sheet, archives := s.inviteTabs(spreadsheet)
+	if sheet == nil {
+		return nil, nil, fmt.Errorf("sheet with name '%s' not found", s.cfg.SheetName)
+	}
+	tabs := append([]*sheets.SheetProperties{sheet}, archives...)
+	rows := make([][][]interface{}, len(tabs))
+	for t, tab := range tabs {
+		resp, err := s.service.Get(ctx, s.cfg.SpreadsheetID, CellRange{Sheet: tab.Title, EndColumn: inviteColumnCount}.A1())
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to retrieve sheet data: %w", err)
+		}
+		rows[t] = resp.Values
+	}
+	return tabs, rows, nil
+}
+
+// cellRequest builds the request that writes text into one cell
+func cellRequest(sheetId int64, rowIndex int, column int, value string) *sheets.Request {
+	return &sheets.Request{
+		UpdateCells: &sheets.UpdateCellsRequest{
+			Range: CellRange{
+				StartRow:    rowIndex,
+				EndRow:      rowIndex + 1,
+				StartColumn: column,
+				EndColumn:   column + 1,
+			}.GridRange(sheetId),
+			Rows: []*sheets.RowData{{Values: []*sheets.CellData{{
+				UserEnteredValue: &sheets.ExtendedValue{StringValue: &value},
+			}}}},
+			Fields: "userEnteredValue",
+		},
+	}
+}
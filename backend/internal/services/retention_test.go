@@ -0,0 +1,46 @@
+package services
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestPersonalDataStores(t *testing.T) {
+	old := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	audit := NewAuditLog("")
+	if err := audit.Record(AuditEntry{Timestamp: old, Action: AuditStatusChanged, Email: "john@example.com"}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	outbox, err := OpenOutbox("", time.Hour)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	msg := testEmail("Welcome aboard")
+	msg.To = []string{"john@example.com"}
+	if _, _, err := outbox.Enqueue(msg); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	reminders := NewReminderLog("")
+	if err := reminders.Record(ReminderRecord{Email: "john@example.com", SubmittedAt: "2023-12-01 09:00:00", ThresholdDays: 7, SentAt: old}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// The delivery log is not configured and is skipped
+	stores := PersonalDataStores{Audit: audit, Outboxes: []*Outbox{outbox}, Reminders: reminders}
+	redacted, err := stores.Redact(old.AddDate(0, 1, 0), true)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if expected := map[string]int{"audit": 1, "outbox": 0, "reminders": 1}; !reflect.DeepEqual(redacted, expected) {
+		t.Errorf("Expected %v redacted, got %v", expected, redacted)
+	}
+
+	erased, err := stores.Erase("john@example.com")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if expected := map[string]int{"audit": 1, "outbox": 1, "reminders": 1}; !reflect.DeepEqual(erased, expected) {
+		t.Errorf("Expected %v erased, got %v", expected, erased)
+	}
+}
@@ -0,0 +1,136 @@
+package services
+
+import (
+	"context"
+	"fmt"
+
+	"google.golang.org/api/sheets/v4"
+)
+
+// statusProtectionDescription identifies the protected range over columns J
+// and K, so formatting can be reapplied without stacking protections
+const statusProtectionDescription = "Invite status, updated by slack-invite-mgr"
+
+// statusColours are the background colours of status values in column J.
+// Pending invites have an empty cell and are left uncoloured.
+var statusColours = []struct {
+	status string
+	colour *sheets.Color
+}{
+	{StatusSent, &sheets.Color{Red: 0.85, Green: 0.94, Blue: 0.83}},
+	{StatusDenied, &sheets.Color{Red: 0.96, Green: 0.80, Blue: 0.80}},
+	{StatusDuplicate, &sheets.Color{Red: 0.90, Green: 0.90, Blue: 0.90}},
+	{StatusNeedsInfo, &sheets.Color{Red: 1, Green: 0.95, Blue: 0.80}},
+}
+
+// SheetFormatting configures the formatting applied by ApplyFormatting
+type SheetFormatting struct {
+	// Editors are the accounts, besides the spreadsheet owner, that may edit
+	// the status columns J and K. Include the service account, or status
+	// updates will be rejected.
+	Editors []string
+}
+
+// ApplyFormatting freezes the header row, colours column J by status, adds a
+// dropdown of allowed statuses to column J and protects columns J and K so
+// only the editors can change them. Rules and protections added by an earlier
+// run are replaced, so it is safe to run repeatedly.
+func (s *SheetsService) ApplyFormatting(ctx context.Context, formatting SheetFormatting) error {
+	sheet, err := s.sheetByName(ctx, s.cfg.SheetName)
+	if err != nil {
+		return err
+	}
+	requests := formattingRequests(sheet, formatting)
+	if _, err := s.batchUpdate(ctx, requests); err != nil {
+		return fmt.Errorf("failed to format sheet: %w", err)
+	}
+	return nil
+}
+
+// formattingRequests builds the requests ApplyFormatting sends for a sheet,
+// removing its own earlier rules and protections first
+func formattingRequests(sheet *sheets.Sheet, formatting SheetFormatting) []*sheets.Request {
+	sheetId := sheet.Properties.SheetId
+	// Column J below the header, open-ended so new rows are covered
+	statusRange := CellRange{StartRow: 1, StartColumn: columnStatus, EndColumn: columnStatus + 1}.GridRange(sheetId)
+
+	requests := []*sheets.Request{{
+		UpdateSheetProperties: &sheets.UpdateSheetPropertiesRequest{
+			Properties: &sheets.SheetProperties{
+				SheetId:        sheetId,
+				GridProperties: &sheets.GridProperties{FrozenRowCount: 1},
+			},
+			Fields: "gridProperties.frozenRowCount",
+		},
+	}}
+
+	// Delete from the end so earlier indexes stay valid
+	for i := len(sheet.ConditionalFormats) - 1; i >= 0; i-- {
+		rule := sheet.ConditionalFormats[i]
+		if len(rule.Ranges) == 1 && sameGridRange(rule.Ranges[0], statusRange) {
+			requests = append(requests, &sheets.Request{
+				DeleteConditionalFormatRule: &sheets.DeleteConditionalFormatRuleRequest{SheetId: sheetId, Index: int64(i)},
+			})
+		}
+	}
+	for i, sc := range statusColours {
+		requests = append(requests, &sheets.Request{
+			AddConditionalFormatRule: &sheets.AddConditionalFormatRuleRequest{
+				Index: int64(i),
+				Rule: &sheets.ConditionalFormatRule{
+					Ranges: []*sheets.GridRange{statusRange},
+					BooleanRule: &sheets.BooleanRule{
+						Condition: &sheets.BooleanCondition{
+							Type:   "TEXT_EQ",
+							Values: []*sheets.ConditionValue{{UserEnteredValue: StatusCellValue(sc.status)}},
+						},
+						Format: &sheets.CellFormat{BackgroundColor: sc.colour},
+					},
+				},
+			},
+		})
+	}
+
+	var allowed []*sheets.ConditionValue
+	for _, status := range KnownStatuses {
+		if value := StatusCellValue(status); value != "" {
+			allowed = append(allowed, &sheets.ConditionValue{UserEnteredValue: value})
+		}
+	}
+	requests = append(requests, &sheets.Request{
+		SetDataValidation: &sheets.SetDataValidationRequest{
+			Range: statusRange,
+			Rule: &sheets.DataValidationRule{
+				Condition:    &sheets.BooleanCondition{Type: "ONE_OF_LIST", Values: allowed},
+				InputMessage: "Leave empty while the invite is pending",
+				ShowCustomUi: true,
+				Strict:       true,
+			},
+		},
+	})
+
+	for _, protected := range sheet.ProtectedRanges {
+		if protected.Description == statusProtectionDescription {
+			requests = append(requests, &sheets.Request{
+				DeleteProtectedRange: &sheets.DeleteProtectedRangeRequest{ProtectedRangeId: protected.ProtectedRangeId},
+			})
+		}
+	}
+	requests = append(requests, &sheets.Request{
+		AddProtectedRange: &sheets.AddProtectedRangeRequest{
+			ProtectedRange: &sheets.ProtectedRange{
+				Range:       CellRange{StartColumn: columnStatus, EndColumn: columnStatusUpdated + 1}.GridRange(sheetId),
+				Description: statusProtectionDescription,
+				Editors:     &sheets.Editors{Users: formatting.Editors},
+			},
+		},
+	})
+	return requests
+}
+
+// sameGridRange reports whether two grid ranges cover the same cells
+func sameGridRange(a, b *sheets.GridRange) bool {
+	return a.SheetId == b.SheetId &&
+		a.StartRowIndex == b.StartRowIndex && a.EndRowIndex == b.EndRowIndex &&
+		a.StartColumnIndex == b.StartColumnIndex && a.EndColumnIndex == b.EndColumnIndex
+}
@@ -2,9 +2,13 @@ package services
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
-	"strconv"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/stevebennett/slack-invite-mgr/backend/internal/config"
 	"google.golang.org/api/sheets/v4"
@@ -16,6 +20,7 @@ type sheetsService interface {
 	Get(ctx context.Context, spreadsheetId string, readRange string) (*sheets.ValueRange, error)
 	BatchUpdate(ctx context.Context, spreadsheetId string, request *sheets.BatchUpdateSpreadsheetRequest) (*sheets.BatchUpdateSpreadsheetResponse, error)
 	SpreadsheetsGet(ctx context.Context, spreadsheetId string) (*sheets.Spreadsheet, error)
+	Append(ctx context.Context, spreadsheetId string, appendRange string, values *sheets.ValueRange) (*sheets.AppendValuesResponse, error)
 }
 
 // realSheetsService wraps *sheets.Service to implement sheetsService
@@ -37,20 +42,59 @@ func (r *realSheetsService) SpreadsheetsGet(ctx context.Context, spreadsheetId s
 	return r.svc.Spreadsheets.Get(spreadsheetId).Context(ctx).Do()
 }
 
+// Append writes values as RAW so user-supplied text such as "=SUM(...)" is
+// never evaluated as a formula
+func (r *realSheetsService) Append(ctx context.Context, spreadsheetId string, appendRange string, values *sheets.ValueRange) (*sheets.AppendValuesResponse, error) {
+	return r.svc.Spreadsheets.Values.Append(spreadsheetId, appendRange, values).
+		ValueInputOption("RAW").
+		InsertDataOption("INSERT_ROWS").
+		Context(ctx).
+		Do()
+}
+
 // SheetsServiceInterface defines the methods we need from the sheets service
 type SheetsServiceInterface interface {
 	GetSheetData(ctx context.Context) ([][]interface{}, error)
-	UpdateInviteStatus(ctx context.Context, emails []string, status string, timestamp string) error
-	UpdateDuplicateRequests(ctx context.Context, timestamp string) error
+	GetAllSheetData(ctx context.Context) ([][]interface{}, error)
+	UpdateInviteStatus(ctx context.Context, update StatusUpdate) ([]StatusUpdateResult, error)
+	UpdateDuplicateRequests(ctx context.Context, timestamp string) ([]StatusUpdateResult, error)
 	GetNewInvites(ctx context.Context) (int, error)
+	AppendInvite(ctx context.Context, invite Invite) error
+	AppendInvites(ctx context.Context, invites []Invite) error
+	EachRow(ctx context.Context, fn func(row []interface{}) error) error
+	ApplyFormatting(ctx context.Context, formatting SheetFormatting) error
+	ArchiveInvites(ctx context.Context, opts ArchiveOptions) (map[string]int, error)
+	GetArchivedSheetData(ctx context.Context) ([][]interface{}, error)
+	RedactInvites(ctx context.Context, before time.Time, dryRun bool) (map[string]int, error)
+	EraseApplicant(ctx context.Context, email string) (map[string]int, error)
 }
 
+// sheetPageRows is how many rows EachRow reads from the sheet per request
+const sheetPageRows = 1000
+
+// maxBatchRequests is how many requests go in one spreadsheets.batchUpdate
+// call, keeping each call well under the API's request size limit
+const maxBatchRequests = 500
+
 // SheetsService handles operations related to Google Sheets
 type SheetsService struct {
 	service sheetsService
 	cfg     *config.SheetsConfig
 }
 
+// rowLocks holds a lock per spreadsheet, shared by every SheetsService in the
+// process, since handlers may each create their own. Deleting rows takes it
+// exclusively, and writes that address rows by position hold it shared from
+// reading the rows until they are written, so they never land on a row that
+// has moved up.
+var rowLocks sync.Map
+
+// rowLock returns the lock on the rows of the service's spreadsheet
+func (s *SheetsService) rowLock() *sync.RWMutex {
+	lock, _ := rowLocks.LoadOrStore(s.cfg.SpreadsheetID, new(sync.RWMutex))
+	return lock.(*sync.RWMutex)
+}
+
 // NewSheetsService creates a new SheetsService instance
 func NewSheetsService(ctx context.Context, cfg *config.SheetsConfig) (SheetsServiceInterface, error) {
 	service, err := config.GetSheetsService(ctx, cfg)
@@ -66,7 +110,7 @@ func NewSheetsService(ctx context.Context, cfg *config.SheetsConfig) (SheetsServ
 // GetSheetData retrieves data from the specified sheet range
 func (s *SheetsService) GetSheetData(ctx context.Context) ([][]interface{}, error) {
 	// Define the range to read (columns A-J)
-	rangeStr := fmt.Sprintf("%s!A:J", s.cfg.SheetName)
+	rangeStr := CellRange{Sheet: s.cfg.SheetName, EndColumn: columnStatus + 1}.A1()
 
 	// Make the API call
 	resp, err := s.service.Get(ctx, s.cfg.SpreadsheetID, rangeStr)
@@ -91,159 +135,229 @@ func (s *SheetsService) GetSheetData(ctx context.Context) ([][]interface{}, erro
 	return filtered, nil
 }
 
+// GetAllSheetData retrieves every row from the sheet, including processed ones
+func (s *SheetsService) GetAllSheetData(ctx context.Context) ([][]interface{}, error) {
+	// Define the range to read (columns A-K)
+	rangeStr := CellRange{Sheet: s.cfg.SheetName, EndColumn: inviteColumnCount}.A1()
+
+	// Make the API call
+	resp, err := s.service.Get(ctx, s.cfg.SpreadsheetID, rangeStr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve sheet data: %w", err)
+	}
+
+	// Ensure every row has columns A through K by padding with empty strings
+	rows := make([][]interface{}, 0, len(resp.Values))
+	for _, row := range resp.Values {
+		for len(row) < 11 {
+			row = append(row, "")
+		}
+		rows = append(rows, row)
+	}
+
+	return rows, nil
+}
+
+// EachRow calls fn with every row of the sheet, padded to columns A-K. The
+// sheet is read in pages so large sheets are never held in memory at once.
+// It stops at the first error returned by fn.
+func (s *SheetsService) EachRow(ctx context.Context, fn func(row []interface{}) error) error {
+	properties, err := s.sheetProperties(ctx, s.cfg.SheetName)
+	if err != nil {
+		return err
+	}
+	rowCount := 0
+	if properties.GridProperties != nil {
+		rowCount = int(properties.GridProperties.RowCount)
+	}
+
+	for start := 0; start < rowCount; start += sheetPageRows {
+		page := CellRange{
+			Sheet:     s.cfg.SheetName,
+			StartRow:  start,
+			EndRow:    min(start+sheetPageRows, rowCount),
+			EndColumn: inviteColumnCount,
+		}
+		rangeStr := page.A1()
+		resp, err := s.service.Get(ctx, s.cfg.SpreadsheetID, rangeStr)
+		if err != nil {
+			return fmt.Errorf("failed to retrieve sheet data: %w", err)
+		}
+		for _, row := range resp.Values {
+			for len(row) < 11 {
+				row = append(row, "")
+			}
+			if err := fn(row); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
 // getSheetIDByName fetches the SheetId for a given sheet name
 func (s *SheetsService) getSheetIDByName(ctx context.Context, sheetName string) (int64, error) {
+	properties, err := s.sheetProperties(ctx, sheetName)
+	if err != nil {
+		return 0, err
+	}
+	return properties.SheetId, nil
+}
+
+// sheetProperties fetches the properties of the sheet with the given name
+func (s *SheetsService) sheetProperties(ctx context.Context, sheetName string) (*sheets.SheetProperties, error) {
+	sheet, err := s.sheetByName(ctx, sheetName)
+	if err != nil {
+		return nil, err
+	}
+	return sheet.Properties, nil
+}
+
+// sheetByName fetches the metadata of the sheet with the given name
+func (s *SheetsService) sheetByName(ctx context.Context, sheetName string) (*sheets.Sheet, error) {
 	spreadsheet, err := s.service.SpreadsheetsGet(ctx, s.cfg.SpreadsheetID)
 	if err != nil {
-		return 0, fmt.Errorf("failed to get spreadsheet metadata: %w", err)
+		return nil, fmt.Errorf("failed to get spreadsheet metadata: %w", err)
 	}
 	for _, sheet := range spreadsheet.Sheets {
 		if sheet.Properties != nil && sheet.Properties.Title == sheetName {
-			return sheet.Properties.SheetId, nil
+			return sheet, nil
 		}
 	}
-	return 0, fmt.Errorf("sheet with name '%s' not found", sheetName)
+	return nil, fmt.Errorf("sheet with name '%s' not found", sheetName)
 }
 
-// UpdateDuplicateRequests marks duplicate email addresses in column D by updating column J to "Duplicate" and column K with the current timestamp
-func (s *SheetsService) UpdateDuplicateRequests(ctx context.Context, timestamp string) error {
+// UpdateDuplicateRequests marks duplicate email addresses in column D by updating column J to "Duplicate" and column K with the current timestamp.
+// Only those two cells are written, so the rest of each row keeps its values and types.
+// Invites in the archive tabs count as earlier occurrences, so people who reapply after their invite was archived are marked too.
+// Rows that already have a status in column J are left alone, so each duplicate is marked once.
+// It returns a result for each invite marked. If a batch fails, the results for earlier batches are returned with the error.
+func (s *SheetsService) UpdateDuplicateRequests(ctx context.Context, timestamp string) ([]StatusUpdateResult, error) {
+	lock := s.rowLock()
+	lock.RLock()
+	defer lock.RUnlock()
+
 	// Get the correct SheetId for the sheet name
 	sheetId, err := s.getSheetIDByName(ctx, s.cfg.SheetName)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	// Define the range to read (columns A-K)
-	rangeStr := fmt.Sprintf("%s!A:K", s.cfg.SheetName)
+	rangeStr := CellRange{Sheet: s.cfg.SheetName, EndColumn: inviteColumnCount}.A1()
 
 	// Make the API call to get all rows
 	resp, err := s.service.Get(ctx, s.cfg.SpreadsheetID, rangeStr)
 	if err != nil {
-		return fmt.Errorf("failed to retrieve sheet data: %w", err)
+		return nil, fmt.Errorf("failed to retrieve sheet data: %w", err)
 	}
 
-	// Map to track the first occurrence of each email address
-	emailMap := make(map[string]int)
-	// Slice to hold the rows that need to be updated
-	var updates []*sheets.ValueRange
+	// Set of email addresses seen so far, starting with the archived invites
+	archived, err := s.GetArchivedSheetData(ctx)
+	if err != nil {
+		return nil, err
+	}
+	emailMap := make(map[string]bool)
+	for _, row := range archived {
+		if invite := InviteFromRow(row); IsKnownStatus(invite.Status) {
+			emailMap[NormalizeEmail(invite.Email)] = true
+		}
+	}
+	// Status cell updates, one per marked invite
+	var updates []*sheets.Request
+	var marked []StatusUpdateResult
 
 	// Iterate through the rows
 	for i, row := range resp.Values {
-		if len(row) < 4 {
+		if len(row) <= columnEmail {
 			continue // Skip rows with fewer than 4 columns
 		}
-		email, ok := row[3].(string)
+		email, ok := row[columnEmail].(string)
 		if !ok {
 			continue // Skip if email is not a string
 		}
 
 		// Normalize email for comparison (lowercase and trim whitespace)
-		email = strings.ToLower(strings.TrimSpace(email))
+		email = NormalizeEmail(email)
 		if email == "" {
 			continue // Skip empty emails
 		}
 
 		// Check if this email has been seen before
-		if firstIndex, exists := emailMap[email]; exists {
-			// If the first occurrence has an empty column J, mark this row as duplicate
-			if len(resp.Values[firstIndex]) < 10 || resp.Values[firstIndex][9] == "" {
-				// Ensure the row has at least 11 columns
-				for len(row) < 11 {
-					row = append(row, "")
-				}
-				// Update column J to "Duplicate" and column K with the timestamp
-				row[9] = "Duplicate"
-				row[10] = timestamp
-				// Prepare the update
-				updates = append(updates, &sheets.ValueRange{
-					Range:  fmt.Sprintf("%s!A%d:K%d", s.cfg.SheetName, i+1, i+1),
-					Values: [][]interface{}{row},
-				})
-			} else {
-				// If the first occurrence has a non-empty column J, mark this row as duplicate if its column J is empty
-				if len(row) < 10 || row[9] == "" {
-					for len(row) < 11 {
-						row = append(row, "")
-					}
-					row[9] = "Duplicate"
-					row[10] = timestamp
-					updates = append(updates, &sheets.ValueRange{
-						Range:  fmt.Sprintf("%s!A%d:K%d", s.cfg.SheetName, i+1, i+1),
-						Values: [][]interface{}{row},
-					})
-				}
-			}
-		} else {
+		if !emailMap[email] {
 			// First occurrence of this email
-			emailMap[email] = i
+			emailMap[email] = true
+			continue
+		}
+		// Only mark rows without a status, so decisions and earlier marks stay
+		previous := cellString(row, columnStatus)
+		if previous != "" {
+			continue
 		}
+		// Update column J to "Duplicate" and column K with the timestamp
+		updates = append(updates, statusCellsRequest(sheetId, i, "Duplicate", timestamp))
+		invite := InviteFromRow(updatedRow(row, "Duplicate", timestamp))
+		marked = append(marked, StatusUpdateResult{
+			Email:          invite.Email,
+			Result:         UpdateResultUpdated,
+			PreviousStatus: NormalizeStatus(previous),
+			Invite:         &invite,
+		})
 	}
 
-	// Apply the updates if any
-	if len(updates) > 0 {
-		// Prepare the batch update request
-		var requests []*sheets.Request
-		for _, update := range updates {
-			// Parse the range string into a GridRange
-			parts := strings.Split(update.Range, "!")
-			if len(parts) != 2 {
-				continue
-			}
-			cellRange := parts[1]
-			// Assuming the range is in the format 'A1:K1', extract start and end
-			startCell := strings.Split(cellRange, ":")[0]
-			endCell := strings.Split(cellRange, ":")[1]
-			startCol := startCell[0] - 'A'
-			endCol := endCell[0] - 'A'
-			startRow, _ := strconv.Atoi(startCell[1:])
-			endRow, _ := strconv.Atoi(endCell[1:])
-
-			// Convert update.Values to []*sheets.RowData
-			var rows []*sheets.RowData
-			for _, row := range update.Values {
-				var cells []*sheets.CellData
-				for _, cell := range row {
-					cells = append(cells, &sheets.CellData{
-						UserEnteredValue: &sheets.ExtendedValue{
-							StringValue: func() *string { s := fmt.Sprintf("%v", cell); return &s }(),
-						},
-					})
-				}
-				rows = append(rows, &sheets.RowData{
-					Values: cells,
-				})
-			}
+	// Apply the updates in batches
+	written, err := s.batchUpdate(ctx, updates)
+	if err != nil {
+		return marked[:written], fmt.Errorf("failed to update duplicate rows: %w", err)
+	}
 
-			requests = append(requests, &sheets.Request{
-				UpdateCells: &sheets.UpdateCellsRequest{
-					Range: &sheets.GridRange{
-						SheetId:          sheetId,
-						StartRowIndex:    int64(startRow - 1),
-						EndRowIndex:      int64(endRow),
-						StartColumnIndex: int64(startCol),
-						EndColumnIndex:   int64(endCol + 1),
-					},
-					Rows:   rows,
-					Fields: "userEnteredValue",
-				},
-			})
-		}
+	return marked, nil
+}
 
-		// Execute the batch update
-		_, err = s.service.BatchUpdate(ctx, s.cfg.SpreadsheetID, &sheets.BatchUpdateSpreadsheetRequest{
-			Requests: requests,
+// batchUpdate sends requests in batches of at most maxBatchRequests and
+// returns how many were applied. Each batch is applied atomically, so on an
+// error the count covers only the batches sent before it.
+func (s *SheetsService) batchUpdate(ctx context.Context, requests []*sheets.Request) (int, error) {
+	written := 0
+	for written < len(requests) {
+		end := min(written+maxBatchRequests, len(requests))
+		_, err := s.service.BatchUpdate(ctx, s.cfg.SpreadsheetID, &sheets.BatchUpdateSpreadsheetRequest{
+			Requests: requests[written:end],
 		})
 		if err != nil {
-			return fmt.Errorf("failed to update duplicate rows: %w", err)
+			return written, err
 		}
+		written = end
 	}
+	return written, nil
+}
 
+// AppendInvite adds an invite as a new row at the end of the sheet
+func (s *SheetsService) AppendInvite(ctx context.Context, invite Invite) error {
+	return s.AppendInvites(ctx, []Invite{invite})
+}
+
+// AppendInvites adds invites as new rows at the end of the sheet in a single request
+func (s *SheetsService) AppendInvites(ctx context.Context, invites []Invite) error {
+	if len(invites) == 0 {
+		return nil
+	}
+	values := make([][]interface{}, len(invites))
+	for i, invite := range invites {
+		values[i] = InviteRow(invite)
+	}
+
+	rangeStr := CellRange{Sheet: s.cfg.SheetName, EndColumn: inviteColumnCount}.A1()
+	_, err := s.service.Append(ctx, s.cfg.SpreadsheetID, rangeStr, &sheets.ValueRange{Values: values})
+	if err != nil {
+		return fmt.Errorf("failed to append invite: %w", err)
+	}
 	return nil
 }
 
 // GetNewInvites returns the number of rows that have an empty column J (new invites that need processing)
 func (s *SheetsService) GetNewInvites(ctx context.Context) (int, error) {
 	// Define the range to read (columns A-J)
-	rangeStr := fmt.Sprintf("%s!A:J", s.cfg.SheetName)
+	rangeStr := CellRange{Sheet: s.cfg.SheetName, EndColumn: columnStatus + 1}.A1()
 
 	// Make the API call
 	resp, err := s.service.Get(ctx, s.cfg.SpreadsheetID, rangeStr)
@@ -262,66 +376,129 @@ func (s *SheetsService) GetNewInvites(ctx context.Context) (int, error) {
 	return newInvites, nil
 }
 
-// UpdateInviteStatus updates the status of invites in the sheet
-func (s *SheetsService) UpdateInviteStatus(ctx context.Context, emails []string, status string, timestamp string) error {
+// Per-email outcomes of a bulk status update
+const (
+	UpdateResultUpdated           = "updated"
+	UpdateResultNotFound          = "not_found"
+	UpdateResultInvalidTransition = "invalid_transition"
+	UpdateResultAlreadyInStatus   = "already_in_status"
+	UpdateResultVersionMismatch   = "version_mismatch"
+)
+
+var (
+	// ErrUnknownEmails is returned by an atomic status update when any of the
+	// requested emails is not in the sheet. Nothing is written in that case.
+	ErrUnknownEmails = errors.New("one or more emails were not found in the sheet")
+	// ErrVersionMismatch is returned when a row has changed since the caller
+	// read it. Nothing is written in that case.
+	ErrVersionMismatch = errors.New("one or more invites have changed since they were read")
+)
+
+// StatusUpdate describes a bulk change to invite statuses
+type StatusUpdate struct {
+	Emails    []string
+	Status    string
+	Timestamp string
+	// Atomic rejects the whole update if any email is not found
+	Atomic bool
+	// ExpectedVersions, when non-nil, lists the row versions the caller last
+	// read. The update is rejected if any matched row has another version.
+	ExpectedVersions []string
+}
+
+// StatusUpdateResult reports what happened to a single email in a bulk update
+type StatusUpdateResult struct {
+	Email          string `json:"email"`
+	Result         string `json:"result"`
+	PreviousStatus string `json:"previousStatus,omitempty"`
+	// Invite is the updated invite, set when Result is UpdateResultUpdated
+	Invite *Invite `json:"-"`
+}
+
+// UpdateInviteStatus updates the status of invites in the sheet and reports
+// the outcome for each requested email
+func (s *SheetsService) UpdateInviteStatus(ctx context.Context, update StatusUpdate) ([]StatusUpdateResult, error) {
+	lock := s.rowLock()
+	lock.RLock()
+	defer lock.RUnlock()
+
 	// Get the correct SheetId for the sheet name
 	sheetId, err := s.getSheetIDByName(ctx, s.cfg.SheetName)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	// Define the range to read (columns A-K)
-	rangeStr := fmt.Sprintf("%s!A:K", s.cfg.SheetName)
+	rangeStr := CellRange{Sheet: s.cfg.SheetName, EndColumn: inviteColumnCount}.A1()
 
 	// Make the API call to get all rows
 	resp, err := s.service.Get(ctx, s.cfg.SpreadsheetID, rangeStr)
 	if err != nil {
-		return fmt.Errorf("failed to retrieve sheet data: %w", err)
+		return nil, fmt.Errorf("failed to retrieve sheet data: %w", err)
 	}
 
-	// Create a map of email to row index
+	// Create a map of normalized email to row index. Duplicate rows are only
+	// used when the email has no other row, so updates land on the original.
 	emailToRow := make(map[string]int)
 	for i, row := range resp.Values {
-		if len(row) >= 4 { // Ensure we have at least the email column
-			if email, ok := row[3].(string); ok {
-				emailToRow[email] = i + 1 // +1 because sheet rows are 1-indexed
-			}
+		email := NormalizeEmail(cellString(row, 3))
+		if email == "" {
+			continue
+		}
+		if existing, exists := emailToRow[email]; exists &&
+			NormalizeStatus(cellString(resp.Values[existing], 9)) != StatusDuplicate {
+			continue
 		}
+		emailToRow[email] = i
 	}
 
-	// Prepare the batch update request
+	// Work out the result for each email, ignoring repeats in the request
+	statusValue := StatusCellValue(update.Status)
+	seen := make(map[string]bool)
+	var results []StatusUpdateResult
 	var requests []*sheets.Request
-	for _, email := range emails {
-		if rowIndex, exists := emailToRow[email]; exists {
-			// Create the update for columns J and K
-			requests = append(requests, &sheets.Request{
-				UpdateCells: &sheets.UpdateCellsRequest{
-					Range: &sheets.GridRange{
-						SheetId:          sheetId,
-						StartRowIndex:    int64(rowIndex - 1),
-						EndRowIndex:      int64(rowIndex),
-						StartColumnIndex: 9,  // Column J
-						EndColumnIndex:   11, // Column K + 1
-					},
-					Rows: []*sheets.RowData{
-						{
-							Values: []*sheets.CellData{
-								{
-									UserEnteredValue: &sheets.ExtendedValue{
-										StringValue: &status,
-									},
-								},
-								{
-									UserEnteredValue: &sheets.ExtendedValue{
-										StringValue: &timestamp,
-									},
-								},
-							},
-						},
-					},
-					Fields: "userEnteredValue",
-				},
-			})
+	for _, email := range update.Emails {
+		key := NormalizeEmail(email)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+
+		rowIndex, exists := emailToRow[key]
+		if !exists {
+			results = append(results, StatusUpdateResult{Email: email, Result: UpdateResultNotFound})
+			continue
+		}
+
+		current := NormalizeStatus(cellString(resp.Values[rowIndex], 9))
+		result := StatusUpdateResult{Email: email, PreviousStatus: current}
+		switch {
+		case update.ExpectedVersions != nil && !containsString(update.ExpectedVersions, RowVersion(resp.Values[rowIndex])):
+			result.Result = UpdateResultVersionMismatch
+		case current == update.Status:
+			result.Result = UpdateResultAlreadyInStatus
+		case !CanTransition(current, update.Status):
+			result.Result = UpdateResultInvalidTransition
+		default:
+			result.Result = UpdateResultUpdated
+			requests = append(requests, statusCellsRequest(sheetId, rowIndex, statusValue, update.Timestamp))
+			updated := updatedRow(resp.Values[rowIndex], statusValue, update.Timestamp)
+			invite := InviteFromRow(updated)
+			result.Invite = &invite
+		}
+		results = append(results, result)
+	}
+
+	for _, result := range results {
+		if result.Result == UpdateResultVersionMismatch {
+			return results, ErrVersionMismatch
+		}
+	}
+	if update.Atomic {
+		for _, result := range results {
+			if result.Result == UpdateResultNotFound {
+				return results, ErrUnknownEmails
+			}
 		}
 	}
 
@@ -331,9 +508,92 @@ func (s *SheetsService) UpdateInviteStatus(ctx context.Context, emails []string,
 			Requests: requests,
 		})
 		if err != nil {
-			return fmt.Errorf("failed to update invite statuses: %w", err)
+			return nil, fmt.Errorf("failed to update invite statuses: %w", err)
 		}
 	}
 
-	return nil
+	return results, nil
+}
+
+// updatedRow returns a copy of row with the status columns J and K replaced
+func updatedRow(row []interface{}, status string, timestamp string) []interface{} {
+	updated := make([]interface{}, 11)
+	copy(updated, row)
+	for i := len(row); i < len(updated); i++ {
+		updated[i] = ""
+	}
+	updated[9] = status
+	updated[10] = timestamp
+	return updated
+}
+
+// statusCellsRequest builds the request that writes columns J and K of a row
+func statusCellsRequest(sheetId int64, rowIndex int, status string, timestamp string) *sheets.Request {
+	return &sheets.Request{
+		UpdateCells: &sheets.UpdateCellsRequest{
+			Range: CellRange{
+				StartRow:    rowIndex,
+				EndRow:      rowIndex + 1,
+				StartColumn: columnStatus,
+				EndColumn:   columnStatusUpdated + 1,
+			}.GridRange(sheetId),
+			Rows: []*sheets.RowData{
+				{
+					Values: []*sheets.CellData{
+						{
+							UserEnteredValue: &sheets.ExtendedValue{
+								StringValue: &status,
+							},
+						},
+						{
+							UserEnteredValue: &sheets.ExtendedValue{
+								StringValue: &timestamp,
+							},
+						},
+					},
+				},
+			},
+			Fields: "userEnteredValue",
+		},
+	}
+}
+
+// RowVersion returns an opaque version for a row, derived from the contents of
+// columns A-K. Any edit to the row, by this application or in the sheet itself,
+// changes the version.
+func RowVersion(row []interface{}) string {
+	hash := sha256.New()
+	for i := 0; i < 11; i++ {
+		if i < len(row) {
+			fmt.Fprint(hash, row[i])
+		}
+		hash.Write([]byte{0x1f})
+	}
+	return hex.EncodeToString(hash.Sum(nil))[:16]
+}
+
+// containsString reports whether values contains value
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// NormalizeEmail lowercases and trims an email for comparison
+func NormalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// cellString safely returns a cell as a string
+func cellString(row []interface{}, index int) string {
+	if len(row) <= index {
+		return ""
+	}
+	if str, ok := row[index].(string); ok {
+		return str
+	}
+	return ""
 }
@@ -0,0 +1,472 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stevebennett/slack-invite-mgr/backend/internal/config"
+	"github.com/stevebennett/slack-invite-mgr/backend/internal/fakesheets"
+	"google.golang.org/api/googleapi"
+)
+
+// newFakeSheetsService returns a SheetsService using the real Google client
+// against a fake Sheets server holding rows in the named tab
+func newFakeSheetsService(t *testing.T, sheetName string, rows [][]interface{}) (SheetsServiceInterface, *fakesheets.Server) {
+	t.Helper()
+	fake := fakesheets.New("spreadsheet-id")
+	fake.AddSheet("Other", nil)
+	fake.AddSheet(sheetName, rows)
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+
+	service, err := NewSheetsService(context.Background(), &config.SheetsConfig{
+		SpreadsheetID: fake.SpreadsheetID(),
+		SheetName:     sheetName,
+		Endpoint:      server.URL,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create sheets service: %v", err)
+	}
+	return service, fake
+}
+
+func TestSheetsServiceAgainstFakeServer(t *testing.T) {
+	header := []interface{}{"Timestamp", "Name", "Role", "Email", "", "Company", "Years", "Reasons", "Source", "Status", "Updated"}
+	rows := [][]interface{}{
+		header,
+		{"3/4/2024 10:00:00", "Jane", "Engineer", "jane@example.com", "", "Acme", "5", "Reasons", "Meetup", "Sent", "2024-03-05 10:00:00"},
+		{"3/12/2024 10:00:00", "John", "Engineer", "john@example.com", "", "Acme", "1", "Reasons", "Meetup"},
+		{"3/14/2024 10:00:00", "Jill", "Engineer", "jill@example.com", "", "Acme", "8", "Reasons", "Twitter"},
+		{"3/15/2024 10:00:00", "John", "Engineer", " JOHN@example.com", "", "Acme", "1", "Again", "Meetup"},
+	}
+	ctx := context.Background()
+
+	t.Run("reads pending invites", func(t *testing.T) {
+		service, _ := newFakeSheetsService(t, "Sheet1", rows)
+		data, err := service.GetSheetData(ctx)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		var emails []string
+		for _, row := range data {
+			emails = append(emails, row[3].(string))
+		}
+		want := []string{"john@example.com", "jill@example.com", " JOHN@example.com"}
+		if !reflect.DeepEqual(emails, want) {
+			t.Errorf("Expected %v, got %v", want, emails)
+		}
+	})
+
+	t.Run("marks duplicates", func(t *testing.T) {
+		service, fake := newFakeSheetsService(t, "Sheet1", rows)
+		marked, err := service.UpdateDuplicateRequests(ctx, "2024-03-16 10:00:00")
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if len(marked) != 1 || marked[0].Invite.Reasons != "Again" || marked[0].PreviousStatus != StatusPending {
+			t.Fatalf("Expected the second John to be marked, got %+v", marked)
+		}
+		got := fake.Rows("Sheet1")
+		if got[4][9] != "Duplicate" || got[4][10] != "2024-03-16 10:00:00" {
+			t.Errorf("Expected row 5 marked duplicate, got %v", got[4])
+		}
+		if !reflect.DeepEqual(got[1], rows[1]) || len(got[2]) != 9 {
+			t.Errorf("Expected other rows unchanged, got %v and %v", got[1], got[2])
+		}
+
+		// A second run finds nothing new to mark
+		if marked, err := service.UpdateDuplicateRequests(ctx, "2024-03-17 10:00:00"); err != nil || len(marked) != 0 {
+			t.Errorf("Expected nothing marked again, got %+v, %v", marked, err)
+		}
+		if got := fake.Rows("Sheet1"); got[4][10] != "2024-03-16 10:00:00" {
+			t.Errorf("Expected the first mark kept, got %v", got[4])
+		}
+	})
+
+	t.Run("marks duplicates without retyping other cells", func(t *testing.T) {
+		typed := [][]interface{}{
+			header,
+			{float64(45365.5), "John", "Engineer", "john@example.com", "", "Acme", float64(1), "Reasons", "Meetup"},
+			{float64(45366.5), "John", "Engineer", "john@example.com", "", "Acme", float64(2), "Again", true},
+		}
+		service, fake := newFakeSheetsService(t, "Sheet1", typed)
+		if _, err := service.UpdateDuplicateRequests(ctx, "2024-03-16 10:00:00"); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		got := fake.UnformattedRows("Sheet1")[2]
+		want := []interface{}{float64(45366.5), "John", "Engineer", "john@example.com", "", "Acme", float64(2), "Again", true, "Duplicate", "2024-03-16 10:00:00"}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("Expected only the status cells written, got %#v", got)
+		}
+	})
+
+	t.Run("marks duplicates in batches", func(t *testing.T) {
+		many := [][]interface{}{header}
+		for i := 0; i < maxBatchRequests+10; i++ {
+			many = append(many, []interface{}{"3/4/2024 10:00:00", "Name", "Role", "same@example.com"})
+		}
+		service, fake := newFakeSheetsService(t, "Sheet1", many)
+		marked, err := service.UpdateDuplicateRequests(ctx, "2024-03-16 10:00:00")
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if len(marked) != maxBatchRequests+9 {
+			t.Errorf("Expected %d invites marked, got %d", maxBatchRequests+9, len(marked))
+		}
+		if calls := fake.Calls(fakesheets.OpSpreadsheetsBatchUpdate); calls != 2 {
+			t.Errorf("Expected 2 batchUpdate calls, got %d", calls)
+		}
+		if got := fake.Rows("Sheet1"); got[len(got)-1][9] != "Duplicate" {
+			t.Errorf("Expected the last row marked, got %v", got[len(got)-1])
+		}
+	})
+
+	t.Run("updates status", func(t *testing.T) {
+		service, fake := newFakeSheetsService(t, "Bob's form responses", rows)
+		results, err := service.UpdateInviteStatus(ctx, StatusUpdate{
+			Emails:    []string{"jill@example.com"},
+			Status:    StatusDenied,
+			Timestamp: "2024-03-16 10:00:00",
+		})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if len(results) != 1 || results[0].Result != UpdateResultUpdated {
+			t.Fatalf("Expected one update, got %+v", results)
+		}
+		got := fake.Rows("Bob's form responses")[3]
+		if got[9] != StatusDenied || got[10] != "2024-03-16 10:00:00" || got[7] != "Reasons" {
+			t.Errorf("Expected row 4 denied with other cells kept, got %v", got)
+		}
+	})
+
+	t.Run("appends invites", func(t *testing.T) {
+		service, fake := newFakeSheetsService(t, "Sheet1", rows)
+		invite := Invite{SubmittedAt: "3/16/2024 10:00:00", Name: "Bob", Role: "Engineer", Email: "bob@example.com", Company: "Acme", YearsExperience: "3", Reasons: "=SUM(1)", Source: "Form", Status: StatusPending}
+		if err := service.AppendInvites(ctx, []Invite{invite, invite}); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		got := fake.Rows("Sheet1")
+		if len(got) != len(rows)+2 {
+			t.Fatalf("Expected %d rows, got %d", len(rows)+2, len(got))
+		}
+		if back := InviteFromRow(got[len(rows)]); back.Email != invite.Email || back.Reasons != "=SUM(1)" {
+			t.Errorf("Expected the invite appended as raw text, got %+v", back)
+		}
+	})
+
+	t.Run("formats the sheet", func(t *testing.T) {
+		service, fake := newFakeSheetsService(t, "Sheet1", rows)
+		formatting := SheetFormatting{Editors: []string{"robot@example.iam.gserviceaccount.com", "admin@example.com"}}
+		// Formatting twice replaces the first run's rules and protection
+		for i := 0; i < 2; i++ {
+			if err := service.ApplyFormatting(ctx, formatting); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+		}
+		sheet := fake.Sheet("Sheet1")
+		if frozen := sheet.Properties.GridProperties.FrozenRowCount; frozen != 1 {
+			t.Errorf("Expected the header row frozen, got %d rows", frozen)
+		}
+		if len(sheet.ConditionalFormats) != len(statusColours) {
+			t.Fatalf("Expected %d conditional formats, got %d", len(statusColours), len(sheet.ConditionalFormats))
+		}
+		if value := sheet.ConditionalFormats[2].BooleanRule.Condition.Values[0].UserEnteredValue; value != "Duplicate" {
+			t.Errorf("Expected the third rule to match Duplicate, got %q", value)
+		}
+		if len(sheet.ProtectedRanges) != 1 {
+			t.Fatalf("Expected 1 protected range, got %d", len(sheet.ProtectedRanges))
+		}
+		protected := sheet.ProtectedRanges[0]
+		if protected.Range.StartColumnIndex != 9 || protected.Range.EndColumnIndex != 11 || !reflect.DeepEqual(protected.Editors.Users, formatting.Editors) {
+			t.Errorf("Expected J:K protected for the editors, got %+v with %+v", protected.Range, protected.Editors)
+		}
+		rule := fake.DataValidation("Sheet1", 500, 9)
+		if rule == nil || rule.Condition.Type != "ONE_OF_LIST" || len(rule.Condition.Values) != len(KnownStatuses)-1 {
+			t.Errorf("Expected a status dropdown in column J, got %+v", rule)
+		}
+		if fake.DataValidation("Sheet1", 0, 9) != nil || fake.DataValidation("Sheet1", 5, 8) != nil {
+			t.Error("Expected no dropdown on the header or other columns")
+		}
+	})
+
+	t.Run("archives processed invites", func(t *testing.T) {
+		history := [][]interface{}{
+			header,
+			{"1/4/2023 10:00:00", "Ann", "Engineer", "ann@example.com", "", "Acme", "5", "Reasons", "Meetup", "Sent", "2023-01-05 10:00:00"},
+			{"3/4/2024 10:00:00", "Ben", "Engineer", "ben@example.com", "", "Acme", "5", "Reasons", "Meetup"},
+			{"1/4/2024 10:00:00", "Cat", "Engineer", "cat@example.com", "", "Acme", "5", "Reasons", "Meetup", "denied", "2024-01-05 10:00:00"},
+			{"1/6/2024 10:00:00", "Ann", "Engineer", "ann@example.com", "", "Acme", "5", "Reasons", "Meetup", "Duplicate", "2024-01-06 10:00:00"},
+			{"2/4/2024 10:00:00", "Dan", "Engineer", "dan@example.com", "", "Acme", "5", "Reasons", "Meetup", "Sent", "2024-03-01 10:00:00"},
+			{"1/4/2024 10:00:00", "Eve", "Engineer", "eve@example.com", "", "Acme", "5", "Reasons", "Meetup", "needs_info", "2024-01-05 10:00:00"},
+		}
+		before := time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)
+
+		service, fake := newFakeSheetsService(t, "Sheet1", history)
+		counts, err := service.ArchiveInvites(ctx, ArchiveOptions{Before: before, DryRun: true})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if counts["Archive"] != 3 || len(fake.Rows("Sheet1")) != len(history) || fake.Sheet("Archive") != nil {
+			t.Fatalf("Expected a dry run to count 3 invites and change nothing, got %v", counts)
+		}
+
+		counts, err = service.ArchiveInvites(ctx, ArchiveOptions{Before: before, PerYear: true})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if !reflect.DeepEqual(counts, map[string]int{"Archive 2023": 1, "Archive 2024": 2}) {
+			t.Errorf("Expected invites archived by year, got %v", counts)
+		}
+		var names []string
+		for _, row := range fake.Rows("Sheet1") {
+			names = append(names, row[1].(string))
+		}
+		if want := []string{"Name", "Ben", "Dan", "Eve"}; !reflect.DeepEqual(names, want) {
+			t.Errorf("Expected %v left on the sheet, got %v", want, names)
+		}
+		if got := fake.Rows("Archive 2024"); len(got) != 3 || !reflect.DeepEqual(got[0], header) || !reflect.DeepEqual(got[1], history[3]) || !reflect.DeepEqual(got[2], history[4]) {
+			t.Errorf("Expected the header and two 2024 invites in order, got %v", got)
+		}
+		if frozen := fake.Sheet("Archive 2023").Properties.GridProperties.FrozenRowCount; frozen != 1 {
+			t.Errorf("Expected the archive header frozen, got %d rows", frozen)
+		}
+
+		// Archived invites stay readable, and a second run finds nothing
+		archived, err := service.GetArchivedSheetData(ctx)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if invites := InvitesFromRows(archived); len(invites) != 3 || invites[0].Name != "Ann" {
+			t.Errorf("Expected the 3 archived invites, got %+v", invites)
+		}
+		if counts, err := service.ArchiveInvites(ctx, ArchiveOptions{Before: before, PerYear: true}); err != nil || len(counts) != 0 {
+			t.Errorf("Expected nothing left to archive, got %v, %v", counts, err)
+		}
+
+		// History covers both, and reapplying after being archived is a duplicate
+		all, err := InviteHistory(ctx, service)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if invites := InvitesFromRows(all); len(invites) != 6 {
+			t.Errorf("Expected 6 invites in the history, got %d", len(invites))
+		}
+		if err := service.AppendInvite(ctx, Invite{SubmittedAt: "3/16/2024 10:00:00", Name: "Cat", Email: "CAT@example.com"}); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		marked, err := service.UpdateDuplicateRequests(ctx, "2024-03-16 10:00:00")
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if len(marked) != 1 || marked[0].Email != "CAT@example.com" {
+			t.Errorf("Expected the reapplication marked duplicate, got %+v", marked)
+		}
+	})
+
+	t.Run("archives in batches", func(t *testing.T) {
+		many := [][]interface{}{header}
+		for i := 0; i < 400; i++ {
+			status := ""
+			if i%2 == 0 {
+				status = "Sent"
+			}
+			many = append(many, []interface{}{"1/4/2023 10:00:00", fmt.Sprint(i), "Role", fmt.Sprintf("user%d@example.com", i), "", "", "", "", "", status})
+		}
+		service, fake := newFakeSheetsService(t, "Sheet1", many)
+		counts, err := service.ArchiveInvites(ctx, ArchiveOptions{Before: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if counts["Archive"] != 200 {
+			t.Errorf("Expected 200 invites archived, got %v", counts)
+		}
+		// One call creates the tab, one copies the header and two move the rows
+		if calls := fake.Calls(fakesheets.OpSpreadsheetsBatchUpdate); calls != 4 {
+			t.Errorf("Expected 4 batchUpdate calls, got %d", calls)
+		}
+		active, archive := fake.Rows("Sheet1"), fake.Rows("Archive")
+		if len(active) != 201 || len(archive) != 201 {
+			t.Fatalf("Expected 201 rows in each tab, got %d and %d", len(active), len(archive))
+		}
+		for i := 1; i <= 200; i++ {
+			if active[i][1] != fmt.Sprint(2*i-1) || archive[i][1] != fmt.Sprint(2*i-2) {
+				t.Fatalf("Expected rows kept in order, got %v and %v at %d", active[i][1], archive[i][1], i)
+			}
+		}
+	})
+
+	t.Run("redacts personal data", func(t *testing.T) {
+		service, fake := newFakeSheetsService(t, "Sheet1", rows)
+		fake.AddSheet("Archive", [][]interface{}{
+			header,
+			{"1/4/2023 10:00:00", "Ann", "Engineer", "ann@example.com", "", "Acme", "5", "Reasons", "Meetup", "denied", "2023-01-05 10:00:00"},
+		})
+		before := time.Date(2024, 3, 10, 0, 0, 0, 0, time.UTC)
+		counts, err := service.RedactInvites(ctx, before, true)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if !reflect.DeepEqual(counts, map[string]int{"Sheet1": 1, "Archive": 1}) || fake.Rows("Sheet1")[1][1] != "Jane" {
+			t.Fatalf("Expected a dry run to count one invite per tab and change nothing, got %v", counts)
+		}
+
+		if _, err := service.RedactInvites(ctx, before, false); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		want := []interface{}{"3/4/2024 10:00:00", RedactedValue, "Engineer", "", "", RedactedValue, "5", RedactedValue, "Meetup", "Sent", "2024-03-05 10:00:00"}
+		if got := fake.Rows("Sheet1"); !reflect.DeepEqual(got[1], want) || !reflect.DeepEqual(got[2], rows[2]) {
+			t.Errorf("Expected only the sent invite redacted, got %v and %v", got[1], got[2])
+		}
+		if got := fake.Rows("Archive")[1]; got[1] != RedactedValue || got[9] != "denied" {
+			t.Errorf("Expected the archived invite redacted, got %v", got)
+		}
+		if counts, err := service.RedactInvites(ctx, before, false); err != nil || len(counts) != 0 {
+			t.Errorf("Expected nothing left to redact, got %v, %v", counts, err)
+		}
+	})
+
+	t.Run("erases an applicant", func(t *testing.T) {
+		service, fake := newFakeSheetsService(t, "Sheet1", rows)
+		fake.AddSheet("Archive 2023", [][]interface{}{
+			header,
+			{"1/4/2023 10:00:00", "John", "Engineer", "John@Example.com", "", "Acme", "5", "Reasons", "Meetup", "denied", "2023-01-05 10:00:00"},
+		})
+		fake.AddSheet("Notes", [][]interface{}{{"john@example.com"}})
+		counts, err := service.EraseApplicant(ctx, "john@example.com")
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if !reflect.DeepEqual(counts, map[string]int{"Sheet1": 2, "Archive 2023": 1}) {
+			t.Errorf("Expected 3 rows erased, got %v", counts)
+		}
+		var emails []string
+		for _, row := range fake.Rows("Sheet1") {
+			emails = append(emails, row[3].(string))
+		}
+		if want := []string{"Email", "jane@example.com", "jill@example.com"}; !reflect.DeepEqual(emails, want) {
+			t.Errorf("Expected %v left on the sheet, got %v", want, emails)
+		}
+		if got := fake.Rows("Archive 2023"); len(got) != 1 {
+			t.Errorf("Expected only the archive header left, got %v", got)
+		}
+		if got := fake.Rows("Notes"); len(got) != 1 {
+			t.Errorf("Expected other tabs untouched, got %v", got)
+		}
+	})
+
+	t.Run("reads every page", func(t *testing.T) {
+		many := [][]interface{}{header}
+		for i := 0; i < 2500; i++ {
+			many = append(many, []interface{}{"3/4/2024 10:00:00", "Name", "Role", fmt.Sprintf("user%d@example.com", i)})
+		}
+		service, fake := newFakeSheetsService(t, "Sheet1", many)
+		count := 0
+		err := service.EachRow(ctx, func(row []interface{}) error {
+			if len(row) != 11 {
+				t.Fatalf("Expected rows padded to 11 columns, got %d", len(row))
+			}
+			count++
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if count != len(many) {
+			t.Errorf("Expected %d rows, got %d", len(many), count)
+		}
+		if calls := fake.Calls(fakesheets.OpValuesGet); calls != 3 {
+			t.Errorf("Expected 3 pages, got %d", calls)
+		}
+	})
+
+	t.Run("reports upstream errors", func(t *testing.T) {
+		server := httptest.NewServer(fakesheets.New("other"))
+		defer server.Close()
+		service, err := NewSheetsService(ctx, &config.SheetsConfig{SpreadsheetID: "spreadsheet-id", SheetName: "Sheet1", Endpoint: server.URL})
+		if err != nil {
+			t.Fatalf("Failed to create sheets service: %v", err)
+		}
+		_, err = service.GetAllSheetData(ctx)
+		var apiErr *googleapi.Error
+		if !errors.As(err, &apiErr) || apiErr.Code != http.StatusNotFound {
+			t.Errorf("Expected a 404 googleapi.Error, got %v", err)
+		}
+	})
+}
+
+func TestSheetsService_StatusUpdateWaitsForErase(t *testing.T) {
+	header := []interface{}{"Timestamp", "Name", "Role", "Email", "", "Company", "Years", "Reasons", "Source", "Status", "Updated"}
+	fake := fakesheets.New("erase-spreadsheet-id")
+	fake.AddSheet("Sheet1", [][]interface{}{
+		header,
+		{"3/12/2024 10:00:00", "John", "Engineer", "john@example.com", "", "Acme", "1", "Reasons", "Meetup"},
+		{"3/14/2024 10:00:00", "Jill", "Engineer", "jill@example.com", "", "Acme", "8", "Reasons", "Twitter"},
+	})
+	// Hold the first batch update, which deletes John's row, until released
+	erasing, release := make(chan struct{}), make(chan struct{})
+	var held bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost && strings.HasSuffix(r.URL.Path, ":batchUpdate") && !held {
+			held = true
+			close(erasing)
+			<-release
+		}
+		fake.ServeHTTP(w, r)
+	}))
+	t.Cleanup(server.Close)
+
+	// Each request handler creates its own service
+	newService := func() SheetsServiceInterface {
+		service, err := NewSheetsService(context.Background(), &config.SheetsConfig{
+			SpreadsheetID: fake.SpreadsheetID(),
+			SheetName:     "Sheet1",
+			Endpoint:      server.URL,
+		})
+		if err != nil {
+			t.Fatalf("Failed to create sheets service: %v", err)
+		}
+		return service
+	}
+	eraser, updater := newService(), newService()
+
+	erased := make(chan error, 1)
+	go func() {
+		_, err := eraser.EraseApplicant(context.Background(), "john@example.com")
+		erased <- err
+	}()
+	<-erasing
+	updated := make(chan error, 1)
+	go func() {
+		_, err := updater.UpdateInviteStatus(context.Background(), StatusUpdate{
+			Emails:    []string{"jill@example.com"},
+			Status:    StatusDenied,
+			Timestamp: "2024-03-16 10:00:00",
+		})
+		updated <- err
+	}()
+	select {
+	case <-updated:
+		t.Fatal("Expected the status update to wait for the erasure")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(release)
+	if err := <-erased; err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := <-updated; err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	got := fake.Rows("Sheet1")
+	if len(got) != 2 || got[1][3] != "jill@example.com" || got[1][9] != StatusDenied {
+		t.Errorf("Expected only Jill's row left and denied, got %v", got)
+	}
+}
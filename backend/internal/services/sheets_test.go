@@ -3,6 +3,7 @@ package services
 import (
 	"context"
 	"errors"
+	"fmt"
 	"reflect"
 	"testing"
 
@@ -17,12 +18,20 @@ type mockSheetsService struct {
 	err           bool
 	updatedValues [][]interface{}
 	spreadsheet   *sheets.Spreadsheet
+	appended      [][]interface{}
+	// pages, when set, holds the values returned for each requested range
+	pages  map[string][][]interface{}
+	ranges []string
 }
 
 func (m *mockSheetsService) Get(ctx context.Context, spreadsheetId string, readRange string) (*sheets.ValueRange, error) {
 	if m.err {
 		return nil, errors.New("mock error")
 	}
+	m.ranges = append(m.ranges, readRange)
+	if m.pages != nil {
+		return &sheets.ValueRange{Values: m.pages[readRange]}, nil
+	}
 	return &sheets.ValueRange{
 		Values: m.values,
 	}, nil
@@ -69,6 +78,14 @@ func (m *mockSheetsService) BatchUpdate(ctx context.Context, spreadsheetId strin
 	return &sheets.BatchUpdateSpreadsheetResponse{}, nil
 }
 
+func (m *mockSheetsService) Append(ctx context.Context, spreadsheetId string, appendRange string, values *sheets.ValueRange) (*sheets.AppendValuesResponse, error) {
+	if m.err {
+		return nil, errors.New("mock error")
+	}
+	m.appended = append(m.appended, values.Values...)
+	return &sheets.AppendValuesResponse{}, nil
+}
+
 func (m *mockSheetsService) SpreadsheetsGet(ctx context.Context, spreadsheetId string) (*sheets.Spreadsheet, error) {
 	if m.err {
 		return nil, errors.New("mock error")
@@ -377,6 +394,22 @@ func TestUpdateDuplicateRequests(t *testing.T) {
 			},
 			expectedError: false,
 		},
+		{
+			name: "Later rows with a status left alone while the first is pending",
+			inputData: [][]interface{}{
+				{"1", "2", "3", "test@example.com", "5", "6", "7", "8", "9", "", ""},
+				{"1", "2", "3", "test@example.com", "5", "6", "7", "8", "9", "Duplicate", "2024-02-01 12:00:00"},
+				{"1", "2", "3", "test@example.com", "5", "6", "7", "8", "9", "Sent", "2024-02-02 12:00:00"},
+				{"1", "2", "3", "test@example.com", "5", "6", "7", "8", "9", "", ""},
+			},
+			expectedOutput: [][]interface{}{
+				{"1", "2", "3", "test@example.com", "5", "6", "7", "8", "9", "", ""},
+				{"1", "2", "3", "test@example.com", "5", "6", "7", "8", "9", "Duplicate", "2024-02-01 12:00:00"},
+				{"1", "2", "3", "test@example.com", "5", "6", "7", "8", "9", "Sent", "2024-02-02 12:00:00"},
+				{"1", "2", "3", "test@example.com", "5", "6", "7", "8", "9", "Duplicate", testTimestamp},
+			},
+			expectedError: false,
+		},
 		{
 			name: "No duplicates",
 			inputData: [][]interface{}{
@@ -438,7 +471,7 @@ func TestUpdateDuplicateRequests(t *testing.T) {
 				service: mockService,
 			}
 
-			err := svc.UpdateDuplicateRequests(context.Background(), testTimestamp)
+			marked, err := svc.UpdateDuplicateRequests(context.Background(), testTimestamp)
 			if tc.expectedError {
 				if err == nil {
 					t.Error("Expected error but got none")
@@ -451,6 +484,21 @@ func TestUpdateDuplicateRequests(t *testing.T) {
 				return
 			}
 
+			expectedMarked := 0
+			for _, row := range tc.expectedOutput {
+				if row[9] == "Duplicate" && row[10] == testTimestamp {
+					expectedMarked++
+				}
+			}
+			if len(marked) != expectedMarked {
+				t.Errorf("Expected %d marked invites, got %d", expectedMarked, len(marked))
+			}
+			for _, result := range marked {
+				if result.Invite.Status != StatusDuplicate || result.PreviousStatus != StatusPending {
+					t.Errorf("Expected a pending invite marked %q, got %+v", StatusDuplicate, result)
+				}
+			}
+
 			if len(tc.expectedOutput) == 0 && len(mockService.updatedValues) == 0 {
 				return
 			}
@@ -541,6 +589,159 @@ func TestGetNewInvites(t *testing.T) {
 	}
 }
 
+func TestUpdateInviteStatus(t *testing.T) {
+	cfg := &config.SheetsConfig{
+		SpreadsheetID: "test-sheet-id",
+		SheetName:     "Sheet1",
+	}
+
+	testTimestamp := "2024-02-14 12:00:00"
+
+	// The mock writes into the input rows, so each case gets a fresh copy
+	inputData := func() [][]interface{} {
+		return [][]interface{}{
+			{"1", "2", "3", "pending@example.com", "5", "6", "7", "8", "9", "", ""},
+			{"1", "2", "3", "sent@example.com", "5", "6", "7", "8", "9", "sent", "2024-01-01 00:00:00"},
+			{"1", "2", "3", "denied@example.com", "5", "6", "7", "8", "9", "denied", "2024-01-01 00:00:00"},
+			{"1", "2", "3", "Pending@Example.com", "5", "6", "7", "8", "9", "Duplicate", "2024-01-01 00:00:00"},
+		}
+	}
+
+	testCases := []struct {
+		name            string
+		update          StatusUpdate
+		expectedResults []StatusUpdateResult
+		expectedRows    map[int][]interface{}
+		expectedError   error
+	}{
+		{
+			name: "mixed results",
+			update: StatusUpdate{
+				Emails:    []string{" PENDING@example.com", "missing@example.com", "sent@example.com", "denied@example.com"},
+				Status:    StatusSent,
+				Timestamp: testTimestamp,
+			},
+			expectedResults: []StatusUpdateResult{
+				{Email: " PENDING@example.com", Result: UpdateResultUpdated, PreviousStatus: StatusPending},
+				{Email: "missing@example.com", Result: UpdateResultNotFound},
+				{Email: "sent@example.com", Result: UpdateResultAlreadyInStatus, PreviousStatus: StatusSent},
+				{Email: "denied@example.com", Result: UpdateResultUpdated, PreviousStatus: StatusDenied},
+			},
+			expectedRows: map[int][]interface{}{
+				0: {"1", "2", "3", "pending@example.com", "5", "6", "7", "8", "9", "sent", testTimestamp},
+				2: {"1", "2", "3", "denied@example.com", "5", "6", "7", "8", "9", "sent", testTimestamp},
+			},
+		},
+		{
+			name: "sent invites cannot be denied",
+			update: StatusUpdate{
+				Emails:    []string{"sent@example.com"},
+				Status:    StatusDenied,
+				Timestamp: testTimestamp,
+			},
+			expectedResults: []StatusUpdateResult{
+				{Email: "sent@example.com", Result: UpdateResultInvalidTransition, PreviousStatus: StatusSent},
+			},
+		},
+		{
+			name: "matching version is updated",
+			update: StatusUpdate{
+				Emails:           []string{"pending@example.com"},
+				Status:           StatusDenied,
+				Timestamp:        testTimestamp,
+				ExpectedVersions: []string{RowVersion([]interface{}{"1", "2", "3", "pending@example.com", "5", "6", "7", "8", "9", "", ""})},
+			},
+			expectedResults: []StatusUpdateResult{
+				{Email: "pending@example.com", Result: UpdateResultUpdated, PreviousStatus: StatusPending},
+			},
+			expectedRows: map[int][]interface{}{
+				0: {"1", "2", "3", "pending@example.com", "5", "6", "7", "8", "9", "denied", testTimestamp},
+			},
+		},
+		{
+			name: "changed row writes nothing",
+			update: StatusUpdate{
+				Emails:           []string{"pending@example.com", "denied@example.com"},
+				Status:           StatusSent,
+				Timestamp:        testTimestamp,
+				ExpectedVersions: []string{RowVersion([]interface{}{"1", "2", "3", "pending@example.com", "5", "6", "7", "8", "9", "", ""}), "stale"},
+			},
+			expectedResults: []StatusUpdateResult{
+				{Email: "pending@example.com", Result: UpdateResultUpdated, PreviousStatus: StatusPending},
+				{Email: "denied@example.com", Result: UpdateResultVersionMismatch, PreviousStatus: StatusDenied},
+			},
+			expectedError: ErrVersionMismatch,
+		},
+		{
+			name: "atomic update with unknown email writes nothing",
+			update: StatusUpdate{
+				Emails:    []string{"pending@example.com", "typo@example.com"},
+				Status:    StatusSent,
+				Timestamp: testTimestamp,
+				Atomic:    true,
+			},
+			expectedResults: []StatusUpdateResult{
+				{Email: "pending@example.com", Result: UpdateResultUpdated, PreviousStatus: StatusPending},
+				{Email: "typo@example.com", Result: UpdateResultNotFound},
+			},
+			expectedError: ErrUnknownEmails,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockService := &mockSheetsService{values: inputData()}
+			svc := &SheetsService{
+				cfg:     cfg,
+				service: mockService,
+			}
+
+			results, err := svc.UpdateInviteStatus(context.Background(), tc.update)
+			if !errors.Is(err, tc.expectedError) {
+				t.Fatalf("Expected error %v, got %v", tc.expectedError, err)
+			}
+			for i := range results {
+				invite := results[i].Invite
+				if (invite != nil) != (results[i].Result == UpdateResultUpdated) {
+					t.Errorf("Result %d: invite should only be set for updated rows", i)
+				}
+				if invite != nil && (invite.Status != tc.update.Status || invite.StatusUpdatedAt != tc.update.Timestamp) {
+					t.Errorf("Result %d: expected updated invite, got %+v", i, invite)
+				}
+				results[i].Invite = nil
+			}
+			if !reflect.DeepEqual(results, tc.expectedResults) {
+				t.Errorf("Expected results %v, got %v", tc.expectedResults, results)
+			}
+
+			if len(tc.expectedRows) == 0 {
+				if mockService.updatedValues != nil {
+					t.Errorf("Expected no writes, got %v", mockService.updatedValues)
+				}
+				return
+			}
+			for rowIndex, want := range tc.expectedRows {
+				if !reflect.DeepEqual(mockService.updatedValues[rowIndex], want) {
+					t.Errorf("Row %d: expected %v, got %v", rowIndex, want, mockService.updatedValues[rowIndex])
+				}
+			}
+		})
+	}
+}
+
+func TestRowVersion(t *testing.T) {
+	full := []interface{}{"1", "2", "3", "a@example.com", "", "", "", "", "", "", ""}
+	short := []interface{}{"1", "2", "3", "a@example.com"}
+	changed := []interface{}{"1", "2", "3", "a@example.com", "", "", "", "", "", "sent", ""}
+
+	if RowVersion(full) != RowVersion(short) {
+		t.Errorf("expected trailing empty cells not to change the version")
+	}
+	if RowVersion(full) == RowVersion(changed) {
+		t.Errorf("expected a status change to change the version")
+	}
+}
+
 // Helper to compare [][]interface{}
 func equal2D(a, b [][]interface{}) bool {
 	if len(a) != len(b) {
@@ -558,3 +759,93 @@ func equal2D(a, b [][]interface{}) bool {
 	}
 	return true
 }
+
+func TestAppendInvite(t *testing.T) {
+	mockService := &mockSheetsService{}
+	svc := &SheetsService{
+		cfg:     &config.SheetsConfig{SpreadsheetID: "test-sheet-id", SheetName: "Sheet1"},
+		service: mockService,
+	}
+
+	invite := Invite{
+		SubmittedAt:     "2/14/2024 12:00:00",
+		Name:            "Jane Doe",
+		Role:            "Engineer",
+		Email:           "jane@example.com",
+		Company:         "Acme",
+		YearsExperience: "5",
+		Reasons:         "=HYPERLINK(\"x\")",
+		Source:          "Website",
+		Status:          StatusPending,
+	}
+	if err := svc.AppendInvite(context.Background(), invite); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expected := [][]interface{}{
+		{"2/14/2024 12:00:00", "Jane Doe", "Engineer", "jane@example.com", "", "Acme", "5", "=HYPERLINK(\"x\")", "Website", "", ""},
+	}
+	if !reflect.DeepEqual(mockService.appended, expected) {
+		t.Errorf("Expected %v, got %v", expected, mockService.appended)
+	}
+
+	// The appended row reads back as the same invite
+	read := InviteFromRow(mockService.appended[0])
+	read.Version = ""
+	if read != invite {
+		t.Errorf("Expected %+v to round-trip, got %+v", invite, read)
+	}
+}
+
+func TestEachRow(t *testing.T) {
+	page := func(first, count int) [][]interface{} {
+		var rows [][]interface{}
+		for i := first; i < first+count; i++ {
+			rows = append(rows, []interface{}{fmt.Sprintf("row %d", i)})
+		}
+		return rows
+	}
+	mockService := &mockSheetsService{
+		spreadsheet: &sheets.Spreadsheet{
+			Sheets: []*sheets.Sheet{{
+				Properties: &sheets.SheetProperties{
+					Title:          "Sheet1",
+					GridProperties: &sheets.GridProperties{RowCount: 2500},
+				},
+			}},
+		},
+		pages: map[string][][]interface{}{
+			"Sheet1!A1:K1000": page(1, 1000),
+			// A block of empty rows is returned as a short page
+			"Sheet1!A1001:K2000": page(1001, 10),
+			"Sheet1!A2001:K2500": page(2001, 500),
+		},
+	}
+	service := &SheetsService{service: mockService, cfg: &config.SheetsConfig{SpreadsheetID: "test-id", SheetName: "Sheet1"}}
+
+	count := 0
+	err := service.EachRow(context.Background(), func(row []interface{}) error {
+		count++
+		if len(row) != 11 {
+			t.Fatalf("Expected rows padded to 11 columns, got %d", len(row))
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if count != 1510 {
+		t.Errorf("Expected 1510 rows, got %d", count)
+	}
+	if len(mockService.ranges) != 3 {
+		t.Errorf("Expected 3 page requests, got %v", mockService.ranges)
+	}
+
+	// An error from the callback stops iteration
+	stop := errors.New("stop")
+	mockService.ranges = nil
+	err = service.EachRow(context.Background(), func(row []interface{}) error { return stop })
+	if !errors.Is(err, stop) || len(mockService.ranges) != 1 {
+		t.Errorf("Expected iteration to stop at the first error, got %v after %d pages", err, len(mockService.ranges))
+	}
+}
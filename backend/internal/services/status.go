@@ -0,0 +1,109 @@
+package services
+
+import (
+	"strings"
+	"time"
+)
+
+// Invite statuses as exposed by the API. Column J holds the raw value written
+// by the dashboard or the sheets tool; an empty cell means the invite is pending.
+const (
+	StatusPending   = "pending"
+	StatusSent      = "sent"
+	StatusDenied    = "denied"
+	StatusDuplicate = "duplicate"
+	// StatusNeedsInfo marks an application that is waiting on more information from the applicant
+	StatusNeedsInfo = "needs_info"
+)
+
+// KnownStatuses lists every status the application understands
+var KnownStatuses = []string{StatusPending, StatusSent, StatusDenied, StatusDuplicate, StatusNeedsInfo}
+
+// NormalizeStatus converts a raw column J value into one of the known statuses.
+// Unrecognised values are returned lowercased and trimmed.
+func NormalizeStatus(raw string) string {
+	status := strings.ToLower(strings.TrimSpace(raw))
+	if status == "" {
+		return StatusPending
+	}
+	return status
+}
+
+// IsKnownStatus reports whether status is one of KnownStatuses
+func IsKnownStatus(status string) bool {
+	for _, known := range KnownStatuses {
+		if status == known {
+			return true
+		}
+	}
+	return false
+}
+
+// statusTransitions lists the statuses each status may be changed to. Sent
+// invites are final; denied and duplicate invites may be reopened, and
+// applications that need more information can be decided once it arrives.
+var statusTransitions = map[string][]string{
+	StatusPending:   {StatusSent, StatusDenied, StatusDuplicate, StatusNeedsInfo},
+	StatusDenied:    {StatusPending, StatusSent},
+	StatusDuplicate: {StatusPending},
+	StatusNeedsInfo: {StatusPending, StatusSent, StatusDenied},
+	StatusSent:      {},
+}
+
+// CanTransition reports whether an invite may move from one status to another
+func CanTransition(from, to string) bool {
+	for _, allowed := range statusTransitions[from] {
+		if allowed == to {
+			return true
+		}
+	}
+	return false
+}
+
+// StatusCellValue returns the column J value written for a status. Pending
+// invites have an empty cell and duplicates keep the capitalised value written
+// by the sheets tool.
+func StatusCellValue(status string) string {
+	switch status {
+	case StatusPending:
+		return ""
+	case StatusDuplicate:
+		return "Duplicate"
+	default:
+		return status
+	}
+}
+
+// submittedAtLayouts are the column A timestamp formats we accept. Google Forms
+// writes "1/2/2006 15:04:05" for en-US spreadsheets; the others cover sheets
+// that have been reformatted or rows written by this application.
+var submittedAtLayouts = []string{
+	"1/2/2006 15:04:05",
+	"1/2/2006",
+	"2006-01-02 15:04:05",
+	"2006-01-02",
+	time.RFC3339,
+}
+
+// ParseTimestamp parses a timestamp cell such as the column A form submission time
+func ParseTimestamp(value string) (time.Time, bool) {
+	return ParseTimestampInLocation(value, time.UTC)
+}
+
+// ParseTimestampInLocation is like ParseTimestamp, but reads timestamps without
+// a time zone as local times in loc, or in UTC if loc is nil
+func ParseTimestampInLocation(value string, loc *time.Location) (time.Time, bool) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return time.Time{}, false
+	}
+	if loc == nil {
+		loc = time.UTC
+	}
+	for _, layout := range submittedAtLayouts {
+		if t, err := time.ParseInLocation(layout, value, loc); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
@@ -0,0 +1,132 @@
+package services
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+// SheetWatcher polls the sheet and publishes events for new invites and
+// status changes made outside this server, such as by the sheets tool or by
+// someone editing the spreadsheet directly
+type SheetWatcher struct {
+	sheets   SheetsServiceInterface
+	broker   *EventBroker
+	interval time.Duration
+	logger   *slog.Logger
+	trigger  chan struct{}
+	// statuses holds the status of each submission seen on the last poll
+	statuses map[string]string
+	seeded   bool
+	// webhooks, if set, is told about new submissions
+	webhooks *WebhookDispatcher
+}
+
+// NewSheetWatcher creates a SheetWatcher that polls every interval
+func NewSheetWatcher(sheets SheetsServiceInterface, broker *EventBroker, interval time.Duration, logger *slog.Logger) *SheetWatcher {
+	return &SheetWatcher{
+		sheets:   sheets,
+		broker:   broker,
+		interval: interval,
+		logger:   logger,
+		trigger:  make(chan struct{}, 1),
+		statuses: make(map[string]string),
+	}
+}
+
+// NotifyNewInvites sends an invite.added webhook for each new submission the
+// watcher finds. Status changes are not forwarded, since they are sent by
+// whichever tool made them.
+func (w *SheetWatcher) NotifyNewInvites(webhooks *WebhookDispatcher) {
+	w.webhooks = webhooks
+}
+
+// Run polls the sheet until ctx is cancelled
+func (w *SheetWatcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		if err := w.Poll(ctx); err != nil {
+			w.logger.Warn("failed to poll sheet for changes", slog.String("error", err.Error()))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-w.trigger:
+		}
+	}
+}
+
+// Trigger requests a poll as soon as possible without waiting for the interval
+func (w *SheetWatcher) Trigger() {
+	select {
+	case w.trigger <- struct{}{}:
+	default:
+	}
+}
+
+// Poll reads the sheet once and publishes events for anything that changed
+// since the previous poll. The first poll only records the current state.
+func (w *SheetWatcher) Poll(ctx context.Context) error {
+	rows, err := w.sheets.GetAllSheetData(ctx)
+	if err != nil {
+		return err
+	}
+
+	current := make(map[string]string, len(rows))
+	for _, row := range rows {
+		invite := InviteFromRow(row)
+		if invite.Email == "" || !IsKnownStatus(invite.Status) {
+			continue // Skip the header row and hand-edited statuses
+		}
+
+		key := submissionKey(invite)
+		current[key] = invite.Status
+
+		if !w.seeded {
+			w.broker.Observe(invite)
+			continue
+		}
+
+		previous, seen := w.statuses[key]
+		switch {
+		case !seen:
+			inv := invite
+			event, _ := w.broker.Publish(InviteEvent{
+				Type:        EventInviteAdded,
+				Email:       invite.Email,
+				SubmittedAt: invite.SubmittedAt,
+				Status:      invite.Status,
+				Invite:      &inv,
+			})
+			if w.webhooks != nil {
+				w.webhooks.Dispatch(event)
+			}
+		case previous != invite.Status:
+			eventType := EventStatusChanged
+			if invite.Status == StatusDuplicate {
+				eventType = EventDuplicateMarked
+			}
+			w.broker.Publish(InviteEvent{
+				Type:           eventType,
+				Email:          invite.Email,
+				SubmittedAt:    invite.SubmittedAt,
+				Status:         invite.Status,
+				PreviousStatus: previous,
+			})
+		}
+	}
+
+	w.statuses = current
+	w.seeded = true
+	return nil
+}
+
+// submissionKey identifies a form submission by its email and timestamp, so
+// rows keep their identity if others are inserted or removed around them
+func submissionKey(invite Invite) string {
+	return NormalizeEmail(invite.Email) + "|" + invite.SubmittedAt
+}
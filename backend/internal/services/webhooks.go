@@ -0,0 +1,391 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stevebennett/slack-invite-mgr/backend/internal/config"
+)
+
+// Webhook delivery statuses
+const (
+	DeliveryDelivered  = "delivered"
+	DeliveryRetrying   = "retrying"
+	DeliveryDeadLetter = "dead_letter"
+)
+
+// Webhook request headers
+const (
+	WebhookSignatureHeader = "X-Webhook-Signature"
+	WebhookTimestampHeader = "X-Webhook-Timestamp"
+	WebhookEventHeader     = "X-Webhook-Event"
+	WebhookDeliveryHeader  = "X-Webhook-Delivery"
+)
+
+const (
+	defaultWebhookMaxAttempts = 5
+	defaultWebhookBackoff     = time.Second
+	webhookTimeout            = 10 * time.Second
+	// memoryDeliveryLogSize bounds the delivery log when it is not backed by a file
+	memoryDeliveryLogSize = 1000
+)
+
+// WebhookPayload is the JSON body sent to webhook subscribers
+type WebhookPayload struct {
+	ID         string      `json:"id"`
+	Event      string      `json:"event"`
+	OccurredAt time.Time   `json:"occurredAt"`
+	Data       InviteEvent `json:"data"`
+}
+
+// WebhookDelivery records one attempt to deliver a webhook
+type WebhookDelivery struct {
+	ID             string    `json:"id"`
+	Subscription   string    `json:"subscription"`
+	URL            string    `json:"url"`
+	Event          string    `json:"event"`
+	Status         string    `json:"status"`
+	Attempt        int       `json:"attempt"`
+	ResponseStatus int       `json:"responseStatus,omitempty"`
+	Error          string    `json:"error,omitempty"`
+	Timestamp      time.Time `json:"timestamp"`
+	// Payload is kept for dead letters so they can be inspected and replayed
+	Payload json.RawMessage `json:"payload,omitempty"`
+}
+
+// WebhookEventName maps an invite event onto the webhook event name
+// subscriptions filter on, e.g. "invite.sent"
+func WebhookEventName(event InviteEvent) string {
+	switch event.Type {
+	case EventInviteAdded:
+		return "invite.added"
+	case EventStatusChanged, EventDuplicateMarked:
+		if event.Status == StatusPending {
+			return "invite.reopened"
+		}
+		return "invite." + event.Status
+	default:
+		return ""
+	}
+}
+
+// SignWebhook returns the signature header value for a delivery. The
+// signature is an HMAC-SHA256 over the timestamp and body joined by a dot, so
+// receivers can reject replays of old deliveries.
+func SignWebhook(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	fmt.Fprintf(mac, "%d.", timestamp)
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// DeliveryLog stores webhook delivery records. When backed by a file, records
+// are appended as JSON lines so the API server and the sheets tool can share it.
+type DeliveryLog struct {
+	mu      sync.Mutex
+	path    string
+	entries []WebhookDelivery
+}
+
+// NewDeliveryLog creates a delivery log backed by path, or kept in memory if path is empty
+func NewDeliveryLog(path string) *DeliveryLog {
+	return &DeliveryLog{path: path}
+}
+
+// Record appends a delivery record
+func (l *DeliveryLog) Record(delivery WebhookDelivery) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.path == "" {
+		l.entries = append(l.entries, delivery)
+		if len(l.entries) > memoryDeliveryLogSize {
+			l.entries = l.entries[len(l.entries)-memoryDeliveryLogSize:]
+		}
+		return nil
+	}
+
+	return appendJSONLine(l.path, delivery)
+}
+
+// List returns up to limit delivery records, newest first, optionally
+// filtered by status. A limit of zero returns every record.
+func (l *DeliveryLog) List(status string, limit int) ([]WebhookDelivery, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	entries := l.entries
+	if l.path != "" {
+		var err error
+		if entries, err = readJSONLines[WebhookDelivery](l.path); err != nil {
+			return nil, err
+		}
+	}
+
+	result := []WebhookDelivery{}
+	for i := len(entries) - 1; i >= 0; i-- {
+		if status != "" && entries[i].Status != status {
+			continue
+		}
+		result = append(result, entries[i])
+		if limit > 0 && len(result) == limit {
+			break
+		}
+	}
+	return result, nil
+}
+
+// Erase drops the payload of every dead letter about email. The records of
+// the attempts are kept, but can no longer be replayed. It returns how many
+// payloads were dropped.
+func (l *DeliveryLog) Erase(email string) (int, error) {
+	email = NormalizeEmail(email)
+	return l.dropPayloads(false, func(delivery WebhookDelivery) bool {
+		var payload WebhookPayload
+		if email == "" || json.Unmarshal(delivery.Payload, &payload) != nil {
+			return false
+		}
+		if payload.Data.Invite != nil && NormalizeEmail(payload.Data.Invite.Email) == email {
+			return true
+		}
+		return NormalizeEmail(payload.Data.Email) == email
+	})
+}
+
+// Redact drops the payloads of dead letters recorded before the cutoff. It
+// returns how many were dropped, or with dryRun would be.
+func (l *DeliveryLog) Redact(before time.Time, dryRun bool) (int, error) {
+	return l.dropPayloads(dryRun, func(delivery WebhookDelivery) bool {
+		return delivery.Timestamp.Before(before)
+	})
+}
+
+// dropPayloads removes the payloads of the records with one that match
+func (l *DeliveryLog) dropPayloads(dryRun bool, match func(WebhookDelivery) bool) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	entries := slices.Clone(l.entries)
+	if l.path != "" {
+		// Hold the file's lock until it is rewritten, so appends wait
+		unlock, err := lockFile(l.path)
+		if err != nil {
+			return 0, err
+		}
+		defer unlock()
+		if entries, err = readJSONLines[WebhookDelivery](l.path); err != nil {
+			return 0, err
+		}
+	}
+
+	dropped := 0
+	for i := range entries {
+		if len(entries[i].Payload) == 0 || !match(entries[i]) {
+			continue
+		}
+		entries[i].Payload = nil
+		dropped++
+	}
+	if dropped == 0 || dryRun {
+		return dropped, nil
+	}
+	if l.path != "" {
+		return dropped, writeJSONLines(l.path, entries)
+	}
+	l.entries = entries
+	return dropped, nil
+}
+
+// WebhookDispatcher delivers invite events to webhook subscriptions in the
+// background, retrying failures with exponential backoff
+type WebhookDispatcher struct {
+	subscriptions []config.WebhookSubscription
+	deliveries    *DeliveryLog
+	client        *http.Client
+	logger        *slog.Logger
+	maxAttempts   int
+	backoff       time.Duration
+	wg            sync.WaitGroup
+	now           func() time.Time
+}
+
+// NewWebhookDispatcher creates a dispatcher for the given subscriptions
+func NewWebhookDispatcher(subscriptions []config.WebhookSubscription, deliveries *DeliveryLog, logger *slog.Logger) *WebhookDispatcher {
+	return &WebhookDispatcher{
+		subscriptions: subscriptions,
+		deliveries:    deliveries,
+		client:        &http.Client{Timeout: webhookTimeout},
+		logger:        logger,
+		maxAttempts:   defaultWebhookMaxAttempts,
+		backoff:       defaultWebhookBackoff,
+		now:           time.Now,
+	}
+}
+
+// Deliveries returns the dispatcher's delivery log
+func (d *WebhookDispatcher) Deliveries() *DeliveryLog {
+	return d.deliveries
+}
+
+// Dispatch sends event to every subscription whose filter matches. Delivery
+// happens in the background; call Wait to block until it has finished.
+func (d *WebhookDispatcher) Dispatch(event InviteEvent) {
+	name := WebhookEventName(event)
+	if name == "" {
+		return
+	}
+
+	payload := WebhookPayload{
+		ID:         uuid.New().String(),
+		Event:      name,
+		OccurredAt: event.OccurredAt,
+		Data:       event,
+	}
+	if payload.OccurredAt.IsZero() {
+		payload.OccurredAt = d.now()
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		d.logger.Error("failed to encode webhook payload", slog.String("error", err.Error()))
+		return
+	}
+
+	for _, sub := range d.subscriptions {
+		if !subscribedTo(sub, name) {
+			continue
+		}
+		d.wg.Add(1)
+		go func(sub config.WebhookSubscription) {
+			defer d.wg.Done()
+			d.deliver(context.Background(), sub, payload.ID, name, body)
+		}(sub)
+	}
+}
+
+// Wait blocks until every dispatched delivery has succeeded or been dead-lettered
+func (d *WebhookDispatcher) Wait() {
+	d.wg.Wait()
+}
+
+// deliver posts body to a subscription, retrying until it succeeds, a
+// non-retryable response is received or the attempts run out
+func (d *WebhookDispatcher) deliver(ctx context.Context, sub config.WebhookSubscription, id, event string, body []byte) {
+	log := d.logger.With(
+		slog.String("webhook", sub.Name),
+		slog.String("event", event),
+		slog.String("delivery_id", id),
+	)
+
+	backoff := d.backoff
+	for attempt := 1; attempt <= d.maxAttempts; attempt++ {
+		responseStatus, err := d.send(ctx, sub, id, event, body)
+
+		record := WebhookDelivery{
+			ID:             id,
+			Subscription:   sub.Name,
+			URL:            sub.URL,
+			Event:          event,
+			Attempt:        attempt,
+			ResponseStatus: responseStatus,
+			Timestamp:      d.now(),
+		}
+		if err != nil {
+			record.Error = err.Error()
+		}
+
+		switch {
+		case err == nil:
+			record.Status = DeliveryDelivered
+			log.Info("webhook delivered", slog.Int("attempt", attempt))
+		case attempt == d.maxAttempts || !retryableDelivery(responseStatus):
+			record.Status = DeliveryDeadLetter
+			record.Payload = body
+			log.Error("webhook dead-lettered", slog.Int("attempt", attempt), slog.String("error", err.Error()))
+		default:
+			record.Status = DeliveryRetrying
+			log.Warn("webhook delivery failed, retrying",
+				slog.Int("attempt", attempt),
+				slog.Duration("backoff", backoff),
+				slog.String("error", err.Error()),
+			)
+		}
+
+		if logErr := d.deliveries.Record(record); logErr != nil {
+			log.Error("failed to record webhook delivery", slog.String("error", logErr.Error()))
+		}
+		if record.Status != DeliveryRetrying {
+			return
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
+
+// send makes a single delivery attempt, returning the response status
+func (d *WebhookDispatcher) send(ctx context.Context, sub config.WebhookSubscription, id, event string, body []byte) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, sub.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	timestamp := d.now().Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "slack-invite-mgr-webhooks")
+	req.Header.Set(WebhookEventHeader, event)
+	req.Header.Set(WebhookDeliveryHeader, id)
+	req.Header.Set(WebhookTimestampHeader, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(WebhookSignatureHeader, SignWebhook(sub.Secret.Reveal(), timestamp, body))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("unexpected response status %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
+
+// retryableDelivery reports whether a failed attempt is worth retrying.
+// Network errors, timeouts, rate limiting and server errors are retried;
+// other client errors mean the receiver rejected the payload.
+func retryableDelivery(responseStatus int) bool {
+	return responseStatus == 0 ||
+		responseStatus == http.StatusRequestTimeout ||
+		responseStatus == http.StatusTooManyRequests ||
+		responseStatus >= http.StatusInternalServerError
+}
+
+// subscribedTo reports whether a subscription wants events with the given name
+func subscribedTo(sub config.WebhookSubscription, event string) bool {
+	if len(sub.Events) == 0 {
+		return true
+	}
+	for _, e := range sub.Events {
+		if e == "*" || e == event {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,225 @@
+package services
+
+import (
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stevebennett/slack-invite-mgr/backend/internal/config"
+)
+
+func TestWebhookEventName(t *testing.T) {
+	tests := []struct {
+		event    InviteEvent
+		expected string
+	}{
+		{InviteEvent{Type: EventInviteAdded, Status: StatusPending}, "invite.added"},
+		{InviteEvent{Type: EventStatusChanged, Status: StatusSent}, "invite.sent"},
+		{InviteEvent{Type: EventStatusChanged, Status: StatusDenied}, "invite.denied"},
+		{InviteEvent{Type: EventDuplicateMarked, Status: StatusDuplicate}, "invite.duplicate"},
+		{InviteEvent{Type: EventStatusChanged, Status: StatusPending}, "invite.reopened"},
+		{InviteEvent{Type: EventResync}, ""},
+	}
+
+	for _, tt := range tests {
+		if got := WebhookEventName(tt.event); got != tt.expected {
+			t.Errorf("WebhookEventName(%s/%s) = %q, want %q", tt.event.Type, tt.event.Status, got, tt.expected)
+		}
+	}
+}
+
+func TestWebhookDispatcher_Deliver(t *testing.T) {
+	tests := []struct {
+		name             string
+		responses        []int
+		events           []string
+		expectedRequests int
+		expectedStatuses []string
+	}{
+		{
+			name:             "delivered first time",
+			responses:        []int{http.StatusOK},
+			expectedRequests: 1,
+			expectedStatuses: []string{DeliveryDelivered},
+		},
+		{
+			name:             "retried after server error",
+			responses:        []int{http.StatusInternalServerError, http.StatusTooManyRequests, http.StatusNoContent},
+			expectedRequests: 3,
+			expectedStatuses: []string{DeliveryRetrying, DeliveryRetrying, DeliveryDelivered},
+		},
+		{
+			name:             "dead-lettered after max attempts",
+			responses:        []int{http.StatusBadGateway, http.StatusBadGateway, http.StatusBadGateway},
+			expectedRequests: 3,
+			expectedStatuses: []string{DeliveryRetrying, DeliveryRetrying, DeliveryDeadLetter},
+		},
+		{
+			name:             "client error is not retried",
+			responses:        []int{http.StatusBadRequest},
+			expectedRequests: 1,
+			expectedStatuses: []string{DeliveryDeadLetter},
+		},
+		{
+			name:             "filtered out by subscription",
+			events:           []string{"invite.denied"},
+			expectedRequests: 0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var mu sync.Mutex
+			requests := 0
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				body, _ := io.ReadAll(r.Body)
+				timestamp, _ := strconv.ParseInt(r.Header.Get(WebhookTimestampHeader), 10, 64)
+				if r.Header.Get(WebhookSignatureHeader) != SignWebhook("s3cret", timestamp, body) {
+					t.Errorf("Signature does not match body")
+				}
+				if r.Header.Get(WebhookEventHeader) != "invite.sent" {
+					t.Errorf("Expected event header invite.sent, got %q", r.Header.Get(WebhookEventHeader))
+				}
+
+				var payload WebhookPayload
+				if err := json.Unmarshal(body, &payload); err != nil || payload.Data.Email != "a@example.com" {
+					t.Errorf("Unexpected payload %s", body)
+				}
+
+				mu.Lock()
+				status := tt.responses[requests]
+				requests++
+				mu.Unlock()
+				w.WriteHeader(status)
+			}))
+			defer server.Close()
+
+			deliveries := NewDeliveryLog("")
+			dispatcher := NewWebhookDispatcher([]config.WebhookSubscription{
+				{Name: "crm", URL: server.URL, Events: tt.events, Secret: "s3cret"},
+			}, deliveries, slog.New(slog.NewTextHandler(io.Discard, nil)))
+			dispatcher.maxAttempts = 3
+			dispatcher.backoff = time.Millisecond
+
+			dispatcher.Dispatch(InviteEvent{Type: EventStatusChanged, Email: "a@example.com", Status: StatusSent})
+			dispatcher.Wait()
+
+			if requests != tt.expectedRequests {
+				t.Errorf("Expected %d requests, got %d", tt.expectedRequests, requests)
+			}
+
+			records, err := deliveries.List("", 0)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if len(records) != len(tt.expectedStatuses) {
+				t.Fatalf("Expected %d delivery records, got %d", len(tt.expectedStatuses), len(records))
+			}
+			for i, status := range tt.expectedStatuses {
+				// Records are listed newest first
+				record := records[len(records)-1-i]
+				if record.Status != status || record.Attempt != i+1 {
+					t.Errorf("Record %d: expected %s on attempt %d, got %s on attempt %d", i, status, i+1, record.Status, record.Attempt)
+				}
+				if (status == DeliveryDeadLetter) != (len(record.Payload) > 0) {
+					t.Errorf("Record %d: payload should only be kept for dead letters", i)
+				}
+			}
+		})
+	}
+}
+
+func TestDeliveryLog_File(t *testing.T) {
+	path := t.TempDir() + "/deliveries.jsonl"
+	log := NewDeliveryLog(path)
+	for _, status := range []string{DeliveryRetrying, DeliveryDeadLetter, DeliveryDelivered} {
+		if err := log.Record(WebhookDelivery{ID: status, Status: status}); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+
+	// A second log on the same file sees the same records
+	records, err := NewDeliveryLog(path).List(DeliveryDeadLetter, 0)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(records) != 1 || records[0].ID != DeliveryDeadLetter {
+		t.Errorf("Expected the dead letter record, got %+v", records)
+	}
+
+	records, _ = log.List("", 2)
+	if len(records) != 2 || records[0].Status != DeliveryDelivered {
+		t.Errorf("Expected the 2 newest records, got %+v", records)
+	}
+}
+
+func TestDeliveryLog_EraseAndRedact(t *testing.T) {
+	payload := func(data InviteEvent) []byte {
+		body, err := json.Marshal(WebhookPayload{ID: "evt", Event: "invite.status_changed", Data: data})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		return body
+	}
+	old := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	recent := old.AddDate(0, 6, 0)
+
+	for _, tc := range []struct {
+		name string
+		path string
+	}{
+		{"in memory", ""},
+		{"file", t.TempDir() + "/deliveries.jsonl"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			log := NewDeliveryLog(tc.path)
+			for _, record := range []WebhookDelivery{
+				{ID: "email", Status: DeliveryDeadLetter, Timestamp: recent, Payload: payload(InviteEvent{Email: "John@Example.com"})},
+				{ID: "invite", Status: DeliveryDeadLetter, Timestamp: recent, Payload: payload(InviteEvent{Invite: &Invite{Email: "john@example.com"}})},
+				{ID: "other", Status: DeliveryDeadLetter, Timestamp: old, Payload: payload(InviteEvent{Email: "jane@example.com"})},
+				{ID: "delivered", Status: DeliveryDelivered, Timestamp: old},
+			} {
+				if err := log.Record(record); err != nil {
+					t.Fatalf("Unexpected error: %v", err)
+				}
+			}
+			payloads := func() map[string]bool {
+				records, err := log.List("", 0)
+				if err != nil {
+					t.Fatalf("Unexpected error: %v", err)
+				}
+				result := make(map[string]bool, len(records))
+				for _, record := range records {
+					result[record.ID] = len(record.Payload) > 0
+				}
+				return result
+			}
+
+			erased, err := log.Erase("john@example.com")
+			if err != nil || erased != 2 {
+				t.Fatalf("Expected 2 payloads erased, got %d %v", erased, err)
+			}
+			if got, want := payloads(), map[string]bool{"email": false, "invite": false, "other": true, "delivered": false}; !reflect.DeepEqual(got, want) {
+				t.Errorf("Expected payloads %v, got %v", want, got)
+			}
+
+			cutoff := old.AddDate(0, 1, 0)
+			if redacted, err := log.Redact(cutoff, true); err != nil || redacted != 1 || !payloads()["other"] {
+				t.Errorf("Expected a dry run to count 1 payload and keep it, got %d %v", redacted, err)
+			}
+			if redacted, err := log.Redact(cutoff, false); err != nil || redacted != 1 || payloads()["other"] {
+				t.Errorf("Expected the old payload to be dropped, got %d %v", redacted, err)
+			}
+			if got := len(payloads()); got != 4 {
+				t.Errorf("Expected every record to be kept, got %d", got)
+			}
+		})
+	}
+}
@@ -1,7 +1,8 @@
-import { useEffect, useState } from 'react';
+import { useEffect, useRef, useState } from 'react';
 import { logger } from '../utils/logger';
 
 interface Invite {
+  submittedAt?: string;
   name: string;
   role: string;
   email: string;
@@ -10,8 +11,15 @@ interface Invite {
   reasons: string;
   source: string;
   status?: 'pending' | 'approved' | 'denied' | 'sent';
+  version?: string;
 }
 
+// An applicant who applies again has several rows with the same email, so a
+// submission is identified by its email and form timestamp. Events from
+// servers that don't send the timestamp match on the email alone.
+const isSameSubmission = (invite: Invite, email: string, submittedAt?: string) =>
+  invite.email === email && (submittedAt === undefined || invite.submittedAt === submittedAt);
+
 type WorkflowStep = 'screening' | 'send-invites' | 'slack-preparation' | 'mark-denied' | 'complete';
 
 export const InvitesTable = () => {
@@ -22,6 +30,21 @@ export const InvitesTable = () => {
   const [currentStep, setCurrentStep] = useState<WorkflowStep>('screening');
   const [copySuccess, setCopySuccess] = useState(false);
   const [updateStatus, setUpdateStatus] = useState<'idle' | 'loading' | 'success' | 'error'>('idle');
+  // Idempotency keys for in-flight status updates, so double clicks and
+  // retries of the same action are only applied once by the backend
+  const idempotencyKeys = useRef<Record<string, string>>({});
+
+  // Build an If-Match header from the row versions we loaded, so the backend
+  // rejects the update if another reviewer changed any of these invites
+  const getIfMatch = (selected: Invite[]) =>
+    selected.map(invite => `"${invite.version ?? ''}"`).join(', ');
+
+  const getIdempotencyKey = (operation: string) => {
+    if (!idempotencyKeys.current[operation]) {
+      idempotencyKeys.current[operation] = crypto.randomUUID();
+    }
+    return idempotencyKeys.current[operation];
+  };
 
   // Get the full URL for API calls using runtime API_URL configuration
   // API_URL is set at container startup via window.APP_CONFIG
@@ -38,7 +61,7 @@ export const InvitesTable = () => {
       }
       const data = await response.json();
       // Initialize all invites as pending
-      setInvites(data.map((invite: Invite) => ({ ...invite, status: 'pending' })));
+      setInvites(data.invites.map((invite: Invite) => ({ ...invite, status: 'pending' })));
     } catch (err) {
       setError(err instanceof Error ? err.message : 'An error occurred');
     } finally {
@@ -50,6 +73,66 @@ export const InvitesTable = () => {
     fetchInvites();
   }, []);
 
+  // Keep the queue up to date with changes made by other reviewers and the
+  // sheets tool. EventSource reconnects on its own and resumes from the last
+  // event it received.
+  useEffect(() => {
+    if (typeof EventSource === 'undefined') {
+      return;
+    }
+    const source = new EventSource(getApiUrl('/invites/stream'));
+
+    source.addEventListener('invite-added', (event) => {
+      const { invite } = JSON.parse((event as MessageEvent).data);
+      if (!invite || invite.status !== 'pending') {
+        return;
+      }
+      setInvites(prevInvites =>
+        prevInvites.some(existing => isSameSubmission(existing, invite.email, invite.submittedAt))
+          ? prevInvites
+          : [...prevInvites, { ...invite, status: 'pending' }]
+      );
+    });
+
+    // Invites processed elsewhere leave the queue; local approvals and
+    // denials of other invites are kept
+    const removeProcessed = (event: Event) => {
+      const { email, submittedAt, status } = JSON.parse((event as MessageEvent).data);
+      if (status === 'pending') {
+        return;
+      }
+      setInvites(prevInvites =>
+        prevInvites.filter(invite => !isSameSubmission(invite, email, submittedAt) || invite.status !== 'pending')
+      );
+    };
+    source.addEventListener('status-changed', removeProcessed);
+    source.addEventListener('duplicate-marked', removeProcessed);
+
+    // Events were missed, so reload the queue while keeping local decisions
+    source.addEventListener('resync', async () => {
+      try {
+        const response = await fetch(getApiUrl('/invites'));
+        if (!response.ok) {
+          throw new Error('Failed to fetch invites');
+        }
+        const data = await response.json();
+        setInvites(prevInvites =>
+          data.invites.map((invite: Invite) => ({
+            ...invite,
+            status: prevInvites.find(existing => isSameSubmission(existing, invite.email, invite.submittedAt))?.status ?? 'pending',
+          }))
+        );
+      } catch (err) {
+        logger.error('Failed to resync invites', {
+          error: err instanceof Error ? err.message : String(err),
+          operation: 'invite_stream_resync',
+        });
+      }
+    });
+
+    return () => source.close();
+  }, []);
+
   const handleApprove = (email: string) => {
     setInvites(invites.map(invite => 
       invite.email === email ? { ...invite, status: 'approved' } : invite
@@ -117,6 +200,8 @@ export const InvitesTable = () => {
         method: 'PATCH',
         headers: {
           'Content-Type': 'application/json',
+          'Idempotency-Key': getIdempotencyKey('mark_sent'),
+          'If-Match': getIfMatch(approvedInvites),
         },
         body: JSON.stringify({
           emails: approvedInvites.map(invite => invite.email),
@@ -127,6 +212,7 @@ export const InvitesTable = () => {
       if (!response.ok) {
         throw new Error('Failed to update invite statuses');
       }
+      delete idempotencyKeys.current.mark_sent;
 
       setUpdateStatus('success');
       // Update the status in our local state
@@ -157,6 +243,8 @@ export const InvitesTable = () => {
         method: 'PATCH',
         headers: {
           'Content-Type': 'application/json',
+          'Idempotency-Key': getIdempotencyKey('mark_denied'),
+          'If-Match': getIfMatch(deniedInvites),
         },
         body: JSON.stringify({
           emails: deniedInvites.map(invite => invite.email),
@@ -167,6 +255,7 @@ export const InvitesTable = () => {
       if (!response.ok) {
         throw new Error('Failed to update invite statuses');
       }
+      delete idempotencyKeys.current.mark_denied;
 
       setUpdateStatus('success');
       setCurrentStep('complete');
@@ -194,7 +283,7 @@ export const InvitesTable = () => {
         throw new Error('Failed to fetch invites');
       }
       const data = await response.json();
-      setInvites(data.map((invite: Invite) => ({ ...invite, status: 'pending' })));
+      setInvites(data.invites.map((invite: Invite) => ({ ...invite, status: 'pending' })));
     } catch (err) {
       setError(err instanceof Error ? err.message : 'An error occurred');
     }
@@ -49,7 +49,7 @@ describe('InvitesTable', () => {
     mockFetch.mockClear();
     mockFetch.mockResolvedValue({
       ok: true,
-      json: () => Promise.resolve(mockInvites),
+      json: () => Promise.resolve({ invites: mockInvites }),
     });
   });
 
@@ -143,7 +143,7 @@ describe('InvitesTable', () => {
     mockFetch
       .mockResolvedValueOnce({
         ok: true,
-        json: () => Promise.resolve(mockInvites),
+        json: () => Promise.resolve({ invites: mockInvites }),
       })
       .mockResolvedValueOnce({
         ok: true,
@@ -218,11 +218,11 @@ describe('InvitesTable', () => {
     mockFetch
       .mockResolvedValueOnce({
         ok: true,
-        json: () => Promise.resolve(mockInvites),
+        json: () => Promise.resolve({ invites: mockInvites }),
       })
       .mockResolvedValueOnce({
         ok: true,
-        json: () => Promise.resolve(mockInvites),
+        json: () => Promise.resolve({ invites: mockInvites }),
       });
 
     render(<InvitesTable />);
@@ -282,7 +282,7 @@ describe('InvitesTable', () => {
     mockFetch
       .mockResolvedValueOnce({
         ok: true,
-        json: () => Promise.resolve(mockInvites),
+        json: () => Promise.resolve({ invites: mockInvites }),
       })
       .mockRejectedValueOnce(new Error('Failed to update'));
 
@@ -329,7 +329,7 @@ describe('InvitesTable', () => {
     // Mock empty invites response
     mockFetch.mockResolvedValueOnce({
       ok: true,
-      json: () => Promise.resolve([]),
+      json: () => Promise.resolve({ invites: [] }),
     });
 
     render(<InvitesTable />);